@@ -1,55 +1,273 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/whyaji/daycare-preschool-api/config"
 	"github.com/whyaji/daycare-preschool-api/internal/delivery/http"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/repository"
 	"github.com/whyaji/daycare-preschool-api/internal/usecase"
 	"github.com/whyaji/daycare-preschool-api/pkg/database"
+	"github.com/whyaji/daycare-preschool-api/pkg/events"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/mailer"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/middleware"
+	"github.com/whyaji/daycare-preschool-api/pkg/push"
+	"github.com/whyaji/daycare-preschool-api/pkg/ratelimit"
+	"github.com/whyaji/daycare-preschool-api/pkg/storage"
 )
 
 func main() {
-	// Load env variables
-	config.LoadEnv()
-	cfg := config.GetConfig()
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err.Error())
+		os.Exit(1)
+	}
+	log := logger.Setup(cfg.LogFormat, cfg.LogLevel)
+	log.Info("effective config", "config", cfg.String())
+
+	// Cancelled on SIGINT/SIGTERM, background jobs stop when it is done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Connect to database
 	db, err := database.ConnectDb(cfg)
 	if err != nil {
-		panic("Failed to connect to database")
+		log.Error("failed to connect to database", "error", err.Error())
+		os.Exit(1)
+	}
+	if err := metrics.RegisterGorm(db, cfg.DBDatabase); err != nil {
+		log.Error("failed to register database metrics", "error", err.Error())
+		os.Exit(1)
 	}
 
 	// Create a new Fiber instance
 	app := fiber.New(fiber.Config{
-		AppName: cfg.AppName,
+		AppName:      cfg.AppName,
+		ErrorHandler: middleware.ErrorHandler,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+		ProxyHeader:  cfg.HTTPProxyHeader,
+		// Room for an attachment and the rest of its multipart form
+		BodyLimit: (cfg.AttachmentMaxSizeMB + 1) << 20,
 	})
+	app.Use(middleware.RequestID())
+	app.Use(metrics.Middleware())
+	app.Use(middleware.RequestLogger(log))
+	app.Use(recover.New())
+
+	// In-memory rate limit counters, swap for a shared store when running
+	// more than one instance
+	rateLimitStore := ratelimit.NewMemoryStore()
 
-	// Group routes
-	api := app.Group("/api/v1")
+	// Health module
+	healthRepo := repository.NewHealthRepository(db)
+	healthUsecase := usecase.NewHealthUsecase(healthRepo, database.SchemaVersion)
+
+	// Emails are only logged until an SMTP relay is configured
+	var mail mailer.Mailer = mailer.NewLogMailer(!cfg.IsProduction())
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUserName, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+
+	// Event module, in-process pub/sub for the event stream, swap the broker
+	// for a shared one when running more than one instance
+	broker := events.NewMemoryBroker(cfg.EventReplaySize)
+	eventRepo := repository.NewEventRepository(db)
+	eventUsecase := usecase.NewEventUsecase(eventRepo, broker, cfg)
 
 	// User module
 	userRepo := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepo)
-	http.NewUserHandler(api, userUsecase)
+	userUsecase := usecase.NewUserUsecase(userRepo, cfg, rateLimitStore, mail)
+
+	// Role module
+	roleRepo := repository.NewRoleRepository(db)
+	roleUsecase := usecase.NewRoleUsecase(roleRepo)
+
+	// Two-factor module
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	twoFactorUsecase, err := usecase.NewTwoFactorUsecase(twoFactorRepo, userRepo, cfg, rateLimitStore)
+	if err != nil {
+		log.Error("failed to set up two-factor authentication", "error", err.Error())
+		os.Exit(1)
+	}
 
 	// Child module
 	childRepo := repository.NewChildRepository(db)
 	childUsecase := usecase.NewChildUsecase(childRepo)
-	http.NewChildHandler(api, childUsecase)
+
+	// Classroom module, attendance changes re-check its ratios
+	classroomRepo := repository.NewClassroomRepository(db)
+	classroomUsecase := usecase.NewClassroomUsecase(classroomRepo)
+
+	// Enrollment module, only enrolled children may attend
+	enrollmentRepo := repository.NewEnrollmentRepository(db)
+	enrollmentUsecase := usecase.NewEnrollmentUsecase(enrollmentRepo, cfg)
+
+	// Calendar module, attendance follows its opening hours
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarUsecase := usecase.NewCalendarUsecase(calendarRepo, cfg)
+
+	// Absence module, expected attendance follows the schedules and the
+	// calendar
+	absenceRepo := repository.NewAbsenceRepository(db)
+	absenceUsecase := usecase.NewAbsenceUsecase(absenceRepo, calendarUsecase, cfg)
+
+	// Medical module
+	medicalRepo := repository.NewMedicalRepository(db)
+	medicalUsecase := usecase.NewMedicalUsecase(medicalRepo)
+
+	// Medication module, doses are recorded on the child diary
+	medicationRepo := repository.NewMedicationRepository(db)
+	medicationUsecase := usecase.NewMedicationUsecase(medicationRepo, cfg, eventUsecase)
+
+	// Incident module, serious incidents are mailed to the admins
+	incidentRepo := repository.NewIncidentRepository(db)
+	incidentUsecase := usecase.NewIncidentUsecase(incidentRepo, cfg, mail, eventUsecase)
+
+	// Attachment module, files are kept on the local disk or in an
+	// S3-compatible bucket
+	var store storage.Storage
+	if cfg.StorageDriver == "s3" {
+		store, err = storage.NewS3Storage(cfg.StorageS3Endpoint, cfg.StorageS3Region, cfg.StorageS3Bucket, cfg.StorageS3AccessKey, cfg.StorageS3SecretKey)
+	} else {
+		store, err = storage.NewLocalStorage(cfg.StorageLocalPath)
+	}
+	if err != nil {
+		log.Error("failed to set up attachment storage", "error", err.Error())
+		os.Exit(1)
+	}
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	attachmentUsecase := usecase.NewAttachmentUsecase(attachmentRepo, store, cfg)
+
+	// Photo feed module, photos are stored as attachments of the posts
+	photoRepo := repository.NewPhotoRepository(db)
+	photoUsecase := usecase.NewPhotoUsecase(photoRepo, attachmentUsecase)
+
+	// Messaging module, files of messages are stored as attachments
+	messageRepo := repository.NewMessageRepository(db)
+	messageUsecase := usecase.NewMessageUsecase(messageRepo, attachmentUsecase, eventUsecase)
+
+	// Notification module, deliveries wait in an outbox table and are sent
+	// in the background
+	var pusher push.Pusher = push.NewLogPusher()
+	if cfg.PushEndpoint != "" {
+		pusher = push.NewHTTPPusher(cfg.PushEndpoint, cfg.PushServerKey)
+	}
+	notificationRepo := repository.NewNotificationRepository(db)
+	notificationUsecase := usecase.NewNotificationUsecase(notificationRepo, calendarUsecase, cfg,
+		usecase.NewEmailSender(mail),
+		usecase.NewPushSender(notificationRepo, pusher),
+		usecase.NewInboxSender(notificationRepo),
+	)
+
+	// Webhook module, attendance changes write their events to an outbox
+	// table in the same transaction and a background worker posts them
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookUsecase, err := usecase.NewWebhookUsecase(webhookRepo, cfg)
+	if err != nil {
+		log.Error("failed to set up webhooks", "error", err.Error())
+		os.Exit(1)
+	}
 
 	// Teacher Attendance module
 	teacherAttendanceRepo := repository.NewTeacherAttendanceRepository(db)
-	teacherAttendanceUsecase := usecase.NewTeacherAttendanceUsecase(teacherAttendanceRepo)
-	http.NewTeacherAttendanceHandler(api, teacherAttendanceUsecase)
+	teacherAttendanceUsecase := usecase.NewTeacherAttendanceUsecase(teacherAttendanceRepo, calendarUsecase, classroomUsecase, eventUsecase)
 
 	// Child Attendance module
 	childAttendanceRepo := repository.NewChildAttendanceRepository(db)
-	childAttendanceUsecase := usecase.NewChildAttendanceUsecase(childAttendanceRepo)
-	http.NewChildAttendanceHandler(api, childAttendanceUsecase)
+	childAttendanceUsecase := usecase.NewChildAttendanceUsecase(childAttendanceRepo, enrollmentUsecase, calendarUsecase, classroomUsecase, eventUsecase, notificationUsecase)
+
+	// Job queue, jobs wait in a table and a worker pool on every instance
+	// runs them. Scheduled jobs are queued once per occurrence.
+	jobRepo := repository.NewJobRepository(db)
+	jobUsecase, err := usecase.NewJobUsecase(jobRepo, cfg,
+		[]usecase.JobHandler{
+			usecase.HandleJob(domain.JobCheckClockOuts, func(ctx context.Context, _ struct{}) error {
+				return notificationUsecase.CheckClockOuts(ctx)
+			}),
+		},
+		[]usecase.JobSchedule{
+			{Name: "check-clock-outs", Spec: "*/5 * * * *", Type: domain.JobCheckClockOuts},
+			{Name: "clean-up-jobs", Spec: "30 3 * * *", Type: domain.JobCleanUpJobs},
+		},
+	)
+	if err != nil {
+		log.Error("failed to set up the job queue", "error", err.Error())
+		os.Exit(1)
+	}
+
+	http.RegisterRoutes(app, http.Services{
+		AppName:                  cfg.AppName,
+		Auth:                     middleware.NewJWTProtected(cfg, userUsecase),
+		StreamAuth:               middleware.NewJWTStreamProtected(cfg, userUsecase),
+		AuthRateLimit:            ratelimit.Middleware(rateLimitStore, ratelimit.Rule{Name: "auth", Max: cfg.AuthRateLimitMax, Window: cfg.AuthRateLimitWindow}),
+		UserUsecase:              userUsecase,
+		TwoFactorUsecase:         twoFactorUsecase,
+		RoleUsecase:              roleUsecase,
+		ChildUsecase:             childUsecase,
+		ClassroomUsecase:         classroomUsecase,
+		EnrollmentUsecase:        enrollmentUsecase,
+		CalendarUsecase:          calendarUsecase,
+		AbsenceUsecase:           absenceUsecase,
+		MedicalUsecase:           medicalUsecase,
+		MedicationUsecase:        medicationUsecase,
+		IncidentUsecase:          incidentUsecase,
+		AttachmentUsecase:        attachmentUsecase,
+		PhotoUsecase:             photoUsecase,
+		MessageUsecase:           messageUsecase,
+		TeacherAttendanceUsecase: teacherAttendanceUsecase,
+		ChildAttendanceUsecase:   childAttendanceUsecase,
+		NotificationUsecase:      notificationUsecase,
+		WebhookUsecase:           webhookUsecase,
+		JobUsecase:               jobUsecase,
+		EventUsecase:             eventUsecase,
+		HealthUsecase:            healthUsecase,
+	})
+
+	// Background work stops with ctx
+	var background sync.WaitGroup
+	background.Go(func() { notificationUsecase.Run(ctx) })
+	background.Go(func() { webhookUsecase.Run(ctx) })
+	background.Go(func() { jobUsecase.Run(ctx) })
 
 	// Start server
-	log.Fatal(app.Listen(cfg.AppPort))
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- app.Listen(cfg.AppPort)
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Error("server stopped", "error", err.Error())
+		_ = database.Close(db)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Drain: fail readiness first, then let in-flight requests finish
+	log.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+	healthUsecase.MarkShuttingDown()
+	// Event streams never finish on their own, end them before draining
+	broker.Close()
+	if err := app.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+		log.Error("graceful shutdown failed", "error", err.Error())
+	}
+	background.Wait()
+
+	if err := database.Close(db); err != nil {
+		log.Error("failed to close database", "error", err.Error())
+	}
+	log.Info("shutdown complete")
 }
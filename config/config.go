@@ -1,14 +1,28 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+
+	defaultJWTSecret   = "secret"
+	minJWTSecretLength = 32
+)
+
 type Config struct {
+	AppEnv       string
 	AppName      string
 	AppPort      string
 	DBConnection string
@@ -18,65 +32,596 @@ type Config struct {
 	DBUserName   string
 	DBPassword   string
 	JWTSecret    string
+	JWTTTL       time.Duration
+	LogLevel     string
+	LogFormat    string
+	DBSlowQuery  time.Duration
+
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	DBConnectTimeout  time.Duration
+
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+	ShutdownTimeout  time.Duration
+	HTTPProxyHeader  string
+
+	AuthRateLimitMax    int
+	AuthRateLimitWindow time.Duration
+	LoginLockoutMax     int
+	LoginLockoutWindow  time.Duration
+	LoginLockoutBase    time.Duration
+	LoginLockoutCap     time.Duration
+
+	TOTPIssuer        string
+	TOTPRequiredRoles []string
+	TOTPEncryptionKey string
+	MFAChallengeTTL   time.Duration
+
+	AppBaseURL           string
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUserName         string
+	SMTPPassword         string
+	SMTPFrom             string
+	EmailVerificationTTL time.Duration
+
+	EnrollmentOfferTTL time.Duration
+	ChildArrivalCutoff string
+
+	CenterOpens    string
+	CenterCloses   string
+	CenterWorkdays []string
+
+	MedicationDoseGrace time.Duration
+
+	StorageDriver       string
+	StorageLocalPath    string
+	StorageS3Endpoint   string
+	StorageS3Region     string
+	StorageS3Bucket     string
+	StorageS3AccessKey  string
+	StorageS3SecretKey  string
+	AttachmentMaxSizeMB int
+	AttachmentURLTTL    time.Duration
+	AttachmentURLKey    string
+
+	EventReplaySize int
+	EventHeartbeat  time.Duration
+
+	NotificationPollInterval  time.Duration
+	NotificationBatchSize     int
+	NotificationMaxAttempts   int
+	NotificationRetryBackoff  time.Duration
+	NotificationClockOutGrace time.Duration
+	PushEndpoint              string
+	PushServerKey             string
+
+	WebhookEncryptionKey string
+	WebhookPollInterval  time.Duration
+	WebhookBatchSize     int
+	WebhookMaxAttempts   int
+	WebhookRetryBackoff  time.Duration
+	WebhookTimeout       time.Duration
+
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobMaxAttempts  int
+	JobRetryBackoff time.Duration
+	JobTimeout      time.Duration
+	JobRetention    time.Duration
 }
 
-func LoadEnv() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+// Weekdays accepted in CENTER_WORKDAYS
+var workdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Load reads the configuration once at startup. Values are resolved in this
+// order: KEY_FILE (secret mounted as a file), KEY from the environment, then
+// the optional .env file, then the built-in default. Setting APP_ENV_ONLY=true
+// skips the .env file entirely.
+func Load() (*Config, error) {
+	if !envOnly() {
+		if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("loading .env file: %w", err)
+		}
+	}
+
+	l := &loader{}
+	cfg := &Config{
+		AppEnv:       strings.ToLower(l.getString("APP_ENV", EnvDevelopment)),
+		AppPort:      l.getString("APP_PORT", ":8080"),
+		AppName:      l.getString("APP_NAME", "Daycare Preschool API"),
+		DBConnection: l.getString("DB_CONNECTION", "mysql"),
+		DBHost:       l.getString("DB_HOST", "localhost"),
+		DBPort:       l.getInt("DB_PORT", 3306),
+		DBDatabase:   l.getString("DB_DATABASE", "daycare"),
+		DBUserName:   l.getString("DB_USERNAME", "root"),
+		DBPassword:   l.getString("DB_PASSWORD", ""),
+		JWTSecret:    l.getString("JWT_SECRET", defaultJWTSecret),
+		JWTTTL:       l.getDuration("JWT_TTL", 24*time.Hour),
+		LogLevel:     strings.ToLower(l.getString("LOG_LEVEL", "info")),
+		LogFormat:    strings.ToLower(l.getString("LOG_FORMAT", "json")),
+		DBSlowQuery:  l.getDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+
+		DBMaxOpenConns:    l.getInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:    l.getInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime: l.getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime: l.getDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		DBConnectTimeout:  l.getDuration("DB_CONNECT_TIMEOUT", 5*time.Second),
+
+		HTTPReadTimeout:  l.getDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		HTTPWriteTimeout: l.getDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		HTTPIdleTimeout:  l.getDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		ShutdownTimeout:  l.getDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		HTTPProxyHeader:  l.getString("HTTP_PROXY_HEADER", ""),
+
+		AuthRateLimitMax:    l.getInt("AUTH_RATE_LIMIT_MAX", 20),
+		AuthRateLimitWindow: l.getDuration("AUTH_RATE_LIMIT_WINDOW", time.Minute),
+		LoginLockoutMax:     l.getInt("LOGIN_LOCKOUT_MAX_FAILURES", 5),
+		LoginLockoutWindow:  l.getDuration("LOGIN_LOCKOUT_WINDOW", 15*time.Minute),
+		LoginLockoutBase:    l.getDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutCap:     l.getDuration("LOGIN_LOCKOUT_CAP", time.Hour),
+
+		TOTPIssuer:        l.getString("TOTP_ISSUER", ""),
+		TOTPRequiredRoles: l.getList("TOTP_REQUIRED_ROLES", []string{"admin"}),
+		TOTPEncryptionKey: l.getString("TOTP_ENCRYPTION_KEY", ""),
+		MFAChallengeTTL:   l.getDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+
+		AppBaseURL:           strings.TrimRight(l.getString("APP_BASE_URL", "http://localhost:8080"), "/"),
+		SMTPHost:             l.getString("SMTP_HOST", ""),
+		SMTPPort:             l.getInt("SMTP_PORT", 587),
+		SMTPUserName:         l.getString("SMTP_USERNAME", ""),
+		SMTPPassword:         l.getString("SMTP_PASSWORD", ""),
+		SMTPFrom:             l.getString("SMTP_FROM", ""),
+		EmailVerificationTTL: l.getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+
+		EnrollmentOfferTTL: l.getDuration("ENROLLMENT_OFFER_TTL", 7*24*time.Hour),
+		ChildArrivalCutoff: l.getString("CHILD_ARRIVAL_CUTOFF", "09:00"),
+
+		CenterOpens:    l.getString("CENTER_OPENS", "08:00"),
+		CenterCloses:   l.getString("CENTER_CLOSES", "16:00"),
+		CenterWorkdays: l.getList("CENTER_WORKDAYS", []string{"mon", "tue", "wed", "thu", "fri"}),
+
+		MedicationDoseGrace: l.getDuration("MEDICATION_DOSE_GRACE", 30*time.Minute),
+
+		StorageDriver:       strings.ToLower(l.getString("STORAGE_DRIVER", "local")),
+		StorageLocalPath:    l.getString("STORAGE_LOCAL_PATH", "storage"),
+		StorageS3Endpoint:   l.getString("STORAGE_S3_ENDPOINT", ""),
+		StorageS3Region:     l.getString("STORAGE_S3_REGION", "us-east-1"),
+		StorageS3Bucket:     l.getString("STORAGE_S3_BUCKET", ""),
+		StorageS3AccessKey:  l.getString("STORAGE_S3_ACCESS_KEY", ""),
+		StorageS3SecretKey:  l.getString("STORAGE_S3_SECRET_KEY", ""),
+		AttachmentMaxSizeMB: l.getInt("ATTACHMENT_MAX_SIZE_MB", 10),
+		AttachmentURLTTL:    l.getDuration("ATTACHMENT_URL_TTL", 15*time.Minute),
+		AttachmentURLKey:    l.getString("ATTACHMENT_URL_KEY", ""),
+
+		EventReplaySize: l.getInt("EVENT_REPLAY_SIZE", 1000),
+		EventHeartbeat:  l.getDuration("EVENT_HEARTBEAT", 20*time.Second),
+
+		NotificationPollInterval:  l.getDuration("NOTIFICATION_POLL_INTERVAL", 10*time.Second),
+		NotificationBatchSize:     l.getInt("NOTIFICATION_BATCH_SIZE", 50),
+		NotificationMaxAttempts:   l.getInt("NOTIFICATION_MAX_ATTEMPTS", 5),
+		NotificationRetryBackoff:  l.getDuration("NOTIFICATION_RETRY_BACKOFF", time.Minute),
+		NotificationClockOutGrace: l.getDuration("NOTIFICATION_CLOCK_OUT_GRACE", time.Hour),
+		PushEndpoint:              l.getString("PUSH_ENDPOINT", ""),
+		PushServerKey:             l.getString("PUSH_SERVER_KEY", ""),
+
+		WebhookEncryptionKey: l.getString("WEBHOOK_ENCRYPTION_KEY", ""),
+		WebhookPollInterval:  l.getDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		WebhookBatchSize:     l.getInt("WEBHOOK_BATCH_SIZE", 50),
+		WebhookMaxAttempts:   l.getInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBackoff:  l.getDuration("WEBHOOK_RETRY_BACKOFF", 30*time.Second),
+		WebhookTimeout:       l.getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		JobWorkers:      l.getInt("JOB_WORKERS", 4),
+		JobPollInterval: l.getDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		JobMaxAttempts:  l.getInt("JOB_MAX_ATTEMPTS", 5),
+		JobRetryBackoff: l.getDuration("JOB_RETRY_BACKOFF", 30*time.Second),
+		JobTimeout:      l.getDuration("JOB_TIMEOUT", 10*time.Minute),
+		JobRetention:    l.getDuration("JOB_RETENTION", 30*24*time.Hour),
+	}
+	if cfg.TOTPIssuer == "" {
+		cfg.TOTPIssuer = cfg.AppName
+	}
+	// Outside production the TOTP secrets may be encrypted with the JWT
+	// secret, production requires a dedicated key
+	if cfg.TOTPEncryptionKey == "" && !cfg.IsProduction() {
+		cfg.TOTPEncryptionKey = cfg.JWTSecret
+	}
+	// Same for the key signing attachment download URLs
+	if cfg.AttachmentURLKey == "" && !cfg.IsProduction() {
+		cfg.AttachmentURLKey = cfg.JWTSecret
+	}
+	// And for the webhook signing secrets
+	if cfg.WebhookEncryptionKey == "" && !cfg.IsProduction() {
+		cfg.WebhookEncryptionKey = cfg.JWTSecret
+	}
+
+	if len(l.errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(l.errs, "; "))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is usable and, in production, that
+// none of the insecure development defaults are still in place.
+func (c *Config) Validate() error {
+	var errs []string
+
+	switch c.AppEnv {
+	case EnvDevelopment, EnvStaging, EnvProduction:
+	default:
+		errs = append(errs, fmt.Sprintf("APP_ENV must be one of %s, %s, %s", EnvDevelopment, EnvStaging, EnvProduction))
+	}
+	if c.AppPort == "" {
+		errs = append(errs, "APP_PORT is required")
+	}
+	if c.DBConnection != "mysql" {
+		errs = append(errs, "DB_CONNECTION must be mysql")
+	}
+	if c.DBHost == "" {
+		errs = append(errs, "DB_HOST is required")
+	}
+	if c.DBPort <= 0 || c.DBPort > 65535 {
+		errs = append(errs, "DB_PORT must be between 1 and 65535")
+	}
+	if c.DBDatabase == "" {
+		errs = append(errs, "DB_DATABASE is required")
+	}
+	if c.DBUserName == "" {
+		errs = append(errs, "DB_USERNAME is required")
+	}
+	if c.JWTSecret == "" {
+		errs = append(errs, "JWT_SECRET is required")
+	}
+	if c.JWTTTL <= 0 {
+		errs = append(errs, "JWT_TTL must be positive")
+	}
+	if c.DBMaxOpenConns <= 0 {
+		errs = append(errs, "DB_MAX_OPEN_CONNS must be positive")
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		errs = append(errs, "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, "SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.AuthRateLimitMax <= 0 || c.AuthRateLimitWindow <= 0 {
+		errs = append(errs, "AUTH_RATE_LIMIT_MAX and AUTH_RATE_LIMIT_WINDOW must be positive")
+	}
+	if c.LoginLockoutMax <= 0 || c.LoginLockoutWindow <= 0 || c.LoginLockoutBase <= 0 || c.LoginLockoutCap < c.LoginLockoutBase {
+		errs = append(errs, "LOGIN_LOCKOUT_* must be positive and LOGIN_LOCKOUT_CAP at least LOGIN_LOCKOUT_BASE")
+	}
+	if c.MFAChallengeTTL <= 0 {
+		errs = append(errs, "MFA_CHALLENGE_TTL must be positive")
+	}
+	if c.TOTPEncryptionKey == "" {
+		errs = append(errs, "TOTP_ENCRYPTION_KEY is required")
+	}
+	if c.AppBaseURL == "" {
+		errs = append(errs, "APP_BASE_URL is required")
 	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		errs = append(errs, "SMTP_FROM is required when SMTP_HOST is set")
+	}
+	if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+		errs = append(errs, "SMTP_PORT must be between 1 and 65535")
+	}
+	if c.EmailVerificationTTL <= 0 {
+		errs = append(errs, "EMAIL_VERIFICATION_TTL must be positive")
+	}
+	if c.EnrollmentOfferTTL <= 0 {
+		errs = append(errs, "ENROLLMENT_OFFER_TTL must be positive")
+	}
+	if _, err := time.Parse("15:04", c.ChildArrivalCutoff); err != nil {
+		errs = append(errs, "CHILD_ARRIVAL_CUTOFF must be a time such as 09:00")
+	}
+	opens, openErr := time.Parse("15:04", c.CenterOpens)
+	closes, closeErr := time.Parse("15:04", c.CenterCloses)
+	if openErr != nil || closeErr != nil || !closes.After(opens) {
+		errs = append(errs, "CENTER_OPENS and CENTER_CLOSES must be times such as 08:00 and CENTER_CLOSES after CENTER_OPENS")
+	}
+	for _, day := range c.CenterWorkdays {
+		if !slices.Contains(workdayNames, day) {
+			errs = append(errs, "CENTER_WORKDAYS must only contain "+strings.Join(workdayNames, ", "))
+			break
+		}
+	}
+	if c.MedicationDoseGrace <= 0 {
+		errs = append(errs, "MEDICATION_DOSE_GRACE must be positive")
+	}
+	switch c.StorageDriver {
+	case "local":
+		if c.StorageLocalPath == "" {
+			errs = append(errs, "STORAGE_LOCAL_PATH is required when STORAGE_DRIVER is local")
+		}
+	case "s3":
+		if c.StorageS3Endpoint == "" || c.StorageS3Region == "" || c.StorageS3Bucket == "" || c.StorageS3AccessKey == "" || c.StorageS3SecretKey == "" {
+			errs = append(errs, "STORAGE_S3_ENDPOINT, STORAGE_S3_REGION, STORAGE_S3_BUCKET, STORAGE_S3_ACCESS_KEY and STORAGE_S3_SECRET_KEY are required when STORAGE_DRIVER is s3")
+		}
+	default:
+		errs = append(errs, "STORAGE_DRIVER must be local or s3")
+	}
+	if c.AttachmentMaxSizeMB <= 0 {
+		errs = append(errs, "ATTACHMENT_MAX_SIZE_MB must be positive")
+	}
+	if c.AttachmentURLTTL <= 0 {
+		errs = append(errs, "ATTACHMENT_URL_TTL must be positive")
+	}
+	if c.AttachmentURLKey == "" {
+		errs = append(errs, "ATTACHMENT_URL_KEY is required")
+	}
+	if c.EventReplaySize < 0 {
+		errs = append(errs, "EVENT_REPLAY_SIZE must not be negative")
+	}
+	if c.EventHeartbeat <= 0 || c.EventHeartbeat >= c.HTTPWriteTimeout {
+		errs = append(errs, "EVENT_HEARTBEAT must be positive and shorter than HTTP_WRITE_TIMEOUT")
+	}
+	if c.NotificationPollInterval <= 0 {
+		errs = append(errs, "NOTIFICATION_POLL_INTERVAL must be positive")
+	}
+	if c.NotificationBatchSize <= 0 {
+		errs = append(errs, "NOTIFICATION_BATCH_SIZE must be positive")
+	}
+	if c.NotificationMaxAttempts <= 0 {
+		errs = append(errs, "NOTIFICATION_MAX_ATTEMPTS must be positive")
+	}
+	if c.NotificationRetryBackoff <= 0 {
+		errs = append(errs, "NOTIFICATION_RETRY_BACKOFF must be positive")
+	}
+	if c.NotificationClockOutGrace < 0 {
+		errs = append(errs, "NOTIFICATION_CLOCK_OUT_GRACE must not be negative")
+	}
+	if c.PushEndpoint != "" && c.PushServerKey == "" {
+		errs = append(errs, "PUSH_SERVER_KEY is required when PUSH_ENDPOINT is set")
+	}
+	if c.WebhookEncryptionKey == "" {
+		errs = append(errs, "WEBHOOK_ENCRYPTION_KEY is required")
+	}
+	if c.WebhookPollInterval <= 0 {
+		errs = append(errs, "WEBHOOK_POLL_INTERVAL must be positive")
+	}
+	if c.WebhookBatchSize <= 0 {
+		errs = append(errs, "WEBHOOK_BATCH_SIZE must be positive")
+	}
+	if c.WebhookMaxAttempts <= 0 {
+		errs = append(errs, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	if c.WebhookRetryBackoff <= 0 {
+		errs = append(errs, "WEBHOOK_RETRY_BACKOFF must be positive")
+	}
+	if c.WebhookTimeout <= 0 {
+		errs = append(errs, "WEBHOOK_TIMEOUT must be positive")
+	}
+	if c.JobWorkers <= 0 {
+		errs = append(errs, "JOB_WORKERS must be positive")
+	}
+	if c.JobPollInterval <= 0 {
+		errs = append(errs, "JOB_POLL_INTERVAL must be positive")
+	}
+	if c.JobMaxAttempts <= 0 {
+		errs = append(errs, "JOB_MAX_ATTEMPTS must be positive")
+	}
+	if c.JobRetryBackoff <= 0 {
+		errs = append(errs, "JOB_RETRY_BACKOFF must be positive")
+	}
+	if c.JobTimeout <= 0 {
+		errs = append(errs, "JOB_TIMEOUT must be positive")
+	}
+	if c.JobRetention <= 0 {
+		errs = append(errs, "JOB_RETENTION must be positive")
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, "LOG_LEVEL must be one of debug, info, warn, error")
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, "LOG_FORMAT must be json or text")
+	}
+
+	if c.IsProduction() {
+		if c.JWTSecret == defaultJWTSecret || len(c.JWTSecret) < minJWTSecretLength {
+			errs = append(errs, fmt.Sprintf("JWT_SECRET must be at least %d characters and not the default in production", minJWTSecretLength))
+		}
+		if c.DBPassword == "" {
+			errs = append(errs, "DB_PASSWORD must be set in production")
+		}
+		if len(c.TOTPEncryptionKey) < minJWTSecretLength || c.TOTPEncryptionKey == c.JWTSecret {
+			errs = append(errs, fmt.Sprintf("TOTP_ENCRYPTION_KEY must be at least %d characters and differ from JWT_SECRET in production", minJWTSecretLength))
+		}
+		if len(c.AttachmentURLKey) < minJWTSecretLength || c.AttachmentURLKey == c.JWTSecret {
+			errs = append(errs, fmt.Sprintf("ATTACHMENT_URL_KEY must be at least %d characters and differ from JWT_SECRET in production", minJWTSecretLength))
+		}
+		if len(c.WebhookEncryptionKey) < minJWTSecretLength || c.WebhookEncryptionKey == c.JWTSecret {
+			errs = append(errs, fmt.Sprintf("WEBHOOK_ENCRYPTION_KEY must be at least %d characters and differ from JWT_SECRET in production", minJWTSecretLength))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == EnvProduction
+}
+
+// String prints the effective configuration with secrets masked, so it is
+// safe to log at startup.
+func (c *Config) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "APP_ENV=%s ", c.AppEnv)
+	fmt.Fprintf(&b, "APP_NAME=%q ", c.AppName)
+	fmt.Fprintf(&b, "APP_PORT=%s ", c.AppPort)
+	fmt.Fprintf(&b, "DB_CONNECTION=%s ", c.DBConnection)
+	fmt.Fprintf(&b, "DB_HOST=%s ", c.DBHost)
+	fmt.Fprintf(&b, "DB_PORT=%d ", c.DBPort)
+	fmt.Fprintf(&b, "DB_DATABASE=%s ", c.DBDatabase)
+	fmt.Fprintf(&b, "DB_USERNAME=%s ", c.DBUserName)
+	fmt.Fprintf(&b, "DB_PASSWORD=%s ", mask(c.DBPassword))
+	fmt.Fprintf(&b, "JWT_SECRET=%s ", mask(c.JWTSecret))
+	fmt.Fprintf(&b, "JWT_TTL=%s ", c.JWTTTL)
+	fmt.Fprintf(&b, "LOG_LEVEL=%s ", c.LogLevel)
+	fmt.Fprintf(&b, "LOG_FORMAT=%s ", c.LogFormat)
+	fmt.Fprintf(&b, "DB_SLOW_QUERY_THRESHOLD=%s ", c.DBSlowQuery)
+	fmt.Fprintf(&b, "DB_MAX_OPEN_CONNS=%d ", c.DBMaxOpenConns)
+	fmt.Fprintf(&b, "DB_MAX_IDLE_CONNS=%d ", c.DBMaxIdleConns)
+	fmt.Fprintf(&b, "DB_CONN_MAX_LIFETIME=%s ", c.DBConnMaxLifetime)
+	fmt.Fprintf(&b, "DB_CONN_MAX_IDLE_TIME=%s ", c.DBConnMaxIdleTime)
+	fmt.Fprintf(&b, "DB_CONNECT_TIMEOUT=%s ", c.DBConnectTimeout)
+	fmt.Fprintf(&b, "HTTP_READ_TIMEOUT=%s ", c.HTTPReadTimeout)
+	fmt.Fprintf(&b, "HTTP_WRITE_TIMEOUT=%s ", c.HTTPWriteTimeout)
+	fmt.Fprintf(&b, "HTTP_IDLE_TIMEOUT=%s ", c.HTTPIdleTimeout)
+	fmt.Fprintf(&b, "SHUTDOWN_TIMEOUT=%s ", c.ShutdownTimeout)
+	fmt.Fprintf(&b, "HTTP_PROXY_HEADER=%s ", c.HTTPProxyHeader)
+	fmt.Fprintf(&b, "AUTH_RATE_LIMIT_MAX=%d ", c.AuthRateLimitMax)
+	fmt.Fprintf(&b, "AUTH_RATE_LIMIT_WINDOW=%s ", c.AuthRateLimitWindow)
+	fmt.Fprintf(&b, "LOGIN_LOCKOUT_MAX_FAILURES=%d ", c.LoginLockoutMax)
+	fmt.Fprintf(&b, "LOGIN_LOCKOUT_WINDOW=%s ", c.LoginLockoutWindow)
+	fmt.Fprintf(&b, "LOGIN_LOCKOUT_BASE=%s ", c.LoginLockoutBase)
+	fmt.Fprintf(&b, "LOGIN_LOCKOUT_CAP=%s ", c.LoginLockoutCap)
+	fmt.Fprintf(&b, "TOTP_ISSUER=%q ", c.TOTPIssuer)
+	fmt.Fprintf(&b, "TOTP_REQUIRED_ROLES=%s ", strings.Join(c.TOTPRequiredRoles, ","))
+	fmt.Fprintf(&b, "TOTP_ENCRYPTION_KEY=%s ", mask(c.TOTPEncryptionKey))
+	fmt.Fprintf(&b, "MFA_CHALLENGE_TTL=%s ", c.MFAChallengeTTL)
+	fmt.Fprintf(&b, "APP_BASE_URL=%s ", c.AppBaseURL)
+	fmt.Fprintf(&b, "SMTP_HOST=%s ", c.SMTPHost)
+	fmt.Fprintf(&b, "SMTP_PORT=%d ", c.SMTPPort)
+	fmt.Fprintf(&b, "SMTP_USERNAME=%s ", c.SMTPUserName)
+	fmt.Fprintf(&b, "SMTP_PASSWORD=%s ", mask(c.SMTPPassword))
+	fmt.Fprintf(&b, "SMTP_FROM=%s ", c.SMTPFrom)
+	fmt.Fprintf(&b, "EMAIL_VERIFICATION_TTL=%s ", c.EmailVerificationTTL)
+	fmt.Fprintf(&b, "ENROLLMENT_OFFER_TTL=%s ", c.EnrollmentOfferTTL)
+	fmt.Fprintf(&b, "CHILD_ARRIVAL_CUTOFF=%s ", c.ChildArrivalCutoff)
+	fmt.Fprintf(&b, "CENTER_OPENS=%s ", c.CenterOpens)
+	fmt.Fprintf(&b, "CENTER_CLOSES=%s ", c.CenterCloses)
+	fmt.Fprintf(&b, "CENTER_WORKDAYS=%s ", strings.Join(c.CenterWorkdays, ","))
+	fmt.Fprintf(&b, "MEDICATION_DOSE_GRACE=%s ", c.MedicationDoseGrace)
+	fmt.Fprintf(&b, "STORAGE_DRIVER=%s ", c.StorageDriver)
+	fmt.Fprintf(&b, "STORAGE_LOCAL_PATH=%s ", c.StorageLocalPath)
+	fmt.Fprintf(&b, "STORAGE_S3_ENDPOINT=%s ", c.StorageS3Endpoint)
+	fmt.Fprintf(&b, "STORAGE_S3_REGION=%s ", c.StorageS3Region)
+	fmt.Fprintf(&b, "STORAGE_S3_BUCKET=%s ", c.StorageS3Bucket)
+	fmt.Fprintf(&b, "STORAGE_S3_ACCESS_KEY=%s ", c.StorageS3AccessKey)
+	fmt.Fprintf(&b, "STORAGE_S3_SECRET_KEY=%s ", mask(c.StorageS3SecretKey))
+	fmt.Fprintf(&b, "ATTACHMENT_MAX_SIZE_MB=%d ", c.AttachmentMaxSizeMB)
+	fmt.Fprintf(&b, "ATTACHMENT_URL_TTL=%s ", c.AttachmentURLTTL)
+	fmt.Fprintf(&b, "ATTACHMENT_URL_KEY=%s ", mask(c.AttachmentURLKey))
+	fmt.Fprintf(&b, "EVENT_REPLAY_SIZE=%d ", c.EventReplaySize)
+	fmt.Fprintf(&b, "EVENT_HEARTBEAT=%s ", c.EventHeartbeat)
+	fmt.Fprintf(&b, "NOTIFICATION_POLL_INTERVAL=%s ", c.NotificationPollInterval)
+	fmt.Fprintf(&b, "NOTIFICATION_BATCH_SIZE=%d ", c.NotificationBatchSize)
+	fmt.Fprintf(&b, "NOTIFICATION_MAX_ATTEMPTS=%d ", c.NotificationMaxAttempts)
+	fmt.Fprintf(&b, "NOTIFICATION_RETRY_BACKOFF=%s ", c.NotificationRetryBackoff)
+	fmt.Fprintf(&b, "NOTIFICATION_CLOCK_OUT_GRACE=%s ", c.NotificationClockOutGrace)
+	fmt.Fprintf(&b, "PUSH_ENDPOINT=%s ", c.PushEndpoint)
+	fmt.Fprintf(&b, "PUSH_SERVER_KEY=%s ", mask(c.PushServerKey))
+	fmt.Fprintf(&b, "WEBHOOK_ENCRYPTION_KEY=%s ", mask(c.WebhookEncryptionKey))
+	fmt.Fprintf(&b, "WEBHOOK_POLL_INTERVAL=%s ", c.WebhookPollInterval)
+	fmt.Fprintf(&b, "WEBHOOK_BATCH_SIZE=%d ", c.WebhookBatchSize)
+	fmt.Fprintf(&b, "WEBHOOK_MAX_ATTEMPTS=%d ", c.WebhookMaxAttempts)
+	fmt.Fprintf(&b, "WEBHOOK_RETRY_BACKOFF=%s ", c.WebhookRetryBackoff)
+	fmt.Fprintf(&b, "WEBHOOK_TIMEOUT=%s ", c.WebhookTimeout)
+	fmt.Fprintf(&b, "JOB_WORKERS=%d ", c.JobWorkers)
+	fmt.Fprintf(&b, "JOB_POLL_INTERVAL=%s ", c.JobPollInterval)
+	fmt.Fprintf(&b, "JOB_MAX_ATTEMPTS=%d ", c.JobMaxAttempts)
+	fmt.Fprintf(&b, "JOB_RETRY_BACKOFF=%s ", c.JobRetryBackoff)
+	fmt.Fprintf(&b, "JOB_TIMEOUT=%s ", c.JobTimeout)
+	fmt.Fprintf(&b, "JOB_RETENTION=%s", c.JobRetention)
+	return b.String()
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return "<empty>"
+	}
+	return "******"
 }
 
-func GetString(key string, fallback string) string {
-	val, ok := os.LookupEnv(key)
+func envOnly() bool {
+	val, err := strconv.ParseBool(os.Getenv("APP_ENV_ONLY"))
+	return err == nil && val
+}
 
+// loader resolves keys and collects every parse error instead of silently
+// falling back, so a typo in the environment is reported at startup.
+type loader struct {
+	errs []string
+}
+
+func (l *loader) lookup(key string) (string, bool) {
+	if path, ok := os.LookupEnv(key + "_FILE"); ok && path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Sprintf("%s_FILE: %v", key, err))
+			return "", false
+		}
+		return strings.TrimSpace(string(content)), true
+	}
+	return os.LookupEnv(key)
+}
+
+func (l *loader) getString(key string, fallback string) string {
+	val, ok := l.lookup(key)
 	if !ok {
 		return fallback
 	}
-
 	return val
 }
 
-func GetInt(key string, fallback int) int {
-	val, ok := os.LookupEnv(key)
+func (l *loader) getInt(key string, fallback int) int {
+	val, ok := l.lookup(key)
 	if !ok {
 		return fallback
 	}
 
 	valAsInt, err := strconv.Atoi(val)
-
 	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s must be an integer", key))
 		return fallback
 	}
 
 	return valAsInt
 }
 
-func GetBool(key string, fallback bool) bool {
-	val, ok := os.LookupEnv(key)
+// getList reads a comma separated list. An empty value means an empty list,
+// which is different from the key not being set.
+func (l *loader) getList(key string, fallback []string) []string {
+	val, ok := l.lookup(key)
 	if !ok {
 		return fallback
 	}
 
-	valAsBool, err := strconv.ParseBool(val)
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
 
-	if err != nil {
+func (l *loader) getDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := l.lookup(key)
+	if !ok {
 		return fallback
 	}
 
-	return valAsBool
-}
-
-func GetConfig() Config {
-	return Config{
-		AppPort:      GetString("APP_PORT", ":8080"),
-		AppName:      GetString("APP_NAME", "Daycare Preschool API"),
-		DBConnection: GetString("DB_CONNECTION", "mysql"),
-		DBHost:       GetString("DB_HOST", "localhost"),
-		DBPort:       GetInt("DB_PORT", 3306),
-		DBDatabase:   GetString("DB_DATABASE", "daycare"),
-		DBUserName:   GetString("DB_USERNAME", "root"),
-		DBPassword:   GetString("DB_PASSWORD", ""),
-		JWTSecret:    GetString("JWT_SECRET", "secret"),
+	valAsDuration, err := time.ParseDuration(val)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s must be a duration such as 24h", key))
+		return fallback
 	}
+
+	return valAsDuration
 }
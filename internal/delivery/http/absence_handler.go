@@ -0,0 +1,142 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type AbsenceHandler struct {
+	usecase usecase.AbsenceUsecase
+}
+
+func NewAbsenceHandler(api fiber.Router, usecase usecase.AbsenceUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *AbsenceHandler {
+	handler := &AbsenceHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to view attendance expectations", domain.RoleAdmin, domain.RoleTeacher)
+
+	absenceGroup := api.Group("/absences")
+	absenceGroup.Use(auth)
+	absenceGroup.Get("/", handler.ListAbsences)
+	absenceGroup.Post("/", handler.ReportAbsence)
+	absenceGroup.Get("/report", staffOnly, handler.AbsenceReport)
+	absenceGroup.Delete("/:id", handler.CancelAbsence)
+
+	expectedGroup := api.Group("/expected-attendance")
+	expectedGroup.Use(auth, staffOnly)
+	expectedGroup.Get("/", handler.ExpectedAttendance)
+	expectedGroup.Get("/not-arrived", handler.NotArrived)
+	return handler
+}
+
+func (h *AbsenceHandler) ReportAbsence(c *fiber.Ctx) error {
+	var input domain.ReportAbsenceRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	absences, err := h.usecase.ReportAbsence(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Absence reported", domain.NewAbsenceResponses(absences))
+}
+
+func (h *AbsenceHandler) ListAbsences(c *fiber.Ctx) error {
+	var filter domain.AbsenceListFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	absences, err := h.usecase.ListAbsences(c.UserContext(), uint(*id), filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewAbsenceResponses(absences))
+}
+
+func (h *AbsenceHandler) CancelAbsence(c *fiber.Ctx) error {
+	absenceId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.CancelAbsence(c.UserContext(), uint(*id), absenceId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Absence cancelled", nil)
+}
+
+func (h *AbsenceHandler) ExpectedAttendance(c *fiber.Ctx) error {
+	query, err := parseExpectedAttendanceQuery(c)
+	if err != nil {
+		return err
+	}
+
+	expected, err := h.usecase.ExpectedAttendance(c.UserContext(), queryDate(query.Date))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", expected)
+}
+
+func (h *AbsenceHandler) NotArrived(c *fiber.Ctx) error {
+	query, err := parseExpectedAttendanceQuery(c)
+	if err != nil {
+		return err
+	}
+
+	notArrived, err := h.usecase.NotArrived(c.UserContext(), queryDate(query.Date), query.Cutoff)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", notArrived)
+}
+
+func (h *AbsenceHandler) AbsenceReport(c *fiber.Ctx) error {
+	var query domain.AbsenceReportQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+
+	month, _ := time.ParseInLocation("2006-01", query.Month, time.Local)
+	report, err := h.usecase.AbsenceReport(c.UserContext(), month)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", report)
+}
+
+func parseExpectedAttendanceQuery(c *fiber.Ctx) (*domain.ExpectedAttendanceQuery, error) {
+	var query domain.ExpectedAttendanceQuery
+	if err := c.QueryParser(&query); err != nil {
+		return nil, apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return nil, err
+	}
+	return &query, nil
+}
+
+// queryDate parses an already validated date query, today when empty
+func queryDate(value string) time.Time {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	date, _ := utils.ParseDateStringToTime(value)
+	return *date
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type AttachmentHandler struct {
+	usecase usecase.AttachmentUsecase
+}
+
+func NewAttachmentHandler(api fiber.Router, usecase usecase.AttachmentUsecase, auth fiber.Handler) *AttachmentHandler {
+	handler := &AttachmentHandler{usecase}
+
+	attachmentGroup := api.Group("/attachments")
+	// Download URLs are signed and expire, the signature is the credential
+	// so browsers can load them directly
+	attachmentGroup.Get("/:id/download", handler.Download)
+	attachmentGroup.Use(auth)
+	attachmentGroup.Get("/", handler.List)
+	attachmentGroup.Post("/", handler.Upload)
+	attachmentGroup.Get("/:id", handler.Get)
+	attachmentGroup.Delete("/:id", handler.Delete)
+	return handler
+}
+
+// Upload reads a multipart form with the file field and the owner of the
+// attachment
+func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
+	var input domain.AttachmentUploadRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return apperror.BadRequest("the file field is required").Wrap(err)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	attachment, err := h.usecase.Upload(c.UserContext(), uint(*id), input, fileHeader.Filename, data)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Attachment uploaded", attachment)
+}
+
+func (h *AttachmentHandler) List(c *fiber.Ctx) error {
+	var filter domain.AttachmentListFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	attachments, err := h.usecase.List(c.UserContext(), uint(*id), filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", attachments)
+}
+
+func (h *AttachmentHandler) Get(c *fiber.Ctx) error {
+	attachmentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	attachment, err := h.usecase.Get(c.UserContext(), uint(*id), attachmentId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", attachment)
+}
+
+func (h *AttachmentHandler) Delete(c *fiber.Ctx) error {
+	attachmentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.Delete(c.UserContext(), uint(*id), attachmentId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Attachment deleted", nil)
+}
+
+func (h *AttachmentHandler) Download(c *fiber.Ctx) error {
+	attachmentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var query domain.AttachmentDownloadQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+
+	file, attachment, err := h.usecase.Download(c.UserContext(), attachmentId, query)
+	if err != nil {
+		return err
+	}
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`%s; filename="%s"`, disposition, attachment.FileName))
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.SendStream(file)
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type CalendarHandler struct {
+	usecase usecase.CalendarUsecase
+}
+
+func NewCalendarHandler(api fiber.Router, usecase usecase.CalendarUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *CalendarHandler {
+	handler := &CalendarHandler{usecase}
+	adminOnly := requireAdmin(userUsecase, "You are not allowed to manage the calendar")
+
+	calendarGroup := api.Group("/calendar")
+	// The feed is read by calendar apps, the secret token in the URL is the
+	// credential so it is registered before the auth middleware
+	calendarGroup.Get("/feed/:token", handler.Feed)
+	calendarGroup.Use(auth)
+	calendarGroup.Get("/days", handler.ListDays)
+	calendarGroup.Post("/days", adminOnly, handler.CreateDay)
+	calendarGroup.Delete("/days/:id", adminOnly, handler.DeleteDay)
+	calendarGroup.Post("/import", adminOnly, handler.ImportDays)
+	calendarGroup.Get("/working-days", handler.WorkingDays)
+	calendarGroup.Get("/events", handler.ListEvents)
+	calendarGroup.Post("/events", adminOnly, handler.CreateEvent)
+	calendarGroup.Put("/events/:id", adminOnly, handler.UpdateEvent)
+	calendarGroup.Delete("/events/:id", adminOnly, handler.DeleteEvent)
+	calendarGroup.Post("/feed-token", handler.RotateFeedToken)
+	return handler
+}
+
+// ListDays returns the holidays, closures and half days between from and
+// to, the next 90 days by default
+func (h *CalendarHandler) ListDays(c *fiber.Ctx) error {
+	from, to, err := parseCalendarRange(c, 90)
+	if err != nil {
+		return err
+	}
+
+	days, err := h.usecase.ListDays(c.UserContext(), from, to)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewCalendarDayResponses(days))
+}
+
+func (h *CalendarHandler) CreateDay(c *fiber.Ctx) error {
+	var input domain.CalendarDayRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	day, err := h.usecase.CreateDay(c.UserContext(), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Calendar day created", domain.NewCalendarDayResponse(day))
+}
+
+func (h *CalendarHandler) DeleteDay(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.usecase.DeleteDay(c.UserContext(), id); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Calendar day deleted", nil)
+}
+
+// ImportDays reads an iCalendar file, sent as the raw body or as the "file"
+// field of a multipart form
+func (h *CalendarHandler) ImportDays(c *fiber.Ctx) error {
+	var query domain.CalendarImportQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+	kind := query.Kind
+	if kind == "" {
+		kind = domain.CalendarHoliday
+	}
+
+	var file io.Reader = bytes.NewReader(c.Body())
+	if strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return apperror.BadRequest("the file field is required").Wrap(err)
+		}
+		opened, err := fileHeader.Open()
+		if err != nil {
+			return err
+		}
+		defer opened.Close()
+		file = opened
+	} else if len(c.Body()) == 0 {
+		return apperror.BadRequest("an iCalendar file is required")
+	}
+
+	result, err := h.usecase.ImportDays(c.UserContext(), file, kind)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Calendar imported", result)
+}
+
+// WorkingDays returns the opening hours of every day between from and to,
+// the next 30 days by default
+func (h *CalendarHandler) WorkingDays(c *fiber.Ctx) error {
+	from, to, err := parseCalendarRange(c, 30)
+	if err != nil {
+		return err
+	}
+
+	days, err := h.usecase.WorkingDays(c.UserContext(), from, to)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewWorkingDaysResponse(from, to, days))
+}
+
+// ListEvents returns the events between from and to, the next 90 days by
+// default
+func (h *CalendarHandler) ListEvents(c *fiber.Ctx) error {
+	from, to, err := parseCalendarRange(c, 90)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	events, err := h.usecase.ListEvents(c.UserContext(), uint(*id), from, to)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewCalendarEventResponses(events))
+}
+
+func (h *CalendarHandler) CreateEvent(c *fiber.Ctx) error {
+	var input domain.CalendarEventRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	event, err := h.usecase.CreateEvent(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Calendar event created", domain.NewCalendarEventResponse(event))
+}
+
+func (h *CalendarHandler) UpdateEvent(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.CalendarEventRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	event, err := h.usecase.UpdateEvent(c.UserContext(), id, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Calendar event updated", domain.NewCalendarEventResponse(event))
+}
+
+func (h *CalendarHandler) DeleteEvent(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.usecase.DeleteEvent(c.UserContext(), id); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Calendar event deleted", nil)
+}
+
+// RotateFeedToken returns a new secret iCal feed URL for the user
+func (h *CalendarHandler) RotateFeedToken(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	feed, err := h.usecase.RotateFeedToken(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Calendar feed created", feed)
+}
+
+func (h *CalendarHandler) Feed(c *fiber.Ctx) error {
+	calendar, err := h.usecase.Feed(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="calendar.ics"`)
+	return calendar.Encode(c)
+}
+
+// parseCalendarRange reads the from and to query, from defaults to today and
+// to to the given number of days after from
+func parseCalendarRange(c *fiber.Ctx, days int) (time.Time, time.Time, error) {
+	var filter domain.CalendarRangeFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return time.Time{}, time.Time{}, apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	from := queryDate(filter.From)
+	to := from.AddDate(0, 0, days)
+	if filter.To != "" {
+		to = queryDate(filter.To)
+	}
+	return from, to, nil
+}
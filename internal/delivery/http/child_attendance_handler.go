@@ -1,37 +1,51 @@
 package http
 
 import (
-	"strconv"
-
 	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/usecase"
-	"github.com/whyaji/daycare-preschool-api/pkg/middleware"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
 )
 
 type ChildAttendanceHandler struct {
 	usecase usecase.ChildAttendanceUsecase
 }
 
-func NewChildAttendanceHandler(api fiber.Router, usecase usecase.ChildAttendanceUsecase) *ChildAttendanceHandler {
+func NewChildAttendanceHandler(api fiber.Router, usecase usecase.ChildAttendanceUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *ChildAttendanceHandler {
 	handler := &ChildAttendanceHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to record child attendance", domain.RoleAdmin, domain.RoleTeacher)
+
 	childAttendanceGroup := api.Group("/child-attendances")
-	childAttendanceGroup.Use(middleware.JWTProtected)
-	childAttendanceGroup.Post("/", handler.ChildArrival)
+	childAttendanceGroup.Use(auth)
+	childAttendanceGroup.Post("/", staffOnly, handler.ChildArrival)
+	childAttendanceGroup.Post("/departure", staffOnly, handler.ChildDeparture)
 	return handler
 }
 
 func (h *ChildAttendanceHandler) ChildArrival(c *fiber.Ctx) error {
-	childIdString := c.FormValue("childId")
+	// Accepts both JSON and form bodies
+	var requestData domain.CreateChildAttendanceRequest
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
+	}
+
+	if err := h.usecase.ChildArrival(c.UserContext(), requestData.ChildID, requestData.Date, requestData.Arrival); err != nil {
+		return err
+	}
+
+	return utils.SendSuccess(c, fiber.StatusCreated, "Child arrival recorded", nil)
+}
 
-	childId, err := strconv.Atoi(childIdString)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid childId",
-		})
+func (h *ChildAttendanceHandler) ChildDeparture(c *fiber.Ctx) error {
+	var requestData domain.ChildDepartureRequest
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
 	}
 
-	date := c.FormValue("date")
-	arrival := c.FormValue("arrival")
+	if err := h.usecase.ChildDeparture(c.UserContext(), requestData.ChildID, requestData.Departure); err != nil {
+		return err
+	}
 
-	return h.usecase.ChildArrival(uint(childId), date, arrival)
+	return utils.SendSuccess(c, fiber.StatusOK, "Child departure recorded", nil)
 }
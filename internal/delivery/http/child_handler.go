@@ -4,18 +4,20 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/usecase"
-	"github.com/whyaji/daycare-preschool-api/pkg/middleware"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
 	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
 )
 
 type ChildHandler struct {
 	usecase usecase.ChildUsecase
 }
 
-func NewChildHandler(api fiber.Router, usecase usecase.ChildUsecase) *ChildHandler {
+func NewChildHandler(api fiber.Router, usecase usecase.ChildUsecase, auth fiber.Handler) *ChildHandler {
 	handler := &ChildHandler{usecase}
 	childGroup := api.Group("/childs")
-	childGroup.Use(middleware.JWTProtected)
+	childGroup.Use(auth)
 	childGroup.Post("/", handler.CreateChild)
 	childGroup.Get("/:id", handler.GetChild)
 	return handler
@@ -23,62 +25,48 @@ func NewChildHandler(api fiber.Router, usecase usecase.ChildUsecase) *ChildHandl
 
 func (h *ChildHandler) GetChild(c *fiber.Ctx) error {
 	id := c.Params("id")
-	child, err := h.usecase.GetChild(id)
+	child, err := h.usecase.GetChild(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	return c.Status(fiber.StatusOK).JSON(child)
+	return utils.SendSuccess(c, fiber.StatusOK, "success", child)
 }
 
 func (h *ChildHandler) CreateChild(c *fiber.Ctx) error {
 	id := utils.GetUserIDFromJwt(c)
-	isAdmin, err := h.usecase.CheckUserAdmin(uint(*id))
+	isAdmin, err := h.usecase.CheckUserAdmin(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isAdmin {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create child"})
+		return apperror.Forbidden("You are not allowed to create child")
 	}
 
-	var errors []string
-
-	// Create a map to first parse the raw JSON data
 	var requestData domain.CreateChildRequest
-	if err := c.BodyParser(&requestData); err != nil {
-		errors = append(errors, err.Error())
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
 	}
 
-	// Validate required fields are not empty (except for AlergyInfo)
-	errorsValidate := h.usecase.ValidateRequiredFields(&requestData)
-	errors = append(errors, errorsValidate...)
-
-	// Manually parse birthDate as time.Time
-	birthDate, err := utils.ParseDateStringToTime(requestData.BirthDate)
-	if err != nil {
-		errors = append(errors, "birthDate "+err.Error())
-	}
+	var errors []types.FieldError
 
-	// Manually parse registeredDate as time.Time
-	registeredDate, err := utils.ParseDateStringToTime(requestData.RegisteredDate)
-	if err != nil {
-		errors = append(errors, "registeredDate "+err.Error())
-	}
+	// Dates are already validated, only convert them to time.Time
+	birthDate, _ := utils.ParseDateStringToTime(requestData.BirthDate)
+	registeredDate, _ := utils.ParseDateStringToTime(requestData.RegisteredDate)
 
-	// Parse the parents string into a slice of User structs
-	parents, err := h.usecase.ParseUserIds(requestData.Parents)
+	// Load the parents and teachers, every id must exist
+	parents, err := h.usecase.ParseUserIds(c.UserContext(), requestData.Parents)
 	if err != nil {
-		errors = append(errors, "parents "+err.Error())
+		errors = append(errors, types.FieldError{Field: "parents", Message: "parents " + err.Error()})
 	}
 
-	// Parse the teachers string into a slice of User structs
-	teachers, err := h.usecase.ParseUserIds(requestData.Teachers)
+	teachers, err := h.usecase.ParseUserIds(c.UserContext(), requestData.Teachers)
 	if err != nil {
-		errors = append(errors, "teachers "+err.Error())
+		errors = append(errors, types.FieldError{Field: "teachers", Message: "teachers " + err.Error()})
 	}
 
 	// If there are any errors, return them
 	if len(errors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+		return apperror.Validation(errors...)
 	}
 
 	// Now parse the entire request into the Child struct
@@ -96,9 +84,9 @@ func (h *ChildHandler) CreateChild(c *fiber.Ctx) error {
 	child.Parents = parents
 	child.Teachers = teachers
 
-	if err := h.usecase.CreateChild(&child); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := h.usecase.CreateChild(c.UserContext(), uint(*id), &child); err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Child created"})
+	return utils.SendSuccess(c, fiber.StatusCreated, "Child created", nil)
 }
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type ClassroomHandler struct {
+	usecase usecase.ClassroomUsecase
+}
+
+func NewClassroomHandler(api fiber.Router, usecase usecase.ClassroomUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *ClassroomHandler {
+	handler := &ClassroomHandler{usecase}
+	adminOnly := requireAdmin(userUsecase, "You are not allowed to manage classrooms")
+
+	classroomGroup := api.Group("/classrooms")
+	classroomGroup.Use(auth)
+	classroomGroup.Get("/", handler.ListClassrooms)
+	classroomGroup.Get("/ratios", handler.Ratios)
+	classroomGroup.Get("/alerts", handler.Alerts)
+	classroomGroup.Get("/:id", handler.GetClassroom)
+	classroomGroup.Get("/:id/ratio", handler.Ratio)
+	classroomGroup.Post("/", adminOnly, handler.CreateClassroom)
+	classroomGroup.Patch("/:id", adminOnly, handler.UpdateClassroom)
+	classroomGroup.Put("/:id/teachers", adminOnly, handler.SetTeachers)
+	classroomGroup.Post("/:id/children", adminOnly, handler.AddChild)
+	classroomGroup.Delete("/:id/children/:childId", adminOnly, handler.RemoveChild)
+	return handler
+}
+
+func (h *ClassroomHandler) ListClassrooms(c *fiber.Ctx) error {
+	classrooms, err := h.usecase.ListClassrooms(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewClassroomResponses(classrooms))
+}
+
+func (h *ClassroomHandler) GetClassroom(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	classroom, err := h.usecase.GetClassroom(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewClassroomResponse(classroom))
+}
+
+func (h *ClassroomHandler) CreateClassroom(c *fiber.Ctx) error {
+	var input domain.ClassroomRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	classroom, err := h.usecase.CreateClassroom(c.UserContext(), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Classroom created", domain.NewClassroomResponse(classroom))
+}
+
+func (h *ClassroomHandler) UpdateClassroom(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.ClassroomRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	classroom, err := h.usecase.UpdateClassroom(c.UserContext(), id, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Classroom updated", domain.NewClassroomResponse(classroom))
+}
+
+func (h *ClassroomHandler) SetTeachers(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.ClassroomTeachersRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	classroom, err := h.usecase.SetTeachers(c.UserContext(), id, input.Teachers)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Teachers updated", domain.NewClassroomResponse(classroom))
+}
+
+func (h *ClassroomHandler) AddChild(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.ClassroomChildRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	classroom, err := h.usecase.AddChild(c.UserContext(), id, input.ChildID)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Child added to classroom", domain.NewClassroomResponse(classroom))
+}
+
+func (h *ClassroomHandler) RemoveChild(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.usecase.RemoveChild(c.UserContext(), id, childId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Child removed from classroom", nil)
+}
+
+func (h *ClassroomHandler) Ratios(c *fiber.Ctx) error {
+	ratios, err := h.usecase.Ratios(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", ratios)
+}
+
+func (h *ClassroomHandler) Ratio(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	ratio, err := h.usecase.Ratio(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", ratio)
+}
+
+func (h *ClassroomHandler) Alerts(c *fiber.Ctx) error {
+	var filter domain.ClassroomAlertFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	alerts, err := h.usecase.Alerts(c.UserContext(), filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewClassroomAlertResponses(alerts))
+}
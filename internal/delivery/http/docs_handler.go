@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/openapi"
+)
+
+type DocsHandler struct {
+	document *openapi.Document
+}
+
+func NewDocsHandler(api fiber.Router, document *openapi.Document) *DocsHandler {
+	handler := &DocsHandler{document}
+	api.Get("/openapi.json", handler.OpenAPI)
+	api.Get("/docs", handler.Docs)
+	return handler
+}
+
+// OpenAPI serves the raw document. It is not wrapped in the response
+// envelope so tooling can consume it directly.
+func (h *DocsHandler) OpenAPI(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.document)
+}
+
+func (h *DocsHandler) Docs(c *fiber.Ctx) error {
+	c.Type("html", "utf-8")
+	return c.SendString(docsPage)
+}
+
+const docsPage = `<!doctype html>
+<html>
+  <head>
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1" />
+    <title>API Docs</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+  </head>
+  <body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+      window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui", persistAuthorization: true });
+    </script>
+  </body>
+</html>`
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type EnrollmentHandler struct {
+	usecase usecase.EnrollmentUsecase
+}
+
+func NewEnrollmentHandler(api fiber.Router, usecase usecase.EnrollmentUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *EnrollmentHandler {
+	handler := &EnrollmentHandler{usecase}
+
+	enrollmentGroup := api.Group("/enrollments")
+	enrollmentGroup.Use(auth, requireAdmin(userUsecase, "You are not allowed to manage enrollments"))
+	enrollmentGroup.Get("/", handler.ListEnrollments)
+	enrollmentGroup.Post("/", handler.CreateEnrollment)
+	enrollmentGroup.Get("/waitlist/:id", handler.Waitlist)
+	enrollmentGroup.Post("/waitlist/:id/offers", handler.OfferSeats)
+	enrollmentGroup.Get("/:id", handler.GetEnrollment)
+	enrollmentGroup.Patch("/:id", handler.UpdateEnrollment)
+	enrollmentGroup.Post("/:id/transitions", handler.Transition)
+	return handler
+}
+
+func (h *EnrollmentHandler) ListEnrollments(c *fiber.Ctx) error {
+	var filter domain.EnrollmentListFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	enrollments, totalPage, err := h.usecase.ListEnrollments(c.UserContext(), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, domain.NewEnrollmentResponses(enrollments))
+}
+
+func (h *EnrollmentHandler) GetEnrollment(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	enrollment, err := h.usecase.GetEnrollment(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewEnrollmentResponse(enrollment))
+}
+
+func (h *EnrollmentHandler) CreateEnrollment(c *fiber.Ctx) error {
+	var input domain.EnrollmentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	actorId := utils.GetUserIDFromJwt(c)
+	enrollment, err := h.usecase.CreateEnrollment(c.UserContext(), uint(*actorId), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Enrollment created", domain.NewEnrollmentResponse(enrollment))
+}
+
+func (h *EnrollmentHandler) UpdateEnrollment(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.UpdateEnrollmentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	enrollment, err := h.usecase.UpdateEnrollment(c.UserContext(), id, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Enrollment updated", domain.NewEnrollmentResponse(enrollment))
+}
+
+func (h *EnrollmentHandler) Transition(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.EnrollmentTransitionRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	actorId := utils.GetUserIDFromJwt(c)
+	enrollment, err := h.usecase.Transition(c.UserContext(), uint(*actorId), id, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Enrollment status changed", domain.NewEnrollmentResponse(enrollment))
+}
+
+func (h *EnrollmentHandler) Waitlist(c *fiber.Ctx) error {
+	classroomId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	waitlist, err := h.usecase.Waitlist(c.UserContext(), classroomId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewWaitlistResponses(waitlist))
+}
+
+func (h *EnrollmentHandler) OfferSeats(c *fiber.Ctx) error {
+	classroomId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	actorId := utils.GetUserIDFromJwt(c)
+	offers, err := h.usecase.OfferSeats(c.UserContext(), uint(*actorId), classroomId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Seats offered", offers)
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/events"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+)
+
+// eventRetry is the reconnect delay suggested to clients, in milliseconds
+const eventRetry = 3000
+
+type EventHandler struct {
+	usecase     usecase.EventUsecase
+	userUsecase usecase.UserUsecase
+}
+
+func NewEventHandler(api fiber.Router, usecase usecase.EventUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *EventHandler {
+	handler := &EventHandler{usecase, userUsecase}
+
+	api.Get("/events", auth, handler.Stream)
+	return handler
+}
+
+// Stream sends the events the user may see as server-sent events until the
+// client leaves or the server shuts down. A reconnecting client resumes with
+// the Last-Event-ID header or the lastEventId query parameter. The access
+// token is checked again on every heartbeat and the stream is closed once it
+// has expired or been revoked, or the user deactivated.
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	lastEventId := c.Get("Last-Event-ID", c.Query("lastEventId"))
+	var after uint64
+	if lastEventId != "" {
+		var err error
+		if after, err = strconv.ParseUint(lastEventId, 10, 64); err != nil {
+			return apperror.BadRequest("invalid last event id").Wrap(err)
+		}
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	claims := c.Locals("user").(*jwt.Token).Claims.(jwt.MapClaims)
+	version, _ := claims["ver"].(float64)
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return apperror.Unauthorized("invalid token claims").Wrap(err)
+	}
+	// The context is kept for the writer, which runs after the handler returns
+	ctx := c.UserContext()
+	stream, err := h.usecase.Subscribe(ctx, uint(*id), after)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer stream.Close()
+
+		// The server write timeout applies to the whole response, every
+		// write pushes it back instead
+		flush := func() bool {
+			conn.SetWriteDeadline(time.Now().Add(stream.WriteTimeout))
+			return w.Flush() == nil
+		}
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+		if stream.Resync {
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", domain.EventResync)
+		}
+		if !flush() {
+			return
+		}
+
+		heartbeat := time.NewTicker(stream.Heartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-stream.Events:
+				if !ok {
+					return
+				}
+				if !event.Audience.Allows(stream.Subscriber) {
+					continue
+				}
+				writeEvent(w, event)
+			case <-heartbeat.C:
+				if !tokenValid(ctx, h.userUsecase, uint(*id), uint(version), expiresAt) {
+					return
+				}
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if !flush() {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// tokenValid reports whether the access token the stream was opened with may
+// still be used. Tokens without an exp claim do not expire.
+func tokenValid(ctx context.Context, tokens usecase.UserUsecase, userId uint, version uint, expiresAt *jwt.NumericDate) bool {
+	if expiresAt != nil && !time.Now().Before(expiresAt.Time) {
+		return false
+	}
+	return tokens.ValidateToken(ctx, userId, version) == nil
+}
+
+func writeEvent(w *bufio.Writer, event events.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+)
+
+// requireAdmin only lets users with the admin role through. It must run
+// after the auth middleware.
+func requireAdmin(usecase usecase.UserUsecase, message string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := utils.GetUserIDFromJwt(c)
+		isAdmin, err := usecase.CheckUserAdmin(c.UserContext(), uint(*id))
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			return apperror.Forbidden(message)
+		}
+		return c.Next()
+	}
+}
+
+// requireRole only lets users with at least one of the roles through. It
+// must run after the auth middleware.
+func requireRole(usecase usecase.UserUsecase, message string, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := utils.GetUserIDFromJwt(c)
+		allowed, err := usecase.CheckUserHasRole(c.UserContext(), uint(*id), roles...)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return apperror.Forbidden(message)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+)
+
+type HealthHandler struct {
+	usecase usecase.HealthUsecase
+}
+
+// NewHealthHandler registers the probes on the app root, outside /api/v1,
+// and without authentication.
+func NewHealthHandler(app fiber.Router, usecase usecase.HealthUsecase) *HealthHandler {
+	handler := &HealthHandler{usecase}
+	app.Get("/healthz", handler.Liveness)
+	app.Get("/readyz", handler.Readiness)
+	return handler
+}
+
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return utils.SendSuccess(c, fiber.StatusOK, "alive", h.usecase.Liveness(c.UserContext()))
+}
+
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	health, ready := h.usecase.Readiness(c.UserContext())
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(types.Response{
+			Code:      "NOT_READY",
+			Message:   "service not ready",
+			Data:      health,
+			RequestID: utils.GetRequestID(c),
+		})
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "ready", health)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type IncidentHandler struct {
+	usecase usecase.IncidentUsecase
+}
+
+func NewIncidentHandler(api fiber.Router, usecase usecase.IncidentUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *IncidentHandler {
+	handler := &IncidentHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to report incidents", domain.RoleAdmin, domain.RoleTeacher)
+	adminOnly := requireAdmin(userUsecase, "You are not allowed to review incidents")
+
+	incidentGroup := api.Group("/incidents")
+	incidentGroup.Use(auth)
+	incidentGroup.Get("/", handler.List)
+	incidentGroup.Post("/", staffOnly, handler.Report)
+	incidentGroup.Get("/children/:childId", handler.History)
+	incidentGroup.Get("/:id", handler.Get)
+	incidentGroup.Put("/:id", staffOnly, handler.Update)
+	incidentGroup.Post("/:id/review", adminOnly, handler.Review)
+	incidentGroup.Post("/:id/acknowledge", handler.Acknowledge)
+	return handler
+}
+
+func (h *IncidentHandler) List(c *fiber.Ctx) error {
+	var filter domain.IncidentListFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incidents, err := h.usecase.List(c.UserContext(), uint(*id), filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewIncidentResponses(incidents))
+}
+
+func (h *IncidentHandler) Report(c *fiber.Ctx) error {
+	var input domain.IncidentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incident, err := h.usecase.Report(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Incident reported", domain.NewIncidentResponse(incident))
+}
+
+func (h *IncidentHandler) History(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	history, err := h.usecase.History(c.UserContext(), uint(*id), childId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", history)
+}
+
+func (h *IncidentHandler) Get(c *fiber.Ctx) error {
+	incidentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incident, err := h.usecase.Get(c.UserContext(), uint(*id), incidentId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewIncidentResponse(incident))
+}
+
+func (h *IncidentHandler) Update(c *fiber.Ctx) error {
+	incidentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.IncidentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incident, err := h.usecase.Update(c.UserContext(), uint(*id), incidentId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Incident updated", domain.NewIncidentResponse(incident))
+}
+
+func (h *IncidentHandler) Review(c *fiber.Ctx) error {
+	incidentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.ReviewIncidentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incident, err := h.usecase.Review(c.UserContext(), uint(*id), incidentId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Incident reviewed", domain.NewIncidentResponse(incident))
+}
+
+func (h *IncidentHandler) Acknowledge(c *fiber.Ctx) error {
+	incidentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.AcknowledgeIncidentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	incident, err := h.usecase.Acknowledge(c.UserContext(), uint(*id), incidentId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Incident acknowledged", domain.NewIncidentResponse(incident))
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type JobHandler struct {
+	usecase usecase.JobUsecase
+}
+
+func NewJobHandler(api fiber.Router, usecase usecase.JobUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *JobHandler {
+	handler := &JobHandler{usecase}
+
+	jobGroup := api.Group("/jobs")
+	jobGroup.Use(auth, requireAdmin(userUsecase, "You are not allowed to manage jobs"))
+	jobGroup.Get("/", handler.List)
+	jobGroup.Get("/schedules", handler.Schedules)
+	jobGroup.Get("/:id", handler.Get)
+	jobGroup.Post("/:id/retry", handler.Retry)
+	jobGroup.Post("/:id/cancel", handler.Cancel)
+	return handler
+}
+
+func (h *JobHandler) List(c *fiber.Ctx) error {
+	var filter domain.JobFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	jobs, totalPage, err := h.usecase.List(c.UserContext(), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, jobs)
+}
+
+func (h *JobHandler) Schedules(c *fiber.Ctx) error {
+	return utils.SendSuccess(c, fiber.StatusOK, "success", h.usecase.Schedules(c.UserContext()))
+}
+
+func (h *JobHandler) Get(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := h.usecase.Get(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", job)
+}
+
+func (h *JobHandler) Retry(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := h.usecase.Retry(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Job queued again", job)
+}
+
+func (h *JobHandler) Cancel(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := h.usecase.Cancel(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Job cancelled", job)
+}
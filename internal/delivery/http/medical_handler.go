@@ -0,0 +1,94 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type MedicalHandler struct {
+	usecase usecase.MedicalUsecase
+}
+
+func NewMedicalHandler(api fiber.Router, usecase usecase.MedicalUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *MedicalHandler {
+	handler := &MedicalHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to view medical alerts", domain.RoleAdmin, domain.RoleTeacher)
+
+	medicalGroup := api.Group("/medical-profiles")
+	medicalGroup.Use(auth)
+	medicalGroup.Get("/alerts", staffOnly, handler.Alerts)
+	medicalGroup.Get("/:childId", handler.GetProfile)
+	medicalGroup.Put("/:childId", handler.UpdateProfile)
+	medicalGroup.Get("/:childId/allergy-check", staffOnly, handler.CheckAllergies)
+	return handler
+}
+
+func (h *MedicalHandler) GetProfile(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	profile, err := h.usecase.GetProfile(c.UserContext(), uint(*id), childId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", profile)
+}
+
+func (h *MedicalHandler) UpdateProfile(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+	var input domain.MedicalProfileRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	profile, err := h.usecase.UpdateProfile(c.UserContext(), uint(*id), childId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Medical profile updated", profile)
+}
+
+// Alerts returns the medical summary of the children of a classroom, or of
+// every child when classroomId is not set
+func (h *MedicalHandler) Alerts(c *fiber.Ctx) error {
+	var filter domain.MedicalAlertFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+
+	alerts, err := h.usecase.Alerts(c.UserContext(), filter.ClassroomID)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", alerts)
+}
+
+func (h *MedicalHandler) CheckAllergies(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+	var query domain.AllergyCheckQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+
+	result, err := h.usecase.CheckAllergies(c.UserContext(), childId, query.Meal)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", result)
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type MedicationHandler struct {
+	usecase usecase.MedicationUsecase
+}
+
+func NewMedicationHandler(api fiber.Router, usecase usecase.MedicationUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *MedicationHandler {
+	handler := &MedicationHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to record medication", domain.RoleAdmin, domain.RoleTeacher)
+
+	medicationGroup := api.Group("/medications")
+	medicationGroup.Use(auth)
+	medicationGroup.Get("/daily", staffOnly, handler.Daily)
+	medicationGroup.Get("/requests", handler.ListRequests)
+	medicationGroup.Post("/requests", handler.CreateRequest)
+	medicationGroup.Get("/requests/:id", handler.GetRequest)
+	medicationGroup.Post("/requests/:id/cancel", handler.CancelRequest)
+	medicationGroup.Post("/requests/:id/administrations", staffOnly, handler.RecordAdministration)
+	medicationGroup.Post("/administrations/:id/countersign", staffOnly, handler.Countersign)
+	return handler
+}
+
+func (h *MedicationHandler) ListRequests(c *fiber.Ctx) error {
+	var filter domain.MedicationRequestFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	requests, err := h.usecase.ListRequests(c.UserContext(), uint(*id), filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewMedicationRequestResponses(requests))
+}
+
+func (h *MedicationHandler) CreateRequest(c *fiber.Ctx) error {
+	var input domain.CreateMedicationRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	request, err := h.usecase.CreateRequest(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Medication request created", domain.NewMedicationRequestResponse(request))
+}
+
+func (h *MedicationHandler) GetRequest(c *fiber.Ctx) error {
+	requestId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	request, err := h.usecase.GetRequest(c.UserContext(), uint(*id), requestId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewMedicationRequestResponse(request))
+}
+
+func (h *MedicationHandler) CancelRequest(c *fiber.Ctx) error {
+	requestId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	request, err := h.usecase.CancelRequest(c.UserContext(), uint(*id), requestId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Medication request cancelled", domain.NewMedicationRequestResponse(request))
+}
+
+func (h *MedicationHandler) RecordAdministration(c *fiber.Ctx) error {
+	requestId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.RecordAdministrationRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	administration, err := h.usecase.RecordAdministration(c.UserContext(), uint(*id), requestId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Medication dose recorded", domain.NewMedicationAdministrationResponse(administration))
+}
+
+func (h *MedicationHandler) Countersign(c *fiber.Ctx) error {
+	administrationId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	administration, err := h.usecase.Countersign(c.UserContext(), uint(*id), administrationId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Medication dose countersigned", domain.NewMedicationAdministrationResponse(administration))
+}
+
+// Daily lists the doses of a day, today by default, with the overdue and
+// missed ones flagged
+func (h *MedicationHandler) Daily(c *fiber.Ctx) error {
+	var query domain.MedicationDailyQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+
+	daily, err := h.usecase.Daily(c.UserContext(), queryDate(query.Date))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", daily)
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type MessageHandler struct {
+	usecase usecase.MessageUsecase
+}
+
+func NewMessageHandler(api fiber.Router, usecase usecase.MessageUsecase, auth fiber.Handler) *MessageHandler {
+	handler := &MessageHandler{usecase}
+
+	messageGroup := api.Group("/messages")
+	messageGroup.Use(auth)
+	messageGroup.Get("/unread", handler.Unread)
+	messageGroup.Get("/threads", handler.ListThreads)
+	messageGroup.Post("/threads", handler.CreateThread)
+	messageGroup.Get("/threads/:id", handler.GetThread)
+	messageGroup.Get("/threads/:id/messages", handler.ListMessages)
+	messageGroup.Post("/threads/:id/messages", handler.Send)
+	messageGroup.Post("/threads/:id/read", handler.MarkRead)
+	return handler
+}
+
+func (h *MessageHandler) Unread(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	unread, err := h.usecase.Unread(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", unread)
+}
+
+func (h *MessageHandler) ListThreads(c *fiber.Ctx) error {
+	var filter domain.MessageThreadFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	threads, totalPage, err := h.usecase.ListThreads(c.UserContext(), uint(*id), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, threads)
+}
+
+func (h *MessageHandler) CreateThread(c *fiber.Ctx) error {
+	var input domain.MessageThreadRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	thread, err := h.usecase.CreateThread(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Thread created", thread)
+}
+
+func (h *MessageHandler) GetThread(c *fiber.Ctx) error {
+	threadId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	thread, err := h.usecase.GetThread(c.UserContext(), uint(*id), threadId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", thread)
+}
+
+func (h *MessageHandler) ListMessages(c *fiber.Ctx) error {
+	threadId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	messages, totalPage, err := h.usecase.ListMessages(c.UserContext(), uint(*id), threadId, paginationFilter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, messages)
+}
+
+// Send reads a JSON body, or a multipart form with the body and up to five
+// files in the files field
+func (h *MessageHandler) Send(c *fiber.Ctx) error {
+	threadId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.MessageRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	var files []usecase.MessageFile
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return apperror.BadRequest("invalid multipart form").Wrap(err)
+		}
+		for _, fileHeader := range form.File["files"] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			files = append(files, usecase.MessageFile{Name: fileHeader.Filename, Data: data})
+		}
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	message, err := h.usecase.Send(c.UserContext(), uint(*id), threadId, input, files)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Message sent", message)
+}
+
+func (h *MessageHandler) MarkRead(c *fiber.Ctx) error {
+	threadId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.MarkReadRequest
+	if len(c.Body()) > 0 {
+		if err := validation.ParseBody(c, &input); err != nil {
+			return err
+		}
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	thread, err := h.usecase.MarkRead(c.UserContext(), uint(*id), threadId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Thread marked as read", thread)
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type NotificationHandler struct {
+	usecase usecase.NotificationUsecase
+}
+
+func NewNotificationHandler(api fiber.Router, usecase usecase.NotificationUsecase, auth fiber.Handler) *NotificationHandler {
+	handler := &NotificationHandler{usecase}
+
+	notificationGroup := api.Group("/notifications")
+	notificationGroup.Use(auth)
+	notificationGroup.Get("/", handler.Inbox)
+	notificationGroup.Get("/unread", handler.Unread)
+	notificationGroup.Post("/read", handler.MarkAllRead)
+	notificationGroup.Post("/:id/read", handler.MarkRead)
+	notificationGroup.Get("/settings", handler.GetSettings)
+	notificationGroup.Put("/settings", handler.SaveSettings)
+	notificationGroup.Get("/devices", handler.ListDevices)
+	notificationGroup.Post("/devices", handler.RegisterDevice)
+	notificationGroup.Delete("/devices/:id", handler.RemoveDevice)
+	return handler
+}
+
+func (h *NotificationHandler) Inbox(c *fiber.Ctx) error {
+	var filter domain.NotificationInboxFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	notifications, totalPage, err := h.usecase.Inbox(c.UserContext(), uint(*id), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, notifications)
+}
+
+func (h *NotificationHandler) Unread(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	unread, err := h.usecase.Unread(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", unread)
+}
+
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	notificationId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.MarkRead(c.UserContext(), uint(*id), notificationId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Notification marked as read", nil)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.MarkAllRead(c.UserContext(), uint(*id)); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Notifications marked as read", nil)
+}
+
+func (h *NotificationHandler) GetSettings(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	settings, err := h.usecase.GetSettings(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", settings)
+}
+
+func (h *NotificationHandler) SaveSettings(c *fiber.Ctx) error {
+	var input domain.NotificationSettingsRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	settings, err := h.usecase.SaveSettings(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Notification settings saved", settings)
+}
+
+func (h *NotificationHandler) ListDevices(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	devices, err := h.usecase.ListDevices(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", devices)
+}
+
+func (h *NotificationHandler) RegisterDevice(c *fiber.Ctx) error {
+	var input domain.PushDeviceRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	device, err := h.usecase.RegisterDevice(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Device registered", device)
+}
+
+func (h *NotificationHandler) RemoveDevice(c *fiber.Ctx) error {
+	deviceId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.RemoveDevice(c.UserContext(), uint(*id), deviceId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Device removed", nil)
+}
@@ -0,0 +1,910 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/openapi"
+)
+
+const apiVersion = "1.0.0"
+
+// NewOpenAPIDocument describes every route registered by RegisterRoutes.
+// When adding a route, add it here as well; the coverage test fails
+// otherwise.
+func NewOpenAPIDocument(appName string) *openapi.Document {
+	doc := openapi.New(appName, apiVersion)
+	doc.Servers = []openapi.Server{{URL: "/"}}
+
+	// Health
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/healthz", Tag: "Health",
+		Summary:  "Liveness probe",
+		Response: domain.HealthResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/readyz", Tag: "Health",
+		Summary:  "Readiness probe: database and schema version, 503 when not ready",
+		Response: domain.HealthResponse{},
+	})
+
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/metrics", Tag: "Health",
+		Summary:     "Prometheus metrics",
+		RawResponse: &openapi.Response{Description: "Prometheus text exposition format", Content: map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}},
+	})
+
+	// Docs
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/openapi.json", Tag: "Docs",
+		Summary:     "OpenAPI document",
+		RawResponse: &openapi.Response{Description: "OpenAPI 3 document", Content: map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "object"}}}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/docs", Tag: "Docs",
+		Summary:     "Interactive API docs",
+		RawResponse: &openapi.Response{Description: "HTML page", Content: map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}},
+	})
+
+	// User
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/register", Tag: "User",
+		Summary: "Register an account for an allowlisted email (rate limited, 429 with Retry-After)",
+		Body:    domain.RegisterRequest{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/login", Tag: "User",
+		Summary: "Log in and receive a JWT, or a two-factor challenge when enabled or required by role (rate limited and locked out after repeated failures)",
+		Body:    domain.LoginRequest{}, Response: domain.AuthResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/restricted", Tag: "User", Auth: true,
+		Summary:  "Current token and user",
+		Response: domain.AuthResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/register-user", Tag: "User", Auth: true,
+		Summary: "Allowlist an email with roles and send the invitation, unknown role ids fail validation (admin)",
+		Body:    domain.RegisterEmailRequest{}, Status: fiber.StatusCreated,
+	})
+
+	// Profile
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/me", Tag: "Profile", Auth: true,
+		Summary:  "Profile of the current user",
+		Response: domain.UserResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPatch, Path: "/api/v1/me", Tag: "Profile", Auth: true,
+		Summary: "Update name, phone, address, job title or job place",
+		Body:    domain.UpdateProfileRequest{}, Response: domain.UserResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/me/email", Tag: "Profile", Auth: true,
+		Summary: "Request an email change, applied once the new address is verified",
+		Body:    domain.ChangeEmailRequest{}, Status: fiber.StatusAccepted,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/email/verify", Tag: "Profile",
+		Summary: "Verify a pending email change with the emailed token",
+		Body:    domain.VerifyEmailRequest{},
+	})
+
+	// User management
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/users/", Tag: "User Management", Auth: true,
+		Summary:  "List and search users (admin)",
+		Response: domain.UserResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "role", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"active", "deactivated", "all"}}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/users/:id", Tag: "User Management", Auth: true,
+		Summary:  "Get a user, including deactivated ones (admin)",
+		Response: domain.UserResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/users/:id/roles", Tag: "User Management", Auth: true,
+		Summary: "Replace the roles of a user (admin)",
+		Body:    domain.UpdateUserRolesRequest{}, Response: domain.UserResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/users/:id/email", Tag: "User Management", Auth: true,
+		Summary: "Change the email of a user, applied once the new address is verified (admin)",
+		Body:    domain.AdminChangeEmailRequest{}, Status: fiber.StatusAccepted,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/users/:id/deactivate", Tag: "User Management", Auth: true,
+		Summary: "Deactivate a user (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/users/:id/reactivate", Tag: "User Management", Auth: true,
+		Summary: "Reactivate a deactivated user (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/invitations/", Tag: "User Management", Auth: true,
+		Summary:  "Allowlisted emails that have not registered yet (admin)",
+		Response: domain.InvitationResponse{}, Paginated: true,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/invitations/:id/resend", Tag: "User Management", Auth: true,
+		Summary: "Send the invitation email again (admin)",
+	})
+
+	// Roles
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/roles/", Tag: "Roles", Auth: true,
+		Summary:  "List roles with their permissions (admin)",
+		Response: []domain.RoleDetailResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/roles/", Tag: "Roles", Auth: true,
+		Summary: "Create a role with a unique name (admin)",
+		Body:    domain.RoleRequest{}, Response: domain.RoleDetailResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPatch, Path: "/api/v1/roles/:id", Tag: "Roles", Auth: true,
+		Summary: "Rename a role, system roles cannot be renamed (admin)",
+		Body:    domain.RoleRequest{}, Response: domain.RoleDetailResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/roles/:id", Tag: "Roles", Auth: true,
+		Summary: "Delete an unused role, 409 ROLE_IN_USE otherwise (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/roles/:id/permissions", Tag: "Roles", Auth: true,
+		Summary: "Replace the permissions of a role (admin)",
+		Body:    domain.UpdateRolePermissionsRequest{}, Response: domain.RoleDetailResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/permissions/", Tag: "Roles", Auth: true,
+		Summary:  "List permissions (admin)",
+		Response: []domain.PermissionResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/permissions/", Tag: "Roles", Auth: true,
+		Summary: "Create a permission (admin)",
+		Body:    domain.PermissionRequest{}, Response: domain.PermissionResponse{}, Status: fiber.StatusCreated,
+	})
+
+	// Two-factor
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/login/2fa", Tag: "Two-Factor",
+		Summary: "Answer a totp challenge with a code or recovery code and receive a JWT",
+		Body:    domain.TwoFactorVerifyRequest{}, Response: domain.AuthResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/login/2fa/enroll", Tag: "Two-Factor",
+		Summary: "Start the enrollment required by a totp_enrollment challenge",
+		Body:    domain.TwoFactorEnrollRequest{}, Response: domain.TOTPEnrollment{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/login/2fa/enroll/confirm", Tag: "Two-Factor",
+		Summary: "Confirm the enrollment, receive a JWT and the recovery codes",
+		Body:    domain.TwoFactorConfirmRequest{}, Response: domain.AuthResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/me/2fa/", Tag: "Two-Factor", Auth: true,
+		Summary:  "Two-factor status of the current user",
+		Response: domain.TwoFactorStatus{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/me/2fa/enroll", Tag: "Two-Factor", Auth: true,
+		Summary:  "Create a TOTP secret with otpauth URL and QR code",
+		Response: domain.TOTPEnrollment{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/me/2fa/confirm", Tag: "Two-Factor", Auth: true,
+		Summary: "Enable two-factor with a first code and receive the recovery codes",
+		Body:    domain.TwoFactorCodeRequest{}, Response: domain.RecoveryCodesResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/me/2fa/recovery-codes", Tag: "Two-Factor", Auth: true,
+		Summary: "Replace the recovery codes",
+		Body:    domain.TwoFactorCodeRequest{}, Response: domain.RecoveryCodesResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/me/2fa/", Tag: "Two-Factor", Auth: true,
+		Summary: "Disable two-factor, not allowed when required by role",
+		Body:    domain.TwoFactorCodeRequest{},
+	})
+
+	// Child
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/childs/", Tag: "Child", Auth: true,
+		Summary: "Create a child (admin)",
+		Body:    domain.CreateChildRequest{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/childs/:id", Tag: "Child", Auth: true,
+		Summary:  "Get a child with parents and teachers",
+		Response: domain.Child{},
+	})
+
+	// Teacher Attendance
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/teacher-attendances/me/clock-in", Tag: "Teacher Attendance", Auth: true,
+		Summary: "Clock in at a work location",
+		Body:    domain.CreateTeacherAttendanceRequest{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/teacher-attendances/me/clock-out", Tag: "Teacher Attendance", Auth: true,
+		Summary: "Clock out at a work location",
+		Body:    domain.CreateTeacherAttendanceRequest{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/teacher-attendances/me/last", Tag: "Teacher Attendance", Auth: true,
+		Summary:  "Last attendance of the current teacher",
+		Response: domain.TeacherAttendance{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/teacher-attendances/me", Tag: "Teacher Attendance", Auth: true,
+		Summary:  "Paginated attendances of the current teacher",
+		Response: domain.TeacherAttendance{}, Paginated: true,
+	})
+
+	// Child Attendance
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/child-attendances/", Tag: "Child Attendance", Auth: true,
+		Summary: "Record a child arrival (staff)",
+		Body:    domain.CreateChildAttendanceRequest{}, Form: true, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/child-attendances/departure", Tag: "Child Attendance", Auth: true,
+		Summary: "Record a child departure on the open arrival of that day (staff)",
+		Body:    domain.ChildDepartureRequest{}, Form: true,
+	})
+
+	// Classrooms
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/classrooms/", Tag: "Classrooms", Auth: true,
+		Summary:  "List classrooms with their teachers and children",
+		Response: []domain.ClassroomResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/classrooms/", Tag: "Classrooms", Auth: true,
+		Summary: "Create a classroom with a unique name (admin)",
+		Body:    domain.ClassroomRequest{}, Response: domain.ClassroomResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/classrooms/ratios", Tag: "Classrooms", Auth: true,
+		Summary:  "Live staff-to-child ratio of every classroom",
+		Response: []domain.ClassroomRatio{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/classrooms/alerts", Tag: "Classrooms", Auth: true,
+		Summary:  "Ratio compliance alerts, open ones by default",
+		Response: []domain.ClassroomAlertResponse{},
+		Query: []openapi.Parameter{
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"open", "resolved", "all"}}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/classrooms/:id", Tag: "Classrooms", Auth: true,
+		Summary:  "Get a classroom",
+		Response: domain.ClassroomResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPatch, Path: "/api/v1/classrooms/:id", Tag: "Classrooms", Auth: true,
+		Summary: "Update a classroom, capacity cannot drop below the enrolled children (admin)",
+		Body:    domain.ClassroomRequest{}, Response: domain.ClassroomResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/classrooms/:id/ratio", Tag: "Classrooms", Auth: true,
+		Summary:  "Live staff-to-child ratio of a classroom",
+		Response: domain.ClassroomRatio{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/classrooms/:id/teachers", Tag: "Classrooms", Auth: true,
+		Summary: "Replace the teacher roster, at most one lead (admin)",
+		Body:    domain.ClassroomTeachersRequest{}, Response: domain.ClassroomResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/classrooms/:id/children", Tag: "Classrooms", Auth: true,
+		Summary: "Place a child in the classroom, checking age range and capacity (admin)",
+		Body:    domain.ClassroomChildRequest{}, Response: domain.ClassroomResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/classrooms/:id/children/:childId", Tag: "Classrooms", Auth: true,
+		Summary: "Remove a child from the classroom (admin)",
+	})
+
+	// Enrollments
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/enrollments/", Tag: "Enrollments", Auth: true,
+		Summary:  "List enrollments (admin)",
+		Response: domain.EnrollmentResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"inquiry", "waitlisted", "offered", "enrolled", "paused", "withdrawn", "graduated"}}},
+			{Name: "classroomId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/enrollments/", Tag: "Enrollments", Auth: true,
+		Summary: "Record an inquiry or put a child on a classroom waitlist, one open enrollment per child (admin)",
+		Body:    domain.EnrollmentRequest{}, Response: domain.EnrollmentResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/enrollments/waitlist/:id", Tag: "Enrollments", Auth: true,
+		Summary:  "Ranked waitlist of a classroom (admin)",
+		Response: []domain.WaitlistEntryResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/enrollments/waitlist/:id/offers", Tag: "Enrollments", Auth: true,
+		Summary:  "Offer the free seats of a classroom to the top of its waitlist (admin)",
+		Response: domain.WaitlistOffersResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/enrollments/:id", Tag: "Enrollments", Auth: true,
+		Summary:  "Get an enrollment with its status history (admin)",
+		Response: domain.EnrollmentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPatch, Path: "/api/v1/enrollments/:id", Tag: "Enrollments", Auth: true,
+		Summary: "Update programme, schedule, dates, priority or the classroom applied for (admin)",
+		Body:    domain.UpdateEnrollmentRequest{}, Response: domain.EnrollmentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/enrollments/:id/transitions", Tag: "Enrollments", Auth: true,
+		Summary: "Move an enrollment to another status (admin)",
+		Body:    domain.EnrollmentTransitionRequest{}, Response: domain.EnrollmentResponse{},
+	})
+
+	// Calendar
+	calendarRange := []openapi.Parameter{
+		{Name: "from", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		{Name: "to", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+	}
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/calendar/days", Tag: "Calendar", Auth: true,
+		Summary:  "Holidays, closures and half days between from and to, the next 90 days by default",
+		Response: []domain.CalendarDayResponse{},
+		Query:    calendarRange,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/calendar/days", Tag: "Calendar", Auth: true,
+		Summary: "Add a holiday, closure or half day with its hours, one entry per date (admin)",
+		Body:    domain.CalendarDayRequest{}, Response: domain.CalendarDayResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/calendar/days/:id", Tag: "Calendar", Auth: true,
+		Summary: "Remove a calendar day (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/calendar/import", Tag: "Calendar", Auth: true,
+		Summary:  "Import the all-day events of an iCalendar file as calendar days, skipping dates already present (admin)",
+		Response: domain.CalendarImportResponse{},
+		Query: []openapi.Parameter{
+			{Name: "kind", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"holiday", "closure"}}},
+		},
+		RawBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"text/calendar": {Schema: &openapi.Schema{Type: "string"}},
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Required: []string{"file"}, Properties: map[string]*openapi.Schema{
+				"file": {Type: "string", Format: "binary"},
+			}}},
+		}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/calendar/working-days", Tag: "Calendar", Auth: true,
+		Summary:  "Opening hours of every day between from and to with the working day totals, the next 30 days by default",
+		Response: domain.WorkingDaysResponse{},
+		Query:    calendarRange,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/calendar/events", Tag: "Calendar", Auth: true,
+		Summary:  "Events between from and to, the next 90 days by default. Parents do not see staff events",
+		Response: []domain.CalendarEventResponse{},
+		Query:    calendarRange,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/calendar/events", Tag: "Calendar", Auth: true,
+		Summary: "Create an event (admin)",
+		Body:    domain.CalendarEventRequest{}, Response: domain.CalendarEventResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/calendar/events/:id", Tag: "Calendar", Auth: true,
+		Summary: "Replace an event (admin)",
+		Body:    domain.CalendarEventRequest{}, Response: domain.CalendarEventResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/calendar/events/:id", Tag: "Calendar", Auth: true,
+		Summary: "Delete an event (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/calendar/feed-token", Tag: "Calendar", Auth: true,
+		Summary:  "Create a secret iCal feed URL for the user, replacing the previous one",
+		Response: domain.CalendarFeedResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/calendar/feed/:token", Tag: "Calendar",
+		Summary:     "iCal feed of closures, half days and events from a month ago to a year ahead, the token authenticates",
+		RawResponse: &openapi.Response{Description: "iCalendar feed", Content: map[string]openapi.MediaType{"text/calendar": {Schema: &openapi.Schema{Type: "string"}}}},
+	})
+
+	// Medical
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medical-profiles/alerts", Tag: "Medical", Auth: true,
+		Summary:  "Allergies, medication due today, overdue immunizations and conditions per child, serious allergies first (staff)",
+		Response: []domain.MedicalAlertResponse{},
+		Query: []openapi.Parameter{
+			{Name: "classroomId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medical-profiles/:childId", Tag: "Medical", Auth: true,
+		Summary:  "Medical profile of a child (staff and its parents)",
+		Response: domain.MedicalProfileResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/medical-profiles/:childId", Tag: "Medical", Auth: true,
+		Summary: "Replace the medical profile of a child (its parents and admins)",
+		Body:    domain.MedicalProfileRequest{}, Response: domain.MedicalProfileResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medical-profiles/:childId/allergy-check", Tag: "Medical", Auth: true,
+		Summary:  "Match the allergens of a child against a meal name or ingredients (staff)",
+		Response: domain.AllergyCheckResponse{},
+		Query: []openapi.Parameter{
+			{Name: "meal", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+	})
+
+	// Medications
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medications/daily", Tag: "Medications", Auth: true,
+		Summary:  "Doses scheduled on a day (today by default) with due, overdue, missed and recorded ones (staff)",
+		Response: domain.MedicationDailyResponse{},
+		Query: []openapi.Parameter{
+			{Name: "date", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medications/requests", Tag: "Medications", Auth: true,
+		Summary:  "Medication requests, parents only see their children",
+		Response: []domain.MedicationRequestResponse{},
+		Query: []openapi.Parameter{
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"active", "cancelled"}}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/medications/requests", Tag: "Medications", Auth: true,
+		Summary: "Ask the teachers to give a medicine, with consent (parents of the child)",
+		Body:    domain.CreateMedicationRequest{}, Response: domain.MedicationRequestResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/medications/requests/:id", Tag: "Medications", Auth: true,
+		Summary:  "Medication request with its recorded doses",
+		Response: domain.MedicationRequestResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/medications/requests/:id/cancel", Tag: "Medications", Auth: true,
+		Summary:  "Cancel a medication request (parents of the child and admins)",
+		Response: domain.MedicationRequestResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/medications/requests/:id/administrations", Tag: "Medications", Auth: true,
+		Summary: "Record a scheduled dose as given or refused with a witness, on the child diary of the day (staff)",
+		Body:    domain.RecordAdministrationRequest{}, Response: domain.MedicationAdministrationResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/medications/administrations/:id/countersign", Tag: "Medications", Auth: true,
+		Summary:  "Countersign a dose as its witness",
+		Response: domain.MedicationAdministrationResponse{},
+	})
+
+	// Incidents
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/incidents/", Tag: "Incidents", Auth: true,
+		Summary:  "Incidents, newest first. Parents only see the reviewed incidents of their children",
+		Response: []domain.IncidentResponse{},
+		Query: []openapi.Parameter{
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "type", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"injury", "illness", "behaviour", "other"}}},
+			{Name: "severity", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"minor", "moderate", "serious"}}},
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"pending_review", "reviewed", "acknowledged"}}},
+			{Name: "from", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "to", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/incidents/", Tag: "Incidents", Auth: true,
+		Summary: "Report an incident, serious ones are mailed to the admins (staff)",
+		Body:    domain.IncidentRequest{}, Response: domain.IncidentResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/incidents/children/:childId", Tag: "Incidents", Auth: true,
+		Summary:  "Incident history of a child with counts by type and severity",
+		Response: domain.IncidentHistoryResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/incidents/:id", Tag: "Incidents", Auth: true,
+		Summary:  "Incident report",
+		Response: domain.IncidentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/incidents/:id", Tag: "Incidents", Auth: true,
+		Summary: "Correct an incident before its review (reporter and admins)",
+		Body:    domain.IncidentRequest{}, Response: domain.IncidentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/incidents/:id/review", Tag: "Incidents", Auth: true,
+		Summary: "Review an incident and share it with the parents (admin)",
+		Body:    domain.ReviewIncidentRequest{}, Response: domain.IncidentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/incidents/:id/acknowledge", Tag: "Incidents", Auth: true,
+		Summary: "Acknowledge a reviewed incident (parents of the child)",
+		Body:    domain.AcknowledgeIncidentRequest{}, Response: domain.IncidentResponse{},
+	})
+
+	// Attachments
+	attachmentOwner := []openapi.Parameter{
+		{Name: "ownerType", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []any{"child", "diary", "incident"}}},
+		{Name: "ownerId", In: "query", Required: true, Schema: &openapi.Schema{Type: "integer"}},
+	}
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/attachments/", Tag: "Attachments", Auth: true,
+		Summary:  "Attachments of a record with signed download URLs",
+		Response: []domain.AttachmentResponse{},
+		Query:    attachmentOwner,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/attachments/", Tag: "Attachments", Auth: true,
+		Summary:  "Upload a JPEG, PNG or PDF file to a child, diary or incident. Images lose their metadata and get a thumbnail",
+		Response: domain.AttachmentResponse{}, Status: fiber.StatusCreated,
+		RawBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Required: []string{"file", "ownerType", "ownerId"}, Properties: map[string]*openapi.Schema{
+				"file":      {Type: "string", Format: "binary"},
+				"ownerType": {Type: "string", Enum: []any{"child", "diary", "incident"}},
+				"ownerId":   {Type: "integer"},
+			}}},
+		}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/attachments/:id", Tag: "Attachments", Auth: true,
+		Summary:  "Attachment with fresh signed download URLs",
+		Response: domain.AttachmentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/attachments/:id", Tag: "Attachments", Auth: true,
+		Summary: "Delete an attachment and its files (uploader and admins)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/attachments/:id/download", Tag: "Attachments",
+		Summary: "Download the file of a signed URL, no token needed",
+		Query: []openapi.Parameter{
+			{Name: "variant", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"original", "thumbnail"}}},
+			{Name: "expires", In: "query", Required: true, Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "signature", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		RawResponse: &openapi.Response{Description: "The file", Content: map[string]openapi.MediaType{
+			"image/jpeg":      {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			"image/png":       {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			"application/pdf": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+		}},
+	})
+
+	// Photos
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/photos/children/:childId/consent", Tag: "Photos", Auth: true,
+		Summary:  "Photo consent of a child",
+		Response: domain.PhotoConsentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/photos/children/:childId/consent", Tag: "Photos", Auth: true,
+		Summary: "Give or withdraw the photo consent (parents and admins). Withdrawing it untags the child from every post",
+		Body:    domain.PhotoConsentRequest{}, Response: domain.PhotoConsentResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/photos/children/:childId/download", Tag: "Photos", Auth: true,
+		Summary: "Zip of the published photos of a child, at most 184 days",
+		Query: []openapi.Parameter{
+			{Name: "from", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "to", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+		RawResponse: &openapi.Response{Description: "Zip archive", Content: map[string]openapi.MediaType{
+			"application/zip": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+		}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/photos/posts", Tag: "Photos", Auth: true,
+		Summary:  "Photo feed, parents only see the published posts tagging their children",
+		Response: domain.PhotoPostResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "drafts", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "from", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "to", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/photos/posts", Tag: "Photos", Auth: true,
+		Summary: "Start a draft post tagged with children having a photo consent (staff)",
+		Body:    domain.PhotoPostRequest{}, Response: domain.PhotoPostResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/photos/posts/:id", Tag: "Photos", Auth: true,
+		Summary:  "Photo post with signed photo URLs",
+		Response: domain.PhotoPostResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/photos/posts/:id", Tag: "Photos", Auth: true,
+		Summary: "Change the caption and tags of a post (author and admins)",
+		Body:    domain.PhotoPostRequest{}, Response: domain.PhotoPostResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/photos/posts/:id", Tag: "Photos", Auth: true,
+		Summary: "Delete a post with its photos (author and admins)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/photos/posts/:id/photos", Tag: "Photos", Auth: true,
+		Summary:  "Add a JPEG or PNG photo to a post (author and admins)",
+		Response: domain.PhotoPostResponse{}, Status: fiber.StatusCreated,
+		RawBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Required: []string{"file"}, Properties: map[string]*openapi.Schema{
+				"file": {Type: "string", Format: "binary"},
+			}}},
+		}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/photos/posts/:id/publish", Tag: "Photos", Auth: true,
+		Summary:  "Share a draft with the parents of the tagged children (author and admins)",
+		Response: domain.PhotoPostResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/photos/posts/:id/reaction", Tag: "Photos", Auth: true,
+		Summary: "Set the reaction of the user on a post",
+		Body:    domain.PhotoReactionRequest{}, Response: domain.PhotoPostResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/photos/posts/:id/reaction", Tag: "Photos", Auth: true,
+		Summary: "Remove the reaction of the user",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/photos/posts/:id/comments", Tag: "Photos", Auth: true,
+		Summary: "Comment on a post",
+		Body:    domain.PhotoCommentRequest{}, Response: domain.PhotoCommentResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/photos/comments/:id", Tag: "Photos", Auth: true,
+		Summary: "Delete a comment (writer and admins)",
+	})
+
+	// Messages
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/messages/unread", Tag: "Messages", Auth: true,
+		Summary:  "Number of threads with unread messages and of unread messages",
+		Response: domain.UnreadMessagesResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/messages/threads", Tag: "Messages", Auth: true,
+		Summary:  "Threads the user takes part in with their unread counts, admins see every thread",
+		Response: domain.MessageThreadResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "kind", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"child", "classroom", "center"}}},
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "classroomId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "unread", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/messages/threads", Tag: "Messages", Auth: true,
+		Summary: "Start a conversation about a child, or an announcement to a classroom (its teachers) or the center (admins)",
+		Body:    domain.MessageThreadRequest{}, Response: domain.MessageThreadResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/messages/threads/:id", Tag: "Messages", Auth: true,
+		Summary:  "Thread with its unread count",
+		Response: domain.MessageThreadResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/messages/threads/:id/messages", Tag: "Messages", Auth: true,
+		Summary:  "Messages of a thread, newest first, with read receipts",
+		Response: domain.MessageResponse{}, Paginated: true,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/messages/threads/:id/messages", Tag: "Messages", Auth: true,
+		Summary:  "Send a message, as JSON or as a multipart form with up to five files",
+		Response: domain.MessageResponse{}, Status: fiber.StatusCreated,
+		RawBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/json": {Schema: &openapi.Schema{Type: "object", Required: []string{"body"}, Properties: map[string]*openapi.Schema{
+				"body": {Type: "string"},
+			}}},
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"body":  {Type: "string"},
+				"files": {Type: "array", Items: &openapi.Schema{Type: "string", Format: "binary"}},
+			}}},
+		}},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/messages/threads/:id/read", Tag: "Messages", Auth: true,
+		Summary: "Mark the thread read up to a message, or up to the latest message without a body",
+		Body:    domain.MarkReadRequest{}, Response: domain.MessageThreadResponse{},
+	})
+
+	// Notifications
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/notifications/", Tag: "Notifications", Auth: true,
+		Summary:  "In-app inbox, newest first",
+		Response: domain.NotificationResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "unread", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/notifications/unread", Tag: "Notifications", Auth: true,
+		Summary:  "Number of unread notifications in the inbox",
+		Response: domain.UnreadNotificationsResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/notifications/read", Tag: "Notifications", Auth: true,
+		Summary: "Mark every notification in the inbox read",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/notifications/:id/read", Tag: "Notifications", Auth: true,
+		Summary: "Mark a notification read",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/notifications/settings", Tag: "Notifications", Auth: true,
+		Summary:  "Quiet hours and the channels of every kind of notification",
+		Response: domain.NotificationSettingsResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/notifications/settings", Tag: "Notifications", Auth: true,
+		Summary: "Replace the quiet hours (empty to turn them off) and the channels of the listed kinds",
+		Body:    domain.NotificationSettingsRequest{}, Response: domain.NotificationSettingsResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/notifications/devices", Tag: "Notifications", Auth: true,
+		Summary:  "Devices registered for push notifications",
+		Response: []domain.PushDeviceResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/notifications/devices", Tag: "Notifications", Auth: true,
+		Summary: "Register the push token of a device",
+		Body:    domain.PushDeviceRequest{}, Response: domain.PushDeviceResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/notifications/devices/:id", Tag: "Notifications", Auth: true,
+		Summary: "Stop push notifications to a device",
+	})
+
+	// Webhooks
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/webhooks/", Tag: "Webhooks", Auth: true,
+		Summary:  "Webhook subscriptions (admin)",
+		Response: []domain.WebhookSubscriptionResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/webhooks/", Tag: "Webhooks", Auth: true,
+		Summary: "Subscribe an endpoint to teacher_attendance.closed or child_attendance.departed (admin). The signing secret is only returned here",
+		Body:    domain.WebhookSubscriptionRequest{}, Response: domain.WebhookSubscriptionResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/webhooks/deliveries/:id/replay", Tag: "Webhooks", Auth: true,
+		Summary:  "Send the event of a delivery to its subscription again as a new delivery (admin)",
+		Response: domain.WebhookDeliveryResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/webhooks/:id", Tag: "Webhooks", Auth: true,
+		Summary:  "Webhook subscription (admin)",
+		Response: domain.WebhookSubscriptionResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPut, Path: "/api/v1/webhooks/:id", Tag: "Webhooks", Auth: true,
+		Summary: "Replace a webhook subscription, inactive ones hold their deliveries (admin)",
+		Body:    domain.WebhookSubscriptionRequest{}, Response: domain.WebhookSubscriptionResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/webhooks/:id", Tag: "Webhooks", Auth: true,
+		Summary: "Delete a webhook subscription, failing its pending deliveries (admin)",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/webhooks/:id/rotate-secret", Tag: "Webhooks", Auth: true,
+		Summary:  "Replace the signing secret, returned only here (admin)",
+		Response: domain.WebhookSubscriptionResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/webhooks/:id/deliveries", Tag: "Webhooks", Auth: true,
+		Summary:  "Delivery log of a webhook subscription, newest first (admin)",
+		Response: domain.WebhookDeliveryResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"pending", "delivered", "failed"}}},
+		},
+	})
+
+	// Jobs
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/jobs/", Tag: "Jobs", Auth: true,
+		Summary:  "Background jobs, newest first; status dead lists the dead letters (admin)",
+		Response: domain.JobResponse{}, Paginated: true,
+		Query: []openapi.Parameter{
+			{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"pending", "running", "succeeded", "dead", "cancelled"}}},
+			{Name: "type", In: "query", Schema: &openapi.Schema{Type: "string"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/jobs/schedules", Tag: "Jobs", Auth: true,
+		Summary:  "Cron-style schedules queueing jobs, with their next run (admin)",
+		Response: []domain.JobScheduleResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/jobs/:id", Tag: "Jobs", Auth: true,
+		Summary:  "Background job (admin)",
+		Response: domain.JobResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/jobs/:id/retry", Tag: "Jobs", Auth: true,
+		Summary:  "Run a dead or cancelled job again with fresh attempts, or a pending one right away (admin)",
+		Response: domain.JobResponse{},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/jobs/:id/cancel", Tag: "Jobs", Auth: true,
+		Summary:  "Cancel a pending job (admin)",
+		Response: domain.JobResponse{},
+	})
+
+	// Events
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/events", Tag: "Events", Auth: true,
+		Summary: "Server-sent events the user may see: child.arrived, child.departed, diary.entry_added, teacher.clocked_in, teacher.clocked_out, message.created and incident.reported. EventSource clients pass the token as access_token and resume with Last-Event-ID; a resync event asks them to reload",
+		Query: []openapi.Parameter{
+			{Name: "Last-Event-ID", In: "header", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "lastEventId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "access_token", In: "query", Schema: &openapi.Schema{Type: "string"}},
+		},
+		RawResponse: &openapi.Response{Description: "The event stream", Content: map[string]openapi.MediaType{
+			"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+		}},
+	})
+
+	// Absences
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/absences/", Tag: "Absences", Auth: true,
+		Summary:  "Absences of a month, parents only see their children",
+		Response: []domain.AbsenceResponse{},
+		Query: []openapi.Parameter{
+			{Name: "childId", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "month", In: "query", Schema: &openapi.Schema{Type: "string", Example: "2025-02"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodPost, Path: "/api/v1/absences/", Tag: "Absences", Auth: true,
+		Summary: "Report a child absent on the expected days of a date range",
+		Body:    domain.ReportAbsenceRequest{}, Response: []domain.AbsenceResponse{}, Status: fiber.StatusCreated,
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/absences/report", Tag: "Absences", Auth: true,
+		Summary:  "Monthly expected, attended and absent days per child (staff)",
+		Response: domain.AbsenceReportResponse{},
+		Query: []openapi.Parameter{
+			{Name: "month", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Example: "2025-02"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodDelete, Path: "/api/v1/absences/:id", Tag: "Absences", Auth: true,
+		Summary: "Cancel a reported absence, parents only upcoming ones",
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/expected-attendance/", Tag: "Absences", Auth: true,
+		Summary:  "Children expected on a day with their arrival or absence (staff)",
+		Response: domain.ExpectedAttendanceResponse{},
+		Query: []openapi.Parameter{
+			{Name: "date", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+	})
+	doc.Add(openapi.Route{
+		Method: fiber.MethodGet, Path: "/api/v1/expected-attendance/not-arrived", Tag: "Absences", Auth: true,
+		Summary:  "Expected children without an absence who had not arrived by the cutoff (staff)",
+		Response: domain.NotArrivedResponse{},
+		Query: []openapi.Parameter{
+			{Name: "date", In: "query", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "cutoff", In: "query", Schema: &openapi.Schema{Type: "string", Example: "09:00"}},
+		},
+	})
+
+	return doc
+}
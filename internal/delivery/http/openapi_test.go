@@ -0,0 +1,31 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/openapi"
+)
+
+func TestOpenAPIDocumentCoversAllRoutes(t *testing.T) {
+	app := fiber.New()
+	next := func(c *fiber.Ctx) error { return c.Next() }
+	RegisterRoutes(app, Services{
+		AppName:       "test",
+		Auth:          next,
+		AuthRateLimit: next,
+		StreamAuth:    next,
+	})
+
+	doc := NewOpenAPIDocument("test")
+
+	for _, route := range app.GetRoutes(true) {
+		// Fiber registers HEAD automatically for every GET route
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		if !doc.Has(route.Method, route.Path) {
+			t.Errorf("route %s %s is missing from the OpenAPI document", route.Method, openapi.Path(route.Path))
+		}
+	}
+}
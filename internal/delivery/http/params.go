@@ -0,0 +1,23 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+)
+
+// paramID parses the :id route parameter.
+func paramID(c *fiber.Ctx) (uint, error) {
+	return paramUint(c, "id")
+}
+
+// paramUint parses a named route parameter holding a positive id.
+func paramUint(c *fiber.Ctx, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Params(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, apperror.Validation(types.FieldError{Field: name, Message: name + " must be a positive integer"})
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,290 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type PhotoHandler struct {
+	usecase usecase.PhotoUsecase
+}
+
+func NewPhotoHandler(api fiber.Router, usecase usecase.PhotoUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *PhotoHandler {
+	handler := &PhotoHandler{usecase}
+	staffOnly := requireRole(userUsecase, "You are not allowed to post photos", domain.RoleAdmin, domain.RoleTeacher)
+
+	photoGroup := api.Group("/photos")
+	photoGroup.Use(auth)
+	photoGroup.Get("/children/:childId/consent", handler.GetConsent)
+	photoGroup.Put("/children/:childId/consent", handler.SetConsent)
+	photoGroup.Get("/children/:childId/download", handler.Download)
+	photoGroup.Get("/posts", handler.Feed)
+	photoGroup.Post("/posts", staffOnly, handler.CreatePost)
+	photoGroup.Get("/posts/:id", handler.GetPost)
+	photoGroup.Put("/posts/:id", staffOnly, handler.UpdatePost)
+	photoGroup.Delete("/posts/:id", staffOnly, handler.DeletePost)
+	photoGroup.Post("/posts/:id/photos", staffOnly, handler.AddPhoto)
+	photoGroup.Post("/posts/:id/publish", staffOnly, handler.Publish)
+	photoGroup.Put("/posts/:id/reaction", handler.React)
+	photoGroup.Delete("/posts/:id/reaction", handler.Unreact)
+	photoGroup.Post("/posts/:id/comments", handler.Comment)
+	photoGroup.Delete("/comments/:id", handler.DeleteComment)
+	return handler
+}
+
+func (h *PhotoHandler) GetConsent(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	consent, err := h.usecase.GetConsent(c.UserContext(), uint(*id), childId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", consent)
+}
+
+func (h *PhotoHandler) SetConsent(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+	var input domain.PhotoConsentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	consent, err := h.usecase.SetConsent(c.UserContext(), uint(*id), childId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Photo consent saved", consent)
+}
+
+func (h *PhotoHandler) Feed(c *fiber.Ctx) error {
+	var filter domain.PhotoFeedFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	posts, totalPage, err := h.usecase.Feed(c.UserContext(), uint(*id), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, posts)
+}
+
+func (h *PhotoHandler) GetPost(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.GetPost(c.UserContext(), uint(*id), postId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", post)
+}
+
+func (h *PhotoHandler) CreatePost(c *fiber.Ctx) error {
+	var input domain.PhotoPostRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.CreatePost(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Photo post created", post)
+}
+
+func (h *PhotoHandler) UpdatePost(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.PhotoPostRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.UpdatePost(c.UserContext(), uint(*id), postId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Photo post updated", post)
+}
+
+func (h *PhotoHandler) DeletePost(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.DeletePost(c.UserContext(), uint(*id), postId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Photo post deleted", nil)
+}
+
+// AddPhoto reads a multipart form with the image in the file field
+func (h *PhotoHandler) AddPhoto(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return apperror.BadRequest("the file field is required").Wrap(err)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.AddPhoto(c.UserContext(), uint(*id), postId, fileHeader.Filename, data)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Photo added", post)
+}
+
+func (h *PhotoHandler) Publish(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.Publish(c.UserContext(), uint(*id), postId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Photo post published", post)
+}
+
+func (h *PhotoHandler) React(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.PhotoReactionRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	post, err := h.usecase.React(c.UserContext(), uint(*id), postId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Reaction saved", post)
+}
+
+func (h *PhotoHandler) Unreact(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.Unreact(c.UserContext(), uint(*id), postId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Reaction removed", nil)
+}
+
+func (h *PhotoHandler) Comment(c *fiber.Ctx) error {
+	postId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.PhotoCommentRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	comment, err := h.usecase.Comment(c.UserContext(), uint(*id), postId, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Comment added", comment)
+}
+
+func (h *PhotoHandler) DeleteComment(c *fiber.Ctx) error {
+	commentId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.DeleteComment(c.UserContext(), uint(*id), commentId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Comment deleted", nil)
+}
+
+// Download streams the photos of the child as a zip. The response has
+// started once the archive is written, so later failures are only logged.
+func (h *PhotoHandler) Download(c *fiber.Ctx) error {
+	childId, err := paramUint(c, "childId")
+	if err != nil {
+		return err
+	}
+	var query domain.PhotoArchiveQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(query); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	ctx := c.UserContext()
+	child, attachments, err := h.usecase.ChildPhotos(ctx, uint(*id), childId, query)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="photos-%d-%s-%s.zip"`, child.ID, query.From, query.To))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.usecase.WriteArchive(ctx, w, attachments); err != nil {
+			logger.FromContext(ctx).Error("failed to write photo archive", "child_id", child.ID, "error", err.Error())
+			return
+		}
+		w.Flush()
+	})
+	return nil
+}
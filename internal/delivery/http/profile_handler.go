@@ -0,0 +1,80 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type ProfileHandler struct {
+	usecase usecase.UserUsecase
+}
+
+func NewProfileHandler(api fiber.Router, usecase usecase.UserUsecase, auth fiber.Handler, authRateLimit fiber.Handler) *ProfileHandler {
+	handler := &ProfileHandler{usecase}
+	api.Get("/me", auth, handler.GetProfile)
+	api.Patch("/me", auth, handler.UpdateProfile)
+	api.Post("/me/email", auth, authRateLimit, handler.ChangeEmail)
+	api.Post("/email/verify", authRateLimit, handler.VerifyEmail)
+	return handler
+}
+
+func (h *ProfileHandler) GetProfile(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	user, err := h.usecase.GetProfile(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewUserResponse(user))
+}
+
+func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	var input domain.UpdateProfileRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	user, err := h.usecase.UpdateProfile(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Profile updated", domain.NewUserResponse(user))
+}
+
+// ChangeEmail asks for the password again so a stolen token alone cannot
+// move the account to another address.
+func (h *ProfileHandler) ChangeEmail(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	var input domain.ChangeEmailRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	user, err := h.usecase.GetProfile(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	if _, err := h.usecase.Authenticate(c.UserContext(), user.Email, input.Password); err != nil {
+		return err
+	}
+
+	if err := h.usecase.RequestEmailChange(c.UserContext(), user.ID, input.Email); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusAccepted, "Verification email sent to the new address", nil)
+}
+
+func (h *ProfileHandler) VerifyEmail(c *fiber.Ctx) error {
+	var input domain.VerifyEmailRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	if err := h.usecase.VerifyEmail(c.UserContext(), input.Token); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Email verified", nil)
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type RoleHandler struct {
+	usecase usecase.RoleUsecase
+}
+
+func NewRoleHandler(api fiber.Router, usecase usecase.RoleUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *RoleHandler {
+	handler := &RoleHandler{usecase}
+	adminOnly := requireAdmin(userUsecase, "You are not allowed to manage roles")
+
+	roleGroup := api.Group("/roles")
+	roleGroup.Use(auth, adminOnly)
+	roleGroup.Get("/", handler.ListRoles)
+	roleGroup.Post("/", handler.CreateRole)
+	roleGroup.Patch("/:id", handler.RenameRole)
+	roleGroup.Delete("/:id", handler.DeleteRole)
+	roleGroup.Put("/:id/permissions", handler.SetPermissions)
+
+	permissionGroup := api.Group("/permissions")
+	permissionGroup.Use(auth, adminOnly)
+	permissionGroup.Get("/", handler.ListPermissions)
+	permissionGroup.Post("/", handler.CreatePermission)
+	return handler
+}
+
+func (h *RoleHandler) ListRoles(c *fiber.Ctx) error {
+	roles, err := h.usecase.ListRoles(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewRoleDetailResponses(roles))
+}
+
+func (h *RoleHandler) CreateRole(c *fiber.Ctx) error {
+	var input domain.RoleRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	role, err := h.usecase.CreateRole(c.UserContext(), input.Name)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Role created", domain.NewRoleDetailResponse(role))
+}
+
+func (h *RoleHandler) RenameRole(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.RoleRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	role, err := h.usecase.RenameRole(c.UserContext(), id, input.Name)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Role renamed", domain.NewRoleDetailResponse(role))
+}
+
+func (h *RoleHandler) DeleteRole(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.usecase.DeleteRole(c.UserContext(), id); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Role deleted", nil)
+}
+
+func (h *RoleHandler) SetPermissions(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.UpdateRolePermissionsRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	role, err := h.usecase.SetPermissions(c.UserContext(), id, input.Permissions)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Permissions updated", domain.NewRoleDetailResponse(role))
+}
+
+func (h *RoleHandler) ListPermissions(c *fiber.Ctx) error {
+	permissions, err := h.usecase.ListPermissions(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewPermissionResponses(permissions))
+}
+
+func (h *RoleHandler) CreatePermission(c *fiber.Ctx) error {
+	var input domain.PermissionRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	permission, err := h.usecase.CreatePermission(c.UserContext(), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Permission created", domain.NewPermissionResponse(permission))
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+)
+
+// Services holds everything the HTTP handlers depend on.
+type Services struct {
+	AppName                  string
+	Auth                     fiber.Handler
+	AuthRateLimit            fiber.Handler
+	StreamAuth               fiber.Handler
+	UserUsecase              usecase.UserUsecase
+	TwoFactorUsecase         usecase.TwoFactorUsecase
+	RoleUsecase              usecase.RoleUsecase
+	ChildUsecase             usecase.ChildUsecase
+	ClassroomUsecase         usecase.ClassroomUsecase
+	EnrollmentUsecase        usecase.EnrollmentUsecase
+	CalendarUsecase          usecase.CalendarUsecase
+	AbsenceUsecase           usecase.AbsenceUsecase
+	MedicalUsecase           usecase.MedicalUsecase
+	MedicationUsecase        usecase.MedicationUsecase
+	IncidentUsecase          usecase.IncidentUsecase
+	AttachmentUsecase        usecase.AttachmentUsecase
+	PhotoUsecase             usecase.PhotoUsecase
+	MessageUsecase           usecase.MessageUsecase
+	TeacherAttendanceUsecase usecase.TeacherAttendanceUsecase
+	ChildAttendanceUsecase   usecase.ChildAttendanceUsecase
+	NotificationUsecase      usecase.NotificationUsecase
+	WebhookUsecase           usecase.WebhookUsecase
+	JobUsecase               usecase.JobUsecase
+	EventUsecase             usecase.EventUsecase
+	HealthUsecase            usecase.HealthUsecase
+}
+
+// RegisterRoutes registers the health probes on the root and every API route
+// on the /api/v1 group. It is shared by main and the OpenAPI coverage test so
+// both see the same route table.
+func RegisterRoutes(app fiber.Router, s Services) {
+	NewHealthHandler(app, s.HealthUsecase)
+	app.Get("/metrics", metrics.Handler())
+
+	api := app.Group("/api/v1")
+	NewDocsHandler(api, NewOpenAPIDocument(s.AppName))
+	NewUserHandler(api, s.UserUsecase, s.TwoFactorUsecase, s.Auth, s.AuthRateLimit)
+	NewTwoFactorHandler(api, s.TwoFactorUsecase, s.UserUsecase, s.Auth, s.AuthRateLimit)
+	NewProfileHandler(api, s.UserUsecase, s.Auth, s.AuthRateLimit)
+	NewUserAdminHandler(api, s.UserUsecase, s.Auth)
+	NewRoleHandler(api, s.RoleUsecase, s.UserUsecase, s.Auth)
+	NewChildHandler(api, s.ChildUsecase, s.Auth)
+	NewClassroomHandler(api, s.ClassroomUsecase, s.UserUsecase, s.Auth)
+	NewEnrollmentHandler(api, s.EnrollmentUsecase, s.UserUsecase, s.Auth)
+	NewCalendarHandler(api, s.CalendarUsecase, s.UserUsecase, s.Auth)
+	NewAbsenceHandler(api, s.AbsenceUsecase, s.UserUsecase, s.Auth)
+	NewMedicalHandler(api, s.MedicalUsecase, s.UserUsecase, s.Auth)
+	NewMedicationHandler(api, s.MedicationUsecase, s.UserUsecase, s.Auth)
+	NewIncidentHandler(api, s.IncidentUsecase, s.UserUsecase, s.Auth)
+	NewAttachmentHandler(api, s.AttachmentUsecase, s.Auth)
+	NewPhotoHandler(api, s.PhotoUsecase, s.UserUsecase, s.Auth)
+	NewMessageHandler(api, s.MessageUsecase, s.Auth)
+	NewTeacherAttendanceHandler(api, s.TeacherAttendanceUsecase, s.Auth)
+	NewChildAttendanceHandler(api, s.ChildAttendanceUsecase, s.UserUsecase, s.Auth)
+	NewNotificationHandler(api, s.NotificationUsecase, s.Auth)
+	NewWebhookHandler(api, s.WebhookUsecase, s.UserUsecase, s.Auth)
+	NewJobHandler(api, s.JobUsecase, s.UserUsecase, s.Auth)
+	NewEventHandler(api, s.EventUsecase, s.UserUsecase, s.StreamAuth)
+}
@@ -1,24 +1,22 @@
 package http
 
 import (
-	"time"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/usecase"
-	"github.com/whyaji/daycare-preschool-api/pkg/middleware"
-	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
 	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
 )
 
 type TeacherAttendanceHandler struct {
 	usecase usecase.TeacherAttendanceUsecase
 }
 
-func NewTeacherAttendanceHandler(api fiber.Router, usecase usecase.TeacherAttendanceUsecase) *TeacherAttendanceHandler {
+func NewTeacherAttendanceHandler(api fiber.Router, usecase usecase.TeacherAttendanceUsecase, auth fiber.Handler) *TeacherAttendanceHandler {
 	handler := &TeacherAttendanceHandler{usecase}
 	teacherAttendanceGroup := api.Group("/teacher-attendances")
-	teacherAttendanceGroup.Use(middleware.JWTProtected)
+	teacherAttendanceGroup.Use(auth)
 	teacherAttendanceGroup.Post("/me/clock-in", handler.ClockIn)
 	teacherAttendanceGroup.Put("/me/clock-out", handler.ClockOut)
 	teacherAttendanceGroup.Get("/me/last", handler.GetLastTeacherAttendance)
@@ -28,208 +26,99 @@ func NewTeacherAttendanceHandler(api fiber.Router, usecase usecase.TeacherAttend
 
 func (h *TeacherAttendanceHandler) ClockIn(c *fiber.Ctx) error {
 	id := utils.GetUserIDFromJwt(c)
-	isTeacher, err := h.usecase.CheckUserTeacher(uint(*id))
+	isTeacher, err := h.usecase.CheckUserTeacher(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isTeacher {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create teacher attendance"})
-	}
-
-	lastTeacherAttendance, err := h.usecase.CheckLastIsClockedOut(uint(*id))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return apperror.Forbidden("You are not allowed to access teacher attendance")
 	}
 
-	// timeNow := time.Now()
-	// create example timeNow is 2025-02-20 07:45:00
-	timeNow := time.Date(2025, 2, 20, 6, 40, 0, 0, time.Local)
-
-	var errors []string
-
-	// Create a map to first parse the raw JSON data
 	var requestData domain.CreateTeacherAttendanceRequest
-	if err := c.BodyParser(&requestData); err != nil {
-		errors = append(errors, err.Error())
-	}
-
-	// Validate required fields are not empty
-	errorsValidate := h.usecase.ValidateRequiredFieldsClock(&requestData)
-	errors = append(errors, errorsValidate...)
-
-	if len(errors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errors})
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
 	}
 
-	isArroundWorkLocation, err := h.usecase.CheckIsInWorkLocation(requestData.Latitude, requestData.Longitude)
+	created, err := h.usecase.ClockIn(c.UserContext(), uint(*id), requestData)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if !isArroundWorkLocation {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You are not in work location"})
-	}
-
-	var morningOvertime int
-	if requestData.IsOvertimeMorning {
-		// 08:00 subtrack with timeNow in minutes
-		// morningOvertime maximal is 60 minutes
-		morningOvertime = 480 - (timeNow.Hour()*60 + timeNow.Minute())
-		if morningOvertime > 60 {
-			morningOvertime = 60
-		}
-	}
-
-	// if lastTeacherAttendanceDate is today, then update the lastTeacherAttendance
-	if lastTeacherAttendance != nil && lastTeacherAttendance.Date.Format("2006-01-02") == timeNow.Format("2006-01-02") && lastTeacherAttendance.ClockIn == nil {
-		lastTeacherAttendance.ClockIn = &timeNow
-		lastTeacherAttendance.OvertimeMorning = morningOvertime
-		if err := h.usecase.UpdateTeacherAttendance(lastTeacherAttendance); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Teacher attendance updated successfully"})
-	}
-
-	teacherAttendance := &domain.TeacherAttendance{
-		UserID:          uint(*id),
-		Date:            timeNow,
-		ClockIn:         &timeNow,
-		OvertimeMorning: morningOvertime,
+		return err
 	}
-
-	if err := h.usecase.CreateTeacherAttendance(teacherAttendance); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if !created {
+		return utils.SendSuccess(c, fiber.StatusOK, "Teacher attendance updated successfully", nil)
 	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Teacher attendance created successfully"})
+	return utils.SendSuccess(c, fiber.StatusCreated, "Teacher attendance created successfully", nil)
 }
 
 func (h *TeacherAttendanceHandler) ClockOut(c *fiber.Ctx) error {
 	id := utils.GetUserIDFromJwt(c)
-	isTeacher, err := h.usecase.CheckUserTeacher(uint(*id))
+	isTeacher, err := h.usecase.CheckUserTeacher(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isTeacher {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create teacher attendance"})
+		return apperror.Forbidden("You are not allowed to access teacher attendance")
 	}
 
-	teacherAttendance, err := h.usecase.CheckLastIsClockedIn(uint(*id))
+	teacherAttendance, err := h.usecase.CheckLastIsClockedIn(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	// timeNow := time.Now()
-	// create example timeNow is 2025-02-20 17:30:00
-	timeNow := time.Date(2025, 2, 20, 16, 30, 0, 0, time.Local)
-
-	var errors []string
-
-	// Create a map to first parse the raw JSON data
 	var requestData domain.CreateTeacherAttendanceRequest
-	if err := c.BodyParser(&requestData); err != nil {
-		errors = append(errors, err.Error())
-	}
-
-	// Validate required fields are not empty
-	errorsValidate := h.usecase.ValidateRequiredFieldsClock(&requestData)
-	errors = append(errors, errorsValidate...)
-
-	if len(errors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errors})
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
 	}
 
-	isArroundWorkLocation, err := h.usecase.CheckIsInWorkLocation(requestData.Latitude, requestData.Longitude)
+	isArroundWorkLocation, err := h.usecase.CheckIsInWorkLocation(c.UserContext(), *requestData.Latitude, *requestData.Longitude)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isArroundWorkLocation {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You are not in work location"})
+		return apperror.BadRequest("You are not in work location").WithCode(apperror.CodeOutsideWorkLocation)
 	}
 
-	var afternoonOvertime int
-	if requestData.IsOvertimeEvening {
-		// 16:00 subtrack with timeNow in minutes
-		// afternoonOvertime maximal is 60 minutes
-		afternoonOvertime = (timeNow.Hour()*60 + timeNow.Minute()) - 960
-		if afternoonOvertime > 60 {
-			afternoonOvertime = 60
-		}
-	}
-
-	var startHour float32
-	startHourFlat := float32(teacherAttendance.ClockIn.Hour()) + float32(teacherAttendance.ClockIn.Minute())/60
-	if teacherAttendance.ClockIn != nil {
-		// if clockIn before 08:00, then startHour is start from 08:00
-		if startHourFlat < 8 {
-			startHour = 8
-		} else {
-			startHour = startHourFlat
-		}
-	}
-
-	// if clockOut after 16:00 then endHour is 16:00
-	var endHour float32
-	endHourFlat := float32(timeNow.Hour()) + float32(timeNow.Minute())/60
-	if endHourFlat > 16 {
-		endHour = 16
-	} else {
-		endHour = endHourFlat
-	}
-
-	// calculate workHour
-	workHour := endHour - startHour
-
-	teacherAttendance.ClockOut = &timeNow
-	teacherAttendance.OvertimeEvening = afternoonOvertime
-	// workHour with 1 decimal
-	teacherAttendance.WorkHour = float32(int(workHour*10)) / 10
-
-	if err := h.usecase.UpdateTeacherAttendance(teacherAttendance); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := h.usecase.ClockOut(c.UserContext(), teacherAttendance, requestData.IsOvertimeEvening); err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Teacher attendance updated successfully"})
+	return utils.SendSuccess(c, fiber.StatusOK, "Teacher attendance updated successfully", nil)
 }
 
 func (h *TeacherAttendanceHandler) GetLastTeacherAttendance(c *fiber.Ctx) error {
 	id := utils.GetUserIDFromJwt(c)
-	isTeacher, err := h.usecase.CheckUserTeacher(uint(*id))
+	isTeacher, err := h.usecase.CheckUserTeacher(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isTeacher {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create teacher attendance"})
+		return apperror.Forbidden("You are not allowed to access teacher attendance")
 	}
 
-	teacherAttendance, err := h.usecase.GetLastTeacherAttendanceByUserId(uint(*id))
+	teacherAttendance, err := h.usecase.GetLastTeacherAttendanceByUserId(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": teacherAttendance})
+	return utils.SendSuccess(c, fiber.StatusOK, "success", teacherAttendance)
 }
 
 func (h *TeacherAttendanceHandler) GetUserTeacherAttendance(c *fiber.Ctx) error {
 	// pagination get teacher attendance by user id
 	id := utils.GetUserIDFromJwt(c)
-	isTeacher, err := h.usecase.CheckUserTeacher(uint(*id))
+	isTeacher, err := h.usecase.CheckUserTeacher(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isTeacher {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create teacher attendance"})
+		return apperror.Forbidden("You are not allowed to access teacher attendance")
 	}
 
 	paginationFilter := utils.GetPaginationFilterFromQuery(c)
 
-	teacherAttendances, totalPage, err := h.usecase.GetTeacherAttendanceByUserId(uint(*id), paginationFilter)
+	teacherAttendances, totalPage, err := h.usecase.GetTeacherAttendanceByUserId(c.UserContext(), uint(*id), paginationFilter)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(types.PaginationResponse{
-		Page:      paginationFilter.Page,
-		TotalPage: totalPage,
-		Data:      teacherAttendances,
-	})
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, teacherAttendances)
 }
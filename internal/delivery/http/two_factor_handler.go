@@ -0,0 +1,165 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type TwoFactorHandler struct {
+	usecase     usecase.TwoFactorUsecase
+	userUsecase usecase.UserUsecase
+}
+
+func NewTwoFactorHandler(api fiber.Router, usecase usecase.TwoFactorUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler, authRateLimit fiber.Handler) *TwoFactorHandler {
+	handler := &TwoFactorHandler{usecase, userUsecase}
+
+	// Second login step, authenticated by the challenge token from /login
+	api.Post("/login/2fa", authRateLimit, handler.Verify)
+	api.Post("/login/2fa/enroll", authRateLimit, handler.LoginEnroll)
+	api.Post("/login/2fa/enroll/confirm", authRateLimit, handler.LoginConfirm)
+
+	twoFactorGroup := api.Group("/me/2fa")
+	twoFactorGroup.Use(auth)
+	twoFactorGroup.Get("/", handler.Status)
+	twoFactorGroup.Post("/enroll", handler.Enroll)
+	twoFactorGroup.Post("/confirm", handler.Confirm)
+	twoFactorGroup.Post("/recovery-codes", handler.RegenerateRecoveryCodes)
+	twoFactorGroup.Delete("/", handler.Disable)
+	return handler
+}
+
+func (h *TwoFactorHandler) Verify(c *fiber.Ctx) error {
+	var input domain.TwoFactorVerifyRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	userId, err := h.usecase.ParseChallenge(input.ChallengeToken, domain.MFAChallengeTOTP)
+	if err != nil {
+		return err
+	}
+
+	if err := h.usecase.Verify(c.UserContext(), userId, input.Code, input.RecoveryCode); err != nil {
+		return err
+	}
+
+	return h.issueToken(c, userId, nil)
+}
+
+func (h *TwoFactorHandler) LoginEnroll(c *fiber.Ctx) error {
+	var input domain.TwoFactorEnrollRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	userId, err := h.usecase.ParseChallenge(input.ChallengeToken, domain.MFAChallengeEnroll)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := h.usecase.Enroll(c.UserContext(), userId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Scan the QR code and confirm with a code", enrollment)
+}
+
+func (h *TwoFactorHandler) LoginConfirm(c *fiber.Ctx) error {
+	var input domain.TwoFactorConfirmRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	userId, err := h.usecase.ParseChallenge(input.ChallengeToken, domain.MFAChallengeEnroll)
+	if err != nil {
+		return err
+	}
+
+	codes, err := h.usecase.Confirm(c.UserContext(), userId, input.Code)
+	if err != nil {
+		return err
+	}
+
+	return h.issueToken(c, userId, codes)
+}
+
+// issueToken finishes the login once the second factor is verified.
+func (h *TwoFactorHandler) issueToken(c *fiber.Ctx, userId uint, recoveryCodes []string) error {
+	user, err := h.userUsecase.GetUserById(c.UserContext(), userId)
+	if err != nil {
+		return err
+	}
+
+	t, exp, err := h.userUsecase.Login(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Login successful", domain.AuthResponse{
+		TokenData:     &domain.TokenData{Token: *t, ExpiredAt: *exp},
+		UserData:      domain.NewUserResponse(user),
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (h *TwoFactorHandler) Status(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	status, err := h.usecase.Status(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", status)
+}
+
+func (h *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	enrollment, err := h.usecase.Enroll(c.UserContext(), uint(*id))
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Scan the QR code and confirm with a code", enrollment)
+}
+
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	var input domain.TwoFactorCodeRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	codes, err := h.usecase.Confirm(c.UserContext(), uint(*id), input.Code)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Two-factor authentication enabled", domain.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	var input domain.TwoFactorCodeRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	codes, err := h.usecase.RegenerateRecoveryCodes(c.UserContext(), uint(*id), input.Code)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Recovery codes regenerated", domain.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	id := utils.GetUserIDFromJwt(c)
+	var input domain.TwoFactorCodeRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	if err := h.usecase.Disable(c.UserContext(), uint(*id), input.Code); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Two-factor authentication disabled", nil)
+}
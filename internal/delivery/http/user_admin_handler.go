@@ -0,0 +1,139 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type UserAdminHandler struct {
+	usecase usecase.UserUsecase
+}
+
+func NewUserAdminHandler(api fiber.Router, usecase usecase.UserUsecase, auth fiber.Handler) *UserAdminHandler {
+	handler := &UserAdminHandler{usecase}
+
+	userGroup := api.Group("/users")
+	userGroup.Use(auth, requireAdmin(usecase, "You are not allowed to manage users"))
+	userGroup.Get("/", handler.ListUsers)
+	userGroup.Get("/:id", handler.GetUser)
+	userGroup.Put("/:id/roles", handler.UpdateRoles)
+	userGroup.Post("/:id/email", handler.ChangeEmail)
+	userGroup.Post("/:id/deactivate", handler.Deactivate)
+	userGroup.Post("/:id/reactivate", handler.Reactivate)
+
+	invitationGroup := api.Group("/invitations")
+	invitationGroup.Use(auth, requireAdmin(usecase, "You are not allowed to manage users"))
+	invitationGroup.Get("/", handler.ListInvitations)
+	invitationGroup.Post("/:id/resend", handler.ResendInvitation)
+	return handler
+}
+
+func (h *UserAdminHandler) ListUsers(c *fiber.Ctx) error {
+	var filter domain.UserListFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	users, totalPage, err := h.usecase.ListUsers(c.UserContext(), paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, domain.NewUserResponses(users))
+}
+
+func (h *UserAdminHandler) GetUser(c *fiber.Ctx) error {
+	userId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.usecase.GetUser(c.UserContext(), userId)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.NewUserResponse(user))
+}
+
+func (h *UserAdminHandler) UpdateRoles(c *fiber.Ctx) error {
+	userId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.UpdateUserRolesRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	actorId := utils.GetUserIDFromJwt(c)
+	user, err := h.usecase.UpdateUserRoles(c.UserContext(), uint(*actorId), userId, input.Roles)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Roles updated", domain.NewUserResponse(user))
+}
+
+func (h *UserAdminHandler) ChangeEmail(c *fiber.Ctx) error {
+	userId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	var input domain.AdminChangeEmailRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	if err := h.usecase.RequestEmailChange(c.UserContext(), userId, input.Email); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusAccepted, "Verification email sent to the new address", nil)
+}
+
+func (h *UserAdminHandler) Deactivate(c *fiber.Ctx) error {
+	userId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	actorId := utils.GetUserIDFromJwt(c)
+	if err := h.usecase.DeactivateUser(c.UserContext(), uint(*actorId), userId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "User deactivated", nil)
+}
+
+func (h *UserAdminHandler) Reactivate(c *fiber.Ctx) error {
+	userId, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.usecase.ReactivateUser(c.UserContext(), userId); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "User reactivated", nil)
+}
+
+func (h *UserAdminHandler) ListInvitations(c *fiber.Ctx) error {
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	invitations, totalPage, err := h.usecase.ListInvitations(c.UserContext(), paginationFilter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, domain.NewInvitationResponses(invitations))
+}
+
+func (h *UserAdminHandler) ResendInvitation(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.usecase.ResendInvitation(c.UserContext(), id); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Invitation sent", nil)
+}
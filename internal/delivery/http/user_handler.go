@@ -1,143 +1,153 @@
 package http
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/usecase"
-	"github.com/whyaji/daycare-preschool-api/pkg/middleware"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
 	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+	"gorm.io/gorm"
 )
 
+var errRegistrationNotAllowed = apperror.Forbidden("this email cannot be used to register").WithCode(apperror.CodeRegistrationNotAllowed)
+
 type UserHandler struct {
-	usecase usecase.UserUsecase
+	usecase          usecase.UserUsecase
+	twoFactorUsecase usecase.TwoFactorUsecase
 }
 
-func NewUserHandler(api fiber.Router, usecase usecase.UserUsecase) {
-	handler := &UserHandler{usecase}
-	api.Post("/register", handler.Register)
-	api.Post("/login", handler.Login)
-	api.Get("/", handler.Accessible)
-	api.Get("/restricted", middleware.JWTProtected, handler.Restricted)
-	api.Post("/register-user", middleware.JWTProtected, handler.RegisterEmail)
+func NewUserHandler(api fiber.Router, usecase usecase.UserUsecase, twoFactorUsecase usecase.TwoFactorUsecase, auth fiber.Handler, authRateLimit fiber.Handler) {
+	handler := &UserHandler{usecase, twoFactorUsecase}
+	api.Post("/register", authRateLimit, handler.Register)
+	api.Post("/login", authRateLimit, handler.Login)
+	api.Get("/restricted", auth, handler.Restricted)
+	api.Post("/register-user", auth, handler.RegisterEmail)
 }
 
 func (h *UserHandler) Register(c *fiber.Ctx) error {
-	var user domain.User
-	if err := c.BodyParser(&user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	var requestData domain.RegisterRequest
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
+	}
+
+	user := domain.User{
+		Name:     requestData.Name,
+		Email:    requestData.Email,
+		Password: requestData.Password,
+		Gender:   requestData.Gender,
+		Phone:    requestData.Phone,
+		Address:  requestData.Address,
+		JobTitle: requestData.JobTitle,
+		JobPlace: requestData.JobPlace,
+	}
+
+	if err := h.usecase.CheckRegisterAttempt(c.UserContext(), user.Email); err != nil {
+		return err
 	}
 
-	registeredEmail, err := h.usecase.CheckRegisteredEmail(user.Email)
+	// Not allowlisted and already registered get the same response so the
+	// endpoint cannot be used to discover allowlisted emails. Other lookup
+	// errors are returned as they are.
+	registeredEmail, err := h.usecase.CheckRegisteredEmail(c.UserContext(), user.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errRegistrationNotAllowed
+	}
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "email cannot be used to register"})
+		return err
+	}
+	if registeredEmail.RegisteredAt != nil {
+		return errRegistrationNotAllowed
 	}
 
-	if _, err := h.usecase.GetUserByEmail(user.Email); err == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "email is already in use"})
+	if _, err := h.usecase.GetUserByEmail(c.UserContext(), user.Email); err == nil {
+		return errRegistrationNotAllowed
 	}
 
-	if err := h.usecase.Register(&user, registeredEmail); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := h.usecase.Register(c.UserContext(), &user, registeredEmail); err != nil {
+		return err
 	}
 
-	if err := h.usecase.AssignRegisteredAtEmail(*registeredEmail); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := h.usecase.AssignRegisteredAtEmail(c.UserContext(), *registeredEmail); err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "User created"})
+	return utils.SendSuccess(c, fiber.StatusCreated, "User created", nil)
 }
 
 func (h *UserHandler) Login(c *fiber.Ctx) error {
-	type LoginInput struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	var input domain.LoginRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
 	}
 
-	var input LoginInput
-	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	user, err := h.usecase.Authenticate(c.UserContext(), input.Email, input.Password)
+	if err != nil {
+		return err
 	}
 
-	user, err := h.usecase.GetUserByEmail(input.Email)
+	// With two-factor enabled or required, the token is only issued by the
+	// /login/2fa endpoints once the challenge is answered
+	challenge, err := h.twoFactorUsecase.Challenge(c.UserContext(), user)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+		return err
 	}
-
-	if err := h.usecase.VerifyPassword(user, input.Password); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	if challenge != nil {
+		return utils.SendSuccess(c, fiber.StatusOK, "Two-factor authentication required", domain.AuthResponse{
+			Challenge: challenge,
+		})
 	}
 
-	t, exp, err := h.usecase.Login(user)
+	t, exp, err := h.usecase.Login(c.UserContext(), user)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	return c.JSON(fiber.Map{"token_data": fiber.Map{"token": t, "expired_at": exp}, "user_data": user})
+	return utils.SendSuccess(c, fiber.StatusOK, "Login successful", domain.AuthResponse{
+		TokenData: &domain.TokenData{Token: *t, ExpiredAt: *exp},
+		UserData:  domain.NewUserResponse(user),
+	})
 }
 
 func (h *UserHandler) RegisterEmail(c *fiber.Ctx) error {
 	id := utils.GetUserIDFromJwt(c)
-	isAdmin, err := h.usecase.CheckUserAdmin(uint(*id))
+	isAdmin, err := h.usecase.CheckUserAdmin(c.UserContext(), uint(*id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if !isAdmin {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "You are not allowed to create child"})
-	}
-
-	var errors []string
-
-	type RegisterEmailInput struct {
-		Email string `json:"email"`
-		Roles string `json:"roles"` // "[1,2]"
-	}
-
-	var requestData RegisterEmailInput
-	if err := c.BodyParser(&requestData); err != nil {
-		errors = append(errors, err.Error())
-	}
-
-	// Validate required fields are not empty
-	if requestData.Email == "" {
-		errors = append(errors, "email is required")
+		return apperror.Forbidden("You are not allowed to register email")
 	}
 
-	if requestData.Roles == "" || requestData.Roles == "[]" {
-		errors = append(errors, "roles is required")
+	var requestData domain.RegisterEmailRequest
+	if err := validation.ParseBody(c, &requestData); err != nil {
+		return err
 	}
 
-	_, err = h.usecase.CheckRegisteredEmail(requestData.Email)
+	_, err = h.usecase.CheckRegisteredEmail(c.UserContext(), requestData.Email)
 	if err == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "email already in registered list"})
+		return apperror.Conflict("email already in registered list")
 	}
 
 	// Parse roles
-	roles, err := h.usecase.ParseRoles(requestData.Roles)
+	roles, err := h.usecase.ParseRoles(c.UserContext(), requestData.Roles)
 	if err != nil {
-		errors = append(errors, err.Error())
-	}
-
-	// If there are any errors, return them
-	if len(errors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+		return err
 	}
 
 	var registeredEmail domain.RegisteredEmail
 	registeredEmail.Email = requestData.Email
 	registeredEmail.Roles = roles
 
-	if err := h.usecase.RegisterEmail(&registeredEmail); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := h.usecase.RegisterEmail(c.UserContext(), &registeredEmail); err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Email registered"})
-}
-
-func (h *UserHandler) Accessible(c *fiber.Ctx) error {
-	return c.SendString("Accessible")
+	return utils.SendSuccess(c, fiber.StatusCreated, "Email registered", nil)
 }
 
 func (h *UserHandler) Restricted(c *fiber.Ctx) error {
@@ -148,9 +158,12 @@ func (h *UserHandler) Restricted(c *fiber.Ctx) error {
 	expiredAt := time.Unix(int64(exp), 0)
 
 	id := claims["id"].(float64)
-	userData, err := h.usecase.GetUserById(uint(id))
+	userData, err := h.usecase.GetProfile(c.UserContext(), uint(id))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user-not-found"})
+		return apperror.NotFound("user not found")
 	}
-	return c.JSON(fiber.Map{"token_data": fiber.Map{"token": user.Raw, "expired_at": expiredAt}, "user_data": userData})
+	return utils.SendSuccess(c, fiber.StatusOK, "success", domain.AuthResponse{
+		TokenData: &domain.TokenData{Token: user.Raw, ExpiredAt: expiredAt.Format(time.RFC3339)},
+		UserData:  domain.NewUserResponse(userData),
+	})
 }
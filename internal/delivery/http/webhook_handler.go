@@ -0,0 +1,143 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/usecase"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/validation"
+)
+
+type WebhookHandler struct {
+	usecase usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(api fiber.Router, usecase usecase.WebhookUsecase, userUsecase usecase.UserUsecase, auth fiber.Handler) *WebhookHandler {
+	handler := &WebhookHandler{usecase}
+
+	webhookGroup := api.Group("/webhooks")
+	webhookGroup.Use(auth, requireAdmin(userUsecase, "You are not allowed to manage webhooks"))
+	webhookGroup.Get("/", handler.List)
+	webhookGroup.Post("/", handler.Create)
+	webhookGroup.Post("/deliveries/:id/replay", handler.Replay)
+	webhookGroup.Get("/:id", handler.Get)
+	webhookGroup.Put("/:id", handler.Update)
+	webhookGroup.Delete("/:id", handler.Delete)
+	webhookGroup.Post("/:id/rotate-secret", handler.RotateSecret)
+	webhookGroup.Get("/:id/deliveries", handler.ListDeliveries)
+	return handler
+}
+
+func (h *WebhookHandler) List(c *fiber.Ctx) error {
+	subscriptions, err := h.usecase.List(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", subscriptions)
+}
+
+func (h *WebhookHandler) Get(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := h.usecase.Get(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "success", subscription)
+}
+
+func (h *WebhookHandler) Create(c *fiber.Ctx) error {
+	var input domain.WebhookSubscriptionRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	id := utils.GetUserIDFromJwt(c)
+	subscription, err := h.usecase.Create(c.UserContext(), uint(*id), input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Webhook created, store the secret now as it is not shown again", subscription)
+}
+
+func (h *WebhookHandler) Update(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	var input domain.WebhookSubscriptionRequest
+	if err := validation.ParseBody(c, &input); err != nil {
+		return err
+	}
+
+	subscription, err := h.usecase.Update(c.UserContext(), id, input)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Webhook updated", subscription)
+}
+
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.usecase.Delete(c.UserContext(), id); err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Webhook deleted", nil)
+}
+
+func (h *WebhookHandler) RotateSecret(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := h.usecase.RotateSecret(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Webhook secret rotated, store the secret now as it is not shown again", subscription)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	var filter domain.WebhookDeliveryFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.BadRequest("invalid query").Wrap(err)
+	}
+	if err := validation.Struct(filter); err != nil {
+		return err
+	}
+
+	paginationFilter := utils.ClampPagination(utils.GetPaginationFilterFromQuery(c))
+	deliveries, totalPage, err := h.usecase.ListDeliveries(c.UserContext(), id, paginationFilter, filter)
+	if err != nil {
+		return err
+	}
+	return utils.SendPaginated(c, paginationFilter.Page, totalPage, deliveries)
+}
+
+func (h *WebhookHandler) Replay(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.usecase.Replay(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return utils.SendSuccess(c, fiber.StatusCreated, "Webhook delivery queued again", delivery)
+}
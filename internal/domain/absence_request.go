@@ -0,0 +1,26 @@
+package domain
+
+// ReportAbsenceRequest reports the child absent from StartDate to EndDate
+// (StartDate only when empty). Only the days the child is expected are
+// recorded.
+type ReportAbsenceRequest struct {
+	ChildID   uint   `json:"childId" validate:"required,gt=0"`
+	StartDate string `json:"startDate" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"endDate" validate:"omitempty,datetime=2006-01-02"`
+	Reason    string `json:"reason" validate:"required,oneof=sick holiday other"`
+	Note      string `json:"note" validate:"max=1000"`
+}
+
+type AbsenceListFilter struct {
+	ChildID uint   `query:"childId"`
+	Month   string `query:"month" validate:"omitempty,datetime=2006-01"`
+}
+
+type ExpectedAttendanceQuery struct {
+	Date   string `query:"date" validate:"omitempty,datetime=2006-01-02"`
+	Cutoff string `query:"cutoff" validate:"omitempty,datetime=15:04"`
+}
+
+type AbsenceReportQuery struct {
+	Month string `query:"month" validate:"required,datetime=2006-01"`
+}
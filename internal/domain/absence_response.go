@@ -0,0 +1,81 @@
+package domain
+
+import "time"
+
+type AbsenceResponse struct {
+	ID         uint      `json:"id"`
+	ChildID    uint      `json:"child_id"`
+	ChildName  string    `json:"child_name"`
+	Date       time.Time `json:"date"`
+	Reason     string    `json:"reason"`
+	Note       string    `json:"note"`
+	ReportedBy uint      `json:"reported_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Attendance status of an expected child on a day
+const (
+	ExpectedPresent = "present"
+	ExpectedAbsent  = "absent"
+	ExpectedPending = "expected"
+)
+
+type ExpectedChildResponse struct {
+	ChildID       uint       `json:"child_id"`
+	Name          string     `json:"name"`
+	Nickname      string     `json:"nickname"`
+	ClassroomID   *uint      `json:"classroom_id"`
+	Status        string     `json:"status"`
+	Arrival       *time.Time `json:"arrival"`
+	AbsenceReason string     `json:"absence_reason,omitempty"`
+}
+
+type ExpectedAttendanceResponse struct {
+	Date     string                  `json:"date"`
+	Closed   bool                    `json:"closed"`
+	Closure  string                  `json:"closure,omitempty"`
+	Expected int                     `json:"expected"`
+	Present  int                     `json:"present"`
+	Absent   int                     `json:"absent"`
+	Children []ExpectedChildResponse `json:"children"`
+}
+
+// NotArrivedResponse lists the expected children without a reported absence
+// who had not arrived by the cutoff. Arrival is set for late arrivals.
+type NotArrivedResponse struct {
+	Date     string                  `json:"date"`
+	Cutoff   string                  `json:"cutoff"`
+	Children []ExpectedChildResponse `json:"children"`
+}
+
+type ChildAbsenceSummary struct {
+	ChildID      uint           `json:"child_id"`
+	Name         string         `json:"name"`
+	ExpectedDays int            `json:"expected_days"`
+	AttendedDays int            `json:"attended_days"`
+	Absences     map[string]int `json:"absences"`
+	// Past expected days without an arrival or a reported absence
+	UnreportedDays int `json:"unreported_days"`
+}
+
+type AbsenceReportResponse struct {
+	Month    string                `json:"month"`
+	Children []ChildAbsenceSummary `json:"children"`
+}
+
+func NewAbsenceResponses(absences []ChildAbsence) []AbsenceResponse {
+	responses := make([]AbsenceResponse, len(absences))
+	for i, absence := range absences {
+		responses[i] = AbsenceResponse{
+			ID:         absence.ID,
+			ChildID:    absence.ChildID,
+			ChildName:  absence.Child.Name,
+			Date:       absence.Date,
+			Reason:     absence.Reason,
+			Note:       absence.Note,
+			ReportedBy: absence.ReportedBy,
+			CreatedAt:  absence.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,19 @@
+package domain
+
+// AttachmentUploadRequest holds the form fields sent with the file
+type AttachmentUploadRequest struct {
+	OwnerType string `json:"ownerType" form:"ownerType" validate:"required,oneof=child diary incident"`
+	OwnerID   uint   `json:"ownerId" form:"ownerId" validate:"required,gt=0"`
+}
+
+type AttachmentListFilter struct {
+	OwnerType string `query:"ownerType" validate:"required,oneof=child diary incident"`
+	OwnerID   uint   `query:"ownerId" validate:"required,gt=0"`
+}
+
+// AttachmentDownloadQuery is the signed part of a download URL
+type AttachmentDownloadQuery struct {
+	Variant   string `query:"variant" validate:"omitempty,oneof=original thumbnail"`
+	Expires   int64  `query:"expires" validate:"required"`
+	Signature string `query:"signature" validate:"required,hexadecimal"`
+}
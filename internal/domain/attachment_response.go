@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// AttachmentResponse carries signed download URLs valid until ExpiresAt
+type AttachmentResponse struct {
+	ID           uint      `json:"id"`
+	OwnerType    string    `json:"owner_type"`
+	OwnerID      uint      `json:"owner_id"`
+	ChildID      *uint     `json:"child_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	UploadedBy   uint      `json:"uploaded_by"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
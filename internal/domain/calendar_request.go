@@ -0,0 +1,30 @@
+package domain
+
+type CalendarDayRequest struct {
+	Date     string `json:"date" validate:"required,datetime=2006-01-02"`
+	Kind     string `json:"kind" validate:"required,oneof=holiday closure half_day"`
+	Name     string `json:"name" validate:"required,max=255"`
+	OpensAt  string `json:"opensAt" validate:"required_if=Kind half_day,omitempty,datetime=15:04"`
+	ClosesAt string `json:"closesAt" validate:"required_if=Kind half_day,omitempty,datetime=15:04"`
+}
+
+type CalendarRangeFilter struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type CalendarImportQuery struct {
+	Kind string `query:"kind" validate:"omitempty,oneof=holiday closure"`
+}
+
+// CalendarEventRequest creates or replaces an event. StartsAt and EndsAt are
+// dates (YYYY-MM-DD) for all-day events.
+type CalendarEventRequest struct {
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=5000"`
+	Location    string `json:"location" validate:"max=255"`
+	StartsAt    string `json:"startsAt" validate:"required"`
+	EndsAt      string `json:"endsAt" validate:"required"`
+	AllDay      bool   `json:"allDay"`
+	Audience    string `json:"audience" validate:"omitempty,oneof=everyone parents staff"`
+}
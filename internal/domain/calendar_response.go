@@ -0,0 +1,128 @@
+package domain
+
+import "time"
+
+type CalendarDayResponse struct {
+	ID       uint   `json:"id"`
+	Date     string `json:"date"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	OpensAt  string `json:"opens_at,omitempty"`
+	ClosesAt string `json:"closes_at,omitempty"`
+}
+
+func NewCalendarDayResponse(day *CalendarDay) *CalendarDayResponse {
+	return &CalendarDayResponse{
+		ID:       day.ID,
+		Date:     day.Date.Format("2006-01-02"),
+		Kind:     day.Kind,
+		Name:     day.Name,
+		OpensAt:  day.OpensAt,
+		ClosesAt: day.ClosesAt,
+	}
+}
+
+func NewCalendarDayResponses(days []CalendarDay) []CalendarDayResponse {
+	responses := make([]CalendarDayResponse, len(days))
+	for i := range days {
+		responses[i] = *NewCalendarDayResponse(&days[i])
+	}
+	return responses
+}
+
+type CalendarImportSkipped struct {
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type CalendarImportResponse struct {
+	Created []CalendarDayResponse   `json:"created"`
+	Skipped []CalendarImportSkipped `json:"skipped"`
+}
+
+type CalendarEventResponse struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	AllDay      bool      `json:"all_day"`
+	Audience    string    `json:"audience"`
+	CreatedBy   uint      `json:"created_by"`
+}
+
+func NewCalendarEventResponse(event *CalendarEvent) *CalendarEventResponse {
+	return &CalendarEventResponse{
+		ID:          event.ID,
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		StartsAt:    event.StartsAt,
+		EndsAt:      event.EndsAt,
+		AllDay:      event.AllDay,
+		Audience:    event.Audience,
+		CreatedBy:   event.CreatedBy,
+	}
+}
+
+func NewCalendarEventResponses(events []CalendarEvent) []CalendarEventResponse {
+	responses := make([]CalendarEventResponse, len(events))
+	for i := range events {
+		responses[i] = *NewCalendarEventResponse(&events[i])
+	}
+	return responses
+}
+
+// OpeningHoursResponse is one day of the center schedule, times are HH:MM
+type OpeningHoursResponse struct {
+	Date   string `json:"date"`
+	Open   bool   `json:"open"`
+	Kind   string `json:"kind,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Opens  string `json:"opens,omitempty"`
+	Closes string `json:"closes,omitempty"`
+}
+
+// WorkingDaysResponse summarises the schedule of a range for leave, payroll
+// and billing calculations
+type WorkingDaysResponse struct {
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	WorkingDays int                    `json:"working_days"`
+	HalfDays    int                    `json:"half_days"`
+	OpenHours   float64                `json:"open_hours"`
+	Days        []OpeningHoursResponse `json:"days"`
+}
+
+func NewWorkingDaysResponse(from, to time.Time, days []OpeningHours) *WorkingDaysResponse {
+	response := &WorkingDaysResponse{
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+		Days: make([]OpeningHoursResponse, len(days)),
+	}
+	for i, day := range days {
+		response.Days[i] = OpeningHoursResponse{Date: day.Date.Format("2006-01-02"), Open: day.Open, Kind: day.Kind, Name: day.Name}
+		if !day.Open {
+			continue
+		}
+		response.Days[i].Opens = formatClock(day.Opens)
+		response.Days[i].Closes = formatClock(day.Closes)
+		response.WorkingDays++
+		if day.Kind == CalendarHalfDay {
+			response.HalfDays++
+		}
+		response.OpenHours += (day.Closes - day.Opens).Hours()
+	}
+	return response
+}
+
+type CalendarFeedResponse struct {
+	URL string `json:"url"`
+}
+
+func formatClock(offset time.Duration) string {
+	minutes := int(offset.Minutes())
+	return time.Date(0, 1, 1, minutes/60, minutes%60, 0, 0, time.UTC).Format("15:04")
+}
@@ -1,7 +1,12 @@
 package domain
 
 type CreateChildAttendanceRequest struct {
-	ChildID uint   `json:"childId"`
-	Date    string `json:"date"`
-	Arrival string `json:"arrival"`
+	ChildID uint   `json:"childId" form:"childId" validate:"required,gt=0"`
+	Date    string `json:"date" form:"date" validate:"required,datetime=2006-01-02"`
+	Arrival string `json:"arrival" form:"arrival" validate:"required,datetime=2006-01-02 15:04:05"`
+}
+
+type ChildDepartureRequest struct {
+	ChildID   uint   `json:"childId" form:"childId" validate:"required,gt=0"`
+	Departure string `json:"departure" form:"departure" validate:"required,datetime=2006-01-02 15:04:05"`
 }
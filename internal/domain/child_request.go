@@ -2,16 +2,16 @@ package domain
 
 // Define a request struct to correctly parse JSON
 type CreateChildRequest struct {
-	Name             string `json:"name"`
-	Nickname         string `json:"nickname"`
-	BirthPlace       string `json:"birthPlace"`
-	BirthDate        string `json:"birthDate"` // Keep as string for parsing
-	Gender           string `json:"gender"`
+	Name             string `json:"name" validate:"required,max=255"`
+	Nickname         string `json:"nickname" validate:"required,max=255"`
+	BirthPlace       string `json:"birthPlace" validate:"required,max=255"`
+	BirthDate        string `json:"birthDate" validate:"required,datetime=2006-01-02"` // Keep as string for parsing
+	Gender           string `json:"gender" validate:"required,oneof=male female"`
 	AlergyInfo       string `json:"alergyInfo"`
 	Notes            string `json:"notes"`
-	NumberOfSiblings int    `json:"numberOfSiblings"`
-	LivingWith       string `json:"livingWith"`
-	RegisteredDate   string `json:"registeredDate"` // Keep as string for parsing
-	Parents          string `json:"parents"`
-	Teachers         string `json:"teachers"`
+	NumberOfSiblings int    `json:"numberOfSiblings" validate:"gte=0,lte=20"`
+	LivingWith       string `json:"livingWith" validate:"required,max=255"`
+	RegisteredDate   string `json:"registeredDate" validate:"required,datetime=2006-01-02"` // Keep as string for parsing
+	Parents          []uint `json:"parents" validate:"required,min=1,unique,dive,gt=0"`
+	Teachers         []uint `json:"teachers" validate:"required,min=1,unique,dive,gt=0"`
 }
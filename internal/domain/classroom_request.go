@@ -0,0 +1,26 @@
+package domain
+
+type ClassroomRequest struct {
+	Name          string `json:"name" validate:"required,max=255"`
+	MinAgeMonths  int    `json:"minAgeMonths" validate:"gte=0"`
+	MaxAgeMonths  int    `json:"maxAgeMonths" validate:"required,gtefield=MinAgeMonths"`
+	Capacity      int    `json:"capacity" validate:"required,gt=0"`
+	ChildrenRatio int    `json:"childrenRatio" validate:"required,gt=0"`
+}
+
+type ClassroomTeacherRequest struct {
+	UserID uint   `json:"userId" validate:"required,gt=0"`
+	Role   string `json:"role" validate:"required,oneof=lead assistant"`
+}
+
+type ClassroomTeachersRequest struct {
+	Teachers []ClassroomTeacherRequest `json:"teachers" validate:"required,dive"`
+}
+
+type ClassroomChildRequest struct {
+	ChildID uint `json:"childId" validate:"required,gt=0"`
+}
+
+type ClassroomAlertFilter struct {
+	Status string `query:"status" validate:"omitempty,oneof=open resolved all"`
+}
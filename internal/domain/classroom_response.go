@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+type ClassroomTeacherResponse struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+type ClassroomChildResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Nickname  string    `json:"nickname"`
+	BirthDate time.Time `json:"birth_date"`
+}
+
+type ClassroomResponse struct {
+	ID            uint                       `json:"id"`
+	Name          string                     `json:"name"`
+	MinAgeMonths  int                        `json:"min_age_months"`
+	MaxAgeMonths  int                        `json:"max_age_months"`
+	Capacity      int                        `json:"capacity"`
+	ChildrenRatio int                        `json:"children_ratio"`
+	Teachers      []ClassroomTeacherResponse `json:"teachers"`
+	Children      []ClassroomChildResponse   `json:"children"`
+}
+
+// ClassroomRatio is the live staffing of a classroom: children checked in
+// today against teachers of its roster who are clocked in.
+type ClassroomRatio struct {
+	ClassroomID     uint   `json:"classroom_id"`
+	Name            string `json:"name"`
+	ChildrenRatio   int    `json:"children_ratio"`
+	ChildrenPresent int    `json:"children_present"`
+	StaffPresent    int    `json:"staff_present"`
+	RequiredStaff   int    `json:"required_staff"`
+	Compliant       bool   `json:"compliant"`
+}
+
+type ClassroomAlertResponse struct {
+	ID              uint       `json:"id"`
+	ClassroomID     uint       `json:"classroom_id"`
+	ChildrenPresent int        `json:"children_present"`
+	StaffPresent    int        `json:"staff_present"`
+	RequiredStaff   int        `json:"required_staff"`
+	Trigger         string     `json:"trigger"`
+	OpenedAt        time.Time  `json:"opened_at"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+}
+
+func NewClassroomResponse(classroom *Classroom) *ClassroomResponse {
+	response := &ClassroomResponse{
+		ID:            classroom.ID,
+		Name:          classroom.Name,
+		MinAgeMonths:  classroom.MinAgeMonths,
+		MaxAgeMonths:  classroom.MaxAgeMonths,
+		Capacity:      classroom.Capacity,
+		ChildrenRatio: classroom.ChildrenRatio,
+		Teachers:      make([]ClassroomTeacherResponse, len(classroom.Teachers)),
+		Children:      make([]ClassroomChildResponse, len(classroom.Children)),
+	}
+	for i, teacher := range classroom.Teachers {
+		response.Teachers[i] = ClassroomTeacherResponse{UserID: teacher.UserID, Name: teacher.User.Name, Role: teacher.Role}
+	}
+	for i, child := range classroom.Children {
+		response.Children[i] = ClassroomChildResponse{ID: child.ID, Name: child.Name, Nickname: child.Nickname, BirthDate: child.BirthDate}
+	}
+	return response
+}
+
+func NewClassroomResponses(classrooms []Classroom) []ClassroomResponse {
+	responses := make([]ClassroomResponse, len(classrooms))
+	for i := range classrooms {
+		responses[i] = *NewClassroomResponse(&classrooms[i])
+	}
+	return responses
+}
+
+func NewClassroomAlertResponses(alerts []ClassroomAlert) []ClassroomAlertResponse {
+	responses := make([]ClassroomAlertResponse, len(alerts))
+	for i, alert := range alerts {
+		responses[i] = ClassroomAlertResponse{
+			ID:              alert.ID,
+			ClassroomID:     alert.ClassroomID,
+			ChildrenPresent: alert.ChildrenPresent,
+			StaffPresent:    alert.StaffPresent,
+			RequiredStaff:   alert.RequiredStaff,
+			Trigger:         alert.Trigger,
+			OpenedAt:        alert.CreatedAt,
+			ResolvedAt:      alert.ResolvedAt,
+		}
+	}
+	return responses
+}
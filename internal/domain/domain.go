@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,7 +24,7 @@ type User struct {
 	ID                uint    `gorm:"primaryKey"`
 	Name              string  `gorm:"size:255;not null"`
 	Email             string  `gorm:"size:255;unique;not null"`
-	Password          string  `gorm:"size:255;not null"`
+	Password          string  `gorm:"size:255;not null" json:"-"`
 	Gender            string  `gorm:"type:enum('male','female');not null"`
 	Phone             string  `gorm:"size:255;not null"`
 	Address           string  `gorm:"type:text;not null"`
@@ -31,18 +33,68 @@ type User struct {
 	Roles             []Role  `gorm:"many2many:user_roles;"`
 	ChildrenAsParent  []Child `gorm:"many2many:child_parents;"`
 	ChildrenAsTeacher []Child `gorm:"many2many:child_teachers;"`
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
-	DeletedAt         gorm.DeletedAt `gorm:"index"`
+	// Email change waiting for the owner of the new address to verify it
+	PendingEmail               *string    `gorm:"size:255;default:null"`
+	EmailVerificationHash      *string    `gorm:"size:64;index;default:null" json:"-"`
+	EmailVerificationExpiresAt *time.Time `gorm:"default:null" json:"-"`
+	// TokenVersion is signed into access tokens, bumping it revokes the
+	// tokens already issued
+	TokenVersion uint `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
-// Role model (for User)
-type Role struct {
-	ID        uint   `gorm:"primaryKey"`
-	Name      string `gorm:"size:255;not null"`
+// TOTP secret of a user. ConfirmedAt is set once the user proved the
+// authenticator works; until then two-factor is not enforced. Rows are hard
+// deleted when two-factor is disabled so the user can enroll again.
+type UserTOTP struct {
+	ID           uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"uniqueIndex;not null"`
+	Secret       string     `gorm:"size:255;not null"`
+	ConfirmedAt  *time.Time `gorm:"default:null"`
+	LastUsedStep int64      `gorm:"default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Single-use recovery code, only the hash is stored
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	CodeHash  string     `gorm:"size:64;not null"`
+	UsedAt    *time.Time `gorm:"default:null"`
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Roles the code checks by name. They cannot be renamed or deleted.
+const (
+	RoleAdmin        = "admin"
+	RoleTeacher      = "teacher"
+	RoleParent       = "parent"
+	RolePsychologist = "psychologist"
+)
+
+var SystemRoles = []string{RoleTeacher, RoleParent, RoleAdmin, RolePsychologist}
+
+// Role model (for User). Roles are hard deleted, and only when unused, so
+// the unique name can be reused.
+type Role struct {
+	ID          uint         `gorm:"primaryKey"`
+	Name        string       `gorm:"size:255;uniqueIndex;not null"`
+	Permissions []Permission `gorm:"many2many:role_permissions;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// Permission that can be attached to roles
+type Permission struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"size:100;uniqueIndex;not null"`
+	Description string `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 // Attendance for Bunda (Workers)
@@ -89,6 +141,7 @@ type Child struct {
 	RegisteredDate   time.Time `gorm:"not null"`
 	Parents          []User    `gorm:"many2many:child_parents;"`
 	Teachers         []User    `gorm:"many2many:child_teachers;"`
+	ClassroomID      *uint     `gorm:"index;default:null"`
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
@@ -96,13 +149,16 @@ type Child struct {
 
 // Attendance for Child
 type ChildAttendance struct {
-	ID              uint      `gorm:"primaryKey"`
-	ChildID         uint      `gorm:"not null"`
-	Date            time.Time `gorm:"not null"`
-	Arrival         time.Time `gorm:"not null"`
-	Departure       *time.Time
-	OvertimeMorning int `gorm:"default:0"`
-	OvertimeEvening int `gorm:"default:0"`
+	ID        uint      `gorm:"primaryKey"`
+	ChildID   uint      `gorm:"not null;uniqueIndex:idx_child_attendances_open,priority:1"`
+	Date      time.Time `gorm:"not null"`
+	Arrival   time.Time `gorm:"not null"`
+	Departure *time.Time
+	// OpenDate is the date while the child has not departed, so a child
+	// has at most one open attendance a day
+	OpenDate        *time.Time `gorm:"type:date;uniqueIndex:idx_child_attendances_open,priority:2"`
+	OvertimeMorning int        `gorm:"default:0"`
+	OvertimeEvening int        `gorm:"default:0"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DeletedAt       gorm.DeletedAt `gorm:"index"`
@@ -165,6 +221,786 @@ type ChildCondition struct {
 	DeletedAt      gorm.DeletedAt `gorm:"index"`
 }
 
+// Severities of an allergy, ordered from least to most serious
+const (
+	AllergyMild            = "mild"
+	AllergyModerate        = "moderate"
+	AllergySevere          = "severe"
+	AllergyLifeThreatening = "life_threatening"
+)
+
+// Medical profile of a child, kept up to date by its parents. The
+// collections are replaced as a whole on every update.
+type ChildMedicalProfile struct {
+	ID                uint                    `gorm:"primaryKey"`
+	ChildID           uint                    `gorm:"uniqueIndex;not null"`
+	Child             Child                   `gorm:"foreignKey:ChildID"`
+	BloodType         string                  `gorm:"size:3"`
+	Conditions        string                  `gorm:"type:text"`
+	DoctorName        string                  `gorm:"size:255"`
+	DoctorPhone       string                  `gorm:"size:30"`
+	DoctorClinic      string                  `gorm:"size:255"`
+	Allergies         []ChildAllergy          `gorm:"foreignKey:ChildID;references:ChildID"`
+	Medications       []ChildMedication       `gorm:"foreignKey:ChildID;references:ChildID"`
+	Immunizations     []ChildImmunization     `gorm:"foreignKey:ChildID;references:ChildID"`
+	EmergencyContacts []ChildEmergencyContact `gorm:"foreignKey:ChildID;references:ChildID"`
+	UpdatedBy         uint                    `gorm:"not null"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type ChildAllergy struct {
+	ID         uint   `gorm:"primaryKey"`
+	ChildID    uint   `gorm:"index;not null"`
+	Allergen   string `gorm:"size:255;not null"`
+	Severity   string `gorm:"type:enum('mild','moderate','severe','life_threatening');not null"`
+	Reaction   string `gorm:"type:text"`
+	ActionPlan string `gorm:"type:text"`
+	CreatedAt  time.Time
+}
+
+// Serious reports whether the allergy needs an action plan and is shown
+// first in alerts
+func (a ChildAllergy) Serious() bool {
+	return a.Severity == AllergySevere || a.Severity == AllergyLifeThreatening
+}
+
+// Medicine the child takes on a schedule. Times holds HH:MM values separated
+// by commas.
+type ChildMedication struct {
+	ID           uint       `gorm:"primaryKey"`
+	ChildID      uint       `gorm:"index;not null"`
+	Name         string     `gorm:"size:255;not null"`
+	Dose         string     `gorm:"size:100;not null"`
+	Times        string     `gorm:"size:255;not null"`
+	StartDate    time.Time  `gorm:"type:date;not null"`
+	EndDate      *time.Time `gorm:"type:date"`
+	Instructions string     `gorm:"type:text"`
+	CreatedAt    time.Time
+}
+
+// ActiveOn reports whether the medication is taken on the day
+func (m ChildMedication) ActiveOn(day time.Time) bool {
+	return !day.Before(m.StartDate) && (m.EndDate == nil || !day.After(*m.EndDate))
+}
+
+// Immunization given or due. A dose without GivenOn is still due.
+type ChildImmunization struct {
+	ID         uint       `gorm:"primaryKey"`
+	ChildID    uint       `gorm:"index;not null"`
+	Vaccine    string     `gorm:"size:255;not null"`
+	DoseNumber int        `gorm:"not null;default:1"`
+	GivenOn    *time.Time `gorm:"type:date"`
+	DueOn      *time.Time `gorm:"type:date"`
+	Notes      string     `gorm:"type:text"`
+	CreatedAt  time.Time
+}
+
+// Emergency contact of a child, priority 1 is called first
+type ChildEmergencyContact struct {
+	ID           uint   `gorm:"primaryKey"`
+	ChildID      uint   `gorm:"index;not null"`
+	Name         string `gorm:"size:255;not null"`
+	Relationship string `gorm:"size:100;not null"`
+	Phone        string `gorm:"size:30;not null"`
+	AltPhone     string `gorm:"size:30"`
+	Priority     int    `gorm:"not null"`
+	CanPickUp    bool   `gorm:"not null;default:false"`
+	CreatedAt    time.Time
+}
+
+// Statuses of a medication request
+const (
+	MedicationRequestActive    = "active"
+	MedicationRequestCancelled = "cancelled"
+)
+
+// Statuses of a recorded dose
+const (
+	DoseGiven   = "given"
+	DoseRefused = "refused"
+)
+
+// Medicine a parent asks the teachers to give, with the parent's consent.
+// Times holds the HH:MM of each daily dose separated by commas.
+type MedicationRequest struct {
+	ID              uint                       `gorm:"primaryKey"`
+	ChildID         uint                       `gorm:"index;not null"`
+	Child           Child                      `gorm:"foreignKey:ChildID"`
+	RequestedBy     uint                       `gorm:"not null"`
+	Drug            string                     `gorm:"size:255;not null"`
+	Dose            string                     `gorm:"size:100;not null"`
+	Times           string                     `gorm:"size:255;not null"`
+	StartDate       time.Time                  `gorm:"type:date;not null"`
+	EndDate         time.Time                  `gorm:"type:date;not null"`
+	Instructions    string                     `gorm:"type:text"`
+	Consent         bool                       `gorm:"not null"`
+	ConsentAt       time.Time                  `gorm:"not null"`
+	Status          string                     `gorm:"type:enum('active','cancelled');not null;default:'active'"`
+	Administrations []MedicationAdministration `gorm:"foreignKey:RequestID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// TimeList returns the daily dose times
+func (r MedicationRequest) TimeList() []string {
+	return strings.Split(r.Times, ",")
+}
+
+// CoversDay reports whether the request is active with a dose on the day
+func (r MedicationRequest) CoversDay(day time.Time) bool {
+	return r.Status == MedicationRequestActive && !day.Before(r.StartDate) && !day.After(r.EndDate)
+}
+
+// Dose given or refused, recorded by a teacher and countersigned by the
+// witness. ScheduledAt is the dose slot of the request it answers.
+type MedicationAdministration struct {
+	ID             uint      `gorm:"primaryKey"`
+	RequestID      uint      `gorm:"uniqueIndex:idx_medication_dose;not null"`
+	ScheduledAt    time.Time `gorm:"uniqueIndex:idx_medication_dose;not null"`
+	ChildID        uint      `gorm:"index;not null"`
+	DiaryID        uint      `gorm:"index;not null"`
+	AdministeredAt time.Time `gorm:"not null"`
+	Status         string    `gorm:"type:enum('given','refused');not null"`
+	AdministeredBy uint      `gorm:"not null"`
+	WitnessID      uint      `gorm:"not null"`
+	WitnessedAt    *time.Time
+	Notes          string `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Kinds of incident
+const (
+	IncidentInjury    = "injury"
+	IncidentIllness   = "illness"
+	IncidentBehaviour = "behaviour"
+	IncidentOther     = "other"
+)
+
+// Severities of an incident, serious ones are mailed to the admins as soon
+// as they are reported
+const (
+	IncidentMinor    = "minor"
+	IncidentModerate = "moderate"
+	IncidentSerious  = "serious"
+)
+
+// Statuses of an incident report. Parents see it once an admin reviewed it
+// and acknowledge it afterwards.
+const (
+	IncidentPendingReview = "pending_review"
+	IncidentReviewed      = "reviewed"
+	IncidentAcknowledged  = "acknowledged"
+)
+
+// Injury or behavioural incident of a child, reported by a teacher
+type Incident struct {
+	ID              uint              `gorm:"primaryKey"`
+	ChildID         uint              `gorm:"index;not null"`
+	Child           Child             `gorm:"foreignKey:ChildID"`
+	ReportedBy      uint              `gorm:"index;not null"`
+	Type            string            `gorm:"type:enum('injury','illness','behaviour','other');not null"`
+	Severity        string            `gorm:"type:enum('minor','moderate','serious');not null"`
+	OccurredAt      time.Time         `gorm:"index;not null"`
+	Location        string            `gorm:"size:255;not null"`
+	Description     string            `gorm:"type:text;not null"`
+	FirstAid        string            `gorm:"type:text"`
+	Witnesses       []IncidentWitness `gorm:"foreignKey:IncidentID"`
+	Photos          []IncidentPhoto   `gorm:"foreignKey:IncidentID"`
+	Status          string            `gorm:"type:enum('pending_review','reviewed','acknowledged');not null;default:'pending_review'"`
+	ReviewedBy      *uint
+	ReviewedAt      *time.Time
+	ReviewNotes     string `gorm:"type:text"`
+	AcknowledgedBy  *uint
+	AcknowledgedAt  *time.Time
+	ParentSignature string `gorm:"size:255"`
+	ParentComment   string `gorm:"type:text"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Person who saw the incident, a staff member when UserID is set
+type IncidentWitness struct {
+	ID         uint   `gorm:"primaryKey"`
+	IncidentID uint   `gorm:"index;not null"`
+	UserID     *uint  `gorm:"default:null"`
+	Name       string `gorm:"size:255;not null"`
+}
+
+type IncidentPhoto struct {
+	ID         uint   `gorm:"primaryKey"`
+	IncidentID uint   `gorm:"index;not null"`
+	URL        string `gorm:"size:2048;not null"`
+	Caption    string `gorm:"size:255"`
+	CreatedAt  time.Time
+}
+
+// Records attachments can belong to
+const (
+	AttachmentChild    = "child"
+	AttachmentDiary    = "diary"
+	AttachmentIncident = "incident"
+	AttachmentPost     = "post"
+	AttachmentMessage  = "message"
+)
+
+// Uploaded file of a record. ChildID is the child whose access rules apply.
+// Images are stored without their metadata and with a thumbnail.
+type Attachment struct {
+	ID           uint   `gorm:"primaryKey"`
+	OwnerType    string `gorm:"size:30;index:idx_attachment_owner;not null"`
+	OwnerID      uint   `gorm:"index:idx_attachment_owner;not null"`
+	ChildID      *uint  `gorm:"index;default:null"`
+	StorageKey   string `gorm:"size:255;not null"`
+	ThumbnailKey string `gorm:"size:255"`
+	FileName     string `gorm:"size:255;not null"`
+	ContentType  string `gorm:"size:100;not null"`
+	Size         int64  `gorm:"not null"`
+	Width        int
+	Height       int
+	UploadedBy   uint `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// Photo consent of a child, given or withdrawn by a parent. Children
+// without a granted consent cannot be tagged in photo posts.
+type PhotoConsent struct {
+	ID        uint `gorm:"primaryKey"`
+	ChildID   uint `gorm:"uniqueIndex;not null"`
+	Granted   bool `gorm:"not null;default:false"`
+	UpdatedBy uint `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Photo or short album posted by a teacher and tagged with children. Drafts
+// (PublishedAt null) collect their photos and are only seen by staff.
+type PhotoPost struct {
+	ID          uint            `gorm:"primaryKey"`
+	AuthorID    uint            `gorm:"index;not null"`
+	Author      User            `gorm:"foreignKey:AuthorID"`
+	Caption     string          `gorm:"type:text"`
+	Children    []Child         `gorm:"many2many:photo_post_children;"`
+	Photos      []Attachment    `gorm:"polymorphic:Owner;polymorphicValue:post"`
+	Reactions   []PhotoReaction `gorm:"foreignKey:PostID"`
+	Comments    []PhotoComment  `gorm:"foreignKey:PostID"`
+	PublishedAt *time.Time      `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Reactions to a photo post, one per user
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+)
+
+type PhotoReaction struct {
+	ID        uint   `gorm:"primaryKey"`
+	PostID    uint   `gorm:"uniqueIndex:idx_photo_reaction;not null"`
+	UserID    uint   `gorm:"uniqueIndex:idx_photo_reaction;not null"`
+	Kind      string `gorm:"type:enum('like','love','laugh','wow');not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PhotoComment struct {
+	ID        uint   `gorm:"primaryKey"`
+	PostID    uint   `gorm:"index;not null"`
+	UserID    uint   `gorm:"not null"`
+	User      User   `gorm:"foreignKey:UserID"`
+	Body      string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Audiences of a message thread. Child threads are conversations between
+// the parents, the teachers of the child and the admins. Classroom and center
+// threads are announcements only staff can write to.
+const (
+	ThreadChild     = "child"
+	ThreadClassroom = "classroom"
+	ThreadCenter    = "center"
+)
+
+type MessageThread struct {
+	ID            uint       `gorm:"primaryKey"`
+	Kind          string     `gorm:"type:enum('child','classroom','center');index;not null"`
+	ChildID       *uint      `gorm:"index;default:null"`
+	Child         *Child     `gorm:"foreignKey:ChildID"`
+	ClassroomID   *uint      `gorm:"index;default:null"`
+	Classroom     *Classroom `gorm:"foreignKey:ClassroomID"`
+	Subject       string     `gorm:"size:255;not null"`
+	CreatedBy     uint       `gorm:"not null"`
+	LastMessageAt time.Time  `gorm:"index;not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Message of a thread. Messages cannot be changed or deleted so the
+// conversation stays available for audit.
+type Message struct {
+	ID          uint         `gorm:"primaryKey"`
+	ThreadID    uint         `gorm:"index;not null"`
+	SenderID    uint         `gorm:"index;not null"`
+	Sender      User         `gorm:"foreignKey:SenderID"`
+	Body        string       `gorm:"type:text;not null"`
+	Attachments []Attachment `gorm:"polymorphic:Owner;polymorphicValue:message"`
+	CreatedAt   time.Time
+}
+
+// Read position of a user in a thread. Every message up to
+// LastReadMessageID counts as read.
+type MessageRead struct {
+	ID                uint `gorm:"primaryKey"`
+	ThreadID          uint `gorm:"uniqueIndex:idx_message_read;not null"`
+	UserID            uint `gorm:"uniqueIndex:idx_message_read;not null"`
+	User              User `gorm:"foreignKey:UserID"`
+	LastReadMessageID uint `gorm:"not null"`
+	ReadAt            time.Time
+}
+
+// Kinds of notification
+const (
+	NotificationChildArrived    = "child.arrived"
+	NotificationChildDeparted   = "child.departed"
+	NotificationMissingClockOut = "teacher.missing_clock_out"
+)
+
+// Channels a notification is delivered on
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+	ChannelInApp = "in_app"
+)
+
+// States of a delivery. Skipped deliveries had nothing to deliver to, such
+// as a push to a user without devices.
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped"
+)
+
+// Notification rendered for a user. It shows in the in-app inbox once its
+// in-app delivery set InboxAt. DedupeKey stops a trigger from notifying a
+// user twice about the same record.
+type Notification struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"uniqueIndex:idx_notification_key;index:idx_notification_inbox;not null"`
+	User       User       `gorm:"foreignKey:UserID"`
+	Type       string     `gorm:"size:64;not null"`
+	DedupeKey  *string    `gorm:"size:191;uniqueIndex:idx_notification_key;default:null"`
+	Title      string     `gorm:"size:255;not null"`
+	Body       string     `gorm:"type:text;not null"`
+	InboxAt    *time.Time `gorm:"index:idx_notification_inbox"`
+	ReadAt     *time.Time
+	Deliveries []NotificationDelivery `gorm:"foreignKey:NotificationID"`
+	CreatedAt  time.Time
+}
+
+// Outbox entry sending a notification on one channel. Pending deliveries are
+// picked up once NextAttemptAt passed, which also holds them back during
+// quiet hours and between retries.
+type NotificationDelivery struct {
+	ID             uint         `gorm:"primaryKey"`
+	NotificationID uint         `gorm:"index;not null"`
+	Notification   Notification `gorm:"foreignKey:NotificationID"`
+	Channel        string       `gorm:"type:enum('email','push','in_app');not null"`
+	Status         string       `gorm:"type:enum('pending','sent','failed','skipped');index:idx_notification_due;not null"`
+	Attempts       int          `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time    `gorm:"index:idx_notification_due;not null"`
+	LastError      string       `gorm:"type:text"`
+	SentAt         *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Channels a user chose for a kind of notification. Kinds without a row use
+// the defaults of their template.
+type NotificationPreference struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"uniqueIndex:idx_notification_preference;not null"`
+	Type      string `gorm:"size:64;uniqueIndex:idx_notification_preference;not null"`
+	Email     bool   `gorm:"not null"`
+	Push      bool   `gorm:"not null"`
+	InApp     bool   `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// Quiet hours of a user as HH:MM, they may span midnight. Email and push
+// deliveries falling in them wait for their end.
+type NotificationSettings struct {
+	UserID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	QuietStart string `gorm:"size:5"`
+	QuietEnd   string `gorm:"size:5"`
+	UpdatedAt  time.Time
+}
+
+// Platforms of push devices
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// Device registered for push notifications. A token belongs to the user who
+// last registered it.
+type PushDevice struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"index;not null"`
+	Token      string `gorm:"size:255;uniqueIndex;not null"`
+	Platform   string `gorm:"type:enum('android','ios','web');not null"`
+	LastSeenAt time.Time
+	CreatedAt  time.Time
+}
+
+// Types of the events sent to webhooks
+const (
+	WebhookTeacherAttendanceClosed = "teacher_attendance.closed"
+	WebhookChildAttendanceDeparted = "child_attendance.departed"
+)
+
+// Integration event written in the same transaction as the change it
+// describes, so it survives a crash right after the commit. The webhook
+// worker fans it out to the subscriptions and sets DispatchedAt.
+type OutboxEvent struct {
+	ID           uint       `gorm:"primaryKey"`
+	Type         string     `gorm:"size:64;not null"`
+	Payload      string     `gorm:"type:text;not null"`
+	CreatedAt    time.Time  `gorm:"not null"`
+	DispatchedAt *time.Time `gorm:"index"`
+}
+
+// Endpoint of another system receiving the listed event types. The signing
+// secret is stored encrypted.
+type WebhookSubscription struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"size:255;not null"`
+	URL        string `gorm:"size:2048;not null"`
+	EventTypes string `gorm:"size:1024;not null"`
+	Secret     string `gorm:"size:255;not null" json:"-"`
+	Active     bool   `gorm:"not null;default:true"`
+	CreatedBy  uint   `gorm:"not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+// EventTypeList returns the event types of the subscription
+func (s WebhookSubscription) EventTypeList() []string {
+	if s.EventTypes == "" {
+		return nil
+	}
+	return strings.Split(s.EventTypes, ",")
+}
+
+// Subscribes reports whether the subscription receives the event type
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	return slices.Contains(s.EventTypeList(), eventType)
+}
+
+// States of a webhook delivery
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// Attempt log of an event sent to a subscription. Replaying a delivery adds
+// a new one so the log keeps every attempt.
+type WebhookDelivery struct {
+	ID             uint                `gorm:"primaryKey"`
+	SubscriptionID uint                `gorm:"index;not null"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+	EventID        uint                `gorm:"index;not null"`
+	Event          OutboxEvent         `gorm:"foreignKey:EventID"`
+	Status         string              `gorm:"type:enum('pending','delivered','failed');index:idx_webhook_due;not null"`
+	Attempts       int                 `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time           `gorm:"index:idx_webhook_due;not null"`
+	ResponseStatus int
+	ResponseBody   string `gorm:"type:text"`
+	LastError      string `gorm:"type:text"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// States of a background job. A failed attempt goes back to pending until
+// the attempts run out and the job is dead.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+	JobCancelled = "cancelled"
+)
+
+// Types of the background jobs
+const (
+	JobCheckClockOuts = "notifications.check_clock_outs"
+	JobCleanUpJobs    = "jobs.clean_up"
+)
+
+// Background job run by the worker pool. A running job whose lease ran out
+// is claimed again, so handlers must be safe to run twice.
+type Job struct {
+	ID          uint       `gorm:"primaryKey"`
+	Type        string     `gorm:"size:64;index;not null"`
+	Payload     string     `gorm:"type:text;not null"`
+	Status      string     `gorm:"type:enum('pending','running','succeeded','dead','cancelled');index:idx_job_due;not null"`
+	Attempts    int        `gorm:"not null;default:0"`
+	MaxAttempts int        `gorm:"not null"`
+	RunAt       time.Time  `gorm:"index:idx_job_due;not null"`
+	LockedUntil *time.Time `gorm:"index"`
+	LastError   string     `gorm:"type:text"`
+	// Occurrence of the schedule that queued the job, unique so instances
+	// sharing the database queue it once
+	ScheduleKey *string `gorm:"size:191;uniqueIndex"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Classroom (group) with its age range, capacity and required ratio of
+// children per present staff member
+type Classroom struct {
+	ID            uint               `gorm:"primaryKey"`
+	Name          string             `gorm:"size:255;uniqueIndex;not null"`
+	MinAgeMonths  int                `gorm:"not null"`
+	MaxAgeMonths  int                `gorm:"not null"`
+	Capacity      int                `gorm:"not null"`
+	ChildrenRatio int                `gorm:"not null"`
+	Teachers      []ClassroomTeacher `gorm:"foreignKey:ClassroomID"`
+	Children      []Child            `gorm:"foreignKey:ClassroomID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+const (
+	ClassroomRoleLead      = "lead"
+	ClassroomRoleAssistant = "assistant"
+)
+
+// Teacher on a classroom roster
+type ClassroomTeacher struct {
+	ID          uint   `gorm:"primaryKey"`
+	ClassroomID uint   `gorm:"uniqueIndex:idx_classroom_teacher;not null"`
+	UserID      uint   `gorm:"uniqueIndex:idx_classroom_teacher;not null"`
+	User        User   `gorm:"foreignKey:UserID"`
+	Role        string `gorm:"type:enum('lead','assistant');not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Ratio compliance alert, open while ResolvedAt is null. At most one alert
+// per classroom is open at a time.
+type ClassroomAlert struct {
+	ID              uint       `gorm:"primaryKey"`
+	ClassroomID     uint       `gorm:"index;not null"`
+	ChildrenPresent int        `gorm:"not null"`
+	StaffPresent    int        `gorm:"not null"`
+	RequiredStaff   int        `gorm:"not null"`
+	Trigger         string     `gorm:"size:255;not null"`
+	ResolvedAt      *time.Time `gorm:"default:null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Enrollment states. Inquiry, waitlisted, offered, enrolled and paused are
+// open; a child has at most one open enrollment.
+const (
+	EnrollmentInquiry    = "inquiry"
+	EnrollmentWaitlisted = "waitlisted"
+	EnrollmentOffered    = "offered"
+	EnrollmentEnrolled   = "enrolled"
+	EnrollmentPaused     = "paused"
+	EnrollmentWithdrawn  = "withdrawn"
+	EnrollmentGraduated  = "graduated"
+)
+
+var OpenEnrollmentStatuses = []string{EnrollmentInquiry, EnrollmentWaitlisted, EnrollmentOffered, EnrollmentEnrolled, EnrollmentPaused}
+
+const (
+	ProgrammeDaycare   = "daycare"
+	ProgrammePreschool = "preschool"
+
+	SessionFullDay = "full_day"
+	SessionHalfDay = "half_day"
+)
+
+// Enrollment of a child, from the first inquiry until withdrawal or
+// graduation. ClassroomID is the classroom applied for, the waitlist is
+// ranked by Priority (highest first) and then WaitlistedAt.
+type Enrollment struct {
+	ID             uint              `gorm:"primaryKey"`
+	ChildID        uint              `gorm:"index;not null"`
+	Child          Child             `gorm:"foreignKey:ChildID"`
+	ClassroomID    *uint             `gorm:"index;default:null"`
+	Classroom      *Classroom        `gorm:"foreignKey:ClassroomID"`
+	Status         string            `gorm:"type:enum('inquiry','waitlisted','offered','enrolled','paused','withdrawn','graduated');default:'inquiry';index;not null"`
+	Programme      string            `gorm:"type:enum('daycare','preschool');not null"`
+	Session        string            `gorm:"type:enum('full_day','half_day');not null"`
+	ScheduleDays   string            `gorm:"size:27;not null"` // comma separated, see ScheduleDays
+	StartDate      *time.Time        `gorm:"type:date;default:null"`
+	EndDate        *time.Time        `gorm:"type:date;default:null"`
+	Priority       int               `gorm:"default:0"`
+	WaitlistedAt   *time.Time        `gorm:"default:null"`
+	OfferExpiresAt *time.Time        `gorm:"default:null"`
+	Notes          string            `gorm:"type:text"`
+	Events         []EnrollmentEvent `gorm:"foreignKey:EnrollmentID"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// Days returns the weekdays the child attends, in week order
+func (e *Enrollment) Days() []string {
+	if e.ScheduleDays == "" {
+		return []string{}
+	}
+	return strings.Split(e.ScheduleDays, ",")
+}
+
+// AttendsOn reports whether the weekly schedule includes day
+func (e *Enrollment) AttendsOn(day time.Weekday) bool {
+	return slices.Contains(e.Days(), Weekdays[day])
+}
+
+// Status change of an enrollment. ChangedBy is null for changes made by the
+// system, such as an expired offer.
+type EnrollmentEvent struct {
+	ID           uint   `gorm:"primaryKey"`
+	EnrollmentID uint   `gorm:"index;not null"`
+	FromStatus   string `gorm:"size:20"`
+	ToStatus     string `gorm:"size:20;not null"`
+	ChangedBy    *uint  `gorm:"default:null"`
+	Reason       string `gorm:"type:text"`
+	CreatedAt    time.Time
+}
+
+// Weekday names used by enrollment schedules, indexed by time.Weekday
+var Weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleDays normalises a list of weekday names into the stored form,
+// ordered from monday to sunday
+func ScheduleDays(days []string) string {
+	ordered := make([]string, 0, len(days))
+	for _, day := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		if slices.Contains(days, day) {
+			ordered = append(ordered, day)
+		}
+	}
+	return strings.Join(ordered, ",")
+}
+
+// Reasons a parent can give for an absence
+const (
+	AbsenceSick    = "sick"
+	AbsenceHoliday = "holiday"
+	AbsenceOther   = "other"
+)
+
+// Reported absence of a child on a day it was expected. Cancelled absences
+// are deleted so the day can be reported again.
+type ChildAbsence struct {
+	ID         uint      `gorm:"primaryKey"`
+	ChildID    uint      `gorm:"uniqueIndex:idx_child_absence_date;not null"`
+	Child      Child     `gorm:"foreignKey:ChildID"`
+	Date       time.Time `gorm:"type:date;uniqueIndex:idx_child_absence_date;not null"`
+	Reason     string    `gorm:"type:enum('sick','holiday','other');not null"`
+	Note       string    `gorm:"type:text"`
+	ReportedBy uint      `gorm:"not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Kinds of center calendar days
+const (
+	CalendarHoliday = "holiday"
+	CalendarClosure = "closure"
+	CalendarHalfDay = "half_day"
+	// CalendarWeekend marks the days outside CENTER_WORKDAYS, it is not
+	// stored
+	CalendarWeekend = "weekend"
+)
+
+// Day the center is closed, a public holiday or a closure of its own, or a
+// half day open with its own hours
+type CalendarDay struct {
+	ID        uint      `gorm:"primaryKey"`
+	Date      time.Time `gorm:"type:date;uniqueIndex;not null"`
+	Kind      string    `gorm:"type:enum('holiday','closure','half_day');not null"`
+	Name      string    `gorm:"size:255;not null"`
+	OpensAt   string    `gorm:"size:5"` // HH:MM, half days only
+	ClosesAt  string    `gorm:"size:5"` // HH:MM, half days only
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Closed reports whether the center is closed for the whole day
+func (d CalendarDay) Closed() bool {
+	return d.Kind != CalendarHalfDay
+}
+
+// Audiences of calendar events
+const (
+	AudienceEveryone = "everyone"
+	AudienceParents  = "parents"
+	AudienceStaff    = "staff"
+)
+
+// Event on the center calendar such as a parents evening or a field trip.
+// EndsAt of an all-day event is the last day of the event.
+type CalendarEvent struct {
+	ID          uint      `gorm:"primaryKey"`
+	Title       string    `gorm:"size:255;not null"`
+	Description string    `gorm:"type:text"`
+	Location    string    `gorm:"size:255"`
+	StartsAt    time.Time `gorm:"index;not null"`
+	EndsAt      time.Time `gorm:"index;not null"`
+	AllDay      bool      `gorm:"not null;default:false"`
+	Audience    string    `gorm:"type:enum('everyone','parents','staff');not null;default:'everyone'"`
+	CreatedBy   uint      `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Secret of the iCal feed URL of a user. Only the hash is stored, rotating
+// the token replaces the row.
+type CalendarFeedToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"uniqueIndex;not null"`
+	TokenHash string `gorm:"size:64;uniqueIndex;not null"`
+	CreatedAt time.Time
+}
+
+// OpeningHours of the center on a day. Opens and Closes are offsets from
+// midnight and hold the regular hours on closed days.
+type OpeningHours struct {
+	Date   time.Time
+	Open   bool
+	Kind   string // empty on regular days
+	Name   string
+	Opens  time.Duration
+	Closes time.Duration
+}
+
+func (h OpeningHours) OpensAt() time.Time {
+	return h.Date.Add(h.Opens)
+}
+
+func (h OpeningHours) ClosesAt() time.Time {
+	return h.Date.Add(h.Closes)
+}
+
 type WorkLocation struct {
 	ID        uint    `gorm:"primaryKey"`
 	Name      string  `gorm:"size:255;not null"`
@@ -175,3 +1011,9 @@ type WorkLocation struct {
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
+
+// Schema version applied by scripts/migrations, checked by the readiness probe
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
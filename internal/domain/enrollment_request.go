@@ -0,0 +1,39 @@
+package domain
+
+type EnrollmentRequest struct {
+	ChildID      uint     `json:"childId" validate:"required,gt=0"`
+	ClassroomID  *uint    `json:"classroomId" validate:"omitempty,gt=0"`
+	Status       string   `json:"status" validate:"omitempty,oneof=inquiry waitlisted"`
+	Programme    string   `json:"programme" validate:"required,oneof=daycare preschool"`
+	Session      string   `json:"session" validate:"required,oneof=full_day half_day"`
+	ScheduleDays []string `json:"scheduleDays" validate:"required,min=1,max=7,unique,dive,oneof=mon tue wed thu fri sat sun"`
+	StartDate    string   `json:"startDate" validate:"omitempty,datetime=2006-01-02"`
+	Priority     int      `json:"priority" validate:"gte=0"`
+	Notes        string   `json:"notes" validate:"max=2000"`
+}
+
+// UpdateEnrollmentRequest only changes the fields that are set
+type UpdateEnrollmentRequest struct {
+	ClassroomID  *uint    `json:"classroomId" validate:"omitempty,gt=0"`
+	Programme    *string  `json:"programme" validate:"omitempty,oneof=daycare preschool"`
+	Session      *string  `json:"session" validate:"omitempty,oneof=full_day half_day"`
+	ScheduleDays []string `json:"scheduleDays" validate:"omitempty,min=1,max=7,unique,dive,oneof=mon tue wed thu fri sat sun"`
+	StartDate    *string  `json:"startDate" validate:"omitempty,datetime=2006-01-02"`
+	Priority     *int     `json:"priority" validate:"omitempty,gte=0"`
+	Notes        *string  `json:"notes" validate:"omitempty,max=2000"`
+}
+
+// EnrollmentTransitionRequest moves an enrollment to another status. Date is
+// the start date when enrolling and the end date when withdrawing or
+// graduating, today when empty.
+type EnrollmentTransitionRequest struct {
+	Status string `json:"status" validate:"required,oneof=waitlisted offered enrolled paused withdrawn graduated"`
+	Date   string `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	Reason string `json:"reason" validate:"max=1000"`
+}
+
+type EnrollmentListFilter struct {
+	Status      string `query:"status" validate:"omitempty,oneof=inquiry waitlisted offered enrolled paused withdrawn graduated"`
+	ClassroomID uint   `query:"classroomId"`
+	ChildID     uint   `query:"childId"`
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "time"
+
+type EnrollmentEventResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *uint     `json:"changed_by"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type EnrollmentResponse struct {
+	ID             uint                      `json:"id"`
+	ChildID        uint                      `json:"child_id"`
+	ChildName      string                    `json:"child_name"`
+	ClassroomID    *uint                     `json:"classroom_id"`
+	ClassroomName  string                    `json:"classroom_name,omitempty"`
+	Status         string                    `json:"status"`
+	Programme      string                    `json:"programme"`
+	Session        string                    `json:"session"`
+	ScheduleDays   []string                  `json:"schedule_days"`
+	StartDate      *time.Time                `json:"start_date"`
+	EndDate        *time.Time                `json:"end_date"`
+	Priority       int                       `json:"priority"`
+	WaitlistedAt   *time.Time                `json:"waitlisted_at"`
+	OfferExpiresAt *time.Time                `json:"offer_expires_at"`
+	Notes          string                    `json:"notes"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	Events         []EnrollmentEventResponse `json:"events,omitempty"`
+}
+
+// WaitlistEntryResponse is a waitlisted enrollment with its position, 1 is
+// the next to be offered a seat
+type WaitlistEntryResponse struct {
+	Rank int `json:"rank"`
+	EnrollmentResponse
+}
+
+type WaitlistOffersResponse struct {
+	Capacity  int                  `json:"capacity"`
+	FreeSeats int                  `json:"free_seats"`
+	Offered   []EnrollmentResponse `json:"offered"`
+}
+
+func NewEnrollmentResponse(enrollment *Enrollment) *EnrollmentResponse {
+	response := &EnrollmentResponse{
+		ID:             enrollment.ID,
+		ChildID:        enrollment.ChildID,
+		ChildName:      enrollment.Child.Name,
+		ClassroomID:    enrollment.ClassroomID,
+		Status:         enrollment.Status,
+		Programme:      enrollment.Programme,
+		Session:        enrollment.Session,
+		ScheduleDays:   enrollment.Days(),
+		StartDate:      enrollment.StartDate,
+		EndDate:        enrollment.EndDate,
+		Priority:       enrollment.Priority,
+		WaitlistedAt:   enrollment.WaitlistedAt,
+		OfferExpiresAt: enrollment.OfferExpiresAt,
+		Notes:          enrollment.Notes,
+		CreatedAt:      enrollment.CreatedAt,
+	}
+	if enrollment.Classroom != nil {
+		response.ClassroomName = enrollment.Classroom.Name
+	}
+	for _, event := range enrollment.Events {
+		response.Events = append(response.Events, EnrollmentEventResponse{
+			FromStatus: event.FromStatus,
+			ToStatus:   event.ToStatus,
+			ChangedBy:  event.ChangedBy,
+			Reason:     event.Reason,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+	return response
+}
+
+func NewEnrollmentResponses(enrollments []Enrollment) []EnrollmentResponse {
+	responses := make([]EnrollmentResponse, len(enrollments))
+	for i := range enrollments {
+		responses[i] = *NewEnrollmentResponse(&enrollments[i])
+	}
+	return responses
+}
+
+func NewWaitlistResponses(enrollments []Enrollment) []WaitlistEntryResponse {
+	responses := make([]WaitlistEntryResponse, len(enrollments))
+	for i := range enrollments {
+		responses[i] = WaitlistEntryResponse{Rank: i + 1, EnrollmentResponse: *NewEnrollmentResponse(&enrollments[i])}
+	}
+	return responses
+}
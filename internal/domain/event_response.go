@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Types of the events sent on the event stream
+const (
+	EventChildArrived      = "child.arrived"
+	EventChildDeparted     = "child.departed"
+	EventDiaryEntryAdded   = "diary.entry_added"
+	EventTeacherClockedIn  = "teacher.clocked_in"
+	EventTeacherClockedOut = "teacher.clocked_out"
+	EventMessageCreated    = "message.created"
+	EventIncidentReported  = "incident.reported"
+)
+
+// EventResync tells a client that events were missed and it must reload its
+// state through the API
+const EventResync = "resync"
+
+type ChildAttendanceEvent struct {
+	ChildID      uint       `json:"child_id"`
+	AttendanceID uint       `json:"attendance_id"`
+	Arrival      time.Time  `json:"arrival"`
+	Departure    *time.Time `json:"departure,omitempty"`
+}
+
+type DiaryEntryEvent struct {
+	ChildID          uint   `json:"child_id"`
+	DiaryID          uint   `json:"diary_id"`
+	AdministrationID uint   `json:"administration_id"`
+	Status           string `json:"status"`
+}
+
+type TeacherAttendanceEvent struct {
+	UserID       uint       `json:"user_id"`
+	AttendanceID uint       `json:"attendance_id"`
+	ClockIn      *time.Time `json:"clock_in,omitempty"`
+	ClockOut     *time.Time `json:"clock_out,omitempty"`
+}
+
+type MessageEvent struct {
+	ThreadID    uint   `json:"thread_id"`
+	MessageID   uint   `json:"message_id"`
+	Kind        string `json:"kind"`
+	ChildID     *uint  `json:"child_id,omitempty"`
+	ClassroomID *uint  `json:"classroom_id,omitempty"`
+	SenderID    uint   `json:"sender_id"`
+}
+
+type IncidentEvent struct {
+	IncidentID uint   `json:"incident_id"`
+	ChildID    uint   `json:"child_id"`
+	Severity   string `json:"severity"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+// IncidentRequest reports an incident, or corrects it while it waits for
+// the admin review
+type IncidentRequest struct {
+	ChildID     uint                     `json:"childId" validate:"required,gt=0"`
+	Type        string                   `json:"type" validate:"required,oneof=injury illness behaviour other"`
+	Severity    string                   `json:"severity" validate:"required,oneof=minor moderate serious"`
+	OccurredAt  string                   `json:"occurredAt" validate:"required,datetime=2006-01-02 15:04:05"`
+	Location    string                   `json:"location" validate:"required,max=255"`
+	Description string                   `json:"description" validate:"required,max=5000"`
+	FirstAid    string                   `json:"firstAid" validate:"max=2000"`
+	Witnesses   []IncidentWitnessRequest `json:"witnesses" validate:"max=10,dive"`
+	Photos      []IncidentPhotoRequest   `json:"photos" validate:"max=10,dive"`
+}
+
+// IncidentWitnessRequest names a witness, staff members are given by
+// UserID and their name is filled in
+type IncidentWitnessRequest struct {
+	UserID *uint  `json:"userId" validate:"omitempty,gt=0"`
+	Name   string `json:"name" validate:"required_without=UserID,max=255"`
+}
+
+type IncidentPhotoRequest struct {
+	URL     string `json:"url" validate:"required,url,max=2048"`
+	Caption string `json:"caption" validate:"max=255"`
+}
+
+// ReviewIncidentRequest closes the admin review, Severity reclassifies the
+// incident when set
+type ReviewIncidentRequest struct {
+	Severity string `json:"severity" validate:"omitempty,oneof=minor moderate serious"`
+	Notes    string `json:"notes" validate:"max=2000"`
+}
+
+// AcknowledgeIncidentRequest is the digital sign-off of a parent, who types
+// their full name as the signature
+type AcknowledgeIncidentRequest struct {
+	Signature string `json:"signature" validate:"required,max=255"`
+	Comment   string `json:"comment" validate:"max=1000"`
+}
+
+type IncidentListFilter struct {
+	ChildID  uint   `query:"childId"`
+	Type     string `query:"type" validate:"omitempty,oneof=injury illness behaviour other"`
+	Severity string `query:"severity" validate:"omitempty,oneof=minor moderate serious"`
+	Status   string `query:"status" validate:"omitempty,oneof=pending_review reviewed acknowledged"`
+	From     string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To       string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,106 @@
+package domain
+
+import "time"
+
+type IncidentWitnessResponse struct {
+	UserID *uint  `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type IncidentPhotoResponse struct {
+	ID      uint   `json:"id"`
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+}
+
+type IncidentResponse struct {
+	ID              uint                      `json:"id"`
+	ChildID         uint                      `json:"child_id"`
+	ChildName       string                    `json:"child_name"`
+	ReportedBy      uint                      `json:"reported_by"`
+	Type            string                    `json:"type"`
+	Severity        string                    `json:"severity"`
+	OccurredAt      time.Time                 `json:"occurred_at"`
+	Location        string                    `json:"location"`
+	Description     string                    `json:"description"`
+	FirstAid        string                    `json:"first_aid"`
+	Witnesses       []IncidentWitnessResponse `json:"witnesses"`
+	Photos          []IncidentPhotoResponse   `json:"photos"`
+	Status          string                    `json:"status"`
+	ReviewedBy      *uint                     `json:"reviewed_by"`
+	ReviewedAt      *time.Time                `json:"reviewed_at"`
+	ReviewNotes     string                    `json:"review_notes"`
+	AcknowledgedBy  *uint                     `json:"acknowledged_by"`
+	AcknowledgedAt  *time.Time                `json:"acknowledged_at"`
+	ParentSignature string                    `json:"parent_signature"`
+	ParentComment   string                    `json:"parent_comment"`
+	CreatedAt       time.Time                 `json:"created_at"`
+}
+
+func NewIncidentResponse(incident *Incident) *IncidentResponse {
+	response := &IncidentResponse{
+		ID:              incident.ID,
+		ChildID:         incident.ChildID,
+		ChildName:       incident.Child.Name,
+		ReportedBy:      incident.ReportedBy,
+		Type:            incident.Type,
+		Severity:        incident.Severity,
+		OccurredAt:      incident.OccurredAt,
+		Location:        incident.Location,
+		Description:     incident.Description,
+		FirstAid:        incident.FirstAid,
+		Witnesses:       make([]IncidentWitnessResponse, len(incident.Witnesses)),
+		Photos:          make([]IncidentPhotoResponse, len(incident.Photos)),
+		Status:          incident.Status,
+		ReviewedBy:      incident.ReviewedBy,
+		ReviewedAt:      incident.ReviewedAt,
+		ReviewNotes:     incident.ReviewNotes,
+		AcknowledgedBy:  incident.AcknowledgedBy,
+		AcknowledgedAt:  incident.AcknowledgedAt,
+		ParentSignature: incident.ParentSignature,
+		ParentComment:   incident.ParentComment,
+		CreatedAt:       incident.CreatedAt,
+	}
+	for i, witness := range incident.Witnesses {
+		response.Witnesses[i] = IncidentWitnessResponse{UserID: witness.UserID, Name: witness.Name}
+	}
+	for i, photo := range incident.Photos {
+		response.Photos[i] = IncidentPhotoResponse{ID: photo.ID, URL: photo.URL, Caption: photo.Caption}
+	}
+	return response
+}
+
+func NewIncidentResponses(incidents []Incident) []IncidentResponse {
+	responses := make([]IncidentResponse, len(incidents))
+	for i := range incidents {
+		responses[i] = *NewIncidentResponse(&incidents[i])
+	}
+	return responses
+}
+
+// IncidentHistoryResponse is the incident history of a child, newest first,
+// with counts by type and severity
+type IncidentHistoryResponse struct {
+	ChildID    uint               `json:"child_id"`
+	ChildName  string             `json:"child_name"`
+	Total      int                `json:"total"`
+	ByType     map[string]int     `json:"by_type"`
+	BySeverity map[string]int     `json:"by_severity"`
+	Incidents  []IncidentResponse `json:"incidents"`
+}
+
+func NewIncidentHistoryResponse(child *Child, incidents []Incident) *IncidentHistoryResponse {
+	response := &IncidentHistoryResponse{
+		ChildID:    child.ID,
+		ChildName:  child.Name,
+		Total:      len(incidents),
+		ByType:     map[string]int{},
+		BySeverity: map[string]int{},
+		Incidents:  NewIncidentResponses(incidents),
+	}
+	for _, incident := range incidents {
+		response.ByType[incident.Type]++
+		response.BySeverity[incident.Severity]++
+	}
+	return response
+}
@@ -0,0 +1,6 @@
+package domain
+
+type JobFilter struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending running succeeded dead cancelled"`
+	Type   string `query:"type" validate:"omitempty,max=64"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobResponse struct {
+	ID          uint            `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error"`
+	ScheduleKey *string         `json:"schedule_key"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func NewJobResponse(job *Job) *JobResponse {
+	return &JobResponse{
+		ID:          job.ID,
+		Type:        job.Type,
+		Payload:     json.RawMessage(job.Payload),
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		ScheduleKey: job.ScheduleKey,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+		CreatedAt:   job.CreatedAt,
+	}
+}
+
+// JobScheduleResponse is a cron-style schedule queueing jobs
+type JobScheduleResponse struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	Type    string    `json:"type"`
+	NextRun time.Time `json:"next_run"`
+}
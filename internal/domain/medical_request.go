@@ -0,0 +1,59 @@
+package domain
+
+// MedicalProfileRequest replaces the medical profile of a child, the lists
+// included
+type MedicalProfileRequest struct {
+	BloodType         string                      `json:"bloodType" validate:"omitempty,oneof=A+ A- B+ B- AB+ AB- O+ O-"`
+	Conditions        string                      `json:"conditions" validate:"max=5000"`
+	DoctorName        string                      `json:"doctorName" validate:"max=255"`
+	DoctorPhone       string                      `json:"doctorPhone" validate:"max=30"`
+	DoctorClinic      string                      `json:"doctorClinic" validate:"max=255"`
+	Allergies         []AllergyRequest            `json:"allergies" validate:"max=50,dive"`
+	Medications       []MedicationScheduleRequest `json:"medications" validate:"max=20,dive"`
+	Immunizations     []ImmunizationRequest       `json:"immunizations" validate:"max=100,dive"`
+	EmergencyContacts []EmergencyContactRequest   `json:"emergencyContacts" validate:"required,min=1,max=10,dive"`
+}
+
+// AllergyRequest describes an allergy, severe and life threatening ones need
+// an action plan
+type AllergyRequest struct {
+	Allergen   string `json:"allergen" validate:"required,max=255"`
+	Severity   string `json:"severity" validate:"required,oneof=mild moderate severe life_threatening"`
+	Reaction   string `json:"reaction" validate:"max=1000"`
+	ActionPlan string `json:"actionPlan" validate:"max=5000"`
+}
+
+type MedicationScheduleRequest struct {
+	Name         string   `json:"name" validate:"required,max=255"`
+	Dose         string   `json:"dose" validate:"required,max=100"`
+	Times        []string `json:"times" validate:"required,min=1,max=12,unique,dive,datetime=15:04"`
+	StartDate    string   `json:"startDate" validate:"required,datetime=2006-01-02"`
+	EndDate      string   `json:"endDate" validate:"omitempty,datetime=2006-01-02"`
+	Instructions string   `json:"instructions" validate:"max=1000"`
+}
+
+// ImmunizationRequest records a dose given on GivenOn or due on DueOn
+type ImmunizationRequest struct {
+	Vaccine    string `json:"vaccine" validate:"required,max=255"`
+	DoseNumber int    `json:"doseNumber" validate:"gte=1,lte=10"`
+	GivenOn    string `json:"givenOn" validate:"required_without=DueOn,omitempty,datetime=2006-01-02"`
+	DueOn      string `json:"dueOn" validate:"required_without=GivenOn,omitempty,datetime=2006-01-02"`
+	Notes      string `json:"notes" validate:"max=1000"`
+}
+
+type EmergencyContactRequest struct {
+	Name         string `json:"name" validate:"required,max=255"`
+	Relationship string `json:"relationship" validate:"required,max=100"`
+	Phone        string `json:"phone" validate:"required,max=30"`
+	AltPhone     string `json:"altPhone" validate:"max=30"`
+	Priority     int    `json:"priority" validate:"required,gte=1,lte=10"`
+	CanPickUp    bool   `json:"canPickUp"`
+}
+
+type MedicalAlertFilter struct {
+	ClassroomID uint `query:"classroomId"`
+}
+
+type AllergyCheckQuery struct {
+	Meal string `query:"meal" validate:"required,max=255"`
+}
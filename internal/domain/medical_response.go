@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of an immunization
+const (
+	ImmunizationGiven   = "given"
+	ImmunizationDue     = "due"
+	ImmunizationOverdue = "overdue"
+)
+
+type AllergyResponse struct {
+	ID         uint   `json:"id"`
+	Allergen   string `json:"allergen"`
+	Severity   string `json:"severity"`
+	Reaction   string `json:"reaction"`
+	ActionPlan string `json:"action_plan"`
+}
+
+type MedicationScheduleResponse struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Dose         string     `json:"dose"`
+	Times        []string   `json:"times"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      *time.Time `json:"end_date"`
+	Instructions string     `json:"instructions"`
+}
+
+type ImmunizationResponse struct {
+	ID         uint       `json:"id"`
+	Vaccine    string     `json:"vaccine"`
+	DoseNumber int        `json:"dose_number"`
+	GivenOn    *time.Time `json:"given_on"`
+	DueOn      *time.Time `json:"due_on"`
+	Status     string     `json:"status"`
+	Notes      string     `json:"notes"`
+}
+
+type EmergencyContactResponse struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone"`
+	AltPhone     string `json:"alt_phone"`
+	Priority     int    `json:"priority"`
+	CanPickUp    bool   `json:"can_pick_up"`
+}
+
+type DoctorResponse struct {
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+	Clinic string `json:"clinic"`
+}
+
+// MedicalProfileResponse is the medical profile of a child. AllergyNotes is
+// the free text recorded with the child before structured allergies.
+type MedicalProfileResponse struct {
+	ChildID           uint                         `json:"child_id"`
+	ChildName         string                       `json:"child_name"`
+	BloodType         string                       `json:"blood_type"`
+	Conditions        string                       `json:"conditions"`
+	AllergyNotes      string                       `json:"allergy_notes"`
+	Doctor            DoctorResponse               `json:"doctor"`
+	Allergies         []AllergyResponse            `json:"allergies"`
+	Medications       []MedicationScheduleResponse `json:"medications"`
+	Immunizations     []ImmunizationResponse       `json:"immunizations"`
+	EmergencyContacts []EmergencyContactResponse   `json:"emergency_contacts"`
+	UpdatedBy         uint                         `json:"updated_by"`
+	UpdatedAt         time.Time                    `json:"updated_at"`
+}
+
+// NewMedicalProfileResponse builds the profile of the child, immunization
+// statuses are relative to today
+func NewMedicalProfileResponse(child *Child, profile *ChildMedicalProfile, today time.Time) *MedicalProfileResponse {
+	response := &MedicalProfileResponse{
+		ChildID:           child.ID,
+		ChildName:         child.Name,
+		BloodType:         profile.BloodType,
+		Conditions:        profile.Conditions,
+		AllergyNotes:      child.AlergyInfo,
+		Doctor:            DoctorResponse{Name: profile.DoctorName, Phone: profile.DoctorPhone, Clinic: profile.DoctorClinic},
+		Allergies:         NewAllergyResponses(profile.Allergies),
+		Medications:       make([]MedicationScheduleResponse, len(profile.Medications)),
+		Immunizations:     make([]ImmunizationResponse, len(profile.Immunizations)),
+		EmergencyContacts: NewEmergencyContactResponses(profile.EmergencyContacts),
+		UpdatedBy:         profile.UpdatedBy,
+		UpdatedAt:         profile.UpdatedAt,
+	}
+	for i, medication := range profile.Medications {
+		response.Medications[i] = MedicationScheduleResponse{
+			ID:           medication.ID,
+			Name:         medication.Name,
+			Dose:         medication.Dose,
+			Times:        strings.Split(medication.Times, ","),
+			StartDate:    medication.StartDate,
+			EndDate:      medication.EndDate,
+			Instructions: medication.Instructions,
+		}
+	}
+	for i, immunization := range profile.Immunizations {
+		response.Immunizations[i] = ImmunizationResponse{
+			ID:         immunization.ID,
+			Vaccine:    immunization.Vaccine,
+			DoseNumber: immunization.DoseNumber,
+			GivenOn:    immunization.GivenOn,
+			DueOn:      immunization.DueOn,
+			Status:     ImmunizationStatus(immunization, today),
+			Notes:      immunization.Notes,
+		}
+	}
+	return response
+}
+
+// ImmunizationStatus tells whether the dose was given, is due or is overdue
+// on today
+func ImmunizationStatus(immunization ChildImmunization, today time.Time) string {
+	switch {
+	case immunization.GivenOn != nil:
+		return ImmunizationGiven
+	case immunization.DueOn != nil && immunization.DueOn.Before(today):
+		return ImmunizationOverdue
+	}
+	return ImmunizationDue
+}
+
+// NewAllergyResponses lists the most serious allergies first
+func NewAllergyResponses(allergies []ChildAllergy) []AllergyResponse {
+	responses := make([]AllergyResponse, len(allergies))
+	for i, allergy := range allergies {
+		responses[i] = AllergyResponse{
+			ID:         allergy.ID,
+			Allergen:   allergy.Allergen,
+			Severity:   allergy.Severity,
+			Reaction:   allergy.Reaction,
+			ActionPlan: allergy.ActionPlan,
+		}
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		return severityRank[responses[i].Severity] > severityRank[responses[j].Severity]
+	})
+	return responses
+}
+
+// NewEmergencyContactResponses lists the contacts by priority
+func NewEmergencyContactResponses(contacts []ChildEmergencyContact) []EmergencyContactResponse {
+	responses := make([]EmergencyContactResponse, len(contacts))
+	for i, contact := range contacts {
+		responses[i] = EmergencyContactResponse{
+			ID:           contact.ID,
+			Name:         contact.Name,
+			Relationship: contact.Relationship,
+			Phone:        contact.Phone,
+			AltPhone:     contact.AltPhone,
+			Priority:     contact.Priority,
+			CanPickUp:    contact.CanPickUp,
+		}
+	}
+	sort.Slice(responses, func(i, j int) bool { return responses[i].Priority < responses[j].Priority })
+	return responses
+}
+
+var severityRank = map[string]int{
+	AllergyMild:            1,
+	AllergyModerate:        2,
+	AllergySevere:          3,
+	AllergyLifeThreatening: 4,
+}
+
+// MedicalAlertResponse is the short summary teachers see for a child with
+// allergies, medication due today, overdue immunizations or conditions
+type MedicalAlertResponse struct {
+	ChildID              uint                      `json:"child_id"`
+	ChildName            string                    `json:"child_name"`
+	ClassroomID          *uint                     `json:"classroom_id"`
+	Severe               bool                      `json:"severe"`
+	Allergies            []string                  `json:"allergies"`
+	MedicationsToday     []string                  `json:"medications_today"`
+	OverdueImmunizations []string                  `json:"overdue_immunizations"`
+	Conditions           string                    `json:"conditions"`
+	FirstContact         *EmergencyContactResponse `json:"first_contact"`
+}
+
+type AllergyCheckResponse struct {
+	ChildID uint              `json:"child_id"`
+	Meal    string            `json:"meal"`
+	Safe    bool              `json:"safe"`
+	Matches []AllergyResponse `json:"matches"`
+}
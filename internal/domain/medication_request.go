@@ -0,0 +1,34 @@
+package domain
+
+// CreateMedicationRequest asks the teachers to give a medicine at Times each
+// day from StartDate to EndDate. Consent must be given.
+type CreateMedicationRequest struct {
+	ChildID      uint     `json:"childId" validate:"required,gt=0"`
+	Drug         string   `json:"drug" validate:"required,max=255"`
+	Dose         string   `json:"dose" validate:"required,max=100"`
+	Times        []string `json:"times" validate:"required,min=1,max=6,unique,dive,datetime=15:04"`
+	StartDate    string   `json:"startDate" validate:"required,datetime=2006-01-02"`
+	EndDate      string   `json:"endDate" validate:"required,datetime=2006-01-02"`
+	Instructions string   `json:"instructions" validate:"max=1000"`
+	Consent      bool     `json:"consent" validate:"required"`
+}
+
+type MedicationRequestFilter struct {
+	ChildID uint   `query:"childId"`
+	Status  string `query:"status" validate:"omitempty,oneof=active cancelled"`
+}
+
+// RecordAdministrationRequest records the dose of Date at Time, given or
+// refused at AdministeredAt and witnessed by another staff member
+type RecordAdministrationRequest struct {
+	Date           string `json:"date" validate:"required,datetime=2006-01-02"`
+	Time           string `json:"time" validate:"required,datetime=15:04"`
+	AdministeredAt string `json:"administeredAt" validate:"required,datetime=2006-01-02 15:04:05"`
+	Status         string `json:"status" validate:"required,oneof=given refused"`
+	WitnessID      uint   `json:"witnessId" validate:"required,gt=0"`
+	Notes          string `json:"notes" validate:"max=1000"`
+}
+
+type MedicationDailyQuery struct {
+	Date string `query:"date" validate:"omitempty,datetime=2006-01-02"`
+}
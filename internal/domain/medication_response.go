@@ -0,0 +1,114 @@
+package domain
+
+import "time"
+
+// Statuses of a dose in the daily view. Doses not recorded are due until
+// the grace period after their time, then overdue, and missed once the day
+// is over.
+const (
+	DoseDue     = "due"
+	DoseOverdue = "overdue"
+	DoseMissed  = "missed"
+	DoseAbsent  = "absent"
+)
+
+type MedicationAdministrationResponse struct {
+	ID             uint       `json:"id"`
+	RequestID      uint       `json:"request_id"`
+	ChildID        uint       `json:"child_id"`
+	DiaryID        uint       `json:"diary_id"`
+	ScheduledAt    time.Time  `json:"scheduled_at"`
+	AdministeredAt time.Time  `json:"administered_at"`
+	Status         string     `json:"status"`
+	AdministeredBy uint       `json:"administered_by"`
+	WitnessID      uint       `json:"witness_id"`
+	WitnessedAt    *time.Time `json:"witnessed_at"`
+	Notes          string     `json:"notes"`
+}
+
+func NewMedicationAdministrationResponse(administration *MedicationAdministration) *MedicationAdministrationResponse {
+	return &MedicationAdministrationResponse{
+		ID:             administration.ID,
+		RequestID:      administration.RequestID,
+		ChildID:        administration.ChildID,
+		DiaryID:        administration.DiaryID,
+		ScheduledAt:    administration.ScheduledAt,
+		AdministeredAt: administration.AdministeredAt,
+		Status:         administration.Status,
+		AdministeredBy: administration.AdministeredBy,
+		WitnessID:      administration.WitnessID,
+		WitnessedAt:    administration.WitnessedAt,
+		Notes:          administration.Notes,
+	}
+}
+
+type MedicationRequestResponse struct {
+	ID              uint                               `json:"id"`
+	ChildID         uint                               `json:"child_id"`
+	ChildName       string                             `json:"child_name"`
+	RequestedBy     uint                               `json:"requested_by"`
+	Drug            string                             `json:"drug"`
+	Dose            string                             `json:"dose"`
+	Times           []string                           `json:"times"`
+	StartDate       time.Time                          `json:"start_date"`
+	EndDate         time.Time                          `json:"end_date"`
+	Instructions    string                             `json:"instructions"`
+	Consent         bool                               `json:"consent"`
+	ConsentAt       time.Time                          `json:"consent_at"`
+	Status          string                             `json:"status"`
+	Administrations []MedicationAdministrationResponse `json:"administrations"`
+	CreatedAt       time.Time                          `json:"created_at"`
+}
+
+func NewMedicationRequestResponse(request *MedicationRequest) *MedicationRequestResponse {
+	response := &MedicationRequestResponse{
+		ID:              request.ID,
+		ChildID:         request.ChildID,
+		ChildName:       request.Child.Name,
+		RequestedBy:     request.RequestedBy,
+		Drug:            request.Drug,
+		Dose:            request.Dose,
+		Times:           request.TimeList(),
+		StartDate:       request.StartDate,
+		EndDate:         request.EndDate,
+		Instructions:    request.Instructions,
+		Consent:         request.Consent,
+		ConsentAt:       request.ConsentAt,
+		Status:          request.Status,
+		Administrations: make([]MedicationAdministrationResponse, len(request.Administrations)),
+		CreatedAt:       request.CreatedAt,
+	}
+	for i := range request.Administrations {
+		response.Administrations[i] = *NewMedicationAdministrationResponse(&request.Administrations[i])
+	}
+	return response
+}
+
+func NewMedicationRequestResponses(requests []MedicationRequest) []MedicationRequestResponse {
+	responses := make([]MedicationRequestResponse, len(requests))
+	for i := range requests {
+		responses[i] = *NewMedicationRequestResponse(&requests[i])
+	}
+	return responses
+}
+
+// MedicationDoseResponse is one scheduled dose of the daily view
+type MedicationDoseResponse struct {
+	RequestID      uint                              `json:"request_id"`
+	ChildID        uint                              `json:"child_id"`
+	ChildName      string                            `json:"child_name"`
+	Drug           string                            `json:"drug"`
+	Dose           string                            `json:"dose"`
+	Instructions   string                            `json:"instructions"`
+	ScheduledAt    time.Time                         `json:"scheduled_at"`
+	Status         string                            `json:"status"`
+	Administration *MedicationAdministrationResponse `json:"administration"`
+}
+
+type MedicationDailyResponse struct {
+	Date                string                   `json:"date"`
+	Overdue             int                      `json:"overdue"`
+	Missed              int                      `json:"missed"`
+	AwaitingCountersign int                      `json:"awaiting_countersign"`
+	Doses               []MedicationDoseResponse `json:"doses"`
+}
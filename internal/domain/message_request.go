@@ -0,0 +1,30 @@
+package domain
+
+// MessageThreadRequest starts a thread with its first message. Child threads
+// need the child, classroom announcements the classroom.
+type MessageThreadRequest struct {
+	Kind        string `json:"kind" validate:"required,oneof=child classroom center"`
+	ChildID     uint   `json:"childId" validate:"required_if=Kind child"`
+	ClassroomID uint   `json:"classroomId" validate:"required_if=Kind classroom"`
+	Subject     string `json:"subject" validate:"required,max=255"`
+	Body        string `json:"body" validate:"required,max=5000"`
+}
+
+// MessageRequest is sent as JSON or as a multipart form with files. The body
+// may only be empty when files are attached.
+type MessageRequest struct {
+	Body string `json:"body" form:"body" validate:"max=5000"`
+}
+
+// MarkReadRequest moves the read position of the user, to the latest message
+// when MessageID is zero
+type MarkReadRequest struct {
+	MessageID uint `json:"messageId"`
+}
+
+type MessageThreadFilter struct {
+	Kind        string `query:"kind" validate:"omitempty,oneof=child classroom center"`
+	ChildID     uint   `query:"childId"`
+	ClassroomID uint   `query:"classroomId"`
+	Unread      bool   `query:"unread"`
+}
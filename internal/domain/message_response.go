@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+type MessageThreadResponse struct {
+	ID            uint      `json:"id"`
+	Kind          string    `json:"kind"`
+	ChildID       *uint     `json:"child_id"`
+	ChildName     string    `json:"child_name,omitempty"`
+	ClassroomID   *uint     `json:"classroom_id"`
+	ClassroomName string    `json:"classroom_name,omitempty"`
+	Subject       string    `json:"subject"`
+	CreatedBy     uint      `json:"created_by"`
+	Unread        int64     `json:"unread"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func NewMessageThreadResponse(thread *MessageThread, unread int64) *MessageThreadResponse {
+	response := &MessageThreadResponse{
+		ID:            thread.ID,
+		Kind:          thread.Kind,
+		ChildID:       thread.ChildID,
+		ClassroomID:   thread.ClassroomID,
+		Subject:       thread.Subject,
+		CreatedBy:     thread.CreatedBy,
+		Unread:        unread,
+		LastMessageAt: thread.LastMessageAt,
+		CreatedAt:     thread.CreatedAt,
+	}
+	if thread.Child != nil {
+		response.ChildName = thread.Child.Name
+	}
+	if thread.Classroom != nil {
+		response.ClassroomName = thread.Classroom.Name
+	}
+	return response
+}
+
+type MessageReceiptResponse struct {
+	UserID uint      `json:"user_id"`
+	Name   string    `json:"name"`
+	ReadAt time.Time `json:"read_at"`
+}
+
+// MessageResponse lists who read the message in child threads. Announcements
+// only carry the number of readers.
+type MessageResponse struct {
+	ID          uint                     `json:"id"`
+	ThreadID    uint                     `json:"thread_id"`
+	SenderID    uint                     `json:"sender_id"`
+	SenderName  string                   `json:"sender_name"`
+	Body        string                   `json:"body"`
+	Attachments []AttachmentResponse     `json:"attachments"`
+	ReadCount   int                      `json:"read_count"`
+	ReadBy      []MessageReceiptResponse `json:"read_by,omitempty"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+type UnreadMessagesResponse struct {
+	Threads  int64 `json:"threads"`
+	Messages int64 `json:"messages"`
+}
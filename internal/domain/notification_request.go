@@ -0,0 +1,25 @@
+package domain
+
+type NotificationInboxFilter struct {
+	Unread bool `query:"unread"`
+}
+
+type NotificationPreferenceRequest struct {
+	Type  string `json:"type" validate:"required,oneof=child.arrived child.departed teacher.missing_clock_out"`
+	Email bool   `json:"email"`
+	Push  bool   `json:"push"`
+	InApp bool   `json:"inApp"`
+}
+
+// NotificationSettingsRequest replaces the quiet hours, empty to turn them
+// off, and the preferences of the listed kinds
+type NotificationSettingsRequest struct {
+	QuietStart  string                          `json:"quietStart" validate:"required_with=QuietEnd,omitempty,datetime=15:04"`
+	QuietEnd    string                          `json:"quietEnd" validate:"required_with=QuietStart,omitempty,datetime=15:04"`
+	Preferences []NotificationPreferenceRequest `json:"preferences" validate:"dive"`
+}
+
+type PushDeviceRequest struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Platform string `json:"platform" validate:"required,oneof=android ios web"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+type NotificationResponse struct {
+	ID        uint       `json:"id"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewNotificationResponse(notification *Notification) *NotificationResponse {
+	return &NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+type UnreadNotificationsResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+type NotificationPreferenceResponse struct {
+	Type  string `json:"type"`
+	Email bool   `json:"email"`
+	Push  bool   `json:"push"`
+	InApp bool   `json:"in_app"`
+}
+
+// NotificationSettingsResponse lists every kind of notification with the
+// channels in use, the defaults where the user chose none
+type NotificationSettingsResponse struct {
+	QuietStart  string                           `json:"quiet_start"`
+	QuietEnd    string                           `json:"quiet_end"`
+	Preferences []NotificationPreferenceResponse `json:"preferences"`
+}
+
+type PushDeviceResponse struct {
+	ID         uint      `json:"id"`
+	Platform   string    `json:"platform"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewPushDeviceResponse(device *PushDevice) *PushDeviceResponse {
+	return &PushDeviceResponse{
+		ID:         device.ID,
+		Platform:   device.Platform,
+		LastSeenAt: device.LastSeenAt,
+		CreatedAt:  device.CreatedAt,
+	}
+}
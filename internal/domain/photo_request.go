@@ -0,0 +1,34 @@
+package domain
+
+type PhotoConsentRequest struct {
+	Granted *bool `json:"granted" validate:"required"`
+}
+
+// PhotoPostRequest creates a draft post, or changes it. Every tagged child
+// needs a granted photo consent.
+type PhotoPostRequest struct {
+	Caption  string `json:"caption" validate:"max=2000"`
+	ChildIDs []uint `json:"childIds" validate:"required,min=1,max=50,unique,dive,gt=0"`
+}
+
+type PhotoFeedFilter struct {
+	ChildID uint   `query:"childId"`
+	Drafts  bool   `query:"drafts"`
+	From    string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To      string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type PhotoReactionRequest struct {
+	Kind string `json:"kind" validate:"required,oneof=like love laugh wow"`
+}
+
+type PhotoCommentRequest struct {
+	Body string `json:"body" validate:"required,max=1000"`
+}
+
+// PhotoArchiveQuery bounds the bulk download of the photos of a child, a
+// school term at most
+type PhotoArchiveQuery struct {
+	From string `query:"from" validate:"required,datetime=2006-01-02"`
+	To   string `query:"to" validate:"required,datetime=2006-01-02"`
+}
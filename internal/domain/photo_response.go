@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+type PhotoConsentResponse struct {
+	ChildID       uint       `json:"child_id"`
+	Granted       bool       `json:"granted"`
+	UpdatedBy     *uint      `json:"updated_by"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+	UntaggedPosts int64      `json:"untagged_posts,omitempty"`
+}
+
+type PhotoTagResponse struct {
+	ChildID uint   `json:"child_id"`
+	Name    string `json:"name"`
+}
+
+type PhotoCommentResponse struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewPhotoCommentResponse(comment *PhotoComment) *PhotoCommentResponse {
+	return &PhotoCommentResponse{
+		ID:        comment.ID,
+		UserID:    comment.UserID,
+		UserName:  comment.User.Name,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+// PhotoPostResponse shows parents only their own children among the tags
+// and only their own and the staff comments
+type PhotoPostResponse struct {
+	ID          uint                   `json:"id"`
+	AuthorID    uint                   `json:"author_id"`
+	AuthorName  string                 `json:"author_name"`
+	Caption     string                 `json:"caption"`
+	Children    []PhotoTagResponse     `json:"children"`
+	Photos      []AttachmentResponse   `json:"photos"`
+	Reactions   map[string]int         `json:"reactions"`
+	MyReaction  string                 `json:"my_reaction,omitempty"`
+	Comments    []PhotoCommentResponse `json:"comments"`
+	PublishedAt *time.Time             `json:"published_at"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+type RoleRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type PermissionRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description"`
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []uint `json:"permissions" validate:"required,unique,dive,gt=0"`
+}
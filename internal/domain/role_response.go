@@ -0,0 +1,52 @@
+package domain
+
+type PermissionResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RoleDetailResponse struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	System      bool                 `json:"system"`
+	Permissions []PermissionResponse `json:"permissions"`
+}
+
+func NewPermissionResponse(permission *Permission) *PermissionResponse {
+	return &PermissionResponse{ID: permission.ID, Name: permission.Name, Description: permission.Description}
+}
+
+func NewPermissionResponses(permissions []Permission) []PermissionResponse {
+	responses := make([]PermissionResponse, len(permissions))
+	for i := range permissions {
+		responses[i] = *NewPermissionResponse(&permissions[i])
+	}
+	return responses
+}
+
+func NewRoleDetailResponse(role *Role) *RoleDetailResponse {
+	return &RoleDetailResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		System:      IsSystemRole(role.Name),
+		Permissions: NewPermissionResponses(role.Permissions),
+	}
+}
+
+func NewRoleDetailResponses(roles []Role) []RoleDetailResponse {
+	responses := make([]RoleDetailResponse, len(roles))
+	for i := range roles {
+		responses[i] = *NewRoleDetailResponse(&roles[i])
+	}
+	return responses
+}
+
+func IsSystemRole(name string) bool {
+	for _, systemRole := range SystemRoles {
+		if systemRole == name {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,16 @@
 package domain
 
 type CreateTeacherAttendanceRequest struct {
-	UserID            string  `json:"userId"`
-	Date              string  `json:"date"`
-	ClockIn           string  `json:"clockIn"`
-	ClockOut          string  `json:"clockOut"`
-	WorkHour          string  `json:"workHour"`
-	OvertimeRegular   string  `json:"overtimeRegular"`
-	OvertimeMorning   string  `json:"overtimeMorning"`
-	OvertimeEvening   string  `json:"overtimeEvening"`
-	IsOvertimeMorning bool    `json:"isOvertimeMorning"`
-	IsOvertimeEvening bool    `json:"isOvertimeEvening"`
-	Latitude          float64 `json:"latitude"`
-	Longitude         float64 `json:"longitude"`
+	UserID            string   `json:"userId"`
+	Date              string   `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	ClockIn           string   `json:"clockIn" validate:"omitempty,datetime=2006-01-02 15:04:05"`
+	ClockOut          string   `json:"clockOut" validate:"omitempty,datetime=2006-01-02 15:04:05"`
+	WorkHour          string   `json:"workHour"`
+	OvertimeRegular   string   `json:"overtimeRegular"`
+	OvertimeMorning   string   `json:"overtimeMorning"`
+	OvertimeEvening   string   `json:"overtimeEvening"`
+	IsOvertimeMorning bool     `json:"isOvertimeMorning"`
+	IsOvertimeEvening bool     `json:"isOvertimeEvening"`
+	Latitude          *float64 `json:"latitude" validate:"required,latitude"`   // pointer so 0 is a valid coordinate
+	Longitude         *float64 `json:"longitude" validate:"required,longitude"` // pointer so 0 is a valid coordinate
 }
@@ -0,0 +1,20 @@
+package domain
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code,omitempty,max=32"`
+}
+
+type TwoFactorEnrollRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+}
+
+type TwoFactorConfirmRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+const (
+	// MFAChallengeTOTP asks for a code from the enrolled authenticator
+	MFAChallengeTOTP = "totp"
+	// MFAChallengeEnroll asks a user whose role requires two-factor to
+	// enroll before the first token is issued
+	MFAChallengeEnroll = "totp_enrollment"
+)
+
+type MFAChallenge struct {
+	Type      string `json:"type"`
+	Token     string `json:"token"`
+	ExpiredAt string `json:"expired_at"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
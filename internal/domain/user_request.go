@@ -0,0 +1,53 @@
+package domain
+
+type RegisterRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Gender   string `json:"gender" validate:"required,oneof=male female"`
+	Phone    string `json:"phone" validate:"required,max=255"`
+	Address  string `json:"address" validate:"required"`
+	JobTitle string `json:"jobTitle" validate:"max=255"`
+	JobPlace string `json:"jobPlace" validate:"max=255"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RegisterEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Roles []uint `json:"roles" validate:"required,min=1,unique,dive,gt=0"`
+}
+
+// UpdateProfileRequest only changes the fields that are sent
+type UpdateProfileRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Phone    *string `json:"phone" validate:"omitempty,min=1,max=255"`
+	Address  *string `json:"address" validate:"omitempty,min=1"`
+	JobTitle *string `json:"jobTitle" validate:"omitempty,max=255"`
+	JobPlace *string `json:"jobPlace" validate:"omitempty,max=255"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+type AdminChangeEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+type UpdateUserRolesRequest struct {
+	Roles []uint `json:"roles" validate:"required,min=1,unique,dive,gt=0"`
+}
+
+type UserListFilter struct {
+	Role   string `query:"role" validate:"max=255"`
+	Status string `query:"status" validate:"omitempty,oneof=active deactivated all"`
+}
@@ -0,0 +1,100 @@
+package domain
+
+import "time"
+
+type TokenData struct {
+	Token     string `json:"token"`
+	ExpiredAt string `json:"expired_at"`
+}
+
+// AuthResponse carries either the issued token or, when two-factor is
+// needed, the challenge to answer before a token is issued.
+type AuthResponse struct {
+	TokenData     *TokenData    `json:"token_data,omitempty"`
+	UserData      *UserResponse `json:"user_data,omitempty"`
+	Challenge     *MFAChallenge `json:"challenge,omitempty"`
+	RecoveryCodes []string      `json:"recovery_codes,omitempty"`
+}
+
+type RoleResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserResponse is the only shape a user is returned in, so the password hash
+// and verification tokens never leave the API.
+type UserResponse struct {
+	ID            uint           `json:"id"`
+	Name          string         `json:"name"`
+	Email         string         `json:"email"`
+	PendingEmail  *string        `json:"pending_email,omitempty"`
+	Gender        string         `json:"gender"`
+	Phone         string         `json:"phone"`
+	Address       string         `json:"address"`
+	JobTitle      string         `json:"job_title"`
+	JobPlace      string         `json:"job_place"`
+	Roles         []RoleResponse `json:"roles"`
+	Active        bool           `json:"active"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeactivatedAt *time.Time     `json:"deactivated_at,omitempty"`
+}
+
+func NewRoleResponses(roles []Role) []RoleResponse {
+	responses := make([]RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = RoleResponse{ID: role.ID, Name: role.Name}
+	}
+	return responses
+}
+
+func NewUserResponse(user *User) *UserResponse {
+	response := &UserResponse{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail,
+		Gender:       user.Gender,
+		Phone:        user.Phone,
+		Address:      user.Address,
+		JobTitle:     user.JobTitle,
+		JobPlace:     user.JobPlace,
+		Roles:        NewRoleResponses(user.Roles),
+		Active:       !user.DeletedAt.Valid,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+	if user.DeletedAt.Valid {
+		response.DeactivatedAt = &user.DeletedAt.Time
+	}
+	return response
+}
+
+func NewUserResponses(users []User) []UserResponse {
+	responses := make([]UserResponse, len(users))
+	for i := range users {
+		responses[i] = *NewUserResponse(&users[i])
+	}
+	return responses
+}
+
+// InvitationResponse is an allowlisted email that has not registered yet
+type InvitationResponse struct {
+	ID        uint           `json:"id"`
+	Email     string         `json:"email"`
+	Roles     []RoleResponse `json:"roles"`
+	InvitedAt time.Time      `json:"invited_at"`
+}
+
+func NewInvitationResponses(registeredEmails []RegisteredEmail) []InvitationResponse {
+	responses := make([]InvitationResponse, len(registeredEmails))
+	for i, registeredEmail := range registeredEmails {
+		responses[i] = InvitationResponse{
+			ID:        registeredEmail.ID,
+			Email:     registeredEmail.Email,
+			Roles:     NewRoleResponses(registeredEmail.Roles),
+			InvitedAt: registeredEmail.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,14 @@
+package domain
+
+// WebhookSubscriptionRequest creates or replaces a subscription. Active
+// defaults to true.
+type WebhookSubscriptionRequest struct {
+	Name       string   `json:"name" validate:"required,max=255"`
+	URL        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1,dive,oneof=teacher_attendance.closed child_attendance.departed"`
+	Active     *bool    `json:"active"`
+}
+
+type WebhookDeliveryFilter struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending delivered failed"`
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookSubscriptionResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Signing secret, only returned when it is created or rotated
+	Secret string `json:"secret,omitempty"`
+}
+
+func NewWebhookSubscriptionResponse(subscription *WebhookSubscription) *WebhookSubscriptionResponse {
+	return &WebhookSubscriptionResponse{
+		ID:         subscription.ID,
+		Name:       subscription.Name,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypeList(),
+		Active:     subscription.Active,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+	}
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `json:"subscription_id"`
+	EventID        uint       `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewWebhookDeliveryResponse(delivery *WebhookDelivery) *WebhookDeliveryResponse {
+	response := &WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.Event.Type,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == WebhookPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return response
+}
+
+// WebhookPayload is the body posted to subscribers. The id is the id of the
+// event, the same on every attempt, so receivers can drop duplicates.
+type WebhookPayload struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Data of teacher_attendance.closed
+type TeacherAttendanceClosedData struct {
+	AttendanceID    uint      `json:"attendance_id"`
+	UserID          uint      `json:"user_id"`
+	Date            string    `json:"date"`
+	ClockIn         time.Time `json:"clock_in"`
+	ClockOut        time.Time `json:"clock_out"`
+	WorkHour        float32   `json:"work_hour"`
+	OvertimeRegular int       `json:"overtime_regular"`
+	OvertimeMorning int       `json:"overtime_morning"`
+	OvertimeEvening int       `json:"overtime_evening"`
+}
+
+// Data of child_attendance.departed
+type ChildAttendanceDepartedData struct {
+	AttendanceID    uint      `json:"attendance_id"`
+	ChildID         uint      `json:"child_id"`
+	Date            string    `json:"date"`
+	Arrival         time.Time `json:"arrival"`
+	Departure       time.Time `json:"departure"`
+	OvertimeMorning int       `json:"overtime_morning"`
+	OvertimeEvening int       `json:"overtime_evening"`
+}
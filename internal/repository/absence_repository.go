@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type AbsenceRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetAbsences(ctx context.Context, childId uint, parentId uint, from, to time.Time) ([]domain.ChildAbsence, error)
+	GetAbsenceById(ctx context.Context, id uint) (*domain.ChildAbsence, error)
+	CreateAbsences(ctx context.Context, absences []domain.ChildAbsence) error
+	DeleteAbsence(ctx context.Context, absence *domain.ChildAbsence) error
+	GetAttendingEnrollments(ctx context.Context, childId uint, from, to time.Time) ([]domain.Enrollment, error)
+	GetArrivals(ctx context.Context, from, to time.Time) ([]domain.ChildAttendance, error)
+}
+
+type absenceRepository struct {
+	db *gorm.DB
+}
+
+func NewAbsenceRepository(db *gorm.DB) AbsenceRepository {
+	return &absenceRepository{db}
+}
+
+func (r *absenceRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *absenceRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *absenceRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+// GetAbsences returns the absences between from and to, of one child when
+// childId is set and of the children of parentId when it is set
+func (r *absenceRepository) GetAbsences(ctx context.Context, childId uint, parentId uint, from, to time.Time) ([]domain.ChildAbsence, error) {
+	var absences []domain.ChildAbsence
+	query := r.db.WithContext(ctx).Preload("Child").
+		Where("date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if childId != 0 {
+		query = query.Where("child_id = ?", childId)
+	}
+	if parentId != 0 {
+		query = query.Where("child_id IN (?)", r.db.Table("child_parents").Select("child_id").Where("user_id = ?", parentId))
+	}
+	err := query.Order("date, child_id").Find(&absences).Error
+	return absences, err
+}
+
+func (r *absenceRepository) GetAbsenceById(ctx context.Context, id uint) (*domain.ChildAbsence, error) {
+	var absence domain.ChildAbsence
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&absence).Error
+	return &absence, err
+}
+
+func (r *absenceRepository) CreateAbsences(ctx context.Context, absences []domain.ChildAbsence) error {
+	return r.db.WithContext(ctx).Omit("Child").Create(&absences).Error
+}
+
+func (r *absenceRepository) DeleteAbsence(ctx context.Context, absence *domain.ChildAbsence) error {
+	return r.db.WithContext(ctx).Delete(absence).Error
+}
+
+// GetAttendingEnrollments returns the enrollments that had the child
+// attending at some point between from and to, of every child when childId
+// is zero. Paused enrollments are left out.
+func (r *absenceRepository) GetAttendingEnrollments(ctx context.Context, childId uint, from, to time.Time) ([]domain.Enrollment, error) {
+	var enrollments []domain.Enrollment
+	query := r.db.WithContext(ctx).Preload("Child").
+		Where("status IN ?", []string{domain.EnrollmentEnrolled, domain.EnrollmentWithdrawn, domain.EnrollmentGraduated}).
+		Where("start_date IS NOT NULL AND start_date <= ?", to.Format("2006-01-02")).
+		Where("end_date IS NULL OR end_date >= ?", from.Format("2006-01-02"))
+	if childId != 0 {
+		query = query.Where("child_id = ?", childId)
+	}
+	err := query.Order("child_id").Find(&enrollments).Error
+	return enrollments, err
+}
+
+// GetArrivals returns the child attendances dated between from and to
+func (r *absenceRepository) GetArrivals(ctx context.Context, from, to time.Time) ([]domain.ChildAttendance, error) {
+	var attendances []domain.ChildAttendance
+	err := r.db.WithContext(ctx).
+		Where("DATE(date) BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("arrival").
+		Find(&attendances).Error
+	return attendances, err
+}
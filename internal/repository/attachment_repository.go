@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetDiary(ctx context.Context, diaryId uint) (*domain.ChildDiary, error)
+	GetIncident(ctx context.Context, incidentId uint) (*domain.Incident, error)
+	GetPost(ctx context.Context, postId uint) (*domain.PhotoPost, error)
+	GetMessageThread(ctx context.Context, messageId uint) (*domain.MessageThread, error)
+	Create(ctx context.Context, attachment *domain.Attachment) error
+	GetById(ctx context.Context, id uint) (*domain.Attachment, error)
+	ListByOwner(ctx context.Context, ownerType string, ownerId uint) ([]domain.Attachment, error)
+	Delete(ctx context.Context, attachment *domain.Attachment) error
+}
+
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db}
+}
+
+func (r *attachmentRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *attachmentRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *attachmentRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *attachmentRepository) GetDiary(ctx context.Context, diaryId uint) (*domain.ChildDiary, error) {
+	var diary domain.ChildDiary
+	err := r.db.WithContext(ctx).Where("id = ?", diaryId).First(&diary).Error
+	return &diary, err
+}
+
+func (r *attachmentRepository) GetIncident(ctx context.Context, incidentId uint) (*domain.Incident, error) {
+	var incident domain.Incident
+	err := r.db.WithContext(ctx).Where("id = ?", incidentId).First(&incident).Error
+	return &incident, err
+}
+
+func (r *attachmentRepository) GetPost(ctx context.Context, postId uint) (*domain.PhotoPost, error) {
+	var post domain.PhotoPost
+	err := r.db.WithContext(ctx).Preload("Children").Where("id = ?", postId).First(&post).Error
+	return &post, err
+}
+
+func (r *attachmentRepository) GetMessageThread(ctx context.Context, messageId uint) (*domain.MessageThread, error) {
+	var thread domain.MessageThread
+	err := r.db.WithContext(ctx).
+		Joins("JOIN messages ON messages.thread_id = message_threads.id").
+		Where("messages.id = ?", messageId).
+		First(&thread).Error
+	return &thread, err
+}
+
+func (r *attachmentRepository) Create(ctx context.Context, attachment *domain.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *attachmentRepository) GetById(ctx context.Context, id uint) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&attachment).Error
+	return &attachment, err
+}
+
+func (r *attachmentRepository) ListByOwner(ctx context.Context, ownerType string, ownerId uint) ([]domain.Attachment, error) {
+	var attachments []domain.Attachment
+	err := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerId).
+		Order("id").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *attachmentRepository) Delete(ctx context.Context, attachment *domain.Attachment) error {
+	return r.db.WithContext(ctx).Delete(attachment).Error
+}
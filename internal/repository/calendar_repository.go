@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type CalendarRepository interface {
+	GetDays(ctx context.Context, from, to time.Time) ([]domain.CalendarDay, error)
+	GetDayById(ctx context.Context, id uint) (*domain.CalendarDay, error)
+	DateExists(ctx context.Context, date time.Time) (bool, error)
+	CreateDay(ctx context.Context, day *domain.CalendarDay) error
+	DeleteDay(ctx context.Context, day *domain.CalendarDay) error
+	CreateDays(ctx context.Context, days []domain.CalendarDay) error
+	GetEvents(ctx context.Context, from, to time.Time, audiences []string) ([]domain.CalendarEvent, error)
+	GetEventById(ctx context.Context, id uint) (*domain.CalendarEvent, error)
+	CreateEvent(ctx context.Context, event *domain.CalendarEvent) error
+	UpdateEvent(ctx context.Context, event *domain.CalendarEvent) error
+	DeleteEvent(ctx context.Context, event *domain.CalendarEvent) error
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	GetFeedToken(ctx context.Context, tokenHash string) (*domain.CalendarFeedToken, error)
+	SaveFeedToken(ctx context.Context, token *domain.CalendarFeedToken) error
+}
+
+type calendarRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarRepository(db *gorm.DB) CalendarRepository {
+	return &calendarRepository{db}
+}
+
+// GetDays returns the calendar days between from and to, both included
+func (r *calendarRepository) GetDays(ctx context.Context, from, to time.Time) ([]domain.CalendarDay, error) {
+	var days []domain.CalendarDay
+	err := r.db.WithContext(ctx).
+		Where("date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("date").
+		Find(&days).Error
+	return days, err
+}
+
+func (r *calendarRepository) GetDayById(ctx context.Context, id uint) (*domain.CalendarDay, error) {
+	var day domain.CalendarDay
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&day).Error
+	return &day, err
+}
+
+func (r *calendarRepository) DateExists(ctx context.Context, date time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.CalendarDay{}).Where("date = ?", date.Format("2006-01-02")).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *calendarRepository) CreateDay(ctx context.Context, day *domain.CalendarDay) error {
+	return r.db.WithContext(ctx).Create(day).Error
+}
+
+func (r *calendarRepository) DeleteDay(ctx context.Context, day *domain.CalendarDay) error {
+	return r.db.WithContext(ctx).Delete(day).Error
+}
+
+func (r *calendarRepository) CreateDays(ctx context.Context, days []domain.CalendarDay) error {
+	return r.db.WithContext(ctx).Create(&days).Error
+}
+
+// GetEvents returns the events overlapping from and to, both dates included.
+// A nil audiences returns the events of every audience.
+func (r *calendarRepository) GetEvents(ctx context.Context, from, to time.Time, audiences []string) ([]domain.CalendarEvent, error) {
+	var events []domain.CalendarEvent
+	query := r.db.WithContext(ctx).
+		Where("starts_at < ? AND ends_at >= ?", to.AddDate(0, 0, 1), from)
+	if audiences != nil {
+		query = query.Where("audience IN ?", audiences)
+	}
+	err := query.Order("starts_at").Find(&events).Error
+	return events, err
+}
+
+func (r *calendarRepository) GetEventById(ctx context.Context, id uint) (*domain.CalendarEvent, error) {
+	var event domain.CalendarEvent
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&event).Error
+	return &event, err
+}
+
+func (r *calendarRepository) CreateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *calendarRepository) UpdateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+func (r *calendarRepository) DeleteEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	return r.db.WithContext(ctx).Delete(event).Error
+}
+
+func (r *calendarRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *calendarRepository) GetFeedToken(ctx context.Context, tokenHash string) (*domain.CalendarFeedToken, error) {
+	var token domain.CalendarFeedToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	return &token, err
+}
+
+// SaveFeedToken replaces the feed token of the user
+func (r *calendarRepository) SaveFeedToken(ctx context.Context, token *domain.CalendarFeedToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&domain.CalendarFeedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
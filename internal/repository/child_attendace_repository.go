@@ -1,12 +1,17 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"gorm.io/gorm"
 )
 
 type ChildAttendanceRepository interface {
-	Create(childAttendance *domain.ChildAttendance) error
+	Create(ctx context.Context, childAttendance *domain.ChildAttendance) error
+	GetOpenByChildAndDate(ctx context.Context, childId uint, date time.Time) (*domain.ChildAttendance, error)
+	Update(ctx context.Context, childAttendance *domain.ChildAttendance, events ...domain.OutboxEvent) error
 }
 
 type childAttendanceRepository struct {
@@ -17,6 +22,30 @@ func NewChildAttendanceRepository(db *gorm.DB) ChildAttendanceRepository {
 	return &childAttendanceRepository{db}
 }
 
-func (r *childAttendanceRepository) Create(childAttendance *domain.ChildAttendance) error {
-	return r.db.Create(childAttendance).Error
+func (r *childAttendanceRepository) Create(ctx context.Context, childAttendance *domain.ChildAttendance) error {
+	return r.db.WithContext(ctx).Create(childAttendance).Error
+}
+
+// GetOpenByChildAndDate returns the attendance of the child on date that has
+// no departure yet.
+func (r *childAttendanceRepository) GetOpenByChildAndDate(ctx context.Context, childId uint, date time.Time) (*domain.ChildAttendance, error) {
+	var childAttendance domain.ChildAttendance
+	if err := r.db.WithContext(ctx).
+		Where("child_id = ? AND DATE(date) = ? AND departure IS NULL", childId, date.Format("2006-01-02")).
+		Order("arrival DESC").
+		First(&childAttendance).Error; err != nil {
+		return nil, err
+	}
+	return &childAttendance, nil
+}
+
+// Update saves the attendance together with the outbox events describing
+// the change
+func (r *childAttendanceRepository) Update(ctx context.Context, childAttendance *domain.ChildAttendance, events ...domain.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(childAttendance).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, events)
+	})
 }
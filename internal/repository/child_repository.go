@@ -1,15 +1,17 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"gorm.io/gorm"
 )
 
 type ChildRepository interface {
-	Create(child *domain.Child) error
-	GetUserWithRoles(userId uint) (domain.User, error)
-	GetUsersByIds(userIds []uint) ([]domain.User, error)
-	GetChild(id string) (*domain.Child, error)
+	Create(ctx context.Context, child *domain.Child, enrollment *domain.Enrollment, event *domain.EnrollmentEvent) error
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	GetUsersByIds(ctx context.Context, userIds []uint) ([]domain.User, error)
+	GetChild(ctx context.Context, id string) (*domain.Child, error)
 }
 
 type childRepository struct {
@@ -20,24 +22,36 @@ func NewChildRepository(db *gorm.DB) ChildRepository {
 	return &childRepository{db}
 }
 
-func (r *childRepository) GetChild(id string) (*domain.Child, error) {
+func (r *childRepository) GetChild(ctx context.Context, id string) (*domain.Child, error) {
 	var child domain.Child
-	err := r.db.Preload("Teachers").Preload("Parents").Where("id = ?", id).First(&child).Error
+	err := r.db.WithContext(ctx).Preload("Teachers").Preload("Parents").Where("id = ?", id).First(&child).Error
 	return &child, err
 }
 
-func (r *childRepository) Create(child *domain.Child) error {
-	return r.db.Create(child).Error
+// Create saves the child together with its initial enrollment and the
+// event recording it
+func (r *childRepository) Create(ctx context.Context, child *domain.Child, enrollment *domain.Enrollment, event *domain.EnrollmentEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(child).Error; err != nil {
+			return err
+		}
+		enrollment.ChildID = child.ID
+		if err := tx.Omit("Child", "Classroom", "Events").Create(enrollment).Error; err != nil {
+			return err
+		}
+		event.EnrollmentID = enrollment.ID
+		return tx.Create(event).Error
+	})
 }
 
-func (r *childRepository) GetUserWithRoles(userId uint) (domain.User, error) {
+func (r *childRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
 	var user domain.User
-	err := r.db.Preload("Roles").Where("id = ?", userId).First(&user).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
 	return user, err
 }
 
-func (r *childRepository) GetUsersByIds(userIds []uint) ([]domain.User, error) {
+func (r *childRepository) GetUsersByIds(ctx context.Context, userIds []uint) ([]domain.User, error) {
 	var users []domain.User
-	err := r.db.Where("id IN ?", userIds).Find(&users).Error
+	err := r.db.WithContext(ctx).Where("id IN ?", userIds).Find(&users).Error
 	return users, err
 }
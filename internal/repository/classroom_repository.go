@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ClassroomRepository interface {
+	GetAll(ctx context.Context) ([]domain.Classroom, error)
+	GetById(ctx context.Context, id uint) (*domain.Classroom, error)
+	NameExists(ctx context.Context, name string, exceptId uint) (bool, error)
+	Create(ctx context.Context, classroom *domain.Classroom) error
+	Update(ctx context.Context, classroom *domain.Classroom) error
+	ReplaceTeachers(ctx context.Context, classroomId uint, teachers []domain.ClassroomTeacher) error
+	GetClassroomIdsByTeacher(ctx context.Context, userId uint) ([]uint, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	CountChildren(ctx context.Context, classroomId uint) (int64, error)
+	CountPendingOffers(ctx context.Context, classroomId uint, now time.Time) (int64, error)
+	HasActiveEnrollment(ctx context.Context, childId uint) (bool, error)
+	SetChildClassroom(ctx context.Context, childId uint, classroomId *uint) error
+	GetUsersWithRoles(ctx context.Context, userIds []uint) ([]domain.User, error)
+	CountPresentChildren(ctx context.Context, classroomId uint, day time.Time) (int64, error)
+	CountPresentStaff(ctx context.Context, classroomId uint, day time.Time) (int64, error)
+	GetOpenAlert(ctx context.Context, classroomId uint) (*domain.ClassroomAlert, error)
+	CreateAlert(ctx context.Context, alert *domain.ClassroomAlert) error
+	ResolveAlert(ctx context.Context, alert *domain.ClassroomAlert) error
+	GetAlerts(ctx context.Context, filter domain.ClassroomAlertFilter) ([]domain.ClassroomAlert, error)
+}
+
+type classroomRepository struct {
+	db *gorm.DB
+}
+
+func NewClassroomRepository(db *gorm.DB) ClassroomRepository {
+	return &classroomRepository{db}
+}
+
+func (r *classroomRepository) GetAll(ctx context.Context) ([]domain.Classroom, error) {
+	var classrooms []domain.Classroom
+	err := r.db.WithContext(ctx).Preload("Teachers.User").Preload("Children").Order("name").Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *classroomRepository) GetById(ctx context.Context, id uint) (*domain.Classroom, error) {
+	var classroom domain.Classroom
+	err := r.db.WithContext(ctx).Preload("Teachers.User").Preload("Children").Where("id = ?", id).First(&classroom).Error
+	return &classroom, err
+}
+
+func (r *classroomRepository) NameExists(ctx context.Context, name string, exceptId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&domain.Classroom{}).
+		Where("name = ? AND id <> ?", name, exceptId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *classroomRepository) Create(ctx context.Context, classroom *domain.Classroom) error {
+	return r.db.WithContext(ctx).Omit("Teachers", "Children").Create(classroom).Error
+}
+
+func (r *classroomRepository) Update(ctx context.Context, classroom *domain.Classroom) error {
+	return r.db.WithContext(ctx).Omit("Teachers", "Children").Save(classroom).Error
+}
+
+func (r *classroomRepository) ReplaceTeachers(ctx context.Context, classroomId uint, teachers []domain.ClassroomTeacher) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("classroom_id = ?", classroomId).Delete(&domain.ClassroomTeacher{}).Error; err != nil {
+			return err
+		}
+		if len(teachers) == 0 {
+			return nil
+		}
+		return tx.Omit("User").Create(&teachers).Error
+	})
+}
+
+func (r *classroomRepository) GetClassroomIdsByTeacher(ctx context.Context, userId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&domain.ClassroomTeacher{}).Where("user_id = ?", userId).Pluck("classroom_id", &ids).Error
+	return ids, err
+}
+
+func (r *classroomRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *classroomRepository) CountChildren(ctx context.Context, classroomId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Child{}).Where("classroom_id = ?", classroomId).Count(&count).Error
+	return count, err
+}
+
+// CountPendingOffers counts the waitlist offers for the classroom that still
+// hold a seat
+func (r *classroomRepository) CountPendingOffers(ctx context.Context, classroomId uint, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Enrollment{}).
+		Where("classroom_id = ? AND status = ? AND offer_expires_at > ?", classroomId, domain.EnrollmentOffered, now).
+		Count(&count).Error
+	return count, err
+}
+
+// HasActiveEnrollment reports whether the child is enrolled or paused
+func (r *classroomRepository) HasActiveEnrollment(ctx context.Context, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Enrollment{}).
+		Where("child_id = ? AND status IN ?", childId, []string{domain.EnrollmentEnrolled, domain.EnrollmentPaused}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *classroomRepository) SetChildClassroom(ctx context.Context, childId uint, classroomId *uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Child{}).Where("id = ?", childId).Update("classroom_id", classroomId).Error
+}
+
+func (r *classroomRepository) GetUsersWithRoles(ctx context.Context, userIds []uint) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id IN ?", userIds).Find(&users).Error
+	return users, err
+}
+
+// CountPresentChildren counts children of the classroom who arrived on day
+// and have not departed yet.
+func (r *classroomRepository) CountPresentChildren(ctx context.Context, classroomId uint, day time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.ChildAttendance{}).
+		Joins("JOIN children ON children.id = child_attendances.child_id AND children.deleted_at IS NULL").
+		Where("children.classroom_id = ? AND DATE(child_attendances.date) = ? AND child_attendances.departure IS NULL", classroomId, day.Format("2006-01-02")).
+		Count(&count).Error
+	return count, err
+}
+
+// CountPresentStaff counts roster teachers clocked in on day and not yet
+// clocked out.
+func (r *classroomRepository) CountPresentStaff(ctx context.Context, classroomId uint, day time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.ClassroomTeacher{}).
+		Where("classroom_teachers.classroom_id = ?", classroomId).
+		Where("EXISTS (?)", r.db.Model(&domain.TeacherAttendance{}).
+			Select("1").
+			Where("teacher_attendances.user_id = classroom_teachers.user_id").
+			Where("DATE(teacher_attendances.date) = ?", day.Format("2006-01-02")).
+			Where("teacher_attendances.clock_in IS NOT NULL AND teacher_attendances.clock_out IS NULL")).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *classroomRepository) GetOpenAlert(ctx context.Context, classroomId uint) (*domain.ClassroomAlert, error) {
+	var alert domain.ClassroomAlert
+	err := r.db.WithContext(ctx).Where("classroom_id = ? AND resolved_at IS NULL", classroomId).First(&alert).Error
+	return &alert, err
+}
+
+func (r *classroomRepository) CreateAlert(ctx context.Context, alert *domain.ClassroomAlert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+func (r *classroomRepository) ResolveAlert(ctx context.Context, alert *domain.ClassroomAlert) error {
+	return r.db.WithContext(ctx).Model(alert).Update("resolved_at", alert.ResolvedAt).Error
+}
+
+func (r *classroomRepository) GetAlerts(ctx context.Context, filter domain.ClassroomAlertFilter) ([]domain.ClassroomAlert, error) {
+	var alerts []domain.ClassroomAlert
+	query := r.db.WithContext(ctx).Order("created_at DESC").Limit(200)
+	switch filter.Status {
+	case "", "open":
+		query = query.Where("resolved_at IS NULL")
+	case "resolved":
+		query = query.Where("resolved_at IS NOT NULL")
+	}
+	err := query.Find(&alerts).Error
+	return alerts, err
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EnrollmentRepository interface {
+	List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.EnrollmentListFilter) ([]domain.Enrollment, int, error)
+	GetById(ctx context.Context, id uint) (*domain.Enrollment, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetClassroom(ctx context.Context, classroomId uint) (*domain.Classroom, error)
+	HasOpenEnrollment(ctx context.Context, childId uint, exceptId uint) (bool, error)
+	Create(ctx context.Context, enrollment *domain.Enrollment, event *domain.EnrollmentEvent) error
+	Update(ctx context.Context, enrollment *domain.Enrollment) error
+	SaveTransition(ctx context.Context, enrollment *domain.Enrollment, event *domain.EnrollmentEvent, placeChild bool, classroomId *uint) error
+	SaveOffers(ctx context.Context, classroomId uint, now time.Time, enrollments []*domain.Enrollment, events []*domain.EnrollmentEvent) (int, error)
+	GetWaitlist(ctx context.Context, classroomId uint) ([]domain.Enrollment, error)
+	GetExpiredOffers(ctx context.Context, classroomId uint, now time.Time) ([]domain.Enrollment, error)
+	CountSeatsTaken(ctx context.Context, classroomId uint, now time.Time) (int64, error)
+	IsEnrolledOn(ctx context.Context, childId uint, day time.Time) (bool, error)
+}
+
+type enrollmentRepository struct {
+	db *gorm.DB
+}
+
+func NewEnrollmentRepository(db *gorm.DB) EnrollmentRepository {
+	return &enrollmentRepository{db}
+}
+
+func (r *enrollmentRepository) List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.EnrollmentListFilter) ([]domain.Enrollment, int, error) {
+	var enrollments []domain.Enrollment
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Enrollment{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ClassroomID != 0 {
+		query = query.Where("classroom_id = ?", filter.ClassroomID)
+	}
+	if filter.ChildID != 0 {
+		query = query.Where("child_id = ?", filter.ChildID)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Child").Preload("Classroom").
+		Order("created_at DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&enrollments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return enrollments, totalPages, nil
+}
+
+func (r *enrollmentRepository) GetById(ctx context.Context, id uint) (*domain.Enrollment, error) {
+	var enrollment domain.Enrollment
+	err := r.db.WithContext(ctx).
+		Preload("Child").
+		Preload("Classroom").
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ?", id).
+		First(&enrollment).Error
+	return &enrollment, err
+}
+
+func (r *enrollmentRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *enrollmentRepository) GetClassroom(ctx context.Context, classroomId uint) (*domain.Classroom, error) {
+	var classroom domain.Classroom
+	err := r.db.WithContext(ctx).Where("id = ?", classroomId).First(&classroom).Error
+	return &classroom, err
+}
+
+func (r *enrollmentRepository) HasOpenEnrollment(ctx context.Context, childId uint, exceptId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Enrollment{}).
+		Where("child_id = ? AND id <> ? AND status IN ?", childId, exceptId, domain.OpenEnrollmentStatuses).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *enrollmentRepository) Create(ctx context.Context, enrollment *domain.Enrollment, event *domain.EnrollmentEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Child", "Classroom", "Events").Create(enrollment).Error; err != nil {
+			return err
+		}
+		event.EnrollmentID = enrollment.ID
+		return tx.Create(event).Error
+	})
+}
+
+func (r *enrollmentRepository) Update(ctx context.Context, enrollment *domain.Enrollment) error {
+	return r.db.WithContext(ctx).Omit("Child", "Classroom", "Events").Save(enrollment).Error
+}
+
+// SaveTransition saves the new status with its event. When placeChild is set
+// the child is moved to classroomId (nil takes it out of its classroom) in
+// the same transaction.
+func (r *enrollmentRepository) SaveTransition(ctx context.Context, enrollment *domain.Enrollment, event *domain.EnrollmentEvent, placeChild bool, classroomId *uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Child", "Classroom", "Events").Save(enrollment).Error; err != nil {
+			return err
+		}
+		event.EnrollmentID = enrollment.ID
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		if !placeChild {
+			return nil
+		}
+		return tx.Model(&domain.Child{}).Where("id = ?", enrollment.ChildID).Update("classroom_id", classroomId).Error
+	})
+}
+
+// SaveOffers saves offers of seats in the classroom with their events while
+// the classroom row is locked, so concurrent offers cannot take the same
+// seat. It returns the seats that were free, only that many of the offers
+// are saved, in order.
+func (r *enrollmentRepository) SaveOffers(ctx context.Context, classroomId uint, now time.Time, enrollments []*domain.Enrollment, events []*domain.EnrollmentEvent) (int, error) {
+	var free int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var classroom domain.Classroom
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", classroomId).First(&classroom).Error; err != nil {
+			return err
+		}
+		taken, err := countSeatsTaken(tx, classroomId, now)
+		if err != nil {
+			return err
+		}
+		free = classroom.Capacity - int(taken)
+		for i := 0; i < len(enrollments) && i < free; i++ {
+			if err := tx.Omit("Child", "Classroom", "Events").Save(enrollments[i]).Error; err != nil {
+				return err
+			}
+			events[i].EnrollmentID = enrollments[i].ID
+			if err := tx.Create(events[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return free, err
+}
+
+// GetWaitlist returns the waitlisted enrollments of a classroom in offer
+// order: highest priority first, then longest waiting.
+func (r *enrollmentRepository) GetWaitlist(ctx context.Context, classroomId uint) ([]domain.Enrollment, error) {
+	var enrollments []domain.Enrollment
+	err := r.db.WithContext(ctx).
+		Preload("Child").
+		Preload("Classroom").
+		Where("classroom_id = ? AND status = ?", classroomId, domain.EnrollmentWaitlisted).
+		Order("priority DESC, waitlisted_at ASC, id ASC").
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *enrollmentRepository) GetExpiredOffers(ctx context.Context, classroomId uint, now time.Time) ([]domain.Enrollment, error) {
+	var enrollments []domain.Enrollment
+	err := r.db.WithContext(ctx).
+		Where("classroom_id = ? AND status = ? AND offer_expires_at <= ?", classroomId, domain.EnrollmentOffered, now).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+// CountSeatsTaken counts the children placed in the classroom plus the
+// offers for it that have not expired yet.
+func (r *enrollmentRepository) CountSeatsTaken(ctx context.Context, classroomId uint, now time.Time) (int64, error) {
+	return countSeatsTaken(r.db.WithContext(ctx), classroomId, now)
+}
+
+func countSeatsTaken(tx *gorm.DB, classroomId uint, now time.Time) (int64, error) {
+	var placed, offered int64
+	if err := tx.Model(&domain.Child{}).Where("classroom_id = ?", classroomId).Count(&placed).Error; err != nil {
+		return 0, err
+	}
+	err := tx.Model(&domain.Enrollment{}).
+		Where("classroom_id = ? AND status = ? AND offer_expires_at > ?", classroomId, domain.EnrollmentOffered, now).
+		Count(&offered).Error
+	return placed + offered, err
+}
+
+// IsEnrolledOn reports whether the child is enrolled on day. A withdrawal or
+// graduation recorded ahead of time still counts until its end date.
+func (r *enrollmentRepository) IsEnrolledOn(ctx context.Context, childId uint, day time.Time) (bool, error) {
+	var count int64
+	date := day.Format("2006-01-02")
+	err := r.db.WithContext(ctx).Model(&domain.Enrollment{}).
+		Where("child_id = ? AND status IN ?", childId, []string{domain.EnrollmentEnrolled, domain.EnrollmentWithdrawn, domain.EnrollmentGraduated}).
+		Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", date, date).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type EventRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	GetParentChildIds(ctx context.Context, userId uint) ([]uint, error)
+}
+
+type eventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) EventRepository {
+	return &eventRepository{db}
+}
+
+func (r *eventRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *eventRepository) GetParentChildIds(ctx context.Context, userId uint) ([]uint, error) {
+	var childIds []uint
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ?", userId).
+		Pluck("child_id", &childIds).Error
+	return childIds, err
+}
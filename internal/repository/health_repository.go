@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	GetSchemaVersion(ctx context.Context) (uint, error)
+}
+
+type healthRepository struct {
+	db *gorm.DB
+}
+
+func NewHealthRepository(db *gorm.DB) HealthRepository {
+	return &healthRepository{db}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// GetSchemaVersion returns the highest applied schema version
+func (r *healthRepository) GetSchemaVersion(ctx context.Context) (uint, error) {
+	var version uint
+	err := r.db.WithContext(ctx).Model(&domain.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IncidentRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetAdmins(ctx context.Context) ([]domain.User, error)
+	List(ctx context.Context, filter domain.IncidentListFilter, parentId uint) ([]domain.Incident, error)
+	GetById(ctx context.Context, id uint) (*domain.Incident, error)
+	Create(ctx context.Context, incident *domain.Incident) error
+	Update(ctx context.Context, incident *domain.Incident) error
+	UpdateStatus(ctx context.Context, incident *domain.Incident) error
+}
+
+type incidentRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentRepository(db *gorm.DB) IncidentRepository {
+	return &incidentRepository{db}
+}
+
+func (r *incidentRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *incidentRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *incidentRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+// GetAdmins returns the active users with the admin role
+func (r *incidentRepository) GetAdmins(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ?", domain.RoleAdmin).
+		Find(&users).Error
+	return users, err
+}
+
+// List returns the incidents matching the filter, newest first. When
+// parentId is not zero only the reviewed incidents of the children of that
+// parent are returned.
+func (r *incidentRepository) List(ctx context.Context, filter domain.IncidentListFilter, parentId uint) ([]domain.Incident, error) {
+	var incidents []domain.Incident
+	query := r.preloadIncident(ctx)
+	if filter.ChildID != 0 {
+		query = query.Where("child_id = ?", filter.ChildID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != "" {
+		query = query.Where("occurred_at >= ?", filter.From)
+	}
+	if filter.To != "" {
+		to, _ := time.ParseInLocation("2006-01-02", filter.To, time.Local)
+		query = query.Where("occurred_at < ?", to.AddDate(0, 0, 1))
+	}
+	if parentId != 0 {
+		query = query.
+			Where("child_id IN (?)", r.db.Table("child_parents").Select("child_id").Where("user_id = ?", parentId)).
+			Where("status <> ?", domain.IncidentPendingReview)
+	}
+	err := query.Order("occurred_at DESC").Order("id DESC").Find(&incidents).Error
+	return incidents, err
+}
+
+func (r *incidentRepository) GetById(ctx context.Context, id uint) (*domain.Incident, error) {
+	var incident domain.Incident
+	err := r.preloadIncident(ctx).Where("id = ?", id).First(&incident).Error
+	return &incident, err
+}
+
+// Create saves the incident with its witnesses and photos
+func (r *incidentRepository) Create(ctx context.Context, incident *domain.Incident) error {
+	return r.db.WithContext(ctx).Omit("Child").Create(incident).Error
+}
+
+// Update saves the incident and replaces its witnesses and photos
+func (r *incidentRepository) Update(ctx context.Context, incident *domain.Incident) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(incident).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&domain.IncidentWitness{}, &domain.IncidentPhoto{}} {
+			if err := tx.Where("incident_id = ?", incident.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		for i := range incident.Witnesses {
+			incident.Witnesses[i].ID = 0
+			incident.Witnesses[i].IncidentID = incident.ID
+		}
+		for i := range incident.Photos {
+			incident.Photos[i].ID = 0
+			incident.Photos[i].IncidentID = incident.ID
+		}
+		if len(incident.Witnesses) > 0 {
+			if err := tx.Create(&incident.Witnesses).Error; err != nil {
+				return err
+			}
+		}
+		if len(incident.Photos) > 0 {
+			return tx.Create(&incident.Photos).Error
+		}
+		return nil
+	})
+}
+
+// UpdateStatus saves the incident without touching its witnesses and photos
+func (r *incidentRepository) UpdateStatus(ctx context.Context, incident *domain.Incident) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(incident).Error
+}
+
+func (r *incidentRepository) preloadIncident(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Preload("Child").
+		Preload("Witnesses").
+		Preload("Photos")
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository interface {
+	Create(ctx context.Context, job *domain.Job) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*domain.Job, error)
+	Save(ctx context.Context, job *domain.Job) error
+	List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.JobFilter) ([]domain.Job, int, error)
+	GetById(ctx context.Context, id uint) (*domain.Job, error)
+	Transition(ctx context.Context, id uint, from []string, updates map[string]any) (bool, error)
+	DeleteFinished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{db}
+}
+
+func (r *jobRepository) Create(ctx context.Context, job *domain.Job) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// Claim locks the next due job, or a running job whose lease ran out after
+// its worker died, and marks it running for the lease. It returns nil when
+// no job is due.
+func (r *jobRepository) Claim(ctx context.Context, now time.Time, lease time.Duration) (*domain.Job, error) {
+	var jobs []domain.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ?)", domain.JobPending, now, domain.JobRunning, now).
+			Order("run_at").
+			Limit(1).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		job := &jobs[0]
+		lockedUntil := now.Add(lease)
+		job.Status, job.LockedUntil, job.StartedAt = domain.JobRunning, &lockedUntil, &now
+		job.Attempts++
+		return tx.Model(job).
+			Select("status", "locked_until", "started_at", "attempts").
+			Updates(job).Error
+	})
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// Save records the outcome of a run
+func (r *jobRepository) Save(ctx context.Context, job *domain.Job) error {
+	return r.db.WithContext(ctx).Model(job).
+		Select("status", "attempts", "run_at", "locked_until", "last_error", "finished_at").
+		Updates(job).Error
+}
+
+func (r *jobRepository) List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.JobFilter) ([]domain.Job, int, error) {
+	var jobs []domain.Job
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Job{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return jobs, totalPages, nil
+}
+
+func (r *jobRepository) GetById(ctx context.Context, id uint) (*domain.Job, error) {
+	var job domain.Job
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	return &job, err
+}
+
+// Transition applies the updates when the job is still in one of the from
+// states, so it does not race with a worker claiming it. It reports whether
+// the job changed.
+func (r *jobRepository) Transition(ctx context.Context, id uint, from []string, updates map[string]any) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.Job{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteFinished removes the succeeded and cancelled jobs finished before the
+// time. Dead jobs are kept for inspection.
+func (r *jobRepository) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND finished_at < ?", []string{domain.JobSucceeded, domain.JobCancelled}, before).
+		Delete(&domain.Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MedicalRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetProfile(ctx context.Context, childId uint) (*domain.ChildMedicalProfile, error)
+	GetProfiles(ctx context.Context, classroomId uint) ([]domain.ChildMedicalProfile, error)
+	GetAllergies(ctx context.Context, childId uint) ([]domain.ChildAllergy, error)
+	SaveProfile(ctx context.Context, profile *domain.ChildMedicalProfile) error
+}
+
+type medicalRepository struct {
+	db *gorm.DB
+}
+
+func NewMedicalRepository(db *gorm.DB) MedicalRepository {
+	return &medicalRepository{db}
+}
+
+func (r *medicalRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *medicalRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *medicalRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *medicalRepository) GetProfile(ctx context.Context, childId uint) (*domain.ChildMedicalProfile, error) {
+	var profile domain.ChildMedicalProfile
+	err := r.preloadProfile(ctx).Where("child_id = ?", childId).First(&profile).Error
+	return &profile, err
+}
+
+// GetProfiles returns the profiles of the current children, of one
+// classroom when classroomId is not zero
+func (r *medicalRepository) GetProfiles(ctx context.Context, classroomId uint) ([]domain.ChildMedicalProfile, error) {
+	var profiles []domain.ChildMedicalProfile
+	query := r.preloadProfile(ctx).Preload("Child").
+		Joins("JOIN children ON children.id = child_medical_profiles.child_id AND children.deleted_at IS NULL")
+	if classroomId != 0 {
+		query = query.Where("children.classroom_id = ?", classroomId)
+	}
+	err := query.Order("children.name").Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *medicalRepository) GetAllergies(ctx context.Context, childId uint) ([]domain.ChildAllergy, error) {
+	var allergies []domain.ChildAllergy
+	err := r.db.WithContext(ctx).Where("child_id = ?", childId).Find(&allergies).Error
+	return allergies, err
+}
+
+// SaveProfile creates or updates the profile and replaces its lists
+func (r *medicalRepository) SaveProfile(ctx context.Context, profile *domain.ChildMedicalProfile) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(profile).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&domain.ChildAllergy{}, &domain.ChildMedication{}, &domain.ChildImmunization{}, &domain.ChildEmergencyContact{}} {
+			if err := tx.Where("child_id = ?", profile.ChildID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if len(profile.Allergies) > 0 {
+			if err := tx.Create(&profile.Allergies).Error; err != nil {
+				return err
+			}
+		}
+		if len(profile.Medications) > 0 {
+			if err := tx.Create(&profile.Medications).Error; err != nil {
+				return err
+			}
+		}
+		if len(profile.Immunizations) > 0 {
+			if err := tx.Create(&profile.Immunizations).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&profile.EmergencyContacts).Error
+	})
+}
+
+func (r *medicalRepository) preloadProfile(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Preload("Allergies").
+		Preload("Medications").
+		Preload("Immunizations").
+		Preload("EmergencyContacts")
+}
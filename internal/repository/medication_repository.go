@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type MedicationRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	ListRequests(ctx context.Context, childId uint, parentId uint, status string) ([]domain.MedicationRequest, error)
+	GetRequestById(ctx context.Context, id uint) (*domain.MedicationRequest, error)
+	CreateRequest(ctx context.Context, request *domain.MedicationRequest) error
+	UpdateRequest(ctx context.Context, request *domain.MedicationRequest) error
+	GetRequestsOn(ctx context.Context, day time.Time) ([]domain.MedicationRequest, error)
+	CreateAdministration(ctx context.Context, administration *domain.MedicationAdministration) error
+	GetAdministrationById(ctx context.Context, id uint) (*domain.MedicationAdministration, error)
+	UpdateAdministration(ctx context.Context, administration *domain.MedicationAdministration) error
+	GetAbsentChildIds(ctx context.Context, day time.Time) ([]uint, error)
+}
+
+type medicationRepository struct {
+	db *gorm.DB
+}
+
+func NewMedicationRepository(db *gorm.DB) MedicationRepository {
+	return &medicationRepository{db}
+}
+
+func (r *medicationRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *medicationRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *medicationRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+// ListRequests returns the requests, of one child when childId is not zero
+// and of the children of a parent when parentId is not zero
+func (r *medicationRepository) ListRequests(ctx context.Context, childId uint, parentId uint, status string) ([]domain.MedicationRequest, error) {
+	var requests []domain.MedicationRequest
+	query := r.db.WithContext(ctx).Preload("Child").Preload("Administrations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("scheduled_at")
+	})
+	if childId != 0 {
+		query = query.Where("child_id = ?", childId)
+	}
+	if parentId != 0 {
+		query = query.Where("child_id IN (?)", r.db.Table("child_parents").Select("child_id").Where("user_id = ?", parentId))
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("start_date DESC").Order("id DESC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *medicationRepository) GetRequestById(ctx context.Context, id uint) (*domain.MedicationRequest, error) {
+	var request domain.MedicationRequest
+	err := r.db.WithContext(ctx).Preload("Child").Preload("Administrations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("scheduled_at")
+	}).Where("id = ?", id).First(&request).Error
+	return &request, err
+}
+
+func (r *medicationRepository) CreateRequest(ctx context.Context, request *domain.MedicationRequest) error {
+	return r.db.WithContext(ctx).Omit("Child", "Administrations").Create(request).Error
+}
+
+func (r *medicationRepository) UpdateRequest(ctx context.Context, request *domain.MedicationRequest) error {
+	return r.db.WithContext(ctx).Omit("Child", "Administrations").Save(request).Error
+}
+
+// GetRequestsOn returns the active requests covering the day with the doses
+// recorded on that day
+func (r *medicationRepository) GetRequestsOn(ctx context.Context, day time.Time) ([]domain.MedicationRequest, error) {
+	var requests []domain.MedicationRequest
+	err := r.db.WithContext(ctx).
+		Preload("Child").
+		Preload("Administrations", "scheduled_at >= ? AND scheduled_at < ?", day, day.AddDate(0, 0, 1)).
+		Where("status = ? AND start_date <= ? AND end_date >= ?", domain.MedicationRequestActive, day.Format("2006-01-02"), day.Format("2006-01-02")).
+		Find(&requests).Error
+	return requests, err
+}
+
+// CreateAdministration records the dose on the diary of the child for that
+// day, creating the diary when the day has none yet
+func (r *medicationRepository) CreateAdministration(ctx context.Context, administration *domain.MedicationAdministration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		day := administration.ScheduledAt
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+		var diary domain.ChildDiary
+		err := tx.Where("child_id = ? AND DATE(date) = ?", administration.ChildID, day.Format("2006-01-02")).First(&diary).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			diary = domain.ChildDiary{ChildID: administration.ChildID, Date: day}
+			err = tx.Create(&diary).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		administration.DiaryID = diary.ID
+		return tx.Create(administration).Error
+	})
+}
+
+func (r *medicationRepository) GetAdministrationById(ctx context.Context, id uint) (*domain.MedicationAdministration, error) {
+	var administration domain.MedicationAdministration
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&administration).Error
+	return &administration, err
+}
+
+func (r *medicationRepository) UpdateAdministration(ctx context.Context, administration *domain.MedicationAdministration) error {
+	return r.db.WithContext(ctx).Save(administration).Error
+}
+
+func (r *medicationRepository) GetAbsentChildIds(ctx context.Context, day time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&domain.ChildAbsence{}).
+		Where("date = ?", day.Format("2006-01-02")).
+		Pluck("child_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// visibleThreads matches the threads a user takes part in: announcements to
+// the center, the threads of the children they are a parent or teacher of,
+// and the announcements to the classrooms of those children or on whose
+// roster they are
+const visibleThreads = `(message_threads.kind = 'center'
+	OR (message_threads.kind = 'child' AND message_threads.child_id IN (
+		SELECT child_id FROM child_parents WHERE user_id = @user
+		UNION SELECT child_id FROM child_teachers WHERE user_id = @user))
+	OR (message_threads.kind = 'classroom' AND (
+		message_threads.classroom_id IN (SELECT classroom_id FROM classroom_teachers WHERE user_id = @user)
+		OR message_threads.classroom_id IN (
+			SELECT children.classroom_id FROM children WHERE children.deleted_at IS NULL AND children.id IN (
+				SELECT child_id FROM child_parents WHERE user_id = @user
+				UNION SELECT child_id FROM child_teachers WHERE user_id = @user)))))`
+
+// unreadMessages matches the messages of others after the read position of
+// the user
+const unreadMessages = `messages.sender_id <> @user AND messages.id > COALESCE((
+	SELECT message_reads.last_read_message_id FROM message_reads
+	WHERE message_reads.thread_id = messages.thread_id AND message_reads.user_id = @user), 0)`
+
+type MessageRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetClassroom(ctx context.Context, classroomId uint) (*domain.Classroom, error)
+	GetClassroomChildIds(ctx context.Context, classroomId uint) ([]uint, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	IsChildTeacher(ctx context.Context, userId uint, childId uint) (bool, error)
+	IsClassroomTeacher(ctx context.Context, userId uint, classroomId uint) (bool, error)
+	IsThreadVisible(ctx context.Context, threadId uint, userId uint) (bool, error)
+	ListThreads(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.MessageThreadFilter, userId uint, all bool) ([]domain.MessageThread, int, error)
+	GetThreadById(ctx context.Context, id uint) (*domain.MessageThread, error)
+	CreateThread(ctx context.Context, thread *domain.MessageThread, message *domain.Message) error
+	CreateMessage(ctx context.Context, message *domain.Message) error
+	DiscardMessage(ctx context.Context, message *domain.Message) error
+	ListMessages(ctx context.Context, paginationFilter types.PaginationFilter, threadId uint) ([]domain.Message, int, error)
+	GetLatestMessageId(ctx context.Context, threadId uint) (uint, error)
+	GetMessageById(ctx context.Context, id uint) (*domain.Message, error)
+	GetReads(ctx context.Context, threadId uint) ([]domain.MessageRead, error)
+	SaveRead(ctx context.Context, read *domain.MessageRead) error
+	CountUnread(ctx context.Context, userId uint, threadIds []uint) (map[uint]int64, error)
+	CountAllUnread(ctx context.Context, userId uint, all bool) (int64, int64, error)
+}
+
+type messageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) MessageRepository {
+	return &messageRepository{db}
+}
+
+func (r *messageRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *messageRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *messageRepository) GetClassroom(ctx context.Context, classroomId uint) (*domain.Classroom, error) {
+	var classroom domain.Classroom
+	err := r.db.WithContext(ctx).Where("id = ?", classroomId).First(&classroom).Error
+	return &classroom, err
+}
+
+func (r *messageRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *messageRepository) IsChildTeacher(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_teachers").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *messageRepository) IsClassroomTeacher(ctx context.Context, userId uint, classroomId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.ClassroomTeacher{}).
+		Where("user_id = ? AND classroom_id = ?", userId, classroomId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *messageRepository) IsThreadVisible(ctx context.Context, threadId uint, userId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.MessageThread{}).
+		Where("id = ?", threadId).
+		Where(visibleThreads, sql.Named("user", userId)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListThreads returns the threads matching the filter, the latest activity
+// first. Unless all is set only the threads the user takes part in are
+// returned.
+func (r *messageRepository) ListThreads(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.MessageThreadFilter, userId uint, all bool) ([]domain.MessageThread, int, error) {
+	var threads []domain.MessageThread
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.MessageThread{})
+	if !all {
+		query = query.Where(visibleThreads, sql.Named("user", userId))
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.ChildID != 0 {
+		query = query.Where("child_id = ?", filter.ChildID)
+	}
+	if filter.ClassroomID != 0 {
+		query = query.Where("classroom_id = ?", filter.ClassroomID)
+	}
+	if filter.Unread {
+		query = query.Where("EXISTS (SELECT 1 FROM messages WHERE messages.thread_id = message_threads.id AND "+unreadMessages+")", sql.Named("user", userId))
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Child").Preload("Classroom").
+		Order("last_message_at DESC").
+		Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&threads).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return threads, totalPages, nil
+}
+
+func (r *messageRepository) GetThreadById(ctx context.Context, id uint) (*domain.MessageThread, error) {
+	var thread domain.MessageThread
+	err := r.db.WithContext(ctx).Preload("Child").Preload("Classroom").Where("id = ?", id).First(&thread).Error
+	return &thread, err
+}
+
+// CreateThread saves the thread with its first message, which counts as read
+// by its sender
+func (r *messageRepository) CreateThread(ctx context.Context, thread *domain.MessageThread, message *domain.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(thread).Error; err != nil {
+			return err
+		}
+		message.ThreadID = thread.ID
+		return r.createMessage(tx, message)
+	})
+}
+
+// CreateMessage saves the message, moves the thread up and marks the message
+// read by its sender
+func (r *messageRepository) CreateMessage(ctx context.Context, message *domain.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.createMessage(tx, message)
+	})
+}
+
+func (r *messageRepository) createMessage(tx *gorm.DB, message *domain.Message) error {
+	if err := tx.Omit(clause.Associations).Create(message).Error; err != nil {
+		return err
+	}
+	err := tx.Model(&domain.MessageThread{}).
+		Where("id = ?", message.ThreadID).
+		Update("last_message_at", message.CreatedAt).Error
+	if err != nil {
+		return err
+	}
+	return saveRead(tx, &domain.MessageRead{
+		ThreadID:          message.ThreadID,
+		UserID:            message.SenderID,
+		LastReadMessageID: message.ID,
+		ReadAt:            message.CreatedAt,
+	})
+}
+
+// DiscardMessage removes a message whose files could not be stored, before
+// it was handed out. Sent messages are never deleted.
+func (r *messageRepository) DiscardMessage(ctx context.Context, message *domain.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner_type = ? AND owner_id = ?", domain.AttachmentMessage, message.ID).Delete(&domain.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Delete(message).Error
+	})
+}
+
+// ListMessages returns the messages of the thread, newest first
+func (r *messageRepository) ListMessages(ctx context.Context, paginationFilter types.PaginationFilter, threadId uint) ([]domain.Message, int, error) {
+	var messages []domain.Message
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Message{}).Where("thread_id = ?", threadId)
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Sender").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return messages, totalPages, nil
+}
+
+func (r *messageRepository) GetLatestMessageId(ctx context.Context, threadId uint) (uint, error) {
+	var id uint
+	err := r.db.WithContext(ctx).Model(&domain.Message{}).
+		Where("thread_id = ?", threadId).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+func (r *messageRepository) GetMessageById(ctx context.Context, id uint) (*domain.Message, error) {
+	var message domain.Message
+	err := r.db.WithContext(ctx).Preload("Sender").Preload("Attachments").Where("id = ?", id).First(&message).Error
+	return &message, err
+}
+
+func (r *messageRepository) GetReads(ctx context.Context, threadId uint) ([]domain.MessageRead, error) {
+	var reads []domain.MessageRead
+	err := r.db.WithContext(ctx).Preload("User").Where("thread_id = ?", threadId).Find(&reads).Error
+	return reads, err
+}
+
+func (r *messageRepository) SaveRead(ctx context.Context, read *domain.MessageRead) error {
+	return saveRead(r.db.WithContext(ctx), read)
+}
+
+// saveRead creates the read position or moves it forward, never back
+func saveRead(tx *gorm.DB, read *domain.MessageRead) error {
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "read_at"}, Value: gorm.Expr("IF(VALUES(last_read_message_id) > last_read_message_id, VALUES(read_at), read_at)")},
+			{Column: clause.Column{Name: "last_read_message_id"}, Value: gorm.Expr("GREATEST(last_read_message_id, VALUES(last_read_message_id))")},
+		},
+	}).Omit(clause.Associations).Create(read).Error
+}
+
+// CountUnread returns the number of unread messages per thread, threads
+// without any are left out
+func (r *messageRepository) CountUnread(ctx context.Context, userId uint, threadIds []uint) (map[uint]int64, error) {
+	var rows []struct {
+		ThreadID uint
+		Unread   int64
+	}
+	err := r.db.WithContext(ctx).Model(&domain.Message{}).
+		Select("messages.thread_id, COUNT(*) AS unread").
+		Where("messages.thread_id IN ?", threadIds).
+		Where(unreadMessages, sql.Named("user", userId)).
+		Group("messages.thread_id").
+		Scan(&rows).Error
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ThreadID] = row.Unread
+	}
+	return counts, err
+}
+
+// CountAllUnread returns the number of threads with unread messages and the
+// number of unread messages of the user
+func (r *messageRepository) CountAllUnread(ctx context.Context, userId uint, all bool) (int64, int64, error) {
+	var totals struct {
+		Threads  int64
+		Messages int64
+	}
+	query := r.db.WithContext(ctx).Model(&domain.Message{}).
+		Select("COUNT(DISTINCT messages.thread_id) AS threads, COUNT(*) AS messages").
+		Joins("JOIN message_threads ON message_threads.id = messages.thread_id").
+		Where(unreadMessages, sql.Named("user", userId))
+	if !all {
+		query = query.Where(visibleThreads, sql.Named("user", userId))
+	}
+	err := query.Scan(&totals).Error
+	return totals.Threads, totals.Messages, err
+}
+
+func (r *messageRepository) GetClassroomChildIds(ctx context.Context, classroomId uint) ([]uint, error) {
+	var childIds []uint
+	err := r.db.WithContext(ctx).Model(&domain.Child{}).
+		Where("classroom_id = ?", classroomId).
+		Pluck("id", &childIds).Error
+	return childIds, err
+}
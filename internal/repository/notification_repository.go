@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository interface {
+	GetUsers(ctx context.Context, userIds []uint) ([]domain.User, error)
+	GetChildWithParents(ctx context.Context, childId uint) (*domain.Child, error)
+	GetPreferences(ctx context.Context, userIds []uint, notificationType string) ([]domain.NotificationPreference, error)
+	GetUserPreferences(ctx context.Context, userId uint) ([]domain.NotificationPreference, error)
+	GetSettings(ctx context.Context, userIds []uint) ([]domain.NotificationSettings, error)
+	SaveSettings(ctx context.Context, settings *domain.NotificationSettings, preferences []domain.NotificationPreference) error
+	HasKey(ctx context.Context, userId uint, key string) (bool, error)
+	Create(ctx context.Context, notification *domain.Notification) error
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.NotificationDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *domain.NotificationDelivery) error
+	MarkInbox(ctx context.Context, notificationId uint, at time.Time) error
+	ListInbox(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.NotificationInboxFilter, userId uint) ([]domain.Notification, int, error)
+	GetInboxById(ctx context.Context, id uint) (*domain.Notification, error)
+	CountUnread(ctx context.Context, userId uint) (int64, error)
+	MarkRead(ctx context.Context, userId uint, id uint, at time.Time) error
+	ListDevices(ctx context.Context, userId uint) ([]domain.PushDevice, error)
+	GetDevice(ctx context.Context, id uint) (*domain.PushDevice, error)
+	SaveDevice(ctx context.Context, device *domain.PushDevice) error
+	DeleteDevice(ctx context.Context, id uint) error
+	GetOpenTeacherAttendances(ctx context.Context, since time.Time) ([]domain.TeacherAttendance, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db}
+}
+
+func (r *notificationRepository) GetUsers(ctx context.Context, userIds []uint) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).Where("id IN ?", userIds).Find(&users).Error
+	return users, err
+}
+
+func (r *notificationRepository) GetChildWithParents(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Preload("Parents").Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *notificationRepository) GetPreferences(ctx context.Context, userIds []uint, notificationType string) ([]domain.NotificationPreference, error) {
+	var preferences []domain.NotificationPreference
+	err := r.db.WithContext(ctx).
+		Where("user_id IN ? AND type = ?", userIds, notificationType).
+		Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *notificationRepository) GetUserPreferences(ctx context.Context, userId uint) ([]domain.NotificationPreference, error) {
+	var preferences []domain.NotificationPreference
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *notificationRepository) GetSettings(ctx context.Context, userIds []uint) ([]domain.NotificationSettings, error) {
+	var settings []domain.NotificationSettings
+	err := r.db.WithContext(ctx).Where("user_id IN ?", userIds).Find(&settings).Error
+	return settings, err
+}
+
+func (r *notificationRepository) SaveSettings(ctx context.Context, settings *domain.NotificationSettings, preferences []domain.NotificationPreference) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"quiet_start", "quiet_end", "updated_at"}),
+		}).Create(settings).Error
+		if err != nil || len(preferences) == 0 {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"email", "push", "in_app", "updated_at"}),
+		}).Create(&preferences).Error
+	})
+}
+
+func (r *notificationRepository) HasKey(ctx context.Context, userId uint, key string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Notification{}).
+		Where("user_id = ? AND dedupe_key = ?", userId, key).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Create stores the notification with its deliveries in one transaction
+func (r *notificationRepository) Create(ctx context.Context, notification *domain.Notification) error {
+	return r.db.WithContext(ctx).Omit("User").Create(notification).Error
+}
+
+// ClaimDue locks the pending deliveries that are due and pushes them back by
+// the lease, so no other instance picks them up while they are sent. A
+// delivery left by a crash is sent again once the lease runs out.
+func (r *notificationRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.NotificationDelivery, error) {
+	var deliveries []domain.NotificationDelivery
+	var ids []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&domain.NotificationDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	// The notifications are loaded outside the lock
+	deliveries = nil
+	err = r.db.WithContext(ctx).Preload("Notification.User").Where("id IN ?", ids).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *notificationRepository) SaveDelivery(ctx context.Context, delivery *domain.NotificationDelivery) error {
+	return r.db.WithContext(ctx).Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "last_error", "sent_at").
+		Updates(delivery).Error
+}
+
+func (r *notificationRepository) MarkInbox(ctx context.Context, notificationId uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.Notification{}).
+		Where("id = ? AND inbox_at IS NULL", notificationId).
+		Update("inbox_at", at).Error
+}
+
+func (r *notificationRepository) ListInbox(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.NotificationInboxFilter, userId uint) ([]domain.Notification, int, error) {
+	var notifications []domain.Notification
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Notification{}).
+		Where("user_id = ? AND inbox_at IS NOT NULL", userId)
+	if filter.Unread {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("inbox_at DESC").
+		Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return notifications, totalPages, nil
+}
+
+func (r *notificationRepository) GetInboxById(ctx context.Context, id uint) (*domain.Notification, error) {
+	var notification domain.Notification
+	err := r.db.WithContext(ctx).Where("id = ? AND inbox_at IS NOT NULL", id).First(&notification).Error
+	return &notification, err
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Notification{}).
+		Where("user_id = ? AND inbox_at IS NOT NULL AND read_at IS NULL", userId).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one notification of the user as read, or all of them when
+// id is zero
+func (r *notificationRepository) MarkRead(ctx context.Context, userId uint, id uint, at time.Time) error {
+	query := r.db.WithContext(ctx).Model(&domain.Notification{}).
+		Where("user_id = ? AND inbox_at IS NOT NULL AND read_at IS NULL", userId)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	}
+	return query.Update("read_at", at).Error
+}
+
+func (r *notificationRepository) ListDevices(ctx context.Context, userId uint) ([]domain.PushDevice, error) {
+	var devices []domain.PushDevice
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("last_seen_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+func (r *notificationRepository) GetDevice(ctx context.Context, id uint) (*domain.PushDevice, error) {
+	var device domain.PushDevice
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&device).Error
+	return &device, err
+}
+
+// SaveDevice registers the token, moving it to the user when another user
+// registered it before on the same device
+func (r *notificationRepository) SaveDevice(ctx context.Context, device *domain.PushDevice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "last_seen_at"}),
+		}).Create(device).Error
+		if err != nil {
+			return err
+		}
+		// The upsert does not report the id of an existing row
+		return tx.Where("token = ?", device.Token).First(device).Error
+	})
+}
+
+func (r *notificationRepository) DeleteDevice(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.PushDevice{}, id).Error
+}
+
+// GetOpenTeacherAttendances returns the clock-ins since the day that have no
+// clock-out yet
+func (r *notificationRepository) GetOpenTeacherAttendances(ctx context.Context, since time.Time) ([]domain.TeacherAttendance, error) {
+	var attendances []domain.TeacherAttendance
+	err := r.db.WithContext(ctx).
+		Where("date >= ? AND clock_in IS NOT NULL AND clock_out IS NULL", since).
+		Order("date").
+		Find(&attendances).Error
+	return attendances, err
+}
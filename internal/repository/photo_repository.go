@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PhotoRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+	GetParentChildIds(ctx context.Context, userId uint) ([]uint, error)
+	GetChild(ctx context.Context, childId uint) (*domain.Child, error)
+	GetChildren(ctx context.Context, childIds []uint) ([]domain.Child, error)
+	GetConsent(ctx context.Context, childId uint) (*domain.PhotoConsent, error)
+	SaveConsent(ctx context.Context, consent *domain.PhotoConsent) error
+	GetConsentedChildIds(ctx context.Context, childIds []uint) ([]uint, error)
+	UntagChild(ctx context.Context, childId uint) (int64, error)
+	ListPosts(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.PhotoFeedFilter, parentId uint) ([]domain.PhotoPost, int, error)
+	GetPostById(ctx context.Context, id uint) (*domain.PhotoPost, error)
+	CreatePost(ctx context.Context, post *domain.PhotoPost) error
+	UpdatePost(ctx context.Context, post *domain.PhotoPost) error
+	PublishPost(ctx context.Context, post *domain.PhotoPost) error
+	DeletePost(ctx context.Context, post *domain.PhotoPost) error
+	SaveReaction(ctx context.Context, reaction *domain.PhotoReaction) error
+	DeleteReaction(ctx context.Context, postId uint, userId uint) error
+	CreateComment(ctx context.Context, comment *domain.PhotoComment) error
+	GetCommentById(ctx context.Context, id uint) (*domain.PhotoComment, error)
+	DeleteComment(ctx context.Context, comment *domain.PhotoComment) error
+	GetChildPhotos(ctx context.Context, childId uint, from time.Time, to time.Time) ([]domain.Attachment, error)
+}
+
+type photoRepository struct {
+	db *gorm.DB
+}
+
+func NewPhotoRepository(db *gorm.DB) PhotoRepository {
+	return &photoRepository{db}
+}
+
+func (r *photoRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
+	return user, err
+}
+
+func (r *photoRepository) IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ? AND child_id = ?", userId, childId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *photoRepository) GetParentChildIds(ctx context.Context, userId uint) ([]uint, error) {
+	var childIds []uint
+	err := r.db.WithContext(ctx).Table("child_parents").
+		Where("user_id = ?", userId).
+		Pluck("child_id", &childIds).Error
+	return childIds, err
+}
+
+func (r *photoRepository) GetChild(ctx context.Context, childId uint) (*domain.Child, error) {
+	var child domain.Child
+	err := r.db.WithContext(ctx).Where("id = ?", childId).First(&child).Error
+	return &child, err
+}
+
+func (r *photoRepository) GetChildren(ctx context.Context, childIds []uint) ([]domain.Child, error) {
+	var children []domain.Child
+	err := r.db.WithContext(ctx).Where("id IN ?", childIds).Find(&children).Error
+	return children, err
+}
+
+func (r *photoRepository) GetConsent(ctx context.Context, childId uint) (*domain.PhotoConsent, error) {
+	var consent domain.PhotoConsent
+	err := r.db.WithContext(ctx).Where("child_id = ?", childId).First(&consent).Error
+	return &consent, err
+}
+
+// SaveConsent creates or replaces the consent of the child
+func (r *photoRepository) SaveConsent(ctx context.Context, consent *domain.PhotoConsent) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "child_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"granted", "updated_by", "updated_at"}),
+	}).Create(consent).Error
+}
+
+// GetConsentedChildIds returns the given children having a granted consent
+func (r *photoRepository) GetConsentedChildIds(ctx context.Context, childIds []uint) ([]uint, error) {
+	var consented []uint
+	err := r.db.WithContext(ctx).Model(&domain.PhotoConsent{}).
+		Where("child_id IN ? AND granted = ?", childIds, true).
+		Pluck("child_id", &consented).Error
+	return consented, err
+}
+
+// UntagChild removes the child from every post and returns how many posts
+// tagged it
+func (r *photoRepository) UntagChild(ctx context.Context, childId uint) (int64, error) {
+	result := r.db.WithContext(ctx).Exec("DELETE FROM photo_post_children WHERE child_id = ?", childId)
+	return result.RowsAffected, result.Error
+}
+
+// ListPosts returns the published posts matching the filter, or the drafts
+// when asked, newest first. When parentId is not zero only the published
+// posts tagging a child of that parent are returned.
+func (r *photoRepository) ListPosts(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.PhotoFeedFilter, parentId uint) ([]domain.PhotoPost, int, error) {
+	var posts []domain.PhotoPost
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.PhotoPost{})
+	dateColumn := "published_at"
+	if filter.Drafts && parentId == 0 {
+		query = query.Where("published_at IS NULL")
+		dateColumn = "created_at"
+	} else {
+		query = query.Where("published_at IS NOT NULL")
+	}
+	if filter.ChildID != 0 {
+		query = query.Where("id IN (?)", r.db.Table("photo_post_children").
+			Select("photo_post_id").
+			Where("child_id = ?", filter.ChildID))
+	}
+	if parentId != 0 {
+		query = query.Where("id IN (?)", r.db.Table("photo_post_children").
+			Select("photo_post_children.photo_post_id").
+			Joins("JOIN child_parents ON child_parents.child_id = photo_post_children.child_id").
+			Where("child_parents.user_id = ?", parentId))
+	}
+	if filter.From != "" {
+		query = query.Where("DATE("+dateColumn+") >= ?", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("DATE("+dateColumn+") <= ?", filter.To)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.preloadPost(query).
+		Order(dateColumn + " DESC").
+		Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return posts, totalPages, nil
+}
+
+func (r *photoRepository) GetPostById(ctx context.Context, id uint) (*domain.PhotoPost, error) {
+	var post domain.PhotoPost
+	err := r.preloadPost(r.db.WithContext(ctx)).Where("id = ?", id).First(&post).Error
+	return &post, err
+}
+
+// CreatePost saves the post and its tags
+func (r *photoRepository) CreatePost(ctx context.Context, post *domain.PhotoPost) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(post).Error; err != nil {
+			return err
+		}
+		return tx.Model(post).Association("Children").Replace(post.Children)
+	})
+}
+
+// UpdatePost saves the caption and replaces the tags of the post
+func (r *photoRepository) UpdatePost(ctx context.Context, post *domain.PhotoPost) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(post).Error; err != nil {
+			return err
+		}
+		return tx.Model(post).Association("Children").Replace(post.Children)
+	})
+}
+
+func (r *photoRepository) PublishPost(ctx context.Context, post *domain.PhotoPost) error {
+	return r.db.WithContext(ctx).Model(post).Update("published_at", post.PublishedAt).Error
+}
+
+// DeletePost removes the post with its tags, reactions, comments and photo
+// rows. The stored files are left to the caller.
+func (r *photoRepository) DeletePost(ctx context.Context, post *domain.PhotoPost) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).Association("Children").Clear(); err != nil {
+			return err
+		}
+		for _, model := range []any{&domain.PhotoReaction{}, &domain.PhotoComment{}} {
+			if err := tx.Unscoped().Where("post_id = ?", post.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("owner_type = ? AND owner_id = ?", domain.AttachmentPost, post.ID).Delete(&domain.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Delete(post).Error
+	})
+}
+
+// SaveReaction creates the reaction of the user or changes its kind
+func (r *photoRepository) SaveReaction(ctx context.Context, reaction *domain.PhotoReaction) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "updated_at"}),
+	}).Create(reaction).Error
+}
+
+func (r *photoRepository) DeleteReaction(ctx context.Context, postId uint, userId uint) error {
+	return r.db.WithContext(ctx).
+		Where("post_id = ? AND user_id = ?", postId, userId).
+		Delete(&domain.PhotoReaction{}).Error
+}
+
+func (r *photoRepository) CreateComment(ctx context.Context, comment *domain.PhotoComment) error {
+	return r.db.WithContext(ctx).Omit("User").Create(comment).Error
+}
+
+func (r *photoRepository) GetCommentById(ctx context.Context, id uint) (*domain.PhotoComment, error) {
+	var comment domain.PhotoComment
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&comment).Error
+	return &comment, err
+}
+
+func (r *photoRepository) DeleteComment(ctx context.Context, comment *domain.PhotoComment) error {
+	return r.db.WithContext(ctx).Delete(comment).Error
+}
+
+// GetChildPhotos returns the photos of the published posts tagging the child
+// between the two days, oldest first
+func (r *photoRepository) GetChildPhotos(ctx context.Context, childId uint, from time.Time, to time.Time) ([]domain.Attachment, error) {
+	var attachments []domain.Attachment
+	err := r.db.WithContext(ctx).
+		Joins("JOIN photo_posts ON photo_posts.id = attachments.owner_id").
+		Joins("JOIN photo_post_children ON photo_post_children.photo_post_id = photo_posts.id").
+		Where("attachments.owner_type = ?", domain.AttachmentPost).
+		Where("photo_post_children.child_id = ?", childId).
+		Where("photo_posts.published_at >= ? AND photo_posts.published_at < ?", from, to.AddDate(0, 0, 1)).
+		Order("photo_posts.published_at").
+		Order("attachments.id").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *photoRepository) preloadPost(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Author").
+		Preload("Children").
+		Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Reactions").
+		Preload("Comments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Comments.User.Roles")
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]domain.Role, error)
+	GetById(ctx context.Context, id uint) (*domain.Role, error)
+	GetByIds(ctx context.Context, ids []uint) ([]domain.Role, error)
+	NameExists(ctx context.Context, name string, exceptId uint) (bool, error)
+	Create(ctx context.Context, role *domain.Role) error
+	Update(ctx context.Context, role *domain.Role) error
+	Delete(ctx context.Context, role *domain.Role) error
+	CountUsage(ctx context.Context, id uint) (int64, error)
+	ReplacePermissions(ctx context.Context, role *domain.Role, permissions []domain.Permission) error
+	GetAllPermissions(ctx context.Context) ([]domain.Permission, error)
+	GetPermissionsByIds(ctx context.Context, ids []uint) ([]domain.Permission, error)
+	PermissionNameExists(ctx context.Context, name string) (bool, error)
+	CreatePermission(ctx context.Context, permission *domain.Permission) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db}
+}
+
+func (r *roleRepository) GetAll(ctx context.Context) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) GetById(ctx context.Context, id uint) (*domain.Role, error) {
+	var role domain.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").Where("id = ?", id).First(&role).Error
+	return &role, err
+}
+
+func (r *roleRepository) GetByIds(ctx context.Context, ids []uint) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) NameExists(ctx context.Context, name string, exceptId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&domain.Role{}).
+		Where("name = ? AND id <> ?", name, exceptId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *domain.Role) error {
+	return r.db.WithContext(ctx).Omit("Permissions").Create(role).Error
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *domain.Role) error {
+	return r.db.WithContext(ctx).Model(role).Update("name", role.Name).Error
+}
+
+// Delete removes the role and its permission links for good
+func (r *roleRepository) Delete(ctx context.Context, role *domain.Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(role).Error
+	})
+}
+
+// CountUsage counts the users and pending invitations holding the role
+func (r *roleRepository) CountUsage(ctx context.Context, id uint) (int64, error) {
+	var users, invitations int64
+	db := r.db.WithContext(ctx)
+	if err := db.Table("user_roles").Where("role_id = ?", id).Count(&users).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Table("registered_email_roles").Where("role_id = ?", id).Count(&invitations).Error; err != nil {
+		return 0, err
+	}
+	return users + invitations, nil
+}
+
+func (r *roleRepository) ReplacePermissions(ctx context.Context, role *domain.Role, permissions []domain.Permission) error {
+	return r.db.WithContext(ctx).Model(role).Association("Permissions").Replace(permissions)
+}
+
+func (r *roleRepository) GetAllPermissions(ctx context.Context) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	err := r.db.WithContext(ctx).Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) GetPermissionsByIds(ctx context.Context, ids []uint) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	if len(ids) == 0 {
+		return permissions, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) PermissionNameExists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Permission{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roleRepository) CreatePermission(ctx context.Context, permission *domain.Permission) error {
+	return r.db.WithContext(ctx).Create(permission).Error
+}
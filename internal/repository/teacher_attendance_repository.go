@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/pkg/types"
 	"github.com/whyaji/daycare-preschool-api/pkg/utils"
@@ -8,12 +10,12 @@ import (
 )
 
 type TeacherAttendanceRepository interface {
-	Create(teacherAttendance *domain.TeacherAttendance) error
-	GetUserWithRoles(userId uint) (domain.User, error)
-	GetLastTeacherAttendanceByUserId(userId uint) (domain.TeacherAttendance, error)
-	UpdateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error
-	GetAllWorkLocation() ([]domain.WorkLocation, error)
-	GetTeacherAttendanceByUserId(userId uint, pagingationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error)
+	Create(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	GetLastTeacherAttendanceByUserId(ctx context.Context, userId uint) (domain.TeacherAttendance, error)
+	UpdateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance, events ...domain.OutboxEvent) error
+	GetAllWorkLocation(ctx context.Context) ([]domain.WorkLocation, error)
+	GetTeacherAttendanceByUserId(ctx context.Context, userId uint, pagingationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error)
 }
 
 type teacherAttendanceRepository struct {
@@ -24,29 +26,29 @@ func NewTeacherAttendanceRepository(db *gorm.DB) TeacherAttendanceRepository {
 	return &teacherAttendanceRepository{db}
 }
 
-func (r *teacherAttendanceRepository) Create(teacherAttendance *domain.TeacherAttendance) error {
-	return r.db.Create(teacherAttendance).Error
+func (r *teacherAttendanceRepository) Create(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error {
+	return r.db.WithContext(ctx).Create(teacherAttendance).Error
 }
 
-func (r *teacherAttendanceRepository) GetUserWithRoles(userId uint) (domain.User, error) {
+func (r *teacherAttendanceRepository) GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error) {
 	var user domain.User
-	err := r.db.Preload("Roles").Where("id = ?", userId).First(&user).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", userId).First(&user).Error
 	return user, err
 }
 
-func (r *teacherAttendanceRepository) GetLastTeacherAttendanceByUserId(userId uint) (domain.TeacherAttendance, error) {
+func (r *teacherAttendanceRepository) GetLastTeacherAttendanceByUserId(ctx context.Context, userId uint) (domain.TeacherAttendance, error) {
 	var teacherAttendance domain.TeacherAttendance
-	err := r.db.Where("user_id = ?", userId).Order("created_at desc").First(&teacherAttendance).Error
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at desc").First(&teacherAttendance).Error
 	return teacherAttendance, err
 }
 
 // get pagination teacher attendance by user id
-func (r *teacherAttendanceRepository) GetTeacherAttendanceByUserId(userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error) {
+func (r *teacherAttendanceRepository) GetTeacherAttendanceByUserId(ctx context.Context, userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error) {
 	var teacherAttendances []domain.TeacherAttendance
 	var totalRecords int64
 
 	// Start query with base condition
-	query := r.db.Model(&domain.TeacherAttendance{}).Where("user_id = ?", userId)
+	query := r.db.WithContext(ctx).Model(&domain.TeacherAttendance{}).Where("user_id = ?", userId)
 
 	// Apply year and month filter based on column date
 	query = utils.ApplyYearMonthFilter(query, paginationFilter.Filters, "date")
@@ -78,13 +80,20 @@ func (r *teacherAttendanceRepository) GetTeacherAttendanceByUserId(userId uint,
 	return teacherAttendances, totalPages, nil
 }
 
-func (r *teacherAttendanceRepository) UpdateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error {
-	return r.db.Save(teacherAttendance).Error
+// UpdateTeacherAttendance saves the attendance together with the outbox
+// events describing the change
+func (r *teacherAttendanceRepository) UpdateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance, events ...domain.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(teacherAttendance).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, events)
+	})
 }
 
 // GetAllWorkLocation gets all work location
-func (r *teacherAttendanceRepository) GetAllWorkLocation() ([]domain.WorkLocation, error) {
+func (r *teacherAttendanceRepository) GetAllWorkLocation(ctx context.Context) ([]domain.WorkLocation, error) {
 	var workLocations []domain.WorkLocation
-	err := r.db.Find(&workLocations).Error
+	err := r.db.WithContext(ctx).Find(&workLocations).Error
 	return workLocations, err
 }
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	GetByUserId(ctx context.Context, userId uint) (*domain.UserTOTP, error)
+	Save(ctx context.Context, totp *domain.UserTOTP) error
+	Confirm(ctx context.Context, totp *domain.UserTOTP, codeHashes []string) error
+	Delete(ctx context.Context, userId uint) error
+	UseStep(ctx context.Context, userId uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId uint, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userId uint) (int64, error)
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db}
+}
+
+func (r *twoFactorRepository) GetByUserId(ctx context.Context, userId uint) (*domain.UserTOTP, error) {
+	var totp domain.UserTOTP
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).First(&totp).Error
+	return &totp, err
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, totp *domain.UserTOTP) error {
+	return r.db.WithContext(ctx).Save(totp).Error
+}
+
+// Confirm marks the secret as confirmed and stores the first recovery codes
+// in one transaction.
+func (r *twoFactorRepository) Confirm(ctx context.Context, totp *domain.UserTOTP, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(totp).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, totp.UserID, codeHashes)
+	})
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userId uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&domain.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&domain.UserTOTP{}).Error
+	})
+}
+
+// UseStep records step as the last used one. It only succeeds when step is
+// newer than the stored one, so a code cannot be replayed, even concurrently.
+func (r *twoFactorRepository) UseStep(ctx context.Context, userId uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId uint, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count).Error
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&domain.UserRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]domain.UserRecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = domain.UserRecoveryCode{UserID: userId, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
@@ -1,19 +1,42 @@
 package repository
 
 import (
+	"context"
+	"strings"
+
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
-	GetByEmail(email string) (*domain.User, error)
-	GetById(id uint) (*domain.User, error)
-	GetByIdWithRoles(id uint) (*domain.User, error)
-	Create(user *domain.User) error
-	CreateRegisteredEmail(registeredEmail *domain.RegisteredEmail) error
-	UpdateRegisteredEmail(registeredEmail *domain.RegisteredEmail) error
-	GetRegisteredByEmail(email string) (*domain.RegisteredEmail, error)
-	GetAllRoles() ([]domain.Role, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetById(ctx context.Context, id uint) (*domain.User, error)
+	GetByIdWithRoles(ctx context.Context, id uint) (*domain.User, error)
+	Create(ctx context.Context, user *domain.User) error
+	CreateRegisteredEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error
+	UpdateRegisteredEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error
+	GetRegisteredByEmail(ctx context.Context, email string) (*domain.RegisteredEmail, error)
+	GetAllRoles(ctx context.Context) ([]domain.Role, error)
+	GetByIdIncludingDeactivated(ctx context.Context, id uint) (*domain.User, error)
+	GetByEmailVerificationHash(ctx context.Context, hash string) (*domain.User, error)
+	EmailInUse(ctx context.Context, email string, exceptId uint) (bool, error)
+	Update(ctx context.Context, user *domain.User) error
+	ReplaceRoles(ctx context.Context, user *domain.User, roles []domain.Role) error
+	Deactivate(ctx context.Context, id uint) error
+	Reactivate(ctx context.Context, id uint) error
+	List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.UserListFilter) ([]domain.User, int, error)
+	GetRegisteredEmailById(ctx context.Context, id uint) (*domain.RegisteredEmail, error)
+	ListPendingRegisteredEmails(ctx context.Context, paginationFilter types.PaginationFilter) ([]domain.RegisteredEmail, int, error)
+}
+
+// userOrderColumns whitelists the columns users can be sorted by
+var userOrderColumns = map[string]string{
+	"id":        "users.id",
+	"name":      "users.name",
+	"email":     "users.email",
+	"createdAt": "users.created_at",
 }
 
 type userRepository struct {
@@ -24,44 +47,172 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
-func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.Preload("Roles").Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) GetById(id uint) (*domain.User, error) {
+func (r *userRepository) GetById(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	err := r.db.Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) GetByIdWithRoles(id uint) (*domain.User, error) {
+func (r *userRepository) GetByIdWithRoles(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	err := r.db.Preload("Roles").Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", id).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) UpdateRegisteredEmail(registeredEmail *domain.RegisteredEmail) error {
-	return r.db.Save(registeredEmail).Error
+func (r *userRepository) UpdateRegisteredEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error {
+	return r.db.WithContext(ctx).Save(registeredEmail).Error
 }
 
-func (r *userRepository) CreateRegisteredEmail(registeredEmail *domain.RegisteredEmail) error {
-	return r.db.Create(registeredEmail).Error
+func (r *userRepository) CreateRegisteredEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error {
+	return r.db.WithContext(ctx).Create(registeredEmail).Error
 }
 
-func (r *userRepository) GetRegisteredByEmail(email string) (*domain.RegisteredEmail, error) {
+func (r *userRepository) GetRegisteredByEmail(ctx context.Context, email string) (*domain.RegisteredEmail, error) {
 	var user domain.RegisteredEmail
-	err := r.db.Preload("Roles").Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) GetAllRoles() ([]domain.Role, error) {
+func (r *userRepository) GetAllRoles(ctx context.Context) ([]domain.Role, error) {
 	var roles []domain.Role
-	err := r.db.Find(&roles).Error
+	err := r.db.WithContext(ctx).Find(&roles).Error
 	return roles, err
 }
+
+func (r *userRepository) GetByIdIncludingDeactivated(ctx context.Context, id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Unscoped().Preload("Roles").Where("id = ?", id).First(&user).Error
+	return &user, err
+}
+
+func (r *userRepository) GetByEmailVerificationHash(ctx context.Context, hash string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Where("email_verification_hash = ?", hash).First(&user).Error
+	return &user, err
+}
+
+// EmailInUse also counts deactivated users, they still hold their address
+// and can be reactivated.
+func (r *userRepository) EmailInUse(ctx context.Context, email string, exceptId uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&domain.User{}).
+		Where("(email = ? OR pending_email = ?) AND id <> ?", email, email, exceptId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Update saves the user columns without touching roles or children
+func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(user).Error
+}
+
+func (r *userRepository) ReplaceRoles(ctx context.Context, user *domain.User, roles []domain.Role) error {
+	return r.db.WithContext(ctx).Model(user).Association("Roles").Replace(roles)
+}
+
+// Deactivate soft deletes the user and revokes its tokens
+func (r *userRepository) Deactivate(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", id).
+			Update("token_version", gorm.Expr("token_version + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&domain.User{}, id).Error
+	})
+}
+
+func (r *userRepository) Reactivate(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&domain.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+func (r *userRepository) List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.UserListFilter) ([]domain.User, int, error) {
+	var users []domain.User
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.User{})
+	switch filter.Status {
+	case "deactivated":
+		query = query.Unscoped().Where("users.deleted_at IS NOT NULL")
+	case "all":
+		query = query.Unscoped()
+	}
+
+	if search := strings.TrimSpace(paginationFilter.Search); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("(users.name LIKE ? OR users.email LIKE ? OR users.phone LIKE ?)", like, like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where("users.id IN (?)", r.db.Table("user_roles").
+			Select("user_roles.user_id").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Where("roles.name = ?", filter.Role))
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	orderColumn, ok := userOrderColumns[paginationFilter.OrderBy]
+	if !ok {
+		orderColumn = userOrderColumns["id"]
+	}
+	direction := "DESC"
+	if strings.EqualFold(paginationFilter.Sort, "asc") {
+		direction = "ASC"
+	}
+
+	err := query.Preload("Roles").
+		Order(orderColumn + " " + direction).
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return users, totalPages, nil
+}
+
+func (r *userRepository) GetRegisteredEmailById(ctx context.Context, id uint) (*domain.RegisteredEmail, error) {
+	var registeredEmail domain.RegisteredEmail
+	err := r.db.WithContext(ctx).Preload("Roles").Where("id = ?", id).First(&registeredEmail).Error
+	return &registeredEmail, err
+}
+
+func (r *userRepository) ListPendingRegisteredEmails(ctx context.Context, paginationFilter types.PaginationFilter) ([]domain.RegisteredEmail, int, error) {
+	var registeredEmails []domain.RegisteredEmail
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.RegisteredEmail{}).Where("registered_at IS NULL")
+	if search := strings.TrimSpace(paginationFilter.Search); search != "" {
+		query = query.Where("email LIKE ?", "%"+search+"%")
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Roles").
+		Order("created_at DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&registeredEmails).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return registeredEmails, totalPages, nil
+}
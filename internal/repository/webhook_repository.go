@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uint) (*domain.WebhookSubscription, error)
+	CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	UpdateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uint, reason string) error
+	DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.WebhookDeliveryFilter, subscriptionId uint) ([]domain.WebhookDelivery, int, error)
+	GetDelivery(ctx context.Context, id uint) (*domain.WebhookDelivery, error)
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db}
+}
+
+// createOutboxEvents stores the events in the transaction of the change they
+// describe
+func createOutboxEvents(tx *gorm.DB, events []domain.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	err := r.db.WithContext(ctx).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookRepository) GetSubscription(ctx context.Context, id uint) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&subscription).Error
+	return &subscription, err
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(subscription).Error
+}
+
+// DeleteSubscription removes the subscription and fails its pending
+// deliveries with the reason
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uint, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, domain.WebhookPending).
+			Updates(map[string]any{"status": domain.WebhookFailed, "last_error": reason}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&domain.WebhookSubscription{}, id).Error
+	})
+}
+
+// DispatchEvents turns the undispatched outbox events into a delivery for
+// every active subscription to their type and marks them dispatched, all in
+// one transaction. It returns the number of events dispatched.
+func (r *webhookRepository) DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error) {
+	var events []domain.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var subscriptions []domain.WebhookSubscription
+		if err := tx.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		var deliveries []domain.WebhookDelivery
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+			for _, subscription := range subscriptions {
+				if !subscription.Subscribes(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, domain.WebhookDelivery{
+					SubscriptionID: subscription.ID,
+					EventID:        event.ID,
+					Status:         domain.WebhookPending,
+					NextAttemptAt:  now,
+				})
+			}
+		}
+		if len(deliveries) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&deliveries).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&domain.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("dispatched_at", now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// ClaimDue locks the pending deliveries that are due and pushes them back by
+// the lease, so no other instance sends them at the same time. A delivery
+// left by a crash is sent again once the lease runs out. Deliveries of paused
+// subscriptions wait.
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	var ids []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		active := tx.Model(&domain.WebhookSubscription{}).Select("id").Where("active = ?", true)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.WebhookPending, now).
+			Where("subscription_id IN (?)", active).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&domain.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	// The subscriptions and events are loaded outside the lock
+	deliveries = nil
+	err = r.db.WithContext(ctx).Preload("Subscription").Preload("Event").Where("id IN ?", ids).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) SaveDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "response_status", "response_body", "last_error", "delivered_at").
+		Updates(delivery).Error
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.WebhookDeliveryFilter, subscriptionId uint) ([]domain.WebhookDelivery, int, error) {
+	var deliveries []domain.WebhookDelivery
+	var totalRecords int64
+
+	query := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).Where("subscription_id = ?", subscriptionId)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Event").
+		Order("id DESC").
+		Offset((paginationFilter.Page - 1) * paginationFilter.Limit).
+		Limit(paginationFilter.Limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int((totalRecords + int64(paginationFilter.Limit) - 1) / int64(paginationFilter.Limit))
+	return deliveries, totalPages, nil
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id uint) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := r.db.WithContext(ctx).Preload("Event").Where("id = ?", id).First(&delivery).Error
+	return &delivery, err
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(delivery).Error
+}
@@ -0,0 +1,382 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// maxAbsenceDays bounds the range of a single absence report
+const maxAbsenceDays = 62
+
+const dateLayout = "2006-01-02"
+
+type AbsenceUsecase interface {
+	ReportAbsence(ctx context.Context, userId uint, input domain.ReportAbsenceRequest) ([]domain.ChildAbsence, error)
+	ListAbsences(ctx context.Context, userId uint, filter domain.AbsenceListFilter) ([]domain.ChildAbsence, error)
+	CancelAbsence(ctx context.Context, userId uint, id uint) error
+	ExpectedAttendance(ctx context.Context, date time.Time) (*domain.ExpectedAttendanceResponse, error)
+	NotArrived(ctx context.Context, date time.Time, cutoff string) (*domain.NotArrivedResponse, error)
+	AbsenceReport(ctx context.Context, month time.Time) (*domain.AbsenceReportResponse, error)
+}
+
+type absenceUsecase struct {
+	repo     repository.AbsenceRepository
+	calendar CenterCalendar
+	cfg      *config.Config
+	now      func() time.Time
+}
+
+func NewAbsenceUsecase(repo repository.AbsenceRepository, calendar CenterCalendar, cfg *config.Config) AbsenceUsecase {
+	return &absenceUsecase{repo, calendar, cfg, time.Now}
+}
+
+// ReportAbsence records the child absent on every day of the range it is
+// expected. Parents can only report their own children and only from today
+// on, staff can also record past days.
+func (u *absenceUsecase) ReportAbsence(ctx context.Context, userId uint, input domain.ReportAbsenceRequest) ([]domain.ChildAbsence, error) {
+	start, _ := utils.ParseDateStringToTime(input.StartDate)
+	end := start
+	if input.EndDate != "" {
+		end, _ = utils.ParseDateStringToTime(input.EndDate)
+	}
+	if end.Before(*start) {
+		return nil, apperror.Validation(types.FieldError{Field: "endDate", Message: "endDate must not be before startDate"})
+	}
+	if end.Sub(*start) >= maxAbsenceDays*24*time.Hour {
+		return nil, apperror.Validation(types.FieldError{Field: "endDate", Message: fmt.Sprintf("an absence cannot span more than %d days", maxAbsenceDays)})
+	}
+
+	if _, err := u.repo.GetChild(ctx, input.ChildID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Validation(types.FieldError{Field: "childId", Message: "unknown child id"})
+	} else if err != nil {
+		return nil, err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, input.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff(user) && start.Before(today(u.now())) {
+		return nil, apperror.Validation(types.FieldError{Field: "startDate", Message: "past absences can only be recorded by staff"})
+	}
+
+	enrollments, err := u.repo.GetAttendingEnrollments(ctx, input.ChildID, *start, *end)
+	if err != nil {
+		return nil, err
+	}
+	closed, err := u.calendar.ClosedDays(ctx, *start, *end)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := u.repo.GetAbsences(ctx, input.ChildID, 0, *start, *end)
+	if err != nil {
+		return nil, err
+	}
+	reported := make(map[string]bool, len(existing))
+	for _, absence := range existing {
+		reported[absence.Date.Format(dateLayout)] = true
+	}
+
+	var absences []domain.ChildAbsence
+	expectedDays := 0
+	for day := *start; !day.After(*end); day = day.AddDate(0, 0, 1) {
+		if !expectedOnAny(enrollments, day, closed) {
+			continue
+		}
+		expectedDays++
+		if reported[day.Format(dateLayout)] {
+			continue
+		}
+		absences = append(absences, domain.ChildAbsence{
+			ChildID:    input.ChildID,
+			Date:       day,
+			Reason:     input.Reason,
+			Note:       input.Note,
+			ReportedBy: userId,
+		})
+	}
+	if expectedDays == 0 {
+		return nil, apperror.Validation(types.FieldError{Field: "startDate", Message: "the child is not expected on any of these days"})
+	}
+	if len(absences) == 0 {
+		return nil, apperror.Conflict("the absence is already reported")
+	}
+
+	if err := u.repo.CreateAbsences(ctx, absences); err != nil {
+		return nil, err
+	}
+	return absences, nil
+}
+
+// ListAbsences returns the absences of a month, limited to their own
+// children for parents
+func (u *absenceUsecase) ListAbsences(ctx context.Context, userId uint, filter domain.AbsenceListFilter) ([]domain.ChildAbsence, error) {
+	from := monthStart(u.now())
+	if filter.Month != "" {
+		from, _ = time.ParseInLocation("2006-01", filter.Month, time.Local)
+	}
+	to := from.AddDate(0, 1, -1)
+
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) {
+		return u.repo.GetAbsences(ctx, filter.ChildID, 0, from, to)
+	}
+	if filter.ChildID != 0 {
+		if _, err := checkChildAccess(ctx, u.repo, userId, filter.ChildID); err != nil {
+			return nil, err
+		}
+	}
+	return u.repo.GetAbsences(ctx, filter.ChildID, userId, from, to)
+}
+
+func (u *absenceUsecase) CancelAbsence(ctx context.Context, userId uint, id uint) error {
+	absence, err := u.repo.GetAbsenceById(ctx, id)
+	if err != nil {
+		return err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, absence.ChildID)
+	if err != nil {
+		return err
+	}
+	if !isStaff(user) && absence.Date.Before(today(u.now())) {
+		return apperror.Forbidden("past absences can only be changed by staff")
+	}
+	return u.repo.DeleteAbsence(ctx, absence)
+}
+
+func (u *absenceUsecase) ExpectedAttendance(ctx context.Context, date time.Time) (*domain.ExpectedAttendanceResponse, error) {
+	children, closure, err := u.expectedChildren(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.ExpectedAttendanceResponse{Date: date.Format(dateLayout), Children: children}
+	if closure != nil {
+		response.Closed, response.Closure = true, closure.Name
+	}
+	for _, child := range children {
+		switch child.Status {
+		case domain.ExpectedPresent:
+			response.Present++
+		case domain.ExpectedAbsent:
+			response.Absent++
+		}
+	}
+	response.Expected = len(children)
+	return response, nil
+}
+
+// NotArrived lists the expected children without a reported absence who did
+// not arrive by the cutoff, CHILD_ARRIVAL_CUTOFF when empty
+func (u *absenceUsecase) NotArrived(ctx context.Context, date time.Time, cutoff string) (*domain.NotArrivedResponse, error) {
+	if cutoff == "" {
+		cutoff = u.cfg.ChildArrivalCutoff
+	}
+	clock, err := time.Parse("15:04", cutoff)
+	if err != nil {
+		return nil, apperror.Validation(types.FieldError{Field: "cutoff", Message: "cutoff must be a time such as 09:00"})
+	}
+	deadline := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location())
+
+	children, _, err := u.expectedChildren(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	response := &domain.NotArrivedResponse{Date: date.Format(dateLayout), Cutoff: cutoff, Children: []domain.ExpectedChildResponse{}}
+	for _, child := range children {
+		if child.Status == domain.ExpectedAbsent {
+			continue
+		}
+		if child.Arrival == nil || child.Arrival.After(deadline) {
+			response.Children = append(response.Children, child)
+		}
+	}
+	return response, nil
+}
+
+// AbsenceReport summarises, per child, the expected days of the month against
+// attended days and reported absences by reason
+func (u *absenceUsecase) AbsenceReport(ctx context.Context, month time.Time) (*domain.AbsenceReportResponse, error) {
+	from := monthStart(month)
+	to := from.AddDate(0, 1, -1)
+	todayKey := today(u.now()).Format(dateLayout)
+
+	enrollments, err := u.repo.GetAttendingEnrollments(ctx, 0, from, to)
+	if err != nil {
+		return nil, err
+	}
+	closed, err := u.calendar.ClosedDays(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	absences, err := u.repo.GetAbsences(ctx, 0, 0, from, to)
+	if err != nil {
+		return nil, err
+	}
+	arrivals, err := u.repo.GetArrivals(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[uint]map[string]string)
+	for _, absence := range absences {
+		if reasons[absence.ChildID] == nil {
+			reasons[absence.ChildID] = make(map[string]string)
+		}
+		reasons[absence.ChildID][absence.Date.Format(dateLayout)] = absence.Reason
+	}
+	attended := make(map[uint]map[string]bool)
+	for _, arrival := range arrivals {
+		if attended[arrival.ChildID] == nil {
+			attended[arrival.ChildID] = make(map[string]bool)
+		}
+		attended[arrival.ChildID][arrival.Date.Format(dateLayout)] = true
+	}
+
+	byChild := make(map[uint][]domain.Enrollment)
+	var summaries []domain.ChildAbsenceSummary
+	for _, enrollment := range enrollments {
+		if byChild[enrollment.ChildID] == nil {
+			summaries = append(summaries, domain.ChildAbsenceSummary{
+				ChildID:  enrollment.ChildID,
+				Name:     enrollment.Child.Name,
+				Absences: map[string]int{},
+			})
+		}
+		byChild[enrollment.ChildID] = append(byChild[enrollment.ChildID], enrollment)
+	}
+
+	for i := range summaries {
+		summary := &summaries[i]
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			if !expectedOnAny(byChild[summary.ChildID], day, closed) {
+				continue
+			}
+			key := day.Format(dateLayout)
+			summary.ExpectedDays++
+			switch reason, absent := reasons[summary.ChildID][key]; {
+			case attended[summary.ChildID][key]:
+				summary.AttendedDays++
+			case absent:
+				summary.Absences[reason]++
+			case key < todayKey:
+				summary.UnreportedDays++
+			}
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	if summaries == nil {
+		summaries = []domain.ChildAbsenceSummary{}
+	}
+	return &domain.AbsenceReportResponse{Month: from.Format("2006-01"), Children: summaries}, nil
+}
+
+// expectedChildren returns the children expected on date with their status,
+// or the closure when the center is closed that day
+func (u *absenceUsecase) expectedChildren(ctx context.Context, date time.Time) ([]domain.ExpectedChildResponse, *domain.CalendarDay, error) {
+	closed, err := u.calendar.ClosedDays(ctx, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	if closure, ok := closed[date.Format(dateLayout)]; ok {
+		return []domain.ExpectedChildResponse{}, &closure, nil
+	}
+
+	enrollments, err := u.repo.GetAttendingEnrollments(ctx, 0, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	absences, err := u.repo.GetAbsences(ctx, 0, 0, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	arrivals, err := u.repo.GetArrivals(ctx, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reasons := make(map[uint]string, len(absences))
+	for _, absence := range absences {
+		reasons[absence.ChildID] = absence.Reason
+	}
+	// Arrivals are ordered, keep the first of the day
+	arrived := make(map[uint]time.Time, len(arrivals))
+	for _, arrival := range arrivals {
+		if _, ok := arrived[arrival.ChildID]; !ok {
+			arrived[arrival.ChildID] = arrival.Arrival
+		}
+	}
+
+	children := []domain.ExpectedChildResponse{}
+	seen := make(map[uint]bool, len(enrollments))
+	for _, enrollment := range enrollments {
+		if seen[enrollment.ChildID] || !expectedOn(&enrollment, date, closed) {
+			continue
+		}
+		seen[enrollment.ChildID] = true
+
+		child := domain.ExpectedChildResponse{
+			ChildID:     enrollment.ChildID,
+			Name:        enrollment.Child.Name,
+			Nickname:    enrollment.Child.Nickname,
+			ClassroomID: enrollment.Child.ClassroomID,
+			Status:      domain.ExpectedPending,
+		}
+		if arrival, ok := arrived[enrollment.ChildID]; ok {
+			child.Status, child.Arrival = domain.ExpectedPresent, &arrival
+		} else if reason, ok := reasons[enrollment.ChildID]; ok {
+			child.Status, child.AbsenceReason = domain.ExpectedAbsent, reason
+		}
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	return children, nil, nil
+}
+
+// isStaff reports whether the user works at the center
+func isStaff(user domain.User) bool {
+	return hasRole(user.Roles, domain.RoleAdmin) || hasRole(user.Roles, domain.RoleTeacher)
+}
+
+// expectedOn reports whether the enrollment has the child attending on day:
+// within its dates, on a scheduled weekday and with the center open
+func expectedOn(enrollment *domain.Enrollment, day time.Time, closed map[string]domain.CalendarDay) bool {
+	key := day.Format(dateLayout)
+	if enrollment.StartDate == nil || key < enrollment.StartDate.Format(dateLayout) {
+		return false
+	}
+	if enrollment.EndDate != nil && key > enrollment.EndDate.Format(dateLayout) {
+		return false
+	}
+	if _, ok := closed[key]; ok {
+		return false
+	}
+	return enrollment.AttendsOn(day.Weekday())
+}
+
+func expectedOnAny(enrollments []domain.Enrollment, day time.Time, closed map[string]domain.CalendarDay) bool {
+	for i := range enrollments {
+		if expectedOn(&enrollments[i], day, closed) {
+			return true
+		}
+	}
+	return false
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
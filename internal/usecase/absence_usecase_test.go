@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+)
+
+// fakeCenterCalendar is open on every day but the closed ones
+type fakeCenterCalendar struct {
+	closed map[string]domain.CalendarDay
+}
+
+func (c *fakeCenterCalendar) ClosedDays(ctx context.Context, from, to time.Time) (map[string]domain.CalendarDay, error) {
+	days := map[string]domain.CalendarDay{}
+	for key, day := range c.closed {
+		if key >= from.Format(dateLayout) && key <= to.Format(dateLayout) {
+			days[key] = day
+		}
+	}
+	return days, nil
+}
+
+// Hours opens the center from 07:00 to 18:00 on the days it is not closed
+func (c *fakeCenterCalendar) Hours(ctx context.Context, t time.Time) (domain.OpeningHours, error) {
+	day := today(t)
+	if closure, ok := c.closed[day.Format(dateLayout)]; ok {
+		return domain.OpeningHours{Date: day, Name: closure.Name}, nil
+	}
+	return domain.OpeningHours{Date: day, Open: true, Opens: 7 * time.Hour, Closes: 18 * time.Hour}, nil
+}
+
+// fakeAbsenceRepository returns all its records whatever the range asked
+type fakeAbsenceRepository struct {
+	repository.AbsenceRepository
+	enrollments []domain.Enrollment
+	absences    []domain.ChildAbsence
+	arrivals    []domain.ChildAttendance
+}
+
+func (r *fakeAbsenceRepository) GetAttendingEnrollments(ctx context.Context, childId uint, from, to time.Time) ([]domain.Enrollment, error) {
+	return r.enrollments, nil
+}
+
+func (r *fakeAbsenceRepository) GetAbsences(ctx context.Context, childId uint, parentId uint, from, to time.Time) ([]domain.ChildAbsence, error) {
+	return r.absences, nil
+}
+
+func (r *fakeAbsenceRepository) GetArrivals(ctx context.Context, from, to time.Time) ([]domain.ChildAttendance, error) {
+	return r.arrivals, nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func testEnrollment(childId uint, name string, days string, start time.Time, end *time.Time) domain.Enrollment {
+	return domain.Enrollment{
+		ChildID:      childId,
+		Child:        domain.Child{ID: childId, Name: name},
+		Status:       domain.EnrollmentEnrolled,
+		ScheduleDays: days,
+		StartDate:    &start,
+		EndDate:      end,
+	}
+}
+
+func TestExpectedOn(t *testing.T) {
+	// 2025-02-20 is a Thursday
+	end := date(2025, 2, 27)
+	enrollment := testEnrollment(1, "Ana", "mon,thu", date(2025, 2, 6), &end)
+	closed := map[string]domain.CalendarDay{"2025-02-13": {Name: "Staff training"}}
+
+	tests := []struct {
+		day  time.Time
+		want bool
+	}{
+		{date(2025, 2, 3), false},  // before the start date
+		{date(2025, 2, 6), true},   // the start date
+		{date(2025, 2, 13), false}, // the center is closed
+		{date(2025, 2, 19), false}, // not a scheduled weekday
+		{date(2025, 2, 20), true},
+		{date(2025, 2, 27), true}, // the end date
+		{date(2025, 3, 3), false}, // after the end date
+	}
+	for _, tt := range tests {
+		if got := expectedOn(&enrollment, tt.day, closed); got != tt.want {
+			t.Errorf("expectedOn(%s) = %v, want %v", tt.day.Format(dateLayout), got, tt.want)
+		}
+	}
+
+	pending := testEnrollment(1, "Ana", "mon,thu", date(2025, 2, 6), nil)
+	pending.StartDate = nil
+	if expectedOn(&pending, date(2025, 2, 20), nil) {
+		t.Error("expectedOn() = true for an enrollment without a start date")
+	}
+}
+
+func TestNotArrivedListsChildrenLateOrMissing(t *testing.T) {
+	day := date(2025, 2, 20)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	repo := &fakeAbsenceRepository{
+		enrollments: []domain.Enrollment{
+			testEnrollment(1, "Ana", "thu", date(2025, 1, 6), nil),
+			testEnrollment(2, "Ben", "thu", date(2025, 1, 6), nil),
+			testEnrollment(3, "Cai", "thu", date(2025, 1, 6), nil),
+			testEnrollment(4, "Dee", "thu", date(2025, 1, 6), nil),
+			testEnrollment(5, "Eve", "mon", date(2025, 1, 6), nil),
+			testEnrollment(6, "Fay", "thu", date(2025, 1, 6), nil),
+		},
+		absences: []domain.ChildAbsence{{ChildID: 4, Date: day, Reason: "sick"}},
+		arrivals: []domain.ChildAttendance{
+			{ChildID: 1, Date: day, Arrival: at(8, 30)},
+			{ChildID: 6, Date: day, Arrival: at(9, 0)},
+			{ChildID: 2, Date: day, Arrival: at(9, 15)},
+		},
+	}
+	u := &absenceUsecase{repo: repo, calendar: &fakeCenterCalendar{}, cfg: &config.Config{ChildArrivalCutoff: "09:00"}}
+
+	response, err := u.NotArrived(context.Background(), day, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, child := range response.Children {
+		names = append(names, child.Name)
+	}
+	// Ben arrived after the cutoff and Cai did not arrive. Fay arrived at
+	// the cutoff, Dee is reported absent and Eve is not expected on Thursdays.
+	if len(names) != 2 || names[0] != "Ben" || names[1] != "Cai" {
+		t.Errorf("not arrived = %v, want [Ben Cai]", names)
+	}
+
+	response, err = u.NotArrived(context.Background(), day, "09:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Children) != 1 || response.Children[0].Name != "Cai" {
+		t.Errorf("not arrived by 09:30 = %+v, want only Cai", response.Children)
+	}
+
+	u.calendar = &fakeCenterCalendar{closed: map[string]domain.CalendarDay{"2025-02-20": {Name: "Staff training"}}}
+	response, err = u.NotArrived(context.Background(), day, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Children) != 0 {
+		t.Errorf("not arrived on a closed day = %+v, want none", response.Children)
+	}
+}
+
+func TestAbsenceReportCountsExpectedDays(t *testing.T) {
+	// February 2025: Mondays 3, 10, 17, 24 and Wednesdays 5, 12, 19, 26
+	withdrawn := date(2025, 2, 5)
+	repo := &fakeAbsenceRepository{
+		enrollments: []domain.Enrollment{
+			testEnrollment(1, "Ana", "mon,wed", date(2025, 1, 6), nil),
+			testEnrollment(2, "Ben", "wed", date(2025, 1, 6), &withdrawn),
+		},
+		absences: []domain.ChildAbsence{{ChildID: 1, Date: date(2025, 2, 12), Reason: "sick"}},
+		arrivals: []domain.ChildAttendance{
+			{ChildID: 1, Date: date(2025, 2, 3)},
+			{ChildID: 1, Date: date(2025, 2, 5)},
+			{ChildID: 2, Date: date(2025, 2, 5)},
+		},
+	}
+	calendar := &fakeCenterCalendar{closed: map[string]domain.CalendarDay{"2025-02-10": {Name: "Staff training"}}}
+	now := time.Date(2025, 2, 20, 12, 0, 0, 0, time.UTC)
+	u := &absenceUsecase{repo: repo, calendar: calendar, cfg: &config.Config{}, now: func() time.Time { return now }}
+
+	response, err := u.AbsenceReport(context.Background(), date(2025, 2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Children) != 2 {
+		t.Fatalf("report has %d children, want 2", len(response.Children))
+	}
+
+	tests := []struct {
+		summary    domain.ChildAbsenceSummary
+		expected   int
+		attended   int
+		sick       int
+		unreported int
+	}{
+		// The 10th is closed, the 17th and 19th passed without news and the
+		// 24th and 26th are still to come
+		{response.Children[0], 7, 2, 1, 2},
+		// Only expected until the withdrawal on the 5th
+		{response.Children[1], 1, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		s := tt.summary
+		if s.ExpectedDays != tt.expected || s.AttendedDays != tt.attended || s.Absences["sick"] != tt.sick || s.UnreportedDays != tt.unreported {
+			t.Errorf("%s: expected %d, attended %d, sick %d, unreported %d, want %d, %d, %d, %d", s.Name,
+				s.ExpectedDays, s.AttendedDays, s.Absences["sick"], s.UnreportedDays, tt.expected, tt.attended, tt.sick, tt.unreported)
+		}
+	}
+}
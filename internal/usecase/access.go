@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+)
+
+// childAccessRepository is implemented by the repositories of the records
+// kept per child
+type childAccessRepository interface {
+	GetUserWithRoles(ctx context.Context, userId uint) (domain.User, error)
+	IsParentOf(ctx context.Context, userId uint, childId uint) (bool, error)
+}
+
+// checkChildAccess lets staff through for any child and parents of one of
+// the children, and returns the user with its roles. Without children only
+// staff are let through.
+func checkChildAccess(ctx context.Context, repo childAccessRepository, userId uint, childIds ...uint) (domain.User, error) {
+	user, err := repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return user, err
+	}
+	if isStaff(user) {
+		return user, nil
+	}
+	for _, childId := range childIds {
+		isParent, err := repo.IsParentOf(ctx, userId, childId)
+		if err != nil {
+			return user, err
+		}
+		if isParent {
+			return user, nil
+		}
+	}
+	return user, apperror.Forbidden("You are not allowed to access this child")
+}
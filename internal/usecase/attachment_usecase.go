@@ -0,0 +1,402 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/imaging"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/storage"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Download variants of an attachment
+const (
+	VariantOriginal  = "original"
+	VariantThumbnail = "thumbnail"
+)
+
+// thumbnailSize bounds the longest side of image thumbnails
+const thumbnailSize = 320
+
+// Accepted upload types, detected from the content and not from the name
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// AttachmentStore keeps files for other modules, which check the access to
+// their own records
+type AttachmentStore interface {
+	// Attach stores the file and saves the attachment, which must have its
+	// owner and uploader set. Only the given content types are accepted.
+	Attach(ctx context.Context, attachment *domain.Attachment, fileName string, data []byte, contentTypes ...string) error
+	Signed(attachments []domain.Attachment) []domain.AttachmentResponse
+	Open(ctx context.Context, attachment *domain.Attachment) (io.ReadCloser, error)
+	RemoveFiles(ctx context.Context, attachments []domain.Attachment)
+}
+
+type AttachmentUsecase interface {
+	AttachmentStore
+	Upload(ctx context.Context, userId uint, input domain.AttachmentUploadRequest, fileName string, data []byte) (*domain.AttachmentResponse, error)
+	List(ctx context.Context, userId uint, filter domain.AttachmentListFilter) ([]domain.AttachmentResponse, error)
+	Get(ctx context.Context, userId uint, id uint) (*domain.AttachmentResponse, error)
+	Delete(ctx context.Context, userId uint, id uint) error
+	Download(ctx context.Context, id uint, query domain.AttachmentDownloadQuery) (io.ReadCloser, *domain.Attachment, error)
+}
+
+type attachmentUsecase struct {
+	repo    repository.AttachmentRepository
+	storage storage.Storage
+	cfg     *config.Config
+	now     func() time.Time
+}
+
+func NewAttachmentUsecase(repo repository.AttachmentRepository, store storage.Storage, cfg *config.Config) AttachmentUsecase {
+	return &attachmentUsecase{repo, store, cfg, time.Now}
+}
+
+// Upload stores a file on a record. Staff may attach to any record, parents
+// only to the profile of their own children.
+func (u *attachmentUsecase) Upload(ctx context.Context, userId uint, input domain.AttachmentUploadRequest, fileName string, data []byte) (*domain.AttachmentResponse, error) {
+	childIds, _, err := u.owner(ctx, input.OwnerType, input.OwnerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Validation(types.FieldError{Field: "ownerId", Message: "unknown " + input.OwnerType})
+	} else if err != nil {
+		return nil, err
+	}
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff(user) {
+		isParent, err := u.repo.IsParentOf(ctx, userId, childIds[0])
+		if err != nil {
+			return nil, err
+		}
+		if input.OwnerType != domain.AttachmentChild || !isParent {
+			return nil, apperror.Forbidden("You are not allowed to attach files to this record")
+		}
+	}
+
+	attachment := &domain.Attachment{
+		OwnerType:  input.OwnerType,
+		OwnerID:    input.OwnerID,
+		ChildID:    &childIds[0],
+		UploadedBy: userId,
+	}
+	if err := u.Attach(ctx, attachment, fileName, data); err != nil {
+		return nil, err
+	}
+	return u.response(attachment), nil
+}
+
+// Attach checks the size and the type detected from the content, then stores
+// the file. Images are stored without their metadata, which holds the GPS
+// position of phone photos, and get a thumbnail.
+func (u *attachmentUsecase) Attach(ctx context.Context, attachment *domain.Attachment, fileName string, data []byte, contentTypes ...string) error {
+	if len(data) > u.cfg.AttachmentMaxSizeMB<<20 {
+		return apperror.New(http.StatusRequestEntityTooLarge, apperror.CodeTooLarge, fmt.Sprintf("files are limited to %d MB", u.cfg.AttachmentMaxSizeMB))
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	extension, ok := attachmentExtensions[contentType]
+	if !ok || (len(contentTypes) > 0 && !slices.Contains(contentTypes, contentType)) {
+		return apperror.New(http.StatusUnsupportedMediaType, apperror.CodeUnsupportedMedia, "this type of file is not accepted here")
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return err
+	}
+	attachment.FileName = cleanFileName(fileName, extension)
+	attachment.ContentType = contentType
+	attachment.StorageKey = fmt.Sprintf("%s/%d/%s%s", attachment.OwnerType, attachment.OwnerID, name, extension)
+
+	if contentType == "application/pdf" {
+		attachment.Size = int64(len(data))
+		if err := u.storage.Put(ctx, attachment.StorageKey, bytes.NewReader(data), attachment.Size, contentType); err != nil {
+			return err
+		}
+	} else {
+		cleaned, thumbnail, err := imaging.Sanitize(data, thumbnailSize)
+		if err != nil {
+			return apperror.BadRequest("the image cannot be read").Wrap(err)
+		}
+		attachment.Size = int64(len(cleaned.Data))
+		attachment.Width, attachment.Height = cleaned.Width, cleaned.Height
+		attachment.ThumbnailKey = fmt.Sprintf("%s/%d/%s-thumb%s", attachment.OwnerType, attachment.OwnerID, name, extension)
+		if err := u.storage.Put(ctx, attachment.StorageKey, bytes.NewReader(cleaned.Data), attachment.Size, contentType); err != nil {
+			return err
+		}
+		if err := u.storage.Put(ctx, attachment.ThumbnailKey, bytes.NewReader(thumbnail.Data), int64(len(thumbnail.Data)), contentType); err != nil {
+			u.removeObjects(ctx, attachment)
+			return err
+		}
+	}
+
+	if err := u.repo.Create(ctx, attachment); err != nil {
+		u.removeObjects(ctx, attachment)
+		return err
+	}
+	return nil
+}
+
+func (u *attachmentUsecase) Signed(attachments []domain.Attachment) []domain.AttachmentResponse {
+	responses := make([]domain.AttachmentResponse, len(attachments))
+	for i := range attachments {
+		responses[i] = *u.response(&attachments[i])
+	}
+	return responses
+}
+
+func (u *attachmentUsecase) Open(ctx context.Context, attachment *domain.Attachment) (io.ReadCloser, error) {
+	return u.storage.Get(ctx, attachment.StorageKey)
+}
+
+// RemoveFiles deletes the files of attachments whose rows are already gone
+func (u *attachmentUsecase) RemoveFiles(ctx context.Context, attachments []domain.Attachment) {
+	for i := range attachments {
+		u.removeObjects(ctx, &attachments[i])
+	}
+}
+
+// List returns the attachments of a record the user may see
+func (u *attachmentUsecase) List(ctx context.Context, userId uint, filter domain.AttachmentListFilter) ([]domain.AttachmentResponse, error) {
+	childIds, parentsMaySee, err := u.owner(ctx, filter.OwnerType, filter.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if !parentsMaySee {
+		childIds = nil
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, childIds...); err != nil {
+		return nil, err
+	}
+
+	attachments, err := u.repo.ListByOwner(ctx, filter.OwnerType, filter.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	return u.Signed(attachments), nil
+}
+
+func (u *attachmentUsecase) Get(ctx context.Context, userId uint, id uint) (*domain.AttachmentResponse, error) {
+	attachment, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	childIds, parentsMaySee, err := u.owner(ctx, attachment.OwnerType, attachment.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if !parentsMaySee {
+		childIds = nil
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, childIds...); err != nil {
+		return nil, err
+	}
+	return u.response(attachment), nil
+}
+
+// Delete removes the attachment and its files. Only the uploader and admins
+// may delete, files of messages are never deleted.
+func (u *attachmentUsecase) Delete(ctx context.Context, userId uint, id uint) error {
+	attachment, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if attachment.OwnerType == domain.AttachmentMessage {
+		return apperror.Conflict("files of messages are kept for audit")
+	}
+	if attachment.UploadedBy != userId {
+		user, err := u.repo.GetUserWithRoles(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if !hasRole(user.Roles, domain.RoleAdmin) {
+			return apperror.Forbidden("Only the uploader and admins can delete this attachment")
+		}
+	}
+
+	if err := u.repo.Delete(ctx, attachment); err != nil {
+		return err
+	}
+	u.removeObjects(ctx, attachment)
+	return nil
+}
+
+// Download opens the file of a signed URL. The signature is the credential:
+// it is only handed out after the access check of Get or List.
+func (u *attachmentUsecase) Download(ctx context.Context, id uint, query domain.AttachmentDownloadQuery) (io.ReadCloser, *domain.Attachment, error) {
+	invalid := apperror.Forbidden("invalid or expired download link")
+	variant := query.Variant
+	if variant == "" {
+		variant = VariantOriginal
+	}
+	if u.now().Unix() > query.Expires || !hmac.Equal([]byte(u.sign(id, variant, query.Expires)), []byte(strings.ToLower(query.Signature))) {
+		return nil, nil, invalid
+	}
+
+	attachment, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := attachment.StorageKey
+	if variant == VariantThumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, nil, apperror.NotFound("the attachment has no thumbnail")
+		}
+		key = attachment.ThumbnailKey
+	}
+
+	file, err := u.storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, apperror.NotFound("file not found").Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, attachment, nil
+}
+
+// owner returns the children whose access rules apply to the record and
+// whether their parents may see it
+func (u *attachmentUsecase) owner(ctx context.Context, ownerType string, ownerId uint) ([]uint, bool, error) {
+	switch ownerType {
+	case domain.AttachmentChild:
+		child, err := u.repo.GetChild(ctx, ownerId)
+		if err != nil {
+			return nil, false, err
+		}
+		return []uint{child.ID}, true, nil
+	case domain.AttachmentDiary:
+		diary, err := u.repo.GetDiary(ctx, ownerId)
+		if err != nil {
+			return nil, false, err
+		}
+		return []uint{diary.ChildID}, true, nil
+	case domain.AttachmentIncident:
+		incident, err := u.repo.GetIncident(ctx, ownerId)
+		if err != nil {
+			return nil, false, err
+		}
+		return []uint{incident.ChildID}, incident.Status != domain.IncidentPendingReview, nil
+	case domain.AttachmentPost:
+		post, err := u.repo.GetPost(ctx, ownerId)
+		if err != nil {
+			return nil, false, err
+		}
+		childIds := make([]uint, len(post.Children))
+		for i, child := range post.Children {
+			childIds[i] = child.ID
+		}
+		return childIds, post.PublishedAt != nil, nil
+	case domain.AttachmentMessage:
+		// Files of announcements are only handed out with the messages
+		thread, err := u.repo.GetMessageThread(ctx, ownerId)
+		if err != nil {
+			return nil, false, err
+		}
+		if thread.ChildID == nil {
+			return nil, false, nil
+		}
+		return []uint{*thread.ChildID}, true, nil
+	}
+	return nil, false, apperror.BadRequest("unknown attachment owner type")
+}
+
+func (u *attachmentUsecase) response(attachment *domain.Attachment) *domain.AttachmentResponse {
+	expiresAt := u.now().Add(u.cfg.AttachmentURLTTL).Truncate(time.Second)
+	response := &domain.AttachmentResponse{
+		ID:          attachment.ID,
+		OwnerType:   attachment.OwnerType,
+		OwnerID:     attachment.OwnerID,
+		ChildID:     attachment.ChildID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Width:       attachment.Width,
+		Height:      attachment.Height,
+		UploadedBy:  attachment.UploadedBy,
+		URL:         u.downloadURL(attachment.ID, VariantOriginal, expiresAt),
+		ExpiresAt:   expiresAt,
+		CreatedAt:   attachment.CreatedAt,
+	}
+	if attachment.ThumbnailKey != "" {
+		response.ThumbnailURL = u.downloadURL(attachment.ID, VariantThumbnail, expiresAt)
+	}
+	return response
+}
+
+func (u *attachmentUsecase) downloadURL(id uint, variant string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	return fmt.Sprintf("%s/api/v1/attachments/%d/download?variant=%s&expires=%d&signature=%s",
+		u.cfg.AppBaseURL, id, variant, expires, u.sign(id, variant, expires))
+}
+
+func (u *attachmentUsecase) sign(id uint, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(u.cfg.AttachmentURLKey))
+	fmt.Fprintf(mac, "attachment:%d:%s:%d", id, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// removeObjects deletes the stored files, failures only leave orphans behind
+// and are logged
+func (u *attachmentUsecase) removeObjects(ctx context.Context, attachment *domain.Attachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := u.storage.Delete(ctx, key); err != nil {
+			logger.FromContext(ctx).Error("failed to delete attachment file", "key", key, "error", err.Error())
+		}
+	}
+}
+
+func randomName() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// cleanFileName keeps the base name of the upload without characters that
+// could break the Content-Disposition header, and the extension matching
+// its detected type
+func cleanFileName(name string, extension string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '\\' || r == '/' {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.TrimSpace(name)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." {
+		name = "file"
+	}
+	if len(name) > 200 {
+		name = strings.ToValidUTF8(name[:200], "")
+	}
+	return name + extension
+}
@@ -0,0 +1,452 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/ical"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// maxCalendarRange bounds the days a single calendar query may span
+const maxCalendarRange = 366
+
+// maxImportedEventDays bounds the days a single imported event may close
+const maxImportedEventDays = 31
+
+// The iCal feed covers the last month and the coming year
+const (
+	feedPastDays   = 30
+	feedFutureDays = 365
+)
+
+// CenterCalendar tells on which days the center is closed and its hours on
+// the others
+type CenterCalendar interface {
+	// ClosedDays returns the holidays and closures between from and to,
+	// both included, keyed by their 2006-01-02 date. Half days are open and
+	// not included.
+	ClosedDays(ctx context.Context, from, to time.Time) (map[string]domain.CalendarDay, error)
+	// Hours returns the opening hours of the center on the day of t
+	Hours(ctx context.Context, t time.Time) (domain.OpeningHours, error)
+}
+
+type CalendarUsecase interface {
+	CenterCalendar
+	ListDays(ctx context.Context, from, to time.Time) ([]domain.CalendarDay, error)
+	CreateDay(ctx context.Context, input domain.CalendarDayRequest) (*domain.CalendarDay, error)
+	DeleteDay(ctx context.Context, id uint) error
+	ImportDays(ctx context.Context, file io.Reader, kind string) (*domain.CalendarImportResponse, error)
+	WorkingDays(ctx context.Context, from, to time.Time) ([]domain.OpeningHours, error)
+	ListEvents(ctx context.Context, userId uint, from, to time.Time) ([]domain.CalendarEvent, error)
+	CreateEvent(ctx context.Context, userId uint, input domain.CalendarEventRequest) (*domain.CalendarEvent, error)
+	UpdateEvent(ctx context.Context, id uint, input domain.CalendarEventRequest) (*domain.CalendarEvent, error)
+	DeleteEvent(ctx context.Context, id uint) error
+	RotateFeedToken(ctx context.Context, userId uint) (*domain.CalendarFeedResponse, error)
+	Feed(ctx context.Context, token string) (*ical.Calendar, error)
+}
+
+type calendarUsecase struct {
+	repo     repository.CalendarRepository
+	cfg      *config.Config
+	opens    time.Duration
+	closes   time.Duration
+	workdays []string
+	now      func() time.Time
+}
+
+func NewCalendarUsecase(repo repository.CalendarRepository, cfg *config.Config) CalendarUsecase {
+	return &calendarUsecase{
+		repo:     repo,
+		cfg:      cfg,
+		opens:    clockOffset(cfg.CenterOpens),
+		closes:   clockOffset(cfg.CenterCloses),
+		workdays: cfg.CenterWorkdays,
+		now:      time.Now,
+	}
+}
+
+func (u *calendarUsecase) ListDays(ctx context.Context, from, to time.Time) ([]domain.CalendarDay, error) {
+	if err := checkCalendarRange(from, to); err != nil {
+		return nil, err
+	}
+	return u.repo.GetDays(ctx, from, to)
+}
+
+func (u *calendarUsecase) CreateDay(ctx context.Context, input domain.CalendarDayRequest) (*domain.CalendarDay, error) {
+	date, err := utils.ParseDateStringToTime(input.Date)
+	if err != nil {
+		return nil, apperror.Validation(types.FieldError{Field: "date", Message: err.Error()})
+	}
+
+	day := &domain.CalendarDay{Date: *date, Kind: input.Kind, Name: input.Name}
+	if input.Kind == domain.CalendarHalfDay {
+		if clockOffset(input.ClosesAt) <= clockOffset(input.OpensAt) {
+			return nil, apperror.Validation(types.FieldError{Field: "closesAt", Message: "closesAt must be after opensAt"})
+		}
+		day.OpensAt, day.ClosesAt = input.OpensAt, input.ClosesAt
+	}
+
+	exists, err := u.repo.DateExists(ctx, *date)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, apperror.Conflict("the calendar already has an entry on this date")
+	}
+
+	if err := u.repo.CreateDay(ctx, day); err != nil {
+		return nil, err
+	}
+	return day, nil
+}
+
+func (u *calendarUsecase) DeleteDay(ctx context.Context, id uint) error {
+	day, err := u.repo.GetDayById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.repo.DeleteDay(ctx, day)
+}
+
+// ImportDays adds a calendar day of the kind for every day covered by the
+// all-day events of an iCalendar file, such as a national holiday calendar.
+// Dates already on the calendar and timed events are skipped and reported.
+func (u *calendarUsecase) ImportDays(ctx context.Context, file io.Reader, kind string) (*domain.CalendarImportResponse, error) {
+	events, err := ical.Parse(file, time.Local)
+	if err != nil {
+		return nil, apperror.BadRequest("invalid iCalendar file: " + err.Error())
+	}
+
+	response := &domain.CalendarImportResponse{
+		Created: []domain.CalendarDayResponse{},
+		Skipped: []domain.CalendarImportSkipped{},
+	}
+	var days []domain.CalendarDay
+	for _, event := range events {
+		name := strings.TrimSpace(event.Summary)
+		if name == "" {
+			name = "Holiday"
+		}
+		if len(name) > 255 {
+			name = name[:255]
+		}
+		skip := func(reason string) {
+			response.Skipped = append(response.Skipped, domain.CalendarImportSkipped{
+				Date: event.Start.Format(dateLayout), Name: name, Reason: reason,
+			})
+		}
+		if !event.AllDay {
+			skip("not an all-day event")
+			continue
+		}
+		if event.End.Sub(event.Start) > maxImportedEventDays*24*time.Hour {
+			skip(fmt.Sprintf("spans more than %d days", maxImportedEventDays))
+			continue
+		}
+		for day := event.Start; day.Before(event.End); day = day.AddDate(0, 0, 1) {
+			days = append(days, domain.CalendarDay{Date: day, Kind: kind, Name: name})
+		}
+	}
+	if len(days) == 0 {
+		return response, nil
+	}
+
+	from, to := days[0].Date, days[0].Date
+	for _, day := range days {
+		if day.Date.Before(from) {
+			from = day.Date
+		}
+		if day.Date.After(to) {
+			to = day.Date
+		}
+	}
+	existing, err := u.repo.GetDays(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, day := range existing {
+		taken[day.Date.Format(dateLayout)] = true
+	}
+
+	var created []domain.CalendarDay
+	for _, day := range days {
+		key := day.Date.Format(dateLayout)
+		if taken[key] {
+			response.Skipped = append(response.Skipped, domain.CalendarImportSkipped{
+				Date: key, Name: day.Name, Reason: "the date is already on the calendar",
+			})
+			continue
+		}
+		taken[key] = true
+		created = append(created, day)
+	}
+	if len(created) > 0 {
+		if err := u.repo.CreateDays(ctx, created); err != nil {
+			return nil, err
+		}
+	}
+	response.Created = domain.NewCalendarDayResponses(created)
+	return response, nil
+}
+
+func (u *calendarUsecase) ClosedDays(ctx context.Context, from, to time.Time) (map[string]domain.CalendarDay, error) {
+	days, err := u.repo.GetDays(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	closed := make(map[string]domain.CalendarDay, len(days))
+	for _, day := range days {
+		if day.Closed() {
+			closed[day.Date.Format(dateLayout)] = day
+		}
+	}
+	return closed, nil
+}
+
+func (u *calendarUsecase) Hours(ctx context.Context, t time.Time) (domain.OpeningHours, error) {
+	day := today(t)
+	days, err := u.repo.GetDays(ctx, day, day)
+	if err != nil {
+		return domain.OpeningHours{}, err
+	}
+	var entry *domain.CalendarDay
+	if len(days) > 0 {
+		entry = &days[0]
+	}
+	return u.hoursOn(day, entry), nil
+}
+
+// WorkingDays returns the opening hours of every day between from and to,
+// both included, the base of leave, payroll and billing calculations
+func (u *calendarUsecase) WorkingDays(ctx context.Context, from, to time.Time) ([]domain.OpeningHours, error) {
+	if err := checkCalendarRange(from, to); err != nil {
+		return nil, err
+	}
+	days, err := u.repo.GetDays(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*domain.CalendarDay, len(days))
+	for i := range days {
+		entries[days[i].Date.Format(dateLayout)] = &days[i]
+	}
+
+	var hours []domain.OpeningHours
+	for day := today(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		hours = append(hours, u.hoursOn(day, entries[day.Format(dateLayout)]))
+	}
+	return hours, nil
+}
+
+// hoursOn applies the calendar entry of the day, if any, over the regular
+// hours and workdays of the center
+func (u *calendarUsecase) hoursOn(day time.Time, entry *domain.CalendarDay) domain.OpeningHours {
+	hours := domain.OpeningHours{Date: day, Open: true, Opens: u.opens, Closes: u.closes}
+	switch {
+	case entry != nil && entry.Closed():
+		hours.Open, hours.Kind, hours.Name = false, entry.Kind, entry.Name
+	case entry != nil:
+		hours.Kind, hours.Name = entry.Kind, entry.Name
+		hours.Opens, hours.Closes = clockOffset(entry.OpensAt), clockOffset(entry.ClosesAt)
+	case !slices.Contains(u.workdays, domain.Weekdays[day.Weekday()]):
+		hours.Open, hours.Kind = false, domain.CalendarWeekend
+	}
+	return hours
+}
+
+// ListEvents returns the events between from and to the user may see.
+// Parents do not see staff only events.
+func (u *calendarUsecase) ListEvents(ctx context.Context, userId uint, from, to time.Time) ([]domain.CalendarEvent, error) {
+	if err := checkCalendarRange(from, to); err != nil {
+		return nil, err
+	}
+	audiences, err := u.audiencesFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.GetEvents(ctx, from, to, audiences)
+}
+
+func (u *calendarUsecase) CreateEvent(ctx context.Context, userId uint, input domain.CalendarEventRequest) (*domain.CalendarEvent, error) {
+	event := &domain.CalendarEvent{CreatedBy: userId}
+	if err := applyEventRequest(event, input); err != nil {
+		return nil, err
+	}
+	if err := u.repo.CreateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (u *calendarUsecase) UpdateEvent(ctx context.Context, id uint, input domain.CalendarEventRequest) (*domain.CalendarEvent, error) {
+	event, err := u.repo.GetEventById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyEventRequest(event, input); err != nil {
+		return nil, err
+	}
+	if err := u.repo.UpdateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (u *calendarUsecase) DeleteEvent(ctx context.Context, id uint) error {
+	event, err := u.repo.GetEventById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.repo.DeleteEvent(ctx, event)
+}
+
+// RotateFeedToken issues a new secret feed URL for the user, the previous
+// URL stops working
+func (u *calendarUsecase) RotateFeedToken(ctx context.Context, userId uint) (*domain.CalendarFeedResponse, error) {
+	token, hash, err := newVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.SaveFeedToken(ctx, &domain.CalendarFeedToken{UserID: userId, TokenHash: hash}); err != nil {
+		return nil, err
+	}
+	return &domain.CalendarFeedResponse{URL: u.cfg.AppBaseURL + "/api/v1/calendar/feed/" + token}, nil
+}
+
+// Feed returns the calendar of the owner of the token: closures, half days
+// and the events of their audience from a month ago to a year ahead
+func (u *calendarUsecase) Feed(ctx context.Context, token string) (*ical.Calendar, error) {
+	feedToken, err := u.repo.GetFeedToken(ctx, hashVerificationToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("calendar feed not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	audiences, err := u.audiencesFor(ctx, feedToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := today(u.now()).AddDate(0, 0, -feedPastDays)
+	to := from.AddDate(0, 0, feedPastDays+feedFutureDays)
+	days, err := u.repo.GetDays(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	events, err := u.repo.GetEvents(ctx, from, to, audiences)
+	if err != nil {
+		return nil, err
+	}
+
+	host := "daycare"
+	if base, err := url.Parse(u.cfg.AppBaseURL); err == nil && base.Host != "" {
+		host = base.Host
+	}
+	calendar := &ical.Calendar{ProdID: "-//" + u.cfg.AppName + "//Center Calendar//EN", Name: u.cfg.AppName}
+	for _, day := range days {
+		event := ical.Event{
+			UID:     fmt.Sprintf("day-%d@%s", day.ID, host),
+			Summary: day.Name + " (center closed)",
+			Start:   day.Date,
+			End:     day.Date.AddDate(0, 0, 1),
+			AllDay:  true,
+		}
+		if !day.Closed() {
+			event.Summary = fmt.Sprintf("%s (open %s-%s)", day.Name, day.OpensAt, day.ClosesAt)
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+	for _, event := range events {
+		feedEvent := ical.Event{
+			UID:         fmt.Sprintf("event-%d@%s", event.ID, host),
+			Summary:     event.Title,
+			Description: event.Description,
+			Location:    event.Location,
+			Start:       event.StartsAt,
+			End:         event.EndsAt,
+			AllDay:      event.AllDay,
+		}
+		if event.AllDay {
+			feedEvent.End = event.EndsAt.AddDate(0, 0, 1)
+		}
+		calendar.Events = append(calendar.Events, feedEvent)
+	}
+	return calendar, nil
+}
+
+// audiencesFor returns the event audiences the user sees, nil for all of
+// them
+func (u *calendarUsecase) audiencesFor(ctx context.Context, userId uint) ([]string, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) || hasRole(user.Roles, domain.RolePsychologist) {
+		return nil, nil
+	}
+	return []string{domain.AudienceEveryone, domain.AudienceParents}, nil
+}
+
+func applyEventRequest(event *domain.CalendarEvent, input domain.CalendarEventRequest) error {
+	parse := utils.ParseDateTimeStringToTime
+	if input.AllDay {
+		parse = utils.ParseDateStringToTime
+	}
+	var fieldErrors []types.FieldError
+	startsAt, err := parse(input.StartsAt)
+	if err != nil {
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "startsAt", Message: err.Error()})
+	}
+	endsAt, err := parse(input.EndsAt)
+	if err != nil {
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "endsAt", Message: err.Error()})
+	}
+	if len(fieldErrors) > 0 {
+		return apperror.Validation(fieldErrors...)
+	}
+	if endsAt.Before(*startsAt) {
+		return apperror.Validation(types.FieldError{Field: "endsAt", Message: "endsAt must not be before startsAt"})
+	}
+
+	event.Title = input.Title
+	event.Description = input.Description
+	event.Location = input.Location
+	event.StartsAt, event.EndsAt = *startsAt, *endsAt
+	event.AllDay = input.AllDay
+	event.Audience = input.Audience
+	if event.Audience == "" {
+		event.Audience = domain.AudienceEveryone
+	}
+	return nil
+}
+
+func checkCalendarRange(from, to time.Time) error {
+	if to.Before(from) {
+		return apperror.Validation(types.FieldError{Field: "to", Message: "to must not be before from"})
+	}
+	if to.Sub(from) > maxCalendarRange*24*time.Hour {
+		return apperror.Validation(types.FieldError{Field: "to", Message: "the range cannot exceed one year"})
+	}
+	return nil
+}
+
+// clockOffset returns the time since midnight of a validated HH:MM value
+func clockOffset(value string) time.Duration {
+	t, _ := time.Parse("15:04", value)
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
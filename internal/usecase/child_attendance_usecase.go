@@ -1,39 +1,177 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
 	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
 )
 
 type ChildAttendanceUsecase interface {
-	ChildArrival(childId uint, date string, arrival string) error
+	ChildArrival(ctx context.Context, childId uint, date string, arrival string) error
+	ChildDeparture(ctx context.Context, childId uint, departure string) error
 }
 
 type childAttendanceUsecase struct {
-	repo repository.ChildAttendanceRepository
+	repo       repository.ChildAttendanceRepository
+	enrollment EnrollmentChecker
+	calendar   CenterCalendar
+	compliance ComplianceChecker
+	events     EventPublisher
+	notify     Notifier
 }
 
-func NewChildAttendanceUsecase(repo repository.ChildAttendanceRepository) ChildAttendanceUsecase {
-	return &childAttendanceUsecase{repo}
+// Children arriving more than this before opening or leaving more than this
+// after closing are charged overtime
+const childOvertimeGrace = 15 * time.Minute
+
+var errChildAlreadyArrived = apperror.Conflict("child has already arrived and not departed on this day").WithCode(apperror.CodeChildAlreadyArrived)
+
+func NewChildAttendanceUsecase(repo repository.ChildAttendanceRepository, enrollment EnrollmentChecker, calendar CenterCalendar, compliance ComplianceChecker, events EventPublisher, notify Notifier) ChildAttendanceUsecase {
+	return &childAttendanceUsecase{repo, enrollment, calendar, compliance, events, notify}
 }
 
-func (u *childAttendanceUsecase) ChildArrival(childId uint, date string, arrival string) error {
+func (u *childAttendanceUsecase) ChildArrival(ctx context.Context, childId uint, date string, arrival string) error {
+	var fieldErrors []types.FieldError
+
 	parsedDate, err := utils.ParseDateStringToTime(date)
 	if err != nil {
-		return err
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "date", Message: err.Error()})
 	}
 
 	parsedArrival, err := utils.ParseDateTimeStringToTime(arrival)
+	if err != nil {
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "arrival", Message: err.Error()})
+	}
+
+	if len(fieldErrors) > 0 {
+		return apperror.Validation(fieldErrors...)
+	}
+
+	hours, err := u.calendar.Hours(ctx, *parsedDate)
 	if err != nil {
 		return err
 	}
+	if !hours.Open {
+		metrics.ChildArrivalRejected()
+		return apperror.Conflict("the center is closed on this day").WithCode(apperror.CodeCenterClosed)
+	}
+
+	// Only children enrolled on that day may attend, paused children too
+	// are rejected
+	enrolled, err := u.enrollment.IsEnrolled(ctx, childId, *parsedDate)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		metrics.ChildArrivalRejected()
+		return apperror.Conflict("child is not enrolled on this day").WithCode(apperror.CodeChildNotEnrolled)
+	}
+
+	// A second arrival before the departure, such as a double tap, is
+	// rejected, the unique open date catches concurrent ones
+	_, err = u.repo.GetOpenByChildAndDate(ctx, childId, *parsedDate)
+	if err == nil {
+		return errChildAlreadyArrived
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 
 	childAttendance := domain.ChildAttendance{
 		ChildID:         childId,
 		Date:            *parsedDate,
 		Arrival:         *parsedArrival,
-		OvertimeMorning: utils.CalculateChildMorningOvertime(*parsedArrival),
+		OpenDate:        parsedDate,
+		OvertimeMorning: utils.CalculateChildMorningOvertime(*parsedArrival, hours.OpensAt().Add(-childOvertimeGrace)),
+	}
+	if err := u.repo.Create(ctx, &childAttendance); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errChildAlreadyArrived
+		}
+		return err
+	}
+
+	metrics.ChildArrival()
+	metrics.ChildOvertimeBlocks(metrics.PeriodMorning, childAttendance.OvertimeMorning)
+	u.compliance.CheckChild(ctx, childId, TriggerChildArrival)
+	u.events.Publish(ctx, domain.EventChildArrived, childAudience(childId), domain.ChildAttendanceEvent{
+		ChildID:      childId,
+		AttendanceID: childAttendance.ID,
+		Arrival:      childAttendance.Arrival,
+	})
+	u.notify.NotifyParents(ctx, childId, domain.NotificationChildArrived, attendanceKey(domain.NotificationChildArrived, &childAttendance), map[string]string{
+		"time": childAttendance.Arrival.Format("15:04"),
+	})
+	return nil
+}
+
+// ChildDeparture closes the open attendance of the child on the day of
+// departure and records the evening overtime.
+func (u *childAttendanceUsecase) ChildDeparture(ctx context.Context, childId uint, departure string) error {
+	parsedDeparture, err := utils.ParseDateTimeStringToTime(departure)
+	if err != nil {
+		return apperror.Validation(types.FieldError{Field: "departure", Message: err.Error()})
+	}
+
+	childAttendance, err := u.repo.GetOpenByChildAndDate(ctx, childId, *parsedDeparture)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Conflict("child has no open arrival on this day")
 	}
-	return u.repo.Create(&childAttendance)
+	if err != nil {
+		return err
+	}
+	if parsedDeparture.Before(childAttendance.Arrival) {
+		return apperror.Validation(types.FieldError{Field: "departure", Message: "departure must be after the arrival"})
+	}
+
+	hours, err := u.calendar.Hours(ctx, childAttendance.Date)
+	if err != nil {
+		return err
+	}
+
+	childAttendance.Departure = parsedDeparture
+	childAttendance.OpenDate = nil
+	childAttendance.OvertimeEvening = utils.CalculateChildEveningOvertime(*parsedDeparture, hours.ClosesAt().Add(childOvertimeGrace))
+	departed, err := newOutboxEvent(domain.WebhookChildAttendanceDeparted, domain.ChildAttendanceDepartedData{
+		AttendanceID:    childAttendance.ID,
+		ChildID:         childAttendance.ChildID,
+		Date:            childAttendance.Date.Format(dateLayout),
+		Arrival:         childAttendance.Arrival,
+		Departure:       *childAttendance.Departure,
+		OvertimeMorning: childAttendance.OvertimeMorning,
+		OvertimeEvening: childAttendance.OvertimeEvening,
+	})
+	if err != nil {
+		return err
+	}
+	if err := u.repo.Update(ctx, childAttendance, departed); err != nil {
+		return err
+	}
+
+	metrics.ChildOvertimeBlocks(metrics.PeriodEvening, childAttendance.OvertimeEvening)
+	u.compliance.CheckChild(ctx, childId, TriggerChildDeparture)
+	u.events.Publish(ctx, domain.EventChildDeparted, childAudience(childId), domain.ChildAttendanceEvent{
+		ChildID:      childId,
+		AttendanceID: childAttendance.ID,
+		Arrival:      childAttendance.Arrival,
+		Departure:    childAttendance.Departure,
+	})
+	u.notify.NotifyParents(ctx, childId, domain.NotificationChildDeparted, attendanceKey(domain.NotificationChildDeparted, childAttendance), map[string]string{
+		"time": childAttendance.Departure.Format("15:04"),
+	})
+	return nil
+}
+
+// attendanceKey notifies the parents once per arrival or departure
+func attendanceKey(notificationType string, childAttendance *domain.ChildAttendance) string {
+	return fmt.Sprintf("%s:%d", notificationType, childAttendance.ID)
 }
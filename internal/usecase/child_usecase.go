@@ -1,18 +1,18 @@
 package usecase
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/repository"
 )
 
 type ChildUsecase interface {
-	CreateChild(child *domain.Child) error
-	CheckUserAdmin(userId uint) (bool, error)
-	ValidateRequiredFields(requestData *domain.CreateChildRequest) []string
-	ParseUserIds(userIds string) ([]domain.User, error)
-	GetChild(id string) (*domain.Child, error)
+	CreateChild(ctx context.Context, actorId uint, child *domain.Child) error
+	CheckUserAdmin(ctx context.Context, userId uint) (bool, error)
+	ParseUserIds(ctx context.Context, userIds []uint) ([]domain.User, error)
+	GetChild(ctx context.Context, id string) (*domain.Child, error)
 }
 
 type childUsecase struct {
@@ -23,63 +23,48 @@ func NewChildUsecase(repo repository.ChildRepository) ChildUsecase {
 	return &childUsecase{repo}
 }
 
-func (u *childUsecase) GetChild(id string) (*domain.Child, error) {
-	return u.repo.GetChild(id)
+func (u *childUsecase) GetChild(ctx context.Context, id string) (*domain.Child, error) {
+	return u.repo.GetChild(ctx, id)
 }
 
-func (u *childUsecase) CreateChild(child *domain.Child) error {
-	return u.repo.Create(child)
+// CreateChild saves the child with an inquiry enrollment from its registered
+// date. The child attends once the enrollment is offered a seat and
+// enrolled, its programme and schedule default to full-day daycare on
+// weekdays and may be changed until then.
+func (u *childUsecase) CreateChild(ctx context.Context, actorId uint, child *domain.Child) error {
+	startDate := today(child.RegisteredDate)
+	enrollment := &domain.Enrollment{
+		Status:       domain.EnrollmentInquiry,
+		Programme:    domain.ProgrammeDaycare,
+		Session:      domain.SessionFullDay,
+		ScheduleDays: domain.ScheduleDays([]string{"mon", "tue", "wed", "thu", "fri"}),
+		StartDate:    &startDate,
+	}
+	event := &domain.EnrollmentEvent{ToStatus: domain.EnrollmentInquiry, ChangedBy: &actorId}
+	return u.repo.Create(ctx, child, enrollment, event)
 }
 
-func (u *childUsecase) CheckUserAdmin(userId uint) (bool, error) {
-	user, err := u.repo.GetUserWithRoles(userId)
+func (u *childUsecase) CheckUserAdmin(ctx context.Context, userId uint) (bool, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
 	if err != nil {
 		return false, err
 	}
 	for _, role := range user.Roles {
-		if role.Name == "admin" {
+		if role.Name == domain.RoleAdmin {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (u *childUsecase) ValidateRequiredFields(requestData *domain.CreateChildRequest) []string {
-	var errors []string
-	if requestData.Name == "" {
-		errors = append(errors, "name is required")
-	}
-	if requestData.Nickname == "" {
-		errors = append(errors, "nickname is required")
-	}
-	if requestData.BirthPlace == "" {
-		errors = append(errors, "birthPlace is required")
-	}
-	if requestData.BirthDate == "" {
-		errors = append(errors, "birthDate is required")
-	}
-	if requestData.Gender == "" {
-		errors = append(errors, "gender is required")
-	}
-	if requestData.LivingWith == "" {
-		errors = append(errors, "livingWith is required")
-	}
-	if requestData.RegisteredDate == "" {
-		errors = append(errors, "registeredDate is required")
-	}
-	if requestData.Parents == "" || requestData.Parents == "[]" {
-		errors = append(errors, "parents is required")
-	}
-	if requestData.Teachers == "" || requestData.Teachers == "[]" {
-		errors = append(errors, "teachers is required")
-	}
-	return errors
-}
-
-func (u *childUsecase) ParseUserIds(userIds string) ([]domain.User, error) {
-	var userIdsArray []uint
-	if err := json.Unmarshal([]byte(userIds), &userIdsArray); err != nil {
+// ParseUserIds loads the users for the given ids and fails if any id is unknown
+func (u *childUsecase) ParseUserIds(ctx context.Context, userIds []uint) ([]domain.User, error) {
+	users, err := u.repo.GetUsersByIds(ctx, userIds)
+	if err != nil {
 		return nil, err
 	}
-	return u.repo.GetUsersByIds(userIdsArray)
+	if len(users) != len(userIds) {
+		return nil, fmt.Errorf("contains unknown user id")
+	}
+	return users, nil
 }
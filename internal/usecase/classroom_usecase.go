@@ -0,0 +1,398 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Events that re-evaluate classroom ratios, recorded on the alert
+const (
+	TriggerTeacherClockIn  = "teacher_clock_in"
+	TriggerTeacherClockOut = "teacher_clock_out"
+	TriggerChildArrival    = "child_arrival"
+	TriggerChildDeparture  = "child_departure"
+)
+
+// ComplianceChecker re-evaluates the ratio of the classrooms affected by an
+// attendance change. Failures are logged, they never fail the attendance.
+type ComplianceChecker interface {
+	CheckTeacher(ctx context.Context, userId uint, trigger string)
+	CheckChild(ctx context.Context, childId uint, trigger string)
+}
+
+type ClassroomUsecase interface {
+	ComplianceChecker
+	ListClassrooms(ctx context.Context) ([]domain.Classroom, error)
+	GetClassroom(ctx context.Context, id uint) (*domain.Classroom, error)
+	CreateClassroom(ctx context.Context, input domain.ClassroomRequest) (*domain.Classroom, error)
+	UpdateClassroom(ctx context.Context, id uint, input domain.ClassroomRequest) (*domain.Classroom, error)
+	SetTeachers(ctx context.Context, id uint, teachers []domain.ClassroomTeacherRequest) (*domain.Classroom, error)
+	AddChild(ctx context.Context, id uint, childId uint) (*domain.Classroom, error)
+	RemoveChild(ctx context.Context, id uint, childId uint) error
+	Ratios(ctx context.Context) ([]domain.ClassroomRatio, error)
+	Ratio(ctx context.Context, id uint) (*domain.ClassroomRatio, error)
+	Alerts(ctx context.Context, filter domain.ClassroomAlertFilter) ([]domain.ClassroomAlert, error)
+}
+
+type classroomUsecase struct {
+	repo repository.ClassroomRepository
+	now  func() time.Time
+}
+
+func NewClassroomUsecase(repo repository.ClassroomRepository) ClassroomUsecase {
+	return &classroomUsecase{repo, time.Now}
+}
+
+func (u *classroomUsecase) ListClassrooms(ctx context.Context) ([]domain.Classroom, error) {
+	return u.repo.GetAll(ctx)
+}
+
+func (u *classroomUsecase) GetClassroom(ctx context.Context, id uint) (*domain.Classroom, error) {
+	return u.repo.GetById(ctx, id)
+}
+
+func (u *classroomUsecase) CreateClassroom(ctx context.Context, input domain.ClassroomRequest) (*domain.Classroom, error) {
+	name := strings.TrimSpace(input.Name)
+	if err := u.checkNameFree(ctx, name, 0); err != nil {
+		return nil, err
+	}
+
+	classroom := &domain.Classroom{
+		Name:          name,
+		MinAgeMonths:  input.MinAgeMonths,
+		MaxAgeMonths:  input.MaxAgeMonths,
+		Capacity:      input.Capacity,
+		ChildrenRatio: input.ChildrenRatio,
+	}
+	if err := u.repo.Create(ctx, classroom); err != nil {
+		return nil, err
+	}
+	return classroom, nil
+}
+
+func (u *classroomUsecase) UpdateClassroom(ctx context.Context, id uint, input domain.ClassroomRequest) (*domain.Classroom, error) {
+	classroom, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if err := u.checkNameFree(ctx, name, classroom.ID); err != nil {
+		return nil, err
+	}
+	if input.Capacity < len(classroom.Children) {
+		return nil, apperror.Validation(types.FieldError{
+			Field:   "capacity",
+			Message: fmt.Sprintf("capacity cannot be below the %d enrolled children", len(classroom.Children)),
+		})
+	}
+
+	classroom.Name = name
+	classroom.MinAgeMonths = input.MinAgeMonths
+	classroom.MaxAgeMonths = input.MaxAgeMonths
+	classroom.Capacity = input.Capacity
+	classroom.ChildrenRatio = input.ChildrenRatio
+	if err := u.repo.Update(ctx, classroom); err != nil {
+		return nil, err
+	}
+
+	u.evaluate(ctx, classroom, "classroom_updated")
+	return classroom, nil
+}
+
+// SetTeachers replaces the roster. Every user must have the teacher role and
+// there is at most one lead teacher.
+func (u *classroomUsecase) SetTeachers(ctx context.Context, id uint, teachers []domain.ClassroomTeacherRequest) (*domain.Classroom, error) {
+	classroom, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := make([]uint, len(teachers))
+	leads := 0
+	for i, teacher := range teachers {
+		userIds[i] = teacher.UserID
+		if teacher.Role == domain.ClassroomRoleLead {
+			leads++
+		}
+	}
+	if leads > 1 {
+		return nil, apperror.Validation(types.FieldError{Field: "teachers", Message: "a classroom has at most one lead teacher"})
+	}
+
+	var fields []types.FieldError
+	if len(userIds) > 0 {
+		users, err := u.repo.GetUsersWithRoles(ctx, userIds)
+		if err != nil {
+			return nil, err
+		}
+		usersById := make(map[uint]domain.User, len(users))
+		for _, user := range users {
+			usersById[user.ID] = user
+		}
+		seen := make(map[uint]bool, len(teachers))
+		for i, teacher := range teachers {
+			field := fmt.Sprintf("teachers[%d].userId", i)
+			user, ok := usersById[teacher.UserID]
+			switch {
+			case seen[teacher.UserID]:
+				fields = append(fields, types.FieldError{Field: field, Message: "teacher is listed twice"})
+			case !ok:
+				fields = append(fields, types.FieldError{Field: field, Message: "unknown user id"})
+			case !hasRole(user.Roles, domain.RoleTeacher):
+				fields = append(fields, types.FieldError{Field: field, Message: "user does not have the teacher role"})
+			}
+			seen[teacher.UserID] = true
+		}
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation(fields...)
+	}
+
+	roster := make([]domain.ClassroomTeacher, len(teachers))
+	for i, teacher := range teachers {
+		roster[i] = domain.ClassroomTeacher{ClassroomID: classroom.ID, UserID: teacher.UserID, Role: teacher.Role}
+	}
+	if err := u.repo.ReplaceTeachers(ctx, classroom.ID, roster); err != nil {
+		return nil, err
+	}
+
+	u.evaluate(ctx, classroom, "roster_updated")
+	return u.repo.GetById(ctx, classroom.ID)
+}
+
+// AddChild places an enrolled child in the classroom, checking the age range
+// and the capacity, where pending waitlist offers hold a seat. A child is in
+// at most one classroom.
+func (u *classroomUsecase) AddChild(ctx context.Context, id uint, childId uint) (*domain.Classroom, error) {
+	classroom, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	child, err := u.repo.GetChild(ctx, childId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Validation(types.FieldError{Field: "childId", Message: "unknown child id"})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if child.ClassroomID != nil && *child.ClassroomID == classroom.ID {
+		return classroom, nil
+	}
+	if child.ClassroomID != nil {
+		return nil, apperror.Conflict("child is already in another classroom, remove it there first")
+	}
+	enrolled, err := u.repo.HasActiveEnrollment(ctx, child.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled {
+		return nil, apperror.Conflict("child is not enrolled").WithCode(apperror.CodeChildNotEnrolled)
+	}
+
+	age := ageInMonths(child.BirthDate, u.now())
+	if age < classroom.MinAgeMonths || age > classroom.MaxAgeMonths {
+		return nil, apperror.Validation(types.FieldError{
+			Field:   "childId",
+			Message: fmt.Sprintf("child is %d months old, classroom takes %d to %d months", age, classroom.MinAgeMonths, classroom.MaxAgeMonths),
+		})
+	}
+
+	placed, err := u.repo.CountChildren(ctx, classroom.ID)
+	if err != nil {
+		return nil, err
+	}
+	offered, err := u.repo.CountPendingOffers(ctx, classroom.ID, u.now())
+	if err != nil {
+		return nil, err
+	}
+	if int(placed+offered) >= classroom.Capacity {
+		return nil, apperror.Conflict("classroom is at capacity")
+	}
+
+	if err := u.repo.SetChildClassroom(ctx, child.ID, &classroom.ID); err != nil {
+		return nil, err
+	}
+	return u.repo.GetById(ctx, classroom.ID)
+}
+
+func (u *classroomUsecase) RemoveChild(ctx context.Context, id uint, childId uint) error {
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		return err
+	}
+	if child.ClassroomID == nil || *child.ClassroomID != id {
+		return apperror.NotFound("child is not in this classroom")
+	}
+	return u.repo.SetChildClassroom(ctx, child.ID, nil)
+}
+
+func (u *classroomUsecase) Ratios(ctx context.Context) ([]domain.ClassroomRatio, error) {
+	classrooms, err := u.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ratios := make([]domain.ClassroomRatio, 0, len(classrooms))
+	for i := range classrooms {
+		ratio, err := u.ratio(ctx, &classrooms[i])
+		if err != nil {
+			return nil, err
+		}
+		ratios = append(ratios, *ratio)
+	}
+	return ratios, nil
+}
+
+func (u *classroomUsecase) Ratio(ctx context.Context, id uint) (*domain.ClassroomRatio, error) {
+	classroom, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.ratio(ctx, classroom)
+}
+
+func (u *classroomUsecase) Alerts(ctx context.Context, filter domain.ClassroomAlertFilter) ([]domain.ClassroomAlert, error) {
+	return u.repo.GetAlerts(ctx, filter)
+}
+
+func (u *classroomUsecase) CheckTeacher(ctx context.Context, userId uint, trigger string) {
+	classroomIds, err := u.repo.GetClassroomIdsByTeacher(ctx, userId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load classrooms of teacher", "user_id", userId, "error", err.Error())
+		return
+	}
+	for _, classroomId := range classroomIds {
+		u.evaluateById(ctx, classroomId, trigger)
+	}
+}
+
+func (u *classroomUsecase) CheckChild(ctx context.Context, childId uint, trigger string) {
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load child for ratio check", "child_id", childId, "error", err.Error())
+		return
+	}
+	if child.ClassroomID != nil {
+		u.evaluateById(ctx, *child.ClassroomID, trigger)
+	}
+}
+
+func (u *classroomUsecase) evaluateById(ctx context.Context, classroomId uint, trigger string) {
+	classroom, err := u.repo.GetById(ctx, classroomId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load classroom for ratio check", "classroom_id", classroomId, "error", err.Error())
+		return
+	}
+	u.evaluate(ctx, classroom, trigger)
+}
+
+// evaluate opens an alert when the classroom falls out of compliance and
+// resolves the open alert once it is compliant again.
+func (u *classroomUsecase) evaluate(ctx context.Context, classroom *domain.Classroom, trigger string) {
+	log := logger.FromContext(ctx).With("classroom_id", classroom.ID, "trigger", trigger)
+
+	ratio, err := u.ratio(ctx, classroom)
+	if err != nil {
+		log.Error("failed to compute classroom ratio", "error", err.Error())
+		return
+	}
+
+	open, err := u.repo.GetOpenAlert(ctx, classroom.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("failed to load open classroom alert", "error", err.Error())
+		return
+	}
+	hasOpen := err == nil
+
+	switch {
+	case !ratio.Compliant && !hasOpen:
+		alert := &domain.ClassroomAlert{
+			ClassroomID:     classroom.ID,
+			ChildrenPresent: ratio.ChildrenPresent,
+			StaffPresent:    ratio.StaffPresent,
+			RequiredStaff:   ratio.RequiredStaff,
+			Trigger:         trigger,
+		}
+		if err := u.repo.CreateAlert(ctx, alert); err != nil {
+			log.Error("failed to open classroom alert", "error", err.Error())
+			return
+		}
+		metrics.ClassroomRatioAlert()
+		log.Warn("classroom out of ratio compliance",
+			"children_present", ratio.ChildrenPresent, "staff_present", ratio.StaffPresent, "required_staff", ratio.RequiredStaff)
+	case ratio.Compliant && hasOpen:
+		now := u.now()
+		open.ResolvedAt = &now
+		if err := u.repo.ResolveAlert(ctx, open); err != nil {
+			log.Error("failed to resolve classroom alert", "error", err.Error())
+			return
+		}
+		log.Info("classroom back in ratio compliance", "alert_id", open.ID)
+	}
+}
+
+func (u *classroomUsecase) ratio(ctx context.Context, classroom *domain.Classroom) (*domain.ClassroomRatio, error) {
+	today := u.now()
+	children, err := u.repo.CountPresentChildren(ctx, classroom.ID, today)
+	if err != nil {
+		return nil, err
+	}
+	staff, err := u.repo.CountPresentStaff(ctx, classroom.ID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	required := requiredStaff(int(children), classroom.ChildrenRatio)
+	return &domain.ClassroomRatio{
+		ClassroomID:     classroom.ID,
+		Name:            classroom.Name,
+		ChildrenRatio:   classroom.ChildrenRatio,
+		ChildrenPresent: int(children),
+		StaffPresent:    int(staff),
+		RequiredStaff:   required,
+		Compliant:       int(staff) >= required,
+	}, nil
+}
+
+func (u *classroomUsecase) checkNameFree(ctx context.Context, name string, exceptId uint) error {
+	if name == "" {
+		return apperror.Validation(types.FieldError{Field: "name", Message: "name is required"})
+	}
+	exists, err := u.repo.NameExists(ctx, name, exceptId)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperror.Conflict("classroom name already exists")
+	}
+	return nil
+}
+
+// requiredStaff is the number of staff needed for children at a ratio of
+// childrenRatio children per staff member, rounded up.
+func requiredStaff(children, childrenRatio int) int {
+	if children <= 0 || childrenRatio <= 0 {
+		return 0
+	}
+	return (children + childrenRatio - 1) / childrenRatio
+}
+
+func ageInMonths(birthDate, now time.Time) int {
+	months := (now.Year()-birthDate.Year())*12 + int(now.Month()) - int(birthDate.Month())
+	if now.Day() < birthDate.Day() {
+		months--
+	}
+	return months
+}
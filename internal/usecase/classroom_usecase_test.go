@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// fakeClassroomRepository has one classroom with a head count and keeps its
+// alerts
+type fakeClassroomRepository struct {
+	repository.ClassroomRepository
+	classroom *domain.Classroom
+	children  int64
+	staff     int64
+	alerts    []*domain.ClassroomAlert
+}
+
+func (r *fakeClassroomRepository) GetById(ctx context.Context, id uint) (*domain.Classroom, error) {
+	return r.classroom, nil
+}
+
+func (r *fakeClassroomRepository) CountPresentChildren(ctx context.Context, classroomId uint, day time.Time) (int64, error) {
+	return r.children, nil
+}
+
+func (r *fakeClassroomRepository) CountPresentStaff(ctx context.Context, classroomId uint, day time.Time) (int64, error) {
+	return r.staff, nil
+}
+
+func (r *fakeClassroomRepository) GetOpenAlert(ctx context.Context, classroomId uint) (*domain.ClassroomAlert, error) {
+	for _, alert := range r.alerts {
+		if alert.ResolvedAt == nil {
+			return alert, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeClassroomRepository) CreateAlert(ctx context.Context, alert *domain.ClassroomAlert) error {
+	alert.ID = uint(len(r.alerts) + 1)
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeClassroomRepository) ResolveAlert(ctx context.Context, alert *domain.ClassroomAlert) error {
+	return nil
+}
+
+func newTestClassroomUsecase(children, staff int64, now time.Time) (*classroomUsecase, *fakeClassroomRepository) {
+	repo := &fakeClassroomRepository{
+		classroom: &domain.Classroom{ID: 1, Name: "Butterflies", ChildrenRatio: 4},
+		children:  children,
+		staff:     staff,
+	}
+	return &classroomUsecase{repo: repo, now: func() time.Time { return now }}, repo
+}
+
+func TestRatioCompliance(t *testing.T) {
+	tests := []struct {
+		name      string
+		children  int64
+		staff     int64
+		required  int
+		compliant bool
+	}{
+		{"nobody present", 0, 0, 0, true},
+		{"staff without children", 0, 1, 0, true},
+		{"below the ratio", 7, 2, 2, true},
+		{"exactly at the ratio", 8, 2, 2, true},
+		{"one child above the ratio", 9, 2, 3, false},
+		{"children without staff", 1, 0, 1, false},
+	}
+	for _, tt := range tests {
+		u, _ := newTestClassroomUsecase(tt.children, tt.staff, time.Now())
+		ratio, err := u.Ratio(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ratio.RequiredStaff != tt.required || ratio.Compliant != tt.compliant {
+			t.Errorf("%s: required %d, compliant %v, want %d, %v", tt.name, ratio.RequiredStaff, ratio.Compliant, tt.required, tt.compliant)
+		}
+	}
+}
+
+func TestRequiredStaffRoundsUp(t *testing.T) {
+	tests := []struct {
+		children, childrenRatio, want int
+	}{
+		{0, 4, 0},
+		{1, 4, 1},
+		{4, 4, 1},
+		{5, 4, 2},
+		{12, 3, 4},
+		{13, 3, 5},
+		{5, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := requiredStaff(tt.children, tt.childrenRatio); got != tt.want {
+			t.Errorf("requiredStaff(%d, %d) = %d, want %d", tt.children, tt.childrenRatio, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateOpensOneAlertAndResolvesIt(t *testing.T) {
+	now := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	u, repo := newTestClassroomUsecase(9, 2, now)
+	ctx := context.Background()
+
+	u.evaluate(ctx, repo.classroom, TriggerChildArrival)
+	u.evaluate(ctx, repo.classroom, TriggerChildArrival)
+	if len(repo.alerts) != 1 {
+		t.Fatalf("opened %d alerts, want 1", len(repo.alerts))
+	}
+	alert := repo.alerts[0]
+	if alert.ChildrenPresent != 9 || alert.StaffPresent != 2 || alert.RequiredStaff != 3 || alert.Trigger != TriggerChildArrival {
+		t.Errorf("alert = %+v", alert)
+	}
+
+	// Back at the ratio once a child departs
+	repo.children = 8
+	u.evaluate(ctx, repo.classroom, TriggerChildDeparture)
+	if alert.ResolvedAt == nil || !alert.ResolvedAt.Equal(now) {
+		t.Errorf("alert resolved at %v, want %v", alert.ResolvedAt, now)
+	}
+	if len(repo.alerts) != 1 {
+		t.Errorf("opened %d alerts, want 1", len(repo.alerts))
+	}
+}
@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// EnrollmentChecker tells whether a child may attend on a day
+type EnrollmentChecker interface {
+	IsEnrolled(ctx context.Context, childId uint, day time.Time) (bool, error)
+}
+
+type EnrollmentUsecase interface {
+	EnrollmentChecker
+	ListEnrollments(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.EnrollmentListFilter) ([]domain.Enrollment, int, error)
+	GetEnrollment(ctx context.Context, id uint) (*domain.Enrollment, error)
+	CreateEnrollment(ctx context.Context, actorId uint, input domain.EnrollmentRequest) (*domain.Enrollment, error)
+	UpdateEnrollment(ctx context.Context, id uint, input domain.UpdateEnrollmentRequest) (*domain.Enrollment, error)
+	Transition(ctx context.Context, actorId uint, id uint, input domain.EnrollmentTransitionRequest) (*domain.Enrollment, error)
+	Waitlist(ctx context.Context, classroomId uint) ([]domain.Enrollment, error)
+	OfferSeats(ctx context.Context, actorId uint, classroomId uint) (*domain.WaitlistOffersResponse, error)
+}
+
+// enrollmentTransitions lists the statuses each status may move to. Enrolling
+// always goes through an offer so the seat is counted against the capacity.
+var enrollmentTransitions = map[string][]string{
+	domain.EnrollmentInquiry:    {domain.EnrollmentWaitlisted, domain.EnrollmentOffered, domain.EnrollmentWithdrawn},
+	domain.EnrollmentWaitlisted: {domain.EnrollmentOffered, domain.EnrollmentWithdrawn},
+	domain.EnrollmentOffered:    {domain.EnrollmentEnrolled, domain.EnrollmentWaitlisted, domain.EnrollmentWithdrawn},
+	domain.EnrollmentEnrolled:   {domain.EnrollmentPaused, domain.EnrollmentWithdrawn, domain.EnrollmentGraduated},
+	domain.EnrollmentPaused:     {domain.EnrollmentEnrolled, domain.EnrollmentWithdrawn, domain.EnrollmentGraduated},
+}
+
+type enrollmentUsecase struct {
+	repo repository.EnrollmentRepository
+	cfg  *config.Config
+	now  func() time.Time
+}
+
+func NewEnrollmentUsecase(repo repository.EnrollmentRepository, cfg *config.Config) EnrollmentUsecase {
+	return &enrollmentUsecase{repo, cfg, time.Now}
+}
+
+func (u *enrollmentUsecase) ListEnrollments(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.EnrollmentListFilter) ([]domain.Enrollment, int, error) {
+	return u.repo.List(ctx, paginationFilter, filter)
+}
+
+func (u *enrollmentUsecase) GetEnrollment(ctx context.Context, id uint) (*domain.Enrollment, error) {
+	return u.repo.GetById(ctx, id)
+}
+
+// CreateEnrollment records an inquiry, or puts the child straight on the
+// waitlist of a classroom. A child has at most one open enrollment.
+func (u *enrollmentUsecase) CreateEnrollment(ctx context.Context, actorId uint, input domain.EnrollmentRequest) (*domain.Enrollment, error) {
+	var fields []types.FieldError
+
+	if _, err := u.repo.GetChild(ctx, input.ChildID); errors.Is(err, gorm.ErrRecordNotFound) {
+		fields = append(fields, types.FieldError{Field: "childId", Message: "unknown child id"})
+	} else if err != nil {
+		return nil, err
+	}
+	if input.ClassroomID != nil {
+		fieldErr, err := u.checkClassroom(ctx, *input.ClassroomID)
+		if err != nil {
+			return nil, err
+		}
+		if fieldErr != nil {
+			fields = append(fields, *fieldErr)
+		}
+	}
+
+	status := input.Status
+	if status == "" {
+		status = domain.EnrollmentInquiry
+	}
+	if status == domain.EnrollmentWaitlisted && input.ClassroomID == nil {
+		fields = append(fields, types.FieldError{Field: "classroomId", Message: "classroomId is required to join the waitlist"})
+	}
+
+	var startDate *time.Time
+	if input.StartDate != "" {
+		startDate, _ = utils.ParseDateStringToTime(input.StartDate)
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation(fields...)
+	}
+
+	open, err := u.repo.HasOpenEnrollment(ctx, input.ChildID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, apperror.Conflict("child already has an open enrollment")
+	}
+
+	enrollment := &domain.Enrollment{
+		ChildID:      input.ChildID,
+		ClassroomID:  input.ClassroomID,
+		Status:       status,
+		Programme:    input.Programme,
+		Session:      input.Session,
+		ScheduleDays: domain.ScheduleDays(input.ScheduleDays),
+		StartDate:    startDate,
+		Priority:     input.Priority,
+		Notes:        input.Notes,
+	}
+	if status == domain.EnrollmentWaitlisted {
+		now := u.now()
+		enrollment.WaitlistedAt = &now
+	}
+
+	event := &domain.EnrollmentEvent{ToStatus: status, ChangedBy: &actorId}
+	if err := u.repo.Create(ctx, enrollment, event); err != nil {
+		return nil, err
+	}
+	return u.repo.GetById(ctx, enrollment.ID)
+}
+
+func (u *enrollmentUsecase) UpdateEnrollment(ctx context.Context, id uint, input domain.UpdateEnrollmentRequest) (*domain.Enrollment, error) {
+	enrollment, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(domain.OpenEnrollmentStatuses, enrollment.Status) {
+		return nil, apperror.Conflict(fmt.Sprintf("a %s enrollment cannot be changed", enrollment.Status))
+	}
+
+	if input.ClassroomID != nil && (enrollment.ClassroomID == nil || *input.ClassroomID != *enrollment.ClassroomID) {
+		// The seat of an offered or enrolled child belongs to its classroom,
+		// moving an enrolled child is done on the classroom
+		if enrollment.Status != domain.EnrollmentInquiry && enrollment.Status != domain.EnrollmentWaitlisted {
+			return nil, apperror.Conflict(fmt.Sprintf("the classroom of a %s enrollment cannot be changed", enrollment.Status))
+		}
+		fieldErr, err := u.checkClassroom(ctx, *input.ClassroomID)
+		if err != nil {
+			return nil, err
+		}
+		if fieldErr != nil {
+			return nil, apperror.Validation(*fieldErr)
+		}
+		enrollment.ClassroomID = input.ClassroomID
+		enrollment.Classroom = nil
+		if enrollment.Status == domain.EnrollmentWaitlisted {
+			// Moving to another waitlist starts at the back of it
+			now := u.now()
+			enrollment.WaitlistedAt = &now
+		}
+	}
+	if input.Programme != nil {
+		enrollment.Programme = *input.Programme
+	}
+	if input.Session != nil {
+		enrollment.Session = *input.Session
+	}
+	if input.ScheduleDays != nil {
+		enrollment.ScheduleDays = domain.ScheduleDays(input.ScheduleDays)
+	}
+	if input.StartDate != nil {
+		enrollment.StartDate, _ = utils.ParseDateStringToTime(*input.StartDate)
+	}
+	if input.Priority != nil {
+		enrollment.Priority = *input.Priority
+	}
+	if input.Notes != nil {
+		enrollment.Notes = *input.Notes
+	}
+
+	if err := u.repo.Update(ctx, enrollment); err != nil {
+		return nil, err
+	}
+	return u.repo.GetById(ctx, enrollment.ID)
+}
+
+func (u *enrollmentUsecase) Transition(ctx context.Context, actorId uint, id uint, input domain.EnrollmentTransitionRequest) (*domain.Enrollment, error) {
+	enrollment, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(enrollmentTransitions[enrollment.Status], input.Status) {
+		return nil, apperror.Conflict(fmt.Sprintf("a %s enrollment cannot become %s", enrollment.Status, input.Status))
+	}
+
+	now := u.now()
+	date := today(now)
+	if input.Date != "" {
+		parsed, _ := utils.ParseDateStringToTime(input.Date)
+		date = *parsed
+	}
+
+	from := enrollment.Status
+	placeChild := false
+	var placement *uint
+
+	switch input.Status {
+	case domain.EnrollmentWaitlisted:
+		if enrollment.ClassroomID == nil {
+			return nil, apperror.Validation(types.FieldError{Field: "classroomId", Message: "set the classroom before joining the waitlist"})
+		}
+		// A declined offer goes to the back of the waitlist
+		enrollment.WaitlistedAt = &now
+		enrollment.OfferExpiresAt = nil
+	case domain.EnrollmentOffered:
+		if enrollment.ClassroomID == nil {
+			return nil, apperror.Validation(types.FieldError{Field: "classroomId", Message: "set the classroom before offering a seat"})
+		}
+		expires := now.Add(u.cfg.EnrollmentOfferTTL)
+		enrollment.OfferExpiresAt = &expires
+	case domain.EnrollmentEnrolled:
+		if from == domain.EnrollmentOffered {
+			if enrollment.OfferExpiresAt != nil && !now.Before(*enrollment.OfferExpiresAt) {
+				return nil, apperror.Conflict("the offer has expired")
+			}
+			if input.Date != "" || enrollment.StartDate == nil {
+				enrollment.StartDate = &date
+			}
+			enrollment.OfferExpiresAt = nil
+			placeChild, placement = true, enrollment.ClassroomID
+		}
+	case domain.EnrollmentWithdrawn, domain.EnrollmentGraduated:
+		if enrollment.StartDate != nil && date.Before(*enrollment.StartDate) && from != domain.EnrollmentInquiry {
+			return nil, apperror.Validation(types.FieldError{Field: "date", Message: "date cannot be before the start date"})
+		}
+		enrollment.EndDate = &date
+		enrollment.OfferExpiresAt = nil
+		placeChild = from == domain.EnrollmentEnrolled || from == domain.EnrollmentPaused
+	}
+
+	enrollment.Status = input.Status
+	event := &domain.EnrollmentEvent{FromStatus: from, ToStatus: input.Status, ChangedBy: &actorId, Reason: input.Reason}
+	if input.Status == domain.EnrollmentOffered {
+		// The seats are counted again with the classroom locked
+		free, err := u.repo.SaveOffers(ctx, *enrollment.ClassroomID, now, []*domain.Enrollment{enrollment}, []*domain.EnrollmentEvent{event})
+		if err != nil {
+			return nil, err
+		}
+		if free <= 0 {
+			return nil, apperror.Conflict("classroom has no free seat")
+		}
+	} else if err := u.repo.SaveTransition(ctx, enrollment, event, placeChild, placement); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("enrollment status changed",
+		"enrollment_id", enrollment.ID, "child_id", enrollment.ChildID, "from", from, "to", input.Status)
+	return u.repo.GetById(ctx, enrollment.ID)
+}
+
+func (u *enrollmentUsecase) Waitlist(ctx context.Context, classroomId uint) ([]domain.Enrollment, error) {
+	if _, err := u.repo.GetClassroom(ctx, classroomId); err != nil {
+		return nil, err
+	}
+	return u.repo.GetWaitlist(ctx, classroomId)
+}
+
+// OfferSeats offers every free seat of the classroom to the top of its
+// waitlist. Expired offers first go back to the end of the waitlist.
+func (u *enrollmentUsecase) OfferSeats(ctx context.Context, actorId uint, classroomId uint) (*domain.WaitlistOffersResponse, error) {
+	now := u.now()
+	if err := u.expireOffers(ctx, classroomId, now); err != nil {
+		return nil, err
+	}
+
+	free, capacity, err := u.freeSeats(ctx, classroomId, now)
+	if err != nil {
+		return nil, err
+	}
+	response := &domain.WaitlistOffersResponse{Capacity: capacity, FreeSeats: max(free, 0), Offered: []domain.EnrollmentResponse{}}
+	if free <= 0 {
+		return response, nil
+	}
+
+	waitlist, err := u.repo.GetWaitlist(ctx, classroomId)
+	if err != nil {
+		return nil, err
+	}
+	var enrollments []*domain.Enrollment
+	var events []*domain.EnrollmentEvent
+	for i := 0; i < len(waitlist) && i < free; i++ {
+		enrollment := &waitlist[i]
+		expires := now.Add(u.cfg.EnrollmentOfferTTL)
+		enrollment.Status = domain.EnrollmentOffered
+		enrollment.OfferExpiresAt = &expires
+		enrollments = append(enrollments, enrollment)
+		events = append(events, &domain.EnrollmentEvent{FromStatus: domain.EnrollmentWaitlisted, ToStatus: domain.EnrollmentOffered, ChangedBy: &actorId, Reason: "seat offered from the waitlist"})
+	}
+	if len(enrollments) == 0 {
+		return response, nil
+	}
+
+	// The seats are counted again with the classroom locked, a concurrent
+	// offer may have taken some since
+	free, err = u.repo.SaveOffers(ctx, classroomId, now, enrollments, events)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(enrollments) && i < free; i++ {
+		response.Offered = append(response.Offered, *domain.NewEnrollmentResponse(enrollments[i]))
+	}
+	response.FreeSeats = max(free-len(response.Offered), 0)
+	return response, nil
+}
+
+func (u *enrollmentUsecase) IsEnrolled(ctx context.Context, childId uint, day time.Time) (bool, error) {
+	return u.repo.IsEnrolledOn(ctx, childId, day)
+}
+
+func (u *enrollmentUsecase) expireOffers(ctx context.Context, classroomId uint, now time.Time) error {
+	expired, err := u.repo.GetExpiredOffers(ctx, classroomId, now)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		enrollment := &expired[i]
+		enrollment.Status = domain.EnrollmentWaitlisted
+		enrollment.WaitlistedAt = &now
+		enrollment.OfferExpiresAt = nil
+
+		event := &domain.EnrollmentEvent{FromStatus: domain.EnrollmentOffered, ToStatus: domain.EnrollmentWaitlisted, Reason: "offer expired"}
+		if err := u.repo.SaveTransition(ctx, enrollment, event, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freeSeats returns the seats of the classroom not taken by a placed child or
+// a pending offer, and its capacity.
+func (u *enrollmentUsecase) freeSeats(ctx context.Context, classroomId uint, now time.Time) (int, int, error) {
+	classroom, err := u.repo.GetClassroom(ctx, classroomId)
+	if err != nil {
+		return 0, 0, err
+	}
+	taken, err := u.repo.CountSeatsTaken(ctx, classroomId, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	return classroom.Capacity - int(taken), classroom.Capacity, nil
+}
+
+func (u *enrollmentUsecase) checkClassroom(ctx context.Context, classroomId uint) (*types.FieldError, error) {
+	_, err := u.repo.GetClassroom(ctx, classroomId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &types.FieldError{Field: "classroomId", Message: "unknown classroom id"}, nil
+	}
+	return nil, err
+}
+
+// today returns the start of the calendar day of t, the form dates parsed
+// from requests have
+func today(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
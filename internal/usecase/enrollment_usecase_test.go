@@ -0,0 +1,257 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+)
+
+// fakeEnrollmentRepository has one classroom with a number of seats taken and
+// keeps the enrollments saved. takenMeanwhile seats are taken by another
+// offer between counting the seats and saving the offers.
+type fakeEnrollmentRepository struct {
+	repository.EnrollmentRepository
+	classroom      domain.Classroom
+	taken          int64
+	takenMeanwhile int64
+	enrollments    map[uint]domain.Enrollment
+	waitlist       []domain.Enrollment
+	events         []*domain.EnrollmentEvent
+	placed         map[uint]*uint
+}
+
+func newFakeEnrollmentRepository(capacity int, taken int64) *fakeEnrollmentRepository {
+	return &fakeEnrollmentRepository{
+		classroom:   domain.Classroom{ID: 1, Name: "Butterflies", Capacity: capacity},
+		taken:       taken,
+		enrollments: map[uint]domain.Enrollment{},
+		placed:      map[uint]*uint{},
+	}
+}
+
+func (r *fakeEnrollmentRepository) GetById(ctx context.Context, id uint) (*domain.Enrollment, error) {
+	enrollment := r.enrollments[id]
+	return &enrollment, nil
+}
+
+func (r *fakeEnrollmentRepository) GetClassroom(ctx context.Context, classroomId uint) (*domain.Classroom, error) {
+	classroom := r.classroom
+	return &classroom, nil
+}
+
+func (r *fakeEnrollmentRepository) SaveTransition(ctx context.Context, enrollment *domain.Enrollment, event *domain.EnrollmentEvent, placeChild bool, classroomId *uint) error {
+	r.enrollments[enrollment.ID] = *enrollment
+	r.events = append(r.events, event)
+	if placeChild {
+		r.placed[enrollment.ChildID] = classroomId
+	}
+	return nil
+}
+
+func (r *fakeEnrollmentRepository) SaveOffers(ctx context.Context, classroomId uint, now time.Time, enrollments []*domain.Enrollment, events []*domain.EnrollmentEvent) (int, error) {
+	r.taken += r.takenMeanwhile
+	free := r.classroom.Capacity - int(r.taken)
+	for i := 0; i < len(enrollments) && i < free; i++ {
+		r.enrollments[enrollments[i].ID] = *enrollments[i]
+		r.events = append(r.events, events[i])
+	}
+	return free, nil
+}
+
+func (r *fakeEnrollmentRepository) CountSeatsTaken(ctx context.Context, classroomId uint, now time.Time) (int64, error) {
+	return r.taken, nil
+}
+
+func (r *fakeEnrollmentRepository) GetWaitlist(ctx context.Context, classroomId uint) ([]domain.Enrollment, error) {
+	return append([]domain.Enrollment(nil), r.waitlist...), nil
+}
+
+func (r *fakeEnrollmentRepository) GetExpiredOffers(ctx context.Context, classroomId uint, now time.Time) ([]domain.Enrollment, error) {
+	return nil, nil
+}
+
+func newTestEnrollmentUsecase(repo *fakeEnrollmentRepository, now time.Time) *enrollmentUsecase {
+	cfg := &config.Config{EnrollmentOfferTTL: 7 * 24 * time.Hour}
+	return &enrollmentUsecase{repo: repo, cfg: cfg, now: func() time.Time { return now }}
+}
+
+// appErrorStatus returns the HTTP status of an AppError, 0 for other errors
+func appErrorStatus(err error) int {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Status
+	}
+	return 0
+}
+
+func TestTransitionRejectsInvalidMoves(t *testing.T) {
+	now := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	classroomId := uint(1)
+
+	tests := []struct {
+		from, to string
+	}{
+		// Enrolling always goes through an offer
+		{domain.EnrollmentInquiry, domain.EnrollmentEnrolled},
+		{domain.EnrollmentWaitlisted, domain.EnrollmentEnrolled},
+		{domain.EnrollmentInquiry, domain.EnrollmentPaused},
+		{domain.EnrollmentEnrolled, domain.EnrollmentOffered},
+		{domain.EnrollmentEnrolled, domain.EnrollmentEnrolled},
+		{domain.EnrollmentPaused, domain.EnrollmentWaitlisted},
+		// Withdrawn and graduated are final
+		{domain.EnrollmentWithdrawn, domain.EnrollmentEnrolled},
+		{domain.EnrollmentWithdrawn, domain.EnrollmentInquiry},
+		{domain.EnrollmentGraduated, domain.EnrollmentEnrolled},
+		{domain.EnrollmentGraduated, domain.EnrollmentWithdrawn},
+	}
+	for _, tt := range tests {
+		repo := newFakeEnrollmentRepository(10, 0)
+		repo.enrollments[1] = domain.Enrollment{ID: 1, ChildID: 1, ClassroomID: &classroomId, Status: tt.from}
+		u := newTestEnrollmentUsecase(repo, now)
+
+		_, err := u.Transition(context.Background(), 1, 1, domain.EnrollmentTransitionRequest{Status: tt.to})
+		if status := appErrorStatus(err); status != http.StatusConflict {
+			t.Errorf("%s to %s: Transition() = %v, want a conflict", tt.from, tt.to, err)
+		}
+		if repo.enrollments[1].Status != tt.from || len(repo.events) != 0 {
+			t.Errorf("%s to %s: the enrollment was saved", tt.from, tt.to)
+		}
+	}
+}
+
+func TestTransitionToOfferedNeedsAFreeSeat(t *testing.T) {
+	now := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	classroomId := uint(1)
+
+	tests := []struct {
+		name      string
+		capacity  int
+		taken     int64
+		wantError bool
+	}{
+		{"last free seat", 2, 1, false},
+		{"classroom full", 2, 2, true},
+		{"classroom over capacity", 2, 3, true},
+	}
+	for _, tt := range tests {
+		repo := newFakeEnrollmentRepository(tt.capacity, tt.taken)
+		repo.enrollments[1] = domain.Enrollment{ID: 1, ChildID: 1, ClassroomID: &classroomId, Status: domain.EnrollmentWaitlisted}
+		u := newTestEnrollmentUsecase(repo, now)
+
+		_, err := u.Transition(context.Background(), 1, 1, domain.EnrollmentTransitionRequest{Status: domain.EnrollmentOffered})
+		if tt.wantError {
+			if status := appErrorStatus(err); status != http.StatusConflict {
+				t.Errorf("%s: Transition() = %v, want a conflict", tt.name, err)
+			}
+			if repo.enrollments[1].Status != domain.EnrollmentWaitlisted {
+				t.Errorf("%s: the offer was saved", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Transition() = %v", tt.name, err)
+		}
+		saved := repo.enrollments[1]
+		if saved.Status != domain.EnrollmentOffered || saved.OfferExpiresAt == nil || !saved.OfferExpiresAt.Equal(now.Add(7*24*time.Hour)) {
+			t.Errorf("%s: saved %s expiring at %v", tt.name, saved.Status, saved.OfferExpiresAt)
+		}
+	}
+}
+
+func TestTransitionAcceptsOfferUntilItExpires(t *testing.T) {
+	now := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	classroomId := uint(1)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		wantError bool
+	}{
+		{"offer pending", now.Add(time.Second), false},
+		{"offer expires now", now, true},
+		{"offer expired", now.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		repo := newFakeEnrollmentRepository(10, 0)
+		expiresAt := tt.expiresAt
+		repo.enrollments[1] = domain.Enrollment{ID: 1, ChildID: 7, ClassroomID: &classroomId, Status: domain.EnrollmentOffered, OfferExpiresAt: &expiresAt}
+		u := newTestEnrollmentUsecase(repo, now)
+
+		_, err := u.Transition(context.Background(), 1, 1, domain.EnrollmentTransitionRequest{Status: domain.EnrollmentEnrolled})
+		if tt.wantError {
+			if status := appErrorStatus(err); status != http.StatusConflict {
+				t.Errorf("%s: Transition() = %v, want a conflict", tt.name, err)
+			}
+			if _, ok := repo.placed[7]; ok {
+				t.Errorf("%s: the child was placed", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Transition() = %v", tt.name, err)
+		}
+		if placed := repo.placed[7]; placed == nil || *placed != classroomId {
+			t.Errorf("%s: child placed in %v, want classroom %d", tt.name, placed, classroomId)
+		}
+		if saved := repo.enrollments[1]; saved.StartDate == nil || !saved.StartDate.Equal(today(now)) {
+			t.Errorf("%s: start date %v, want %v", tt.name, saved.StartDate, today(now))
+		}
+	}
+}
+
+func TestOfferSeatsOffersOnlyFreeSeats(t *testing.T) {
+	now := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	classroomId := uint(1)
+
+	tests := []struct {
+		name           string
+		capacity       int
+		taken          int64
+		takenMeanwhile int64
+		waiting        int
+		offered        int
+		freeSeats      int
+	}{
+		{"more waiting than seats", 3, 1, 0, 3, 2, 0},
+		{"fewer waiting than seats", 3, 0, 0, 1, 1, 2},
+		{"classroom full", 3, 3, 0, 2, 0, 0},
+		{"classroom over capacity", 3, 4, 0, 2, 0, 0},
+		{"seat taken by a concurrent offer", 3, 1, 1, 3, 1, 0},
+		{"every seat taken by a concurrent offer", 3, 1, 2, 3, 0, 0},
+	}
+	for _, tt := range tests {
+		repo := newFakeEnrollmentRepository(tt.capacity, tt.taken)
+		repo.takenMeanwhile = tt.takenMeanwhile
+		for i := range tt.waiting {
+			enrollment := domain.Enrollment{ID: uint(i + 1), ChildID: uint(i + 1), ClassroomID: &classroomId, Status: domain.EnrollmentWaitlisted}
+			repo.enrollments[enrollment.ID] = enrollment
+			repo.waitlist = append(repo.waitlist, enrollment)
+		}
+		u := newTestEnrollmentUsecase(repo, now)
+
+		response, err := u.OfferSeats(context.Background(), 1, classroomId)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(response.Offered) != tt.offered || response.FreeSeats != tt.freeSeats {
+			t.Errorf("%s: offered %d with %d free seats, want %d with %d", tt.name, len(response.Offered), response.FreeSeats, tt.offered, tt.freeSeats)
+		}
+		// The top of the waitlist gets the seats, the rest keeps waiting
+		for i := range tt.waiting {
+			want := domain.EnrollmentWaitlisted
+			if i < tt.offered {
+				want = domain.EnrollmentOffered
+			}
+			if got := repo.enrollments[uint(i+1)].Status; got != want {
+				t.Errorf("%s: waitlist rank %d is %s, want %s", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/events"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// EventPublisher sends a domain event to the open event streams. Failures
+// are logged, they never fail the change that raised the event.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, audience events.Audience, data any)
+}
+
+// EventStream is an open subscription with the user it is filtered for
+type EventStream struct {
+	*events.Subscription
+	Subscriber events.Subscriber
+	// Heartbeat is how often a comment keeps an idle stream open
+	Heartbeat time.Duration
+	// WriteTimeout bounds each write to the stream
+	WriteTimeout time.Duration
+}
+
+type EventUsecase interface {
+	EventPublisher
+	Subscribe(ctx context.Context, userId uint, lastEventId uint64) (*EventStream, error)
+}
+
+type eventUsecase struct {
+	repo   repository.EventRepository
+	broker events.Broker
+	cfg    *config.Config
+}
+
+func NewEventUsecase(repo repository.EventRepository, broker events.Broker, cfg *config.Config) EventUsecase {
+	return &eventUsecase{repo, broker, cfg}
+}
+
+func (u *eventUsecase) Publish(ctx context.Context, eventType string, audience events.Audience, data any) {
+	log := logger.FromContext(ctx).With("event_type", eventType)
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Error("failed to encode event", "error", err.Error())
+		return
+	}
+	if err := u.broker.Publish(ctx, events.Event{Type: eventType, Data: payload, Audience: audience}); err != nil && !errors.Is(err, events.ErrClosed) {
+		log.Error("failed to publish event", "error", err.Error())
+	}
+}
+
+// Subscribe opens a stream for the user. The children of a parent are
+// loaded once, a parent linked to another child reconnects to see its
+// events.
+func (u *eventUsecase) Subscribe(ctx context.Context, userId uint, lastEventId uint64) (*EventStream, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Unauthorized("user no longer exists")
+	} else if err != nil {
+		return nil, err
+	}
+	subscriber := events.Subscriber{UserID: user.ID, Staff: isStaff(user)}
+	if !subscriber.Staff {
+		if subscriber.ChildIDs, err = u.repo.GetParentChildIds(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription, err := u.broker.Subscribe(ctx, lastEventId)
+	if errors.Is(err, events.ErrClosed) {
+		return nil, apperror.New(http.StatusServiceUnavailable, apperror.CodeUnavailable, "the server is shutting down")
+	} else if err != nil {
+		return nil, err
+	}
+	return &EventStream{
+		Subscription: subscription,
+		Subscriber:   subscriber,
+		Heartbeat:    u.cfg.EventHeartbeat,
+		WriteTimeout: u.cfg.HTTPWriteTimeout,
+	}, nil
+}
+
+// childAudience sends an event to the staff and the parents of the child
+func childAudience(childId uint) events.Audience {
+	return events.Audience{ChildIDs: []uint{childId}}
+}
+
+// staffAudience sends an event to the staff only
+var staffAudience = events.Audience{StaffOnly: true}
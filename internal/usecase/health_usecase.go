@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+
+	readinessTimeout = 2 * time.Second
+)
+
+type HealthUsecase interface {
+	Liveness(ctx context.Context) domain.HealthResponse
+	Readiness(ctx context.Context) (domain.HealthResponse, bool)
+	MarkShuttingDown()
+}
+
+type healthUsecase struct {
+	repo           repository.HealthRepository
+	schemaVersion  uint
+	isShuttingDown atomic.Bool
+}
+
+func NewHealthUsecase(repo repository.HealthRepository, schemaVersion uint) HealthUsecase {
+	return &healthUsecase{repo: repo, schemaVersion: schemaVersion}
+}
+
+// Liveness only reports that the process is serving requests
+func (u *healthUsecase) Liveness(ctx context.Context) domain.HealthResponse {
+	return domain.HealthResponse{Status: HealthStatusOK}
+}
+
+// Readiness checks the database and the schema version. It reports not
+// ready while shutting down so load balancers stop sending traffic.
+func (u *healthUsecase) Readiness(ctx context.Context) (domain.HealthResponse, bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	var checks []domain.HealthCheck
+	ready := true
+
+	if u.isShuttingDown.Load() {
+		ready = false
+		checks = append(checks, domain.HealthCheck{Name: "server", Status: HealthStatusUnavailable, Error: "shutting down"})
+	}
+
+	// Database errors may name hosts and users, they are only logged
+	if err := u.repo.Ping(ctx); err != nil {
+		ready = false
+		logger.FromContext(ctx).Error("readiness database check failed", "error", err.Error())
+		checks = append(checks, domain.HealthCheck{Name: "database", Status: HealthStatusUnavailable})
+	} else {
+		checks = append(checks, domain.HealthCheck{Name: "database", Status: HealthStatusOK})
+
+		version, err := u.repo.GetSchemaVersion(ctx)
+		switch {
+		case err != nil:
+			ready = false
+			logger.FromContext(ctx).Error("readiness schema version check failed", "error", err.Error())
+			checks = append(checks, domain.HealthCheck{Name: "migrations", Status: HealthStatusUnavailable, Error: "schema version unknown, run migrations"})
+		case version < u.schemaVersion:
+			ready = false
+			checks = append(checks, domain.HealthCheck{Name: "migrations", Status: HealthStatusUnavailable, Error: fmt.Sprintf("schema version %d, expected %d", version, u.schemaVersion)})
+		default:
+			checks = append(checks, domain.HealthCheck{Name: "migrations", Status: HealthStatusOK})
+		}
+	}
+
+	status := HealthStatusOK
+	if !ready {
+		status = HealthStatusUnavailable
+	}
+	return domain.HealthResponse{Status: status, Checks: checks}, ready
+}
+
+func (u *healthUsecase) MarkShuttingDown() {
+	u.isShuttingDown.Store(true)
+}
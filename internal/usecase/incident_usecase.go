@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/mailer"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type IncidentUsecase interface {
+	Report(ctx context.Context, userId uint, input domain.IncidentRequest) (*domain.Incident, error)
+	Update(ctx context.Context, userId uint, id uint, input domain.IncidentRequest) (*domain.Incident, error)
+	List(ctx context.Context, userId uint, filter domain.IncidentListFilter) ([]domain.Incident, error)
+	Get(ctx context.Context, userId uint, id uint) (*domain.Incident, error)
+	Review(ctx context.Context, userId uint, id uint, input domain.ReviewIncidentRequest) (*domain.Incident, error)
+	Acknowledge(ctx context.Context, userId uint, id uint, input domain.AcknowledgeIncidentRequest) (*domain.Incident, error)
+	History(ctx context.Context, userId uint, childId uint) (*domain.IncidentHistoryResponse, error)
+}
+
+type incidentUsecase struct {
+	repo   repository.IncidentRepository
+	cfg    *config.Config
+	mail   mailer.Mailer
+	events EventPublisher
+	now    func() time.Time
+}
+
+func NewIncidentUsecase(repo repository.IncidentRepository, cfg *config.Config, mail mailer.Mailer, events EventPublisher) IncidentUsecase {
+	return &incidentUsecase{repo, cfg, mail, events, time.Now}
+}
+
+// Report records an incident for admin review. Serious incidents are mailed
+// to the admins right away. Parents only learn about it once it is reviewed,
+// so the event goes to the staff.
+func (u *incidentUsecase) Report(ctx context.Context, userId uint, input domain.IncidentRequest) (*domain.Incident, error) {
+	incident := &domain.Incident{ReportedBy: userId, Status: domain.IncidentPendingReview}
+	if err := u.apply(ctx, incident, input); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	metrics.IncidentReported(incident.Severity)
+	u.events.Publish(ctx, domain.EventIncidentReported, staffAudience, domain.IncidentEvent{
+		IncidentID: incident.ID,
+		ChildID:    incident.ChildID,
+		Severity:   incident.Severity,
+	})
+	if incident.Severity == domain.IncidentSerious {
+		u.notifyAdmins(ctx, incident)
+	}
+	return incident, nil
+}
+
+// Update corrects an incident while it waits for the review. Only the
+// teacher who reported it and admins may change it.
+func (u *incidentUsecase) Update(ctx context.Context, userId uint, id uint, input domain.IncidentRequest) (*domain.Incident, error) {
+	incident, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.ReportedBy != userId {
+		user, err := u.repo.GetUserWithRoles(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRole(user.Roles, domain.RoleAdmin) {
+			return nil, apperror.Forbidden("Only the reporter and admins can change this incident")
+		}
+	}
+	if incident.Status != domain.IncidentPendingReview {
+		return nil, apperror.Conflict("the incident is already reviewed")
+	}
+
+	wasSerious := incident.Severity == domain.IncidentSerious
+	if err := u.apply(ctx, incident, input); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	if !wasSerious && incident.Severity == domain.IncidentSerious {
+		u.notifyAdmins(ctx, incident)
+	}
+	return incident, nil
+}
+
+// List returns every incident to staff and the reviewed incidents of their
+// children to parents
+func (u *incidentUsecase) List(ctx context.Context, userId uint, filter domain.IncidentListFilter) ([]domain.Incident, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) {
+		return u.repo.List(ctx, filter, 0)
+	}
+	return u.repo.List(ctx, filter, userId)
+}
+
+// Get returns the incident to staff and, once reviewed, to the parents of
+// the child
+func (u *incidentUsecase) Get(ctx context.Context, userId uint, id uint) (*domain.Incident, error) {
+	incident, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, incident.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff(user) && incident.Status == domain.IncidentPendingReview {
+		return nil, apperror.NotFound("incident not found")
+	}
+	return incident, nil
+}
+
+// Review closes the admin review, which shares the report with the parents.
+// An incident reclassified as serious is mailed to the admins.
+func (u *incidentUsecase) Review(ctx context.Context, userId uint, id uint, input domain.ReviewIncidentRequest) (*domain.Incident, error) {
+	incident, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status != domain.IncidentPendingReview {
+		return nil, apperror.Conflict("the incident is already reviewed")
+	}
+
+	wasSerious := incident.Severity == domain.IncidentSerious
+	if input.Severity != "" {
+		incident.Severity = input.Severity
+	}
+	now := u.now()
+	incident.Status = domain.IncidentReviewed
+	incident.ReviewedBy = &userId
+	incident.ReviewedAt = &now
+	incident.ReviewNotes = input.Notes
+	if err := u.repo.UpdateStatus(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	if !wasSerious && incident.Severity == domain.IncidentSerious {
+		u.notifyAdmins(ctx, incident)
+	}
+	return incident, nil
+}
+
+// Acknowledge records the sign-off of a parent of the child on a reviewed
+// incident
+func (u *incidentUsecase) Acknowledge(ctx context.Context, userId uint, id uint, input domain.AcknowledgeIncidentRequest) (*domain.Incident, error) {
+	incident, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	isParent, err := u.repo.IsParentOf(ctx, userId, incident.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParent {
+		return nil, apperror.Forbidden("Only the parents of the child can acknowledge an incident")
+	}
+	switch incident.Status {
+	case domain.IncidentPendingReview:
+		return nil, apperror.NotFound("incident not found")
+	case domain.IncidentAcknowledged:
+		return nil, apperror.Conflict("the incident is already acknowledged")
+	}
+
+	now := u.now()
+	incident.Status = domain.IncidentAcknowledged
+	incident.AcknowledgedBy = &userId
+	incident.AcknowledgedAt = &now
+	incident.ParentSignature = input.Signature
+	incident.ParentComment = input.Comment
+	if err := u.repo.UpdateStatus(ctx, incident); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// History returns the incidents of the child, parents only get the reviewed
+// ones
+func (u *incidentUsecase) History(ctx context.Context, userId uint, childId uint) (*domain.IncidentHistoryResponse, error) {
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, childId)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentId uint
+	if !isStaff(user) {
+		parentId = userId
+	}
+	incidents, err := u.repo.List(ctx, domain.IncidentListFilter{ChildID: childId}, parentId)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewIncidentHistoryResponse(child, incidents), nil
+}
+
+// apply checks the child, the time and the witnesses and copies the request
+// onto the incident. Staff witnesses get their name from their account.
+func (u *incidentUsecase) apply(ctx context.Context, incident *domain.Incident, input domain.IncidentRequest) error {
+	child, err := u.repo.GetChild(ctx, input.ChildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Validation(types.FieldError{Field: "childId", Message: "unknown child id"})
+	} else if err != nil {
+		return err
+	}
+
+	occurredAt, _ := utils.ParseDateTimeStringToTime(input.OccurredAt)
+	if occurredAt.After(u.now()) {
+		return apperror.Validation(types.FieldError{Field: "occurredAt", Message: "occurredAt must not be in the future"})
+	}
+
+	witnesses := make([]domain.IncidentWitness, len(input.Witnesses))
+	for i, witness := range input.Witnesses {
+		witnesses[i] = domain.IncidentWitness{UserID: witness.UserID, Name: witness.Name}
+		if witness.UserID == nil {
+			continue
+		}
+		user, err := u.repo.GetUserWithRoles(ctx, *witness.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !isStaff(user)) {
+			return apperror.Validation(types.FieldError{Field: fmt.Sprintf("witnesses[%d].userId", i), Message: "the witness must be a staff member"})
+		} else if err != nil {
+			return err
+		}
+		witnesses[i].Name = user.Name
+	}
+
+	photos := make([]domain.IncidentPhoto, len(input.Photos))
+	for i, photo := range input.Photos {
+		photos[i] = domain.IncidentPhoto{URL: photo.URL, Caption: photo.Caption}
+	}
+
+	incident.ChildID = child.ID
+	incident.Child = *child
+	incident.Type = input.Type
+	incident.Severity = input.Severity
+	incident.OccurredAt = *occurredAt
+	incident.Location = input.Location
+	incident.Description = input.Description
+	incident.FirstAid = input.FirstAid
+	incident.Witnesses = witnesses
+	incident.Photos = photos
+	return nil
+}
+
+// notifyAdmins mails a serious incident to every admin. Failures are
+// logged, the report itself is already saved.
+func (u *incidentUsecase) notifyAdmins(ctx context.Context, incident *domain.Incident) {
+	log := logger.FromContext(ctx).With("incident_id", incident.ID)
+	log.Warn("serious incident reported", "child_id", incident.ChildID, "type", incident.Type)
+
+	admins, err := u.repo.GetAdmins(ctx)
+	if err != nil {
+		log.Error("failed to load admins for incident notification", "error", err.Error())
+		return
+	}
+	for _, admin := range admins {
+		err := u.mail.Send(ctx, mailer.Message{
+			To:      admin.Email,
+			Subject: fmt.Sprintf("[%s] Serious incident: %s", u.cfg.AppName, incident.Child.Name),
+			Body: fmt.Sprintf("Hello %s,\n\nA serious %s incident was reported for %s.\n\nWhen: %s\nWhere: %s\nIncident: #%d\n\n%s\n\nFirst aid: %s\n\nPlease review it as soon as possible.\n",
+				admin.Name, incident.Type, incident.Child.Name, incident.OccurredAt.Format("2006-01-02 15:04"), incident.Location, incident.ID, incident.Description, incident.FirstAid),
+		})
+		if err != nil {
+			log.Error("failed to mail incident notification", "user_id", admin.ID, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/cron"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// JobHandler runs the jobs of one type. Handlers must be safe to run twice,
+// a job is claimed again when its worker dies while running it.
+type JobHandler interface {
+	Type() string
+	Run(ctx context.Context, payload []byte) error
+}
+
+type jobHandler struct {
+	jobType string
+	run     func(ctx context.Context, payload []byte) error
+}
+
+func (h *jobHandler) Type() string {
+	return h.jobType
+}
+
+func (h *jobHandler) Run(ctx context.Context, payload []byte) error {
+	return h.run(ctx, payload)
+}
+
+// HandleJob runs the jobs of jobType with their JSON payload decoded into T.
+// A payload that does not decode fails the job without retries.
+func HandleJob[T any](jobType string, run func(ctx context.Context, payload T) error) JobHandler {
+	return &jobHandler{jobType, func(ctx context.Context, raw []byte) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return PermanentJobError(fmt.Errorf("decoding payload: %w", err))
+		}
+		return run(ctx, payload)
+	}}
+}
+
+type permanentJobError struct {
+	err error
+}
+
+func (e *permanentJobError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentJobError) Unwrap() error {
+	return e.err
+}
+
+// PermanentJobError marks a failure a retry cannot fix, the job is dead
+// right away
+func PermanentJobError(err error) error {
+	return &permanentJobError{err}
+}
+
+// JobSchedule queues a job of Type with an empty payload at every time
+// matching Spec, a five field cron expression in the server time zone
+type JobSchedule struct {
+	Name string
+	Spec string
+	Type string
+}
+
+type jobSchedule struct {
+	JobSchedule
+	cron *cron.Schedule
+}
+
+// JobQueue queues background jobs
+type JobQueue interface {
+	Enqueue(ctx context.Context, jobType string, payload any, runAt time.Time) (*domain.Job, error)
+}
+
+type JobUsecase interface {
+	JobQueue
+	// Run starts the worker pool and the scheduler and returns once they
+	// stopped after ctx is done
+	Run(ctx context.Context)
+	List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.JobFilter) ([]domain.JobResponse, int, error)
+	Get(ctx context.Context, id uint) (*domain.JobResponse, error)
+	Retry(ctx context.Context, id uint) (*domain.JobResponse, error)
+	Cancel(ctx context.Context, id uint) (*domain.JobResponse, error)
+	Schedules(ctx context.Context) []domain.JobScheduleResponse
+}
+
+type jobUsecase struct {
+	repo      repository.JobRepository
+	cfg       *config.Config
+	handlers  map[string]JobHandler
+	schedules []jobSchedule
+	// wake tells an idle worker of this instance about a new job
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// NewJobUsecase runs the jobs with the handlers and queues the scheduled
+// ones. Old finished jobs are cleaned up by a built-in jobs.clean_up
+// handler.
+func NewJobUsecase(repo repository.JobRepository, cfg *config.Config, handlers []JobHandler, schedules []JobSchedule) (JobUsecase, error) {
+	u := &jobUsecase{repo: repo, cfg: cfg, handlers: map[string]JobHandler{}, wake: make(chan struct{}, 1), now: time.Now}
+
+	handlers = append(handlers, HandleJob(domain.JobCleanUpJobs, u.cleanUp))
+	for _, handler := range handlers {
+		if _, ok := u.handlers[handler.Type()]; ok {
+			return nil, fmt.Errorf("jobs: type %s has two handlers", handler.Type())
+		}
+		u.handlers[handler.Type()] = handler
+	}
+
+	names := map[string]bool{}
+	for _, schedule := range schedules {
+		parsed, err := cron.Parse(schedule.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("jobs: schedule %s: %w", schedule.Name, err)
+		}
+		if parsed.Next(u.now()).IsZero() {
+			return nil, fmt.Errorf("jobs: schedule %s never runs", schedule.Name)
+		}
+		if _, ok := u.handlers[schedule.Type]; !ok {
+			return nil, fmt.Errorf("jobs: schedule %s queues %s, which has no handler", schedule.Name, schedule.Type)
+		}
+		if names[schedule.Name] {
+			return nil, fmt.Errorf("jobs: schedule %s is defined twice", schedule.Name)
+		}
+		names[schedule.Name] = true
+		u.schedules = append(u.schedules, jobSchedule{schedule, parsed})
+	}
+	return u, nil
+}
+
+func (u *jobUsecase) Enqueue(ctx context.Context, jobType string, payload any, runAt time.Time) (*domain.Job, error) {
+	return u.enqueue(ctx, jobType, payload, runAt, nil)
+}
+
+func (u *jobUsecase) enqueue(ctx context.Context, jobType string, payload any, runAt time.Time, scheduleKey *string) (*domain.Job, error) {
+	if _, ok := u.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("jobs: no handler for type %s", jobType)
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &domain.Job{
+		Type:        jobType,
+		Payload:     string(encoded),
+		Status:      domain.JobPending,
+		MaxAttempts: u.cfg.JobMaxAttempts,
+		RunAt:       runAt,
+		ScheduleKey: scheduleKey,
+	}
+	if err := u.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	if !runAt.After(u.now()) {
+		select {
+		case u.wake <- struct{}{}:
+		default:
+		}
+	}
+	return job, nil
+}
+
+func (u *jobUsecase) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for range u.cfg.JobWorkers {
+		workers.Go(func() { u.work(ctx) })
+	}
+	workers.Go(func() { u.schedule(ctx) })
+	workers.Wait()
+}
+
+// work runs the due jobs one after another, polling while none is due
+func (u *jobUsecase) work(ctx context.Context) {
+	// The lease outlasts the timeout of a run, so a job is only claimed
+	// again when its worker died
+	lease := u.cfg.JobTimeout + time.Minute
+	for ctx.Err() == nil {
+		job, err := u.repo.Claim(ctx, u.now(), lease)
+		if err != nil && ctx.Err() == nil {
+			logger.FromContext(ctx).Error("failed to claim job", "error", err.Error())
+		}
+		if job != nil {
+			u.run(ctx, job)
+			continue
+		}
+
+		timer := time.NewTimer(u.cfg.JobPollInterval)
+		select {
+		case <-ctx.Done():
+		case <-u.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// run runs one claimed job and records the outcome. A failed run is retried
+// with a doubling backoff until the attempts run out and the job is dead. A
+// run interrupted by shutdown is put back without counting the attempt.
+func (u *jobUsecase) run(ctx context.Context, job *domain.Job) {
+	log := logger.FromContext(ctx).With("job_id", job.ID, "job_type", job.Type)
+
+	var err error
+	if handler, ok := u.handlers[job.Type]; ok {
+		runCtx, cancel := context.WithTimeout(ctx, u.cfg.JobTimeout)
+		err = handler.Run(runCtx, []byte(job.Payload))
+		cancel()
+	} else {
+		err = PermanentJobError(fmt.Errorf("no handler for job type %s", job.Type))
+	}
+
+	now := u.now()
+	job.LockedUntil = nil
+	var outcome string
+	var permanent *permanentJobError
+	switch {
+	case err == nil:
+		job.Status, job.FinishedAt, job.LastError = domain.JobSucceeded, &now, ""
+		outcome = domain.JobSucceeded
+	case ctx.Err() != nil:
+		job.Status, job.RunAt = domain.JobPending, now
+		job.Attempts--
+		outcome = "interrupted"
+		log.Warn("job interrupted by shutdown, it will run again", "error", err.Error())
+	case errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts:
+		job.Status, job.FinishedAt, job.LastError = domain.JobDead, &now, err.Error()
+		outcome = domain.JobDead
+		log.Error("job failed for good", "attempts", job.Attempts, "error", err.Error())
+	default:
+		job.Status, job.LastError = domain.JobPending, err.Error()
+		job.RunAt = now.Add(u.cfg.JobRetryBackoff << min(job.Attempts-1, maxBackoffDoublings))
+		outcome = "retry"
+		log.Warn("job failed, it will be retried", "attempts", job.Attempts, "error", err.Error())
+	}
+	metrics.JobRun(job.Type, outcome)
+
+	if err := u.repo.Save(context.WithoutCancel(ctx), job); err != nil {
+		log.Error("failed to save job", "error", err.Error())
+	}
+}
+
+// schedule queues the scheduled jobs when their time comes. Every instance
+// runs it, the schedule key of the occurrence makes sure one job is queued.
+// Occurrences missed while no instance was running are skipped.
+func (u *jobUsecase) schedule(ctx context.Context) {
+	if len(u.schedules) == 0 {
+		return
+	}
+	ticker := time.NewTicker(min(u.cfg.JobPollInterval, time.Minute))
+	defer ticker.Stop()
+
+	next := make([]time.Time, len(u.schedules))
+	for i, schedule := range u.schedules {
+		next[i] = schedule.cron.Next(u.now())
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := u.now()
+		for i, schedule := range u.schedules {
+			if now.Before(next[i]) {
+				continue
+			}
+			// Only the latest occurrence is queued after a stall
+			due := next[i]
+			for later := schedule.cron.Next(due); !later.After(now); later = schedule.cron.Next(later) {
+				due = later
+			}
+			key := schedule.Name + "@" + due.Format(time.RFC3339)
+			_, err := u.enqueue(ctx, schedule.Type, struct{}{}, due, &key)
+			if err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+				logger.FromContext(ctx).Error("failed to queue scheduled job", "schedule", schedule.Name, "error", err.Error())
+				continue
+			}
+			next[i] = schedule.cron.Next(now)
+		}
+	}
+}
+
+// cleanUp deletes the jobs that succeeded or were cancelled longer than
+// JOB_RETENTION ago
+func (u *jobUsecase) cleanUp(ctx context.Context, _ struct{}) error {
+	deleted, err := u.repo.DeleteFinished(ctx, u.now().Add(-u.cfg.JobRetention))
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("cleaned up finished jobs", "deleted", deleted)
+	return nil
+}
+
+func (u *jobUsecase) List(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.JobFilter) ([]domain.JobResponse, int, error) {
+	jobs, totalPages, err := u.repo.List(ctx, paginationFilter, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.JobResponse, len(jobs))
+	for i := range jobs {
+		responses[i] = *domain.NewJobResponse(&jobs[i])
+	}
+	return responses, totalPages, nil
+}
+
+func (u *jobUsecase) Get(ctx context.Context, id uint) (*domain.JobResponse, error) {
+	job, err := u.repo.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Job not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return domain.NewJobResponse(job), nil
+}
+
+// Retry runs a dead or cancelled job again with fresh attempts, or a pending
+// one right away
+func (u *jobUsecase) Retry(ctx context.Context, id uint) (*domain.JobResponse, error) {
+	return u.transition(ctx, id, []string{domain.JobPending, domain.JobDead, domain.JobCancelled}, map[string]any{
+		"status":       domain.JobPending,
+		"attempts":     0,
+		"run_at":       u.now(),
+		"locked_until": nil,
+		"finished_at":  nil,
+	}, "only pending, dead or cancelled jobs can be retried")
+}
+
+// Cancel stops a pending job from running. A running job cannot be stopped.
+func (u *jobUsecase) Cancel(ctx context.Context, id uint) (*domain.JobResponse, error) {
+	return u.transition(ctx, id, []string{domain.JobPending}, map[string]any{
+		"status":      domain.JobCancelled,
+		"finished_at": u.now(),
+	}, "only pending jobs can be cancelled")
+}
+
+func (u *jobUsecase) transition(ctx context.Context, id uint, from []string, updates map[string]any, conflict string) (*domain.JobResponse, error) {
+	changed, err := u.repo.Transition(ctx, id, from, updates)
+	if err != nil {
+		return nil, err
+	}
+	job, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, apperror.Conflict(conflict)
+	}
+	if job.Status == domain.JobPending {
+		select {
+		case u.wake <- struct{}{}:
+		default:
+		}
+	}
+	return job, nil
+}
+
+func (u *jobUsecase) Schedules(ctx context.Context) []domain.JobScheduleResponse {
+	now := u.now()
+	responses := make([]domain.JobScheduleResponse, len(u.schedules))
+	for i, schedule := range u.schedules {
+		responses[i] = domain.JobScheduleResponse{
+			Name:    schedule.Name,
+			Spec:    schedule.Spec,
+			Type:    schedule.Type,
+			NextRun: schedule.cron.Next(now),
+		}
+	}
+	return responses
+}
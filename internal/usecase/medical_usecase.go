@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type MedicalUsecase interface {
+	GetProfile(ctx context.Context, userId uint, childId uint) (*domain.MedicalProfileResponse, error)
+	UpdateProfile(ctx context.Context, userId uint, childId uint, input domain.MedicalProfileRequest) (*domain.MedicalProfileResponse, error)
+	Alerts(ctx context.Context, classroomId uint) ([]domain.MedicalAlertResponse, error)
+	CheckAllergies(ctx context.Context, childId uint, meal string) (*domain.AllergyCheckResponse, error)
+}
+
+type medicalUsecase struct {
+	repo repository.MedicalRepository
+	now  func() time.Time
+}
+
+func NewMedicalUsecase(repo repository.MedicalRepository) MedicalUsecase {
+	return &medicalUsecase{repo, time.Now}
+}
+
+// GetProfile returns the medical profile of the child to staff and to its
+// parents. A child without a profile gets an empty one.
+func (u *medicalUsecase) GetProfile(ctx context.Context, userId uint, childId uint) (*domain.MedicalProfileResponse, error) {
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, childId); err != nil {
+		return nil, err
+	}
+
+	profile, err := u.repo.GetProfile(ctx, childId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		profile = &domain.ChildMedicalProfile{ChildID: childId}
+	} else if err != nil {
+		return nil, err
+	}
+	return domain.NewMedicalProfileResponse(child, profile, today(u.now())), nil
+}
+
+// UpdateProfile replaces the medical profile of the child. Parents update
+// their own children, among staff only admins may change it.
+func (u *medicalUsecase) UpdateProfile(ctx context.Context, userId uint, childId uint, input domain.MedicalProfileRequest) (*domain.MedicalProfileResponse, error) {
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, childId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) && !hasRole(user.Roles, domain.RoleAdmin) {
+		return nil, apperror.Forbidden("Only parents and admins can update the medical profile")
+	}
+
+	profile, err := u.repo.GetProfile(ctx, childId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		profile = &domain.ChildMedicalProfile{ChildID: childId}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := applyMedicalProfile(profile, input); err != nil {
+		return nil, err
+	}
+	profile.UpdatedBy = userId
+
+	if err := u.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return domain.NewMedicalProfileResponse(child, profile, today(u.now())), nil
+}
+
+// Alerts summarises, for the children of a classroom or of the whole center,
+// what teachers must know today: allergies, medication taken today, overdue
+// immunizations and conditions. Children with nothing to report are left
+// out, those with a serious allergy come first.
+func (u *medicalUsecase) Alerts(ctx context.Context, classroomId uint) ([]domain.MedicalAlertResponse, error) {
+	profiles, err := u.repo.GetProfiles(ctx, classroomId)
+	if err != nil {
+		return nil, err
+	}
+
+	day := today(u.now())
+	var severe, others []domain.MedicalAlertResponse
+	for _, profile := range profiles {
+		alert := domain.MedicalAlertResponse{
+			ChildID:              profile.ChildID,
+			ChildName:            profile.Child.Name,
+			ClassroomID:          profile.Child.ClassroomID,
+			Allergies:            []string{},
+			MedicationsToday:     []string{},
+			OverdueImmunizations: []string{},
+			Conditions:           profile.Conditions,
+		}
+		for _, allergy := range domain.NewAllergyResponses(profile.Allergies) {
+			alert.Allergies = append(alert.Allergies, fmt.Sprintf("%s (%s)", allergy.Allergen, allergy.Severity))
+		}
+		for _, allergy := range profile.Allergies {
+			alert.Severe = alert.Severe || allergy.Serious()
+		}
+		for _, medication := range profile.Medications {
+			if medication.ActiveOn(day) {
+				alert.MedicationsToday = append(alert.MedicationsToday, fmt.Sprintf("%s %s at %s", medication.Name, medication.Dose, strings.ReplaceAll(medication.Times, ",", ", ")))
+			}
+		}
+		for _, immunization := range profile.Immunizations {
+			if domain.ImmunizationStatus(immunization, day) == domain.ImmunizationOverdue {
+				alert.OverdueImmunizations = append(alert.OverdueImmunizations, fmt.Sprintf("%s dose %d", immunization.Vaccine, immunization.DoseNumber))
+			}
+		}
+		if contacts := domain.NewEmergencyContactResponses(profile.EmergencyContacts); len(contacts) > 0 {
+			alert.FirstContact = &contacts[0]
+		}
+
+		if len(alert.Allergies) == 0 && len(alert.MedicationsToday) == 0 && len(alert.OverdueImmunizations) == 0 && alert.Conditions == "" {
+			continue
+		}
+		if alert.Severe {
+			severe = append(severe, alert)
+		} else {
+			others = append(others, alert)
+		}
+	}
+	return append(append([]domain.MedicalAlertResponse{}, severe...), others...), nil
+}
+
+// CheckAllergies matches the allergens of the child against the name or
+// ingredients of a meal. Matching is by name only, a safe result does not
+// replace reading the labels.
+func (u *medicalUsecase) CheckAllergies(ctx context.Context, childId uint, meal string) (*domain.AllergyCheckResponse, error) {
+	if _, err := u.repo.GetChild(ctx, childId); err != nil {
+		return nil, err
+	}
+	allergies, err := u.repo.GetAllergies(ctx, childId)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := strings.ToLower(meal)
+	var matches []domain.ChildAllergy
+	for _, allergy := range allergies {
+		allergen := strings.ToLower(strings.TrimSpace(allergy.Allergen))
+		if strings.Contains(normalized, allergen) || (len(allergen) > 3 && strings.Contains(normalized, strings.TrimSuffix(allergen, "s"))) {
+			matches = append(matches, allergy)
+		}
+	}
+	return &domain.AllergyCheckResponse{
+		ChildID: childId,
+		Meal:    meal,
+		Safe:    len(matches) == 0,
+		Matches: domain.NewAllergyResponses(matches),
+	}, nil
+}
+
+// applyMedicalProfile checks the rules the validator cannot express and
+// copies the request onto the profile
+func applyMedicalProfile(profile *domain.ChildMedicalProfile, input domain.MedicalProfileRequest) error {
+	var fieldErrors []types.FieldError
+
+	allergies := make([]domain.ChildAllergy, len(input.Allergies))
+	for i, allergy := range input.Allergies {
+		allergies[i] = domain.ChildAllergy{
+			ChildID:    profile.ChildID,
+			Allergen:   strings.TrimSpace(allergy.Allergen),
+			Severity:   allergy.Severity,
+			Reaction:   allergy.Reaction,
+			ActionPlan: allergy.ActionPlan,
+		}
+		if allergies[i].Serious() && strings.TrimSpace(allergy.ActionPlan) == "" {
+			fieldErrors = append(fieldErrors, types.FieldError{Field: fmt.Sprintf("allergies[%d].actionPlan", i), Message: "actionPlan is required for severe allergies"})
+		}
+	}
+
+	medications := make([]domain.ChildMedication, len(input.Medications))
+	for i, medication := range input.Medications {
+		startDate, _ := utils.ParseDateStringToTime(medication.StartDate)
+		medications[i] = domain.ChildMedication{
+			ChildID:      profile.ChildID,
+			Name:         medication.Name,
+			Dose:         medication.Dose,
+			Times:        strings.Join(medication.Times, ","),
+			StartDate:    *startDate,
+			Instructions: medication.Instructions,
+		}
+		if medication.EndDate != "" {
+			medications[i].EndDate, _ = utils.ParseDateStringToTime(medication.EndDate)
+			if medications[i].EndDate.Before(*startDate) {
+				fieldErrors = append(fieldErrors, types.FieldError{Field: fmt.Sprintf("medications[%d].endDate", i), Message: "endDate must not be before startDate"})
+			}
+		}
+	}
+
+	immunizations := make([]domain.ChildImmunization, len(input.Immunizations))
+	for i, immunization := range input.Immunizations {
+		immunizations[i] = domain.ChildImmunization{
+			ChildID:    profile.ChildID,
+			Vaccine:    immunization.Vaccine,
+			DoseNumber: max(immunization.DoseNumber, 1),
+			Notes:      immunization.Notes,
+		}
+		if immunization.GivenOn != "" {
+			immunizations[i].GivenOn, _ = utils.ParseDateStringToTime(immunization.GivenOn)
+		}
+		if immunization.DueOn != "" {
+			immunizations[i].DueOn, _ = utils.ParseDateStringToTime(immunization.DueOn)
+		}
+	}
+
+	contacts := make([]domain.ChildEmergencyContact, len(input.EmergencyContacts))
+	priorities := make(map[int]bool, len(input.EmergencyContacts))
+	for i, contact := range input.EmergencyContacts {
+		contacts[i] = domain.ChildEmergencyContact{
+			ChildID:      profile.ChildID,
+			Name:         contact.Name,
+			Relationship: contact.Relationship,
+			Phone:        contact.Phone,
+			AltPhone:     contact.AltPhone,
+			Priority:     contact.Priority,
+			CanPickUp:    contact.CanPickUp,
+		}
+		if priorities[contact.Priority] {
+			fieldErrors = append(fieldErrors, types.FieldError{Field: fmt.Sprintf("emergencyContacts[%d].priority", i), Message: "priority must be unique"})
+		}
+		priorities[contact.Priority] = true
+	}
+
+	if len(fieldErrors) > 0 {
+		return apperror.Validation(fieldErrors...)
+	}
+
+	profile.BloodType = input.BloodType
+	profile.Conditions = input.Conditions
+	profile.DoctorName = input.DoctorName
+	profile.DoctorPhone = input.DoctorPhone
+	profile.DoctorClinic = input.DoctorClinic
+	profile.Allergies = allergies
+	profile.Medications = medications
+	profile.Immunizations = immunizations
+	profile.EmergencyContacts = contacts
+	return nil
+}
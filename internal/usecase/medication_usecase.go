@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// maxMedicationDays bounds the days a single medication request may span
+const maxMedicationDays = 90
+
+type MedicationUsecase interface {
+	CreateRequest(ctx context.Context, userId uint, input domain.CreateMedicationRequest) (*domain.MedicationRequest, error)
+	ListRequests(ctx context.Context, userId uint, filter domain.MedicationRequestFilter) ([]domain.MedicationRequest, error)
+	GetRequest(ctx context.Context, userId uint, id uint) (*domain.MedicationRequest, error)
+	CancelRequest(ctx context.Context, userId uint, id uint) (*domain.MedicationRequest, error)
+	RecordAdministration(ctx context.Context, userId uint, requestId uint, input domain.RecordAdministrationRequest) (*domain.MedicationAdministration, error)
+	Countersign(ctx context.Context, userId uint, id uint) (*domain.MedicationAdministration, error)
+	Daily(ctx context.Context, day time.Time) (*domain.MedicationDailyResponse, error)
+}
+
+type medicationUsecase struct {
+	repo   repository.MedicationRepository
+	cfg    *config.Config
+	events EventPublisher
+	now    func() time.Time
+}
+
+func NewMedicationUsecase(repo repository.MedicationRepository, cfg *config.Config, events EventPublisher) MedicationUsecase {
+	return &medicationUsecase{repo, cfg, events, time.Now}
+}
+
+// CreateRequest records the request and consent of a parent for one of
+// their children, from today on
+func (u *medicationUsecase) CreateRequest(ctx context.Context, userId uint, input domain.CreateMedicationRequest) (*domain.MedicationRequest, error) {
+	startDate, _ := utils.ParseDateStringToTime(input.StartDate)
+	endDate, _ := utils.ParseDateStringToTime(input.EndDate)
+	if endDate.Before(*startDate) {
+		return nil, apperror.Validation(types.FieldError{Field: "endDate", Message: "endDate must not be before startDate"})
+	}
+	if endDate.Sub(*startDate) >= maxMedicationDays*24*time.Hour {
+		return nil, apperror.Validation(types.FieldError{Field: "endDate", Message: fmt.Sprintf("a request cannot span more than %d days", maxMedicationDays)})
+	}
+	if startDate.Before(today(u.now())) {
+		return nil, apperror.Validation(types.FieldError{Field: "startDate", Message: "startDate must not be in the past"})
+	}
+
+	child, err := u.repo.GetChild(ctx, input.ChildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Validation(types.FieldError{Field: "childId", Message: "unknown child id"})
+	} else if err != nil {
+		return nil, err
+	}
+	isParent, err := u.repo.IsParentOf(ctx, userId, input.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParent {
+		return nil, apperror.Forbidden("Only the parents of the child can request medication")
+	}
+
+	times := slices.Clone(input.Times)
+	slices.Sort(times)
+	request := &domain.MedicationRequest{
+		ChildID:      input.ChildID,
+		Child:        *child,
+		RequestedBy:  userId,
+		Drug:         input.Drug,
+		Dose:         input.Dose,
+		Times:        strings.Join(times, ","),
+		StartDate:    *startDate,
+		EndDate:      *endDate,
+		Instructions: input.Instructions,
+		Consent:      input.Consent,
+		ConsentAt:    u.now(),
+		Status:       domain.MedicationRequestActive,
+	}
+	if err := u.repo.CreateRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ListRequests returns every request to staff and the requests of their
+// children to parents
+func (u *medicationUsecase) ListRequests(ctx context.Context, userId uint, filter domain.MedicationRequestFilter) ([]domain.MedicationRequest, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) {
+		return u.repo.ListRequests(ctx, filter.ChildID, 0, filter.Status)
+	}
+	return u.repo.ListRequests(ctx, filter.ChildID, userId, filter.Status)
+}
+
+func (u *medicationUsecase) GetRequest(ctx context.Context, userId uint, id uint) (*domain.MedicationRequest, error) {
+	request, err := u.repo.GetRequestById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, request.ChildID); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// CancelRequest stops a request, doses already recorded are kept. Parents
+// of the child and admins may cancel.
+func (u *medicationUsecase) CancelRequest(ctx context.Context, userId uint, id uint) (*domain.MedicationRequest, error) {
+	request, err := u.repo.GetRequestById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := checkChildAccess(ctx, u.repo, userId, request.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) && !hasRole(user.Roles, domain.RoleAdmin) {
+		return nil, apperror.Forbidden("Only parents and admins can cancel a medication request")
+	}
+	if request.Status == domain.MedicationRequestCancelled {
+		return nil, apperror.Conflict("the medication request is already cancelled")
+	}
+
+	request.Status = domain.MedicationRequestCancelled
+	if err := u.repo.UpdateRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// RecordAdministration records a scheduled dose as given or refused. The
+// witness must be another staff member and countersigns it afterwards.
+func (u *medicationUsecase) RecordAdministration(ctx context.Context, userId uint, requestId uint, input domain.RecordAdministrationRequest) (*domain.MedicationAdministration, error) {
+	request, err := u.repo.GetRequestById(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != domain.MedicationRequestActive {
+		return nil, apperror.Conflict("the medication request is cancelled")
+	}
+
+	day, _ := utils.ParseDateStringToTime(input.Date)
+	administeredAt, _ := utils.ParseDateTimeStringToTime(input.AdministeredAt)
+	if !request.CoversDay(*day) {
+		return nil, apperror.Validation(types.FieldError{Field: "date", Message: "the request does not cover this date"})
+	}
+	if !slices.Contains(request.TimeList(), input.Time) {
+		return nil, apperror.Validation(types.FieldError{Field: "time", Message: "no dose is scheduled at this time"})
+	}
+	scheduledAt := day.Add(clockOffset(input.Time))
+	for _, administration := range request.Administrations {
+		if administration.ScheduledAt.Equal(scheduledAt) {
+			return nil, apperror.Conflict("this dose is already recorded")
+		}
+	}
+
+	if input.WitnessID == userId {
+		return nil, apperror.Validation(types.FieldError{Field: "witnessId", Message: "the witness must be another staff member"})
+	}
+	witness, err := u.repo.GetUserWithRoles(ctx, input.WitnessID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !isStaff(witness)) {
+		return nil, apperror.Validation(types.FieldError{Field: "witnessId", Message: "the witness must be another staff member"})
+	} else if err != nil {
+		return nil, err
+	}
+
+	administration := &domain.MedicationAdministration{
+		RequestID:      request.ID,
+		ScheduledAt:    scheduledAt,
+		ChildID:        request.ChildID,
+		AdministeredAt: *administeredAt,
+		Status:         input.Status,
+		AdministeredBy: userId,
+		WitnessID:      input.WitnessID,
+		Notes:          input.Notes,
+	}
+	if err := u.repo.CreateAdministration(ctx, administration); err != nil {
+		return nil, err
+	}
+
+	u.events.Publish(ctx, domain.EventDiaryEntryAdded, childAudience(administration.ChildID), domain.DiaryEntryEvent{
+		ChildID:          administration.ChildID,
+		DiaryID:          administration.DiaryID,
+		AdministrationID: administration.ID,
+		Status:           administration.Status,
+	})
+	return administration, nil
+}
+
+// Countersign is the sign-off of the witness named on the administration
+func (u *medicationUsecase) Countersign(ctx context.Context, userId uint, id uint) (*domain.MedicationAdministration, error) {
+	administration, err := u.repo.GetAdministrationById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if administration.WitnessID != userId {
+		return nil, apperror.Forbidden("Only the witness can countersign this dose")
+	}
+	if administration.WitnessedAt != nil {
+		return nil, apperror.Conflict("the dose is already countersigned")
+	}
+
+	now := u.now()
+	administration.WitnessedAt = &now
+	if err := u.repo.UpdateAdministration(ctx, administration); err != nil {
+		return nil, err
+	}
+	return administration, nil
+}
+
+// Daily lists every dose scheduled on the day with its status. Doses of
+// absent children are marked absent instead of overdue or missed.
+func (u *medicationUsecase) Daily(ctx context.Context, day time.Time) (*domain.MedicationDailyResponse, error) {
+	requests, err := u.repo.GetRequestsOn(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	absentIds, err := u.repo.GetAbsentChildIds(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	response := &domain.MedicationDailyResponse{Date: day.Format(dateLayout), Doses: []domain.MedicationDoseResponse{}}
+	for _, request := range requests {
+		for _, clock := range request.TimeList() {
+			dose := domain.MedicationDoseResponse{
+				RequestID:    request.ID,
+				ChildID:      request.ChildID,
+				ChildName:    request.Child.Name,
+				Drug:         request.Drug,
+				Dose:         request.Dose,
+				Instructions: request.Instructions,
+				ScheduledAt:  day.Add(clockOffset(clock)),
+				Status:       domain.DoseDue,
+			}
+			for i := range request.Administrations {
+				if request.Administrations[i].ScheduledAt.Equal(dose.ScheduledAt) {
+					dose.Administration = domain.NewMedicationAdministrationResponse(&request.Administrations[i])
+				}
+			}
+
+			switch {
+			case dose.Administration != nil:
+				dose.Status = dose.Administration.Status
+				if dose.Administration.WitnessedAt == nil {
+					response.AwaitingCountersign++
+				}
+			case slices.Contains(absentIds, request.ChildID):
+				dose.Status = domain.DoseAbsent
+			case day.Before(today(now)):
+				dose.Status = domain.DoseMissed
+				response.Missed++
+			case now.After(dose.ScheduledAt.Add(u.cfg.MedicationDoseGrace)):
+				dose.Status = domain.DoseOverdue
+				response.Overdue++
+			}
+			response.Doses = append(response.Doses, dose)
+		}
+	}
+	sort.SliceStable(response.Doses, func(i, j int) bool {
+		if !response.Doses[i].ScheduledAt.Equal(response.Doses[j].ScheduledAt) {
+			return response.Doses[i].ScheduledAt.Before(response.Doses[j].ScheduledAt)
+		}
+		return response.Doses[i].ChildName < response.Doses[j].ChildName
+	})
+	return response, nil
+}
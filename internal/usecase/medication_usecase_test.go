@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+)
+
+// fakeMedicationRepository returns the same requests on every day
+type fakeMedicationRepository struct {
+	repository.MedicationRepository
+	requests  []domain.MedicationRequest
+	absentIds []uint
+}
+
+func (r *fakeMedicationRepository) GetRequestsOn(ctx context.Context, day time.Time) ([]domain.MedicationRequest, error) {
+	return r.requests, nil
+}
+
+func (r *fakeMedicationRepository) GetAbsentChildIds(ctx context.Context, day time.Time) ([]uint, error) {
+	return r.absentIds, nil
+}
+
+func TestDailyDoseStatus(t *testing.T) {
+	day := date(2025, 2, 20)
+	now := day.Add(12*time.Hour + 20*time.Minute)
+	witnessedAt := now
+
+	tests := []struct {
+		name           string
+		day            time.Time
+		clock          string
+		administration *domain.MedicationAdministration
+		absent         bool
+		want           string
+	}{
+		{"later today", day, "17:00", nil, false, domain.DoseDue},
+		{"within the grace period", day, "12:00", nil, false, domain.DoseDue},
+		{"grace period ends now", day, "11:50", nil, false, domain.DoseDue},
+		{"grace period passed", day, "11:49", nil, false, domain.DoseOverdue},
+		{"child absent", day, "08:00", nil, true, domain.DoseAbsent},
+		{"given", day, "08:00", &domain.MedicationAdministration{Status: domain.DoseGiven, WitnessedAt: &witnessedAt}, false, domain.DoseGiven},
+		{"refused", day, "08:00", &domain.MedicationAdministration{Status: domain.DoseRefused, WitnessedAt: &witnessedAt}, false, domain.DoseRefused},
+		{"earlier day", day.AddDate(0, 0, -1), "17:00", nil, false, domain.DoseMissed},
+		{"child absent on an earlier day", day.AddDate(0, 0, -1), "08:00", nil, true, domain.DoseAbsent},
+	}
+	for _, tt := range tests {
+		request := domain.MedicationRequest{ID: 1, ChildID: 1, Child: domain.Child{ID: 1, Name: "Ana"}, Times: tt.clock}
+		if tt.administration != nil {
+			tt.administration.ScheduledAt = tt.day.Add(clockOffset(tt.clock))
+			request.Administrations = []domain.MedicationAdministration{*tt.administration}
+		}
+		repo := &fakeMedicationRepository{requests: []domain.MedicationRequest{request}}
+		if tt.absent {
+			repo.absentIds = []uint{1}
+		}
+		u := &medicationUsecase{repo: repo, cfg: &config.Config{MedicationDoseGrace: 30 * time.Minute}, now: func() time.Time { return now }}
+
+		response, err := u.Daily(context.Background(), tt.day)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(response.Doses) != 1 || response.Doses[0].Status != tt.want {
+			t.Errorf("%s: doses %+v, want one %s", tt.name, response.Doses, tt.want)
+		}
+	}
+}
+
+func TestDailyCountsDosesToFollowUp(t *testing.T) {
+	day := date(2025, 2, 20)
+	now := day.Add(12 * time.Hour)
+	requests := []domain.MedicationRequest{
+		{
+			ID: 1, ChildID: 1, Child: domain.Child{ID: 1, Name: "Ana"}, Times: "08:00,10:00,17:00",
+			// The 08:00 dose waits for the witness to countersign
+			Administrations: []domain.MedicationAdministration{{ScheduledAt: day.Add(8 * time.Hour), Status: domain.DoseGiven}},
+		},
+		{ID: 2, ChildID: 2, Child: domain.Child{ID: 2, Name: "Ben"}, Times: "09:00"},
+		{ID: 3, ChildID: 3, Child: domain.Child{ID: 3, Name: "Cai"}, Times: "09:00"},
+	}
+	repo := &fakeMedicationRepository{requests: requests, absentIds: []uint{3}}
+	u := &medicationUsecase{repo: repo, cfg: &config.Config{MedicationDoseGrace: 30 * time.Minute}, now: func() time.Time { return now }}
+
+	response, err := u.Daily(context.Background(), day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Doses) != 5 || response.Overdue != 2 || response.Missed != 0 || response.AwaitingCountersign != 1 {
+		t.Errorf("%d doses, %d overdue, %d missed, %d awaiting countersign, want 5, 2, 0, 1",
+			len(response.Doses), response.Overdue, response.Missed, response.AwaitingCountersign)
+	}
+	// Doses are in time order, then by name
+	var order []string
+	for _, dose := range response.Doses {
+		order = append(order, dose.ScheduledAt.Format("15:04")+" "+dose.ChildName)
+	}
+	want := []string{"08:00 Ana", "09:00 Ben", "09:00 Cai", "10:00 Ana", "17:00 Ana"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Errorf("doses in order %v, want %v", order, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/events"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// maxMessageFiles bounds the files attached to one message
+const maxMessageFiles = 5
+
+// MessageFile is a file uploaded with a message
+type MessageFile struct {
+	Name string
+	Data []byte
+}
+
+type MessageUsecase interface {
+	ListThreads(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.MessageThreadFilter) ([]domain.MessageThreadResponse, int, error)
+	GetThread(ctx context.Context, userId uint, id uint) (*domain.MessageThreadResponse, error)
+	CreateThread(ctx context.Context, userId uint, input domain.MessageThreadRequest) (*domain.MessageThreadResponse, error)
+	ListMessages(ctx context.Context, userId uint, threadId uint, paginationFilter types.PaginationFilter) ([]domain.MessageResponse, int, error)
+	Send(ctx context.Context, userId uint, threadId uint, input domain.MessageRequest, files []MessageFile) (*domain.MessageResponse, error)
+	MarkRead(ctx context.Context, userId uint, threadId uint, input domain.MarkReadRequest) (*domain.MessageThreadResponse, error)
+	Unread(ctx context.Context, userId uint) (*domain.UnreadMessagesResponse, error)
+}
+
+type messageUsecase struct {
+	repo   repository.MessageRepository
+	files  AttachmentStore
+	events EventPublisher
+	now    func() time.Time
+}
+
+func NewMessageUsecase(repo repository.MessageRepository, files AttachmentStore, events EventPublisher) MessageUsecase {
+	return &messageUsecase{repo, files, events, time.Now}
+}
+
+// ListThreads returns the threads the user takes part in with their unread
+// counts. Admins see every thread.
+func (u *messageUsecase) ListThreads(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.MessageThreadFilter) ([]domain.MessageThreadResponse, int, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, 0, err
+	}
+	threads, totalPages, err := u.repo.ListThreads(ctx, paginationFilter, filter, userId, hasRole(user.Roles, domain.RoleAdmin))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(threads) == 0 {
+		return []domain.MessageThreadResponse{}, totalPages, nil
+	}
+
+	threadIds := make([]uint, len(threads))
+	for i, thread := range threads {
+		threadIds[i] = thread.ID
+	}
+	unread, err := u.repo.CountUnread(ctx, userId, threadIds)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.MessageThreadResponse, len(threads))
+	for i := range threads {
+		responses[i] = *domain.NewMessageThreadResponse(&threads[i], unread[threads[i].ID])
+	}
+	return responses, totalPages, nil
+}
+
+func (u *messageUsecase) GetThread(ctx context.Context, userId uint, id uint) (*domain.MessageThreadResponse, error) {
+	thread, _, err := u.thread(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.threadResponse(ctx, userId, thread)
+}
+
+// CreateThread starts a conversation about a child, open to its parents,
+// its teachers and the admins, or an announcement to a classroom (its
+// teachers and admins) or to the center (admins)
+func (u *messageUsecase) CreateThread(ctx context.Context, userId uint, input domain.MessageThreadRequest) (*domain.MessageThreadResponse, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	isAdmin := hasRole(user.Roles, domain.RoleAdmin)
+
+	thread := &domain.MessageThread{Kind: input.Kind, Subject: input.Subject, CreatedBy: userId}
+	allowed := isAdmin
+	switch input.Kind {
+	case domain.ThreadChild:
+		child, err := u.repo.GetChild(ctx, input.ChildID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.Validation(types.FieldError{Field: "childId", Message: "unknown child id"})
+		} else if err != nil {
+			return nil, err
+		}
+		thread.ChildID, thread.Child = &child.ID, child
+		if !allowed {
+			if allowed, err = u.isChildMember(ctx, userId, child.ID); err != nil {
+				return nil, err
+			}
+		}
+	case domain.ThreadClassroom:
+		classroom, err := u.repo.GetClassroom(ctx, input.ClassroomID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.Validation(types.FieldError{Field: "classroomId", Message: "unknown classroom id"})
+		} else if err != nil {
+			return nil, err
+		}
+		thread.ClassroomID, thread.Classroom = &classroom.ID, classroom
+		if !allowed {
+			if allowed, err = u.repo.IsClassroomTeacher(ctx, userId, classroom.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !allowed {
+		return nil, apperror.Forbidden("You are not allowed to start this conversation")
+	}
+
+	now := u.now()
+	thread.LastMessageAt = now
+	message := &domain.Message{SenderID: userId, Body: input.Body, CreatedAt: now}
+	if err := u.repo.CreateThread(ctx, thread, message); err != nil {
+		return nil, err
+	}
+	u.publish(ctx, thread, message)
+	return domain.NewMessageThreadResponse(thread, 0), nil
+}
+
+// ListMessages returns the messages of a thread, newest first, with their
+// read receipts
+func (u *messageUsecase) ListMessages(ctx context.Context, userId uint, threadId uint, paginationFilter types.PaginationFilter) ([]domain.MessageResponse, int, error) {
+	thread, _, err := u.thread(ctx, userId, threadId)
+	if err != nil {
+		return nil, 0, err
+	}
+	messages, totalPages, err := u.repo.ListMessages(ctx, paginationFilter, thread.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	reads, err := u.repo.GetReads(ctx, thread.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.MessageResponse, len(messages))
+	for i := range messages {
+		responses[i] = *u.response(thread, &messages[i], reads)
+	}
+	return responses, totalPages, nil
+}
+
+// Send adds a message with its files to a thread. Everyone in a child thread
+// may write, announcements are written by the staff who may start them.
+func (u *messageUsecase) Send(ctx context.Context, userId uint, threadId uint, input domain.MessageRequest, files []MessageFile) (*domain.MessageResponse, error) {
+	input.Body = strings.TrimSpace(input.Body)
+	if input.Body == "" && len(files) == 0 {
+		return nil, apperror.Validation(types.FieldError{Field: "body", Message: "body is required without files"})
+	}
+	if len(files) > maxMessageFiles {
+		return nil, apperror.Validation(types.FieldError{Field: "files", Message: fmt.Sprintf("at most %d files can be attached", maxMessageFiles)})
+	}
+	thread, user, err := u.thread(ctx, userId, threadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkWrite(ctx, user, thread); err != nil {
+		return nil, err
+	}
+
+	message := &domain.Message{ThreadID: thread.ID, SenderID: userId, Sender: user, Body: input.Body, CreatedAt: u.now()}
+	if err := u.repo.CreateMessage(ctx, message); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		attachment := &domain.Attachment{OwnerType: domain.AttachmentMessage, OwnerID: message.ID, ChildID: thread.ChildID, UploadedBy: userId}
+		if err := u.files.Attach(ctx, attachment, file.Name, file.Data); err != nil {
+			u.discard(ctx, message)
+			return nil, err
+		}
+		message.Attachments = append(message.Attachments, *attachment)
+	}
+	u.publish(ctx, thread, message)
+	return u.response(thread, message, nil), nil
+}
+
+// MarkRead moves the read position of the user up to the message, or to the
+// latest message of the thread
+func (u *messageUsecase) MarkRead(ctx context.Context, userId uint, threadId uint, input domain.MarkReadRequest) (*domain.MessageThreadResponse, error) {
+	thread, _, err := u.thread(ctx, userId, threadId)
+	if err != nil {
+		return nil, err
+	}
+	messageId := input.MessageID
+	if messageId == 0 {
+		if messageId, err = u.repo.GetLatestMessageId(ctx, thread.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		message, err := u.repo.GetMessageById(ctx, messageId)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && message.ThreadID != thread.ID) {
+			return nil, apperror.Validation(types.FieldError{Field: "messageId", Message: "unknown message id"})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	read := &domain.MessageRead{ThreadID: thread.ID, UserID: userId, LastReadMessageID: messageId, ReadAt: u.now()}
+	if err := u.repo.SaveRead(ctx, read); err != nil {
+		return nil, err
+	}
+	return u.threadResponse(ctx, userId, thread)
+}
+
+func (u *messageUsecase) Unread(ctx context.Context, userId uint) (*domain.UnreadMessagesResponse, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	threads, messages, err := u.repo.CountAllUnread(ctx, userId, hasRole(user.Roles, domain.RoleAdmin))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UnreadMessagesResponse{Threads: threads, Messages: messages}, nil
+}
+
+// thread returns the thread when the user takes part in it, admins read
+// every thread. Other threads do not exist for the user.
+func (u *messageUsecase) thread(ctx context.Context, userId uint, id uint) (*domain.MessageThread, domain.User, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, user, err
+	}
+	thread, err := u.repo.GetThreadById(ctx, id)
+	if err != nil {
+		return nil, user, err
+	}
+	if hasRole(user.Roles, domain.RoleAdmin) {
+		return thread, user, nil
+	}
+	visible, err := u.repo.IsThreadVisible(ctx, thread.ID, userId)
+	if err != nil {
+		return nil, user, err
+	}
+	if !visible {
+		return nil, user, apperror.NotFound("thread not found")
+	}
+	return thread, user, nil
+}
+
+func (u *messageUsecase) checkWrite(ctx context.Context, user domain.User, thread *domain.MessageThread) error {
+	if hasRole(user.Roles, domain.RoleAdmin) {
+		return nil
+	}
+	allowed := false
+	var err error
+	switch thread.Kind {
+	case domain.ThreadChild:
+		allowed, err = u.isChildMember(ctx, user.ID, *thread.ChildID)
+	case domain.ThreadClassroom:
+		allowed, err = u.repo.IsClassroomTeacher(ctx, user.ID, *thread.ClassroomID)
+	}
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return apperror.Forbidden("Only staff can write to announcements")
+	}
+	return nil
+}
+
+// isChildMember tells whether the user is a parent or a teacher of the child
+func (u *messageUsecase) isChildMember(ctx context.Context, userId uint, childId uint) (bool, error) {
+	isParent, err := u.repo.IsParentOf(ctx, userId, childId)
+	if err != nil || isParent {
+		return isParent, err
+	}
+	return u.repo.IsChildTeacher(ctx, userId, childId)
+}
+
+func (u *messageUsecase) threadResponse(ctx context.Context, userId uint, thread *domain.MessageThread) (*domain.MessageThreadResponse, error) {
+	unread, err := u.repo.CountUnread(ctx, userId, []uint{thread.ID})
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewMessageThreadResponse(thread, unread[thread.ID]), nil
+}
+
+// response lists the readers of the message other than its sender. Readers
+// of announcements are only counted.
+func (u *messageUsecase) response(thread *domain.MessageThread, message *domain.Message, reads []domain.MessageRead) *domain.MessageResponse {
+	response := &domain.MessageResponse{
+		ID:          message.ID,
+		ThreadID:    message.ThreadID,
+		SenderID:    message.SenderID,
+		SenderName:  message.Sender.Name,
+		Body:        message.Body,
+		Attachments: u.files.Signed(message.Attachments),
+		CreatedAt:   message.CreatedAt,
+	}
+	for _, read := range reads {
+		if read.UserID == message.SenderID || read.LastReadMessageID < message.ID {
+			continue
+		}
+		response.ReadCount++
+		if thread.Kind == domain.ThreadChild {
+			response.ReadBy = append(response.ReadBy, domain.MessageReceiptResponse{UserID: read.UserID, Name: read.User.Name, ReadAt: read.ReadAt})
+		}
+	}
+	return response
+}
+
+// discard removes a message whose files could not be stored, failures are
+// logged
+func (u *messageUsecase) discard(ctx context.Context, message *domain.Message) {
+	if err := u.repo.DiscardMessage(ctx, message); err != nil {
+		logger.FromContext(ctx).Error("failed to discard message", "message_id", message.ID, "error", err.Error())
+	}
+	u.files.RemoveFiles(ctx, message.Attachments)
+}
+
+// publish tells the members of the thread about a new message. Classroom
+// announcements reach the parents of the children on the roster.
+func (u *messageUsecase) publish(ctx context.Context, thread *domain.MessageThread, message *domain.Message) {
+	var audience events.Audience
+	switch thread.Kind {
+	case domain.ThreadCenter:
+		audience.Everyone = true
+	case domain.ThreadChild:
+		audience.ChildIDs = []uint{*thread.ChildID}
+	case domain.ThreadClassroom:
+		childIds, err := u.repo.GetClassroomChildIds(ctx, *thread.ClassroomID)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to load classroom children for message event", "thread_id", thread.ID, "error", err.Error())
+			audience.StaffOnly = true
+		}
+		audience.ChildIDs = childIds
+	}
+	u.events.Publish(ctx, domain.EventMessageCreated, audience, domain.MessageEvent{
+		ThreadID:    thread.ID,
+		MessageID:   message.ID,
+		Kind:        thread.Kind,
+		ChildID:     thread.ChildID,
+		ClassroomID: thread.ClassroomID,
+		SenderID:    message.SenderID,
+	})
+}
@@ -0,0 +1,578 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/mailer"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/push"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Notifier queues notifications to users. Failures are logged, they never
+// fail the change that triggered them.
+type Notifier interface {
+	// NotifyParents notifies the parents of the child, the name of the child
+	// is added to data as child
+	NotifyParents(ctx context.Context, childId uint, notificationType string, key string, data map[string]string)
+	NotifyUser(ctx context.Context, userId uint, notificationType string, key string, data map[string]string)
+}
+
+// NotificationSender delivers notifications on one channel. It returns
+// errNothingToSend when the user cannot be reached on it.
+type NotificationSender interface {
+	Channel() string
+	Send(ctx context.Context, notification *domain.Notification) error
+}
+
+var errNothingToSend = errors.New("nothing to send to")
+
+// notificationLease is how long a claimed delivery is held back from other
+// instances while it is being sent
+const notificationLease = 5 * time.Minute
+
+// maxBackoffDoublings caps the retry backoff at 1024 times its base
+const maxBackoffDoublings = 10
+
+// clockOutLookbackDays is how many days back open clock-ins are checked
+const clockOutLookbackDays = 7
+
+type notificationTemplate struct {
+	title *template.Template
+	body  *template.Template
+	// Channels used when the user chose none
+	email, push, inApp bool
+}
+
+func newNotificationTemplate(title, body string, email, push, inApp bool) notificationTemplate {
+	return notificationTemplate{
+		title: template.Must(template.New("title").Option("missingkey=error").Parse(title)),
+		body:  template.Must(template.New("body").Option("missingkey=error").Parse(body)),
+		email: email, push: push, inApp: inApp,
+	}
+}
+
+// notificationTypes lists the kinds of notification in the order of the
+// settings response
+var notificationTypes = []string{
+	domain.NotificationChildArrived,
+	domain.NotificationChildDeparted,
+	domain.NotificationMissingClockOut,
+}
+
+var notificationTemplates = map[string]notificationTemplate{
+	domain.NotificationChildArrived: newNotificationTemplate(
+		"{{.child}} has arrived",
+		"{{.child}} arrived at the center at {{.time}}.",
+		false, true, true),
+	domain.NotificationChildDeparted: newNotificationTemplate(
+		"{{.child}} has been picked up",
+		"{{.child}} left the center at {{.time}}.",
+		false, true, true),
+	domain.NotificationMissingClockOut: newNotificationTemplate(
+		"You have not clocked out",
+		"You clocked in at {{.clockIn}} on {{.date}} and have not clocked out yet. Clock out now, or ask an admin to correct your attendance.",
+		true, true, true),
+}
+
+type NotificationUsecase interface {
+	Notifier
+	// Run sends the due deliveries until ctx is done
+	Run(ctx context.Context)
+	// CheckClockOuts reminds the teachers who forgot to clock out, it runs
+	// as a scheduled job
+	CheckClockOuts(ctx context.Context) error
+	Inbox(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.NotificationInboxFilter) ([]domain.NotificationResponse, int, error)
+	Unread(ctx context.Context, userId uint) (*domain.UnreadNotificationsResponse, error)
+	MarkRead(ctx context.Context, userId uint, id uint) error
+	MarkAllRead(ctx context.Context, userId uint) error
+	GetSettings(ctx context.Context, userId uint) (*domain.NotificationSettingsResponse, error)
+	SaveSettings(ctx context.Context, userId uint, input domain.NotificationSettingsRequest) (*domain.NotificationSettingsResponse, error)
+	ListDevices(ctx context.Context, userId uint) ([]domain.PushDeviceResponse, error)
+	RegisterDevice(ctx context.Context, userId uint, input domain.PushDeviceRequest) (*domain.PushDeviceResponse, error)
+	RemoveDevice(ctx context.Context, userId uint, id uint) error
+}
+
+type notificationUsecase struct {
+	repo     repository.NotificationRepository
+	calendar CenterCalendar
+	cfg      *config.Config
+	senders  map[string]NotificationSender
+	now      func() time.Time
+}
+
+func NewNotificationUsecase(repo repository.NotificationRepository, calendar CenterCalendar, cfg *config.Config, senders ...NotificationSender) NotificationUsecase {
+	bySender := map[string]NotificationSender{}
+	for _, sender := range senders {
+		bySender[sender.Channel()] = sender
+	}
+	return &notificationUsecase{repo, calendar, cfg, bySender, time.Now}
+}
+
+func (u *notificationUsecase) NotifyParents(ctx context.Context, childId uint, notificationType string, key string, data map[string]string) {
+	child, err := u.repo.GetChildWithParents(ctx, childId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load child for notification", "child_id", childId, "type", notificationType, "error", err.Error())
+		return
+	}
+	data["child"] = child.Nickname
+	if data["child"] == "" {
+		data["child"] = child.Name
+	}
+	u.queue(ctx, child.Parents, notificationType, key, data)
+}
+
+func (u *notificationUsecase) NotifyUser(ctx context.Context, userId uint, notificationType string, key string, data map[string]string) {
+	users, err := u.repo.GetUsers(ctx, []uint{userId})
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load user for notification", "user_id", userId, "type", notificationType, "error", err.Error())
+		return
+	}
+	u.queue(ctx, users, notificationType, key, data)
+}
+
+// queue renders the notification once and stores it for every user with a
+// delivery per channel they chose. Email and push wait for the end of the
+// quiet hours of the user.
+func (u *notificationUsecase) queue(ctx context.Context, users []domain.User, notificationType string, key string, data map[string]string) {
+	log := logger.FromContext(ctx).With("type", notificationType)
+	if len(users) == 0 {
+		return
+	}
+	tmpl, ok := notificationTemplates[notificationType]
+	if !ok {
+		log.Error("unknown notification type")
+		return
+	}
+	var title, body strings.Builder
+	if err := tmpl.title.Execute(&title, data); err != nil {
+		log.Error("failed to render notification", "error", err.Error())
+		return
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		log.Error("failed to render notification", "error", err.Error())
+		return
+	}
+
+	userIds := make([]uint, len(users))
+	for i, user := range users {
+		userIds[i] = user.ID
+	}
+	preferences, err := u.repo.GetPreferences(ctx, userIds, notificationType)
+	if err != nil {
+		log.Error("failed to load notification preferences", "error", err.Error())
+		return
+	}
+	byUser := map[uint]domain.NotificationPreference{}
+	for _, preference := range preferences {
+		byUser[preference.UserID] = preference
+	}
+	settings, err := u.repo.GetSettings(ctx, userIds)
+	if err != nil {
+		log.Error("failed to load notification settings", "error", err.Error())
+		return
+	}
+	quiet := map[uint]domain.NotificationSettings{}
+	for _, setting := range settings {
+		quiet[setting.UserID] = setting
+	}
+
+	now := u.now()
+	for _, user := range users {
+		var dedupeKey *string
+		if key != "" {
+			if exists, err := u.repo.HasKey(ctx, user.ID, key); err != nil {
+				log.Error("failed to check notification key", "user_id", user.ID, "error", err.Error())
+				continue
+			} else if exists {
+				continue
+			}
+			dedupeKey = &key
+		}
+
+		preference, ok := byUser[user.ID]
+		if !ok {
+			preference = domain.NotificationPreference{Email: tmpl.email, Push: tmpl.push, InApp: tmpl.inApp}
+		}
+		sendAt := now
+		if until, ok := quietUntil(quiet[user.ID], now); ok {
+			sendAt = until
+		}
+
+		notification := &domain.Notification{
+			UserID:    user.ID,
+			Type:      notificationType,
+			DedupeKey: dedupeKey,
+			Title:     title.String(),
+			Body:      body.String(),
+		}
+		if preference.InApp {
+			notification.Deliveries = append(notification.Deliveries, domain.NotificationDelivery{Channel: domain.ChannelInApp, Status: domain.DeliveryPending, NextAttemptAt: now})
+		}
+		if preference.Email {
+			notification.Deliveries = append(notification.Deliveries, domain.NotificationDelivery{Channel: domain.ChannelEmail, Status: domain.DeliveryPending, NextAttemptAt: sendAt})
+		}
+		if preference.Push {
+			notification.Deliveries = append(notification.Deliveries, domain.NotificationDelivery{Channel: domain.ChannelPush, Status: domain.DeliveryPending, NextAttemptAt: sendAt})
+		}
+		if len(notification.Deliveries) == 0 {
+			continue
+		}
+		if err := u.repo.Create(ctx, notification); err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Error("failed to queue notification", "user_id", user.ID, "error", err.Error())
+		}
+	}
+}
+
+// quietUntil returns the end of the quiet hours when now falls in them. The
+// hours may span midnight.
+func quietUntil(settings domain.NotificationSettings, now time.Time) (time.Time, bool) {
+	if settings.QuietStart == "" || settings.QuietStart == settings.QuietEnd {
+		return time.Time{}, false
+	}
+	start, end := clockOffset(settings.QuietStart), clockOffset(settings.QuietEnd)
+	day := today(now)
+	offset := now.Sub(day)
+	switch {
+	case start < end && offset >= start && offset < end:
+		return day.Add(end), true
+	case start > end && offset >= start:
+		return day.AddDate(0, 0, 1).Add(end), true
+	case start > end && offset < end:
+		return day.Add(end), true
+	}
+	return time.Time{}, false
+}
+
+func (u *notificationUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.cfg.NotificationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		u.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch sends the due deliveries, batch after batch until none is left
+func (u *notificationUsecase) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := u.repo.ClaimDue(ctx, u.now(), notificationLease, u.cfg.NotificationBatchSize)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to claim notification deliveries", "error", err.Error())
+			return
+		}
+		for i := range deliveries {
+			u.deliver(ctx, &deliveries[i])
+		}
+		if len(deliveries) < u.cfg.NotificationBatchSize {
+			return
+		}
+	}
+}
+
+// deliver sends one delivery and records the outcome. Failed attempts are
+// retried with a doubling backoff until the attempts run out. A delivery
+// that started is finished on shutdown.
+func (u *notificationUsecase) deliver(ctx context.Context, delivery *domain.NotificationDelivery) {
+	ctx = context.WithoutCancel(ctx)
+	log := logger.FromContext(ctx).With("delivery_id", delivery.ID, "channel", delivery.Channel)
+
+	var err error
+	if sender, ok := u.senders[delivery.Channel]; ok {
+		err = sender.Send(ctx, &delivery.Notification)
+	} else {
+		err = fmt.Errorf("no sender for channel %s", delivery.Channel)
+	}
+
+	now := u.now()
+	delivery.Attempts++
+	var outcome string
+	switch {
+	case err == nil:
+		delivery.Status, delivery.SentAt, delivery.LastError = domain.DeliverySent, &now, ""
+		outcome = domain.DeliverySent
+	case errors.Is(err, errNothingToSend):
+		delivery.Status, delivery.LastError = domain.DeliverySkipped, err.Error()
+		outcome = domain.DeliverySkipped
+	case delivery.Attempts >= u.cfg.NotificationMaxAttempts:
+		delivery.Status, delivery.LastError = domain.DeliveryFailed, err.Error()
+		outcome = domain.DeliveryFailed
+		log.Error("notification delivery failed", "attempts", delivery.Attempts, "error", err.Error())
+	default:
+		delivery.NextAttemptAt = now.Add(u.cfg.NotificationRetryBackoff << min(delivery.Attempts-1, maxBackoffDoublings))
+		delivery.LastError = err.Error()
+		outcome = "retry"
+		log.Warn("notification delivery will be retried", "attempts", delivery.Attempts, "error", err.Error())
+	}
+	metrics.NotificationDelivery(delivery.Channel, outcome)
+
+	if err := u.repo.SaveDelivery(ctx, delivery); err != nil {
+		log.Error("failed to save notification delivery", "error", err.Error())
+	}
+}
+
+// CheckClockOuts reminds teachers still clocked in after the grace period
+// past closing, or on a later day. Each clock-in is only reminded once, so
+// the check can run again after a failure.
+func (u *notificationUsecase) CheckClockOuts(ctx context.Context) error {
+	now := u.now()
+	attendances, err := u.repo.GetOpenTeacherAttendances(ctx, today(now).AddDate(0, 0, -clockOutLookbackDays))
+	if err != nil {
+		return err
+	}
+	for _, attendance := range attendances {
+		hours, err := u.calendar.Hours(ctx, attendance.Date)
+		if err != nil {
+			return err
+		}
+		deadline := today(attendance.Date).AddDate(0, 0, 1)
+		if hours.Open {
+			deadline = hours.ClosesAt().Add(u.cfg.NotificationClockOutGrace)
+		}
+		if now.Before(deadline) {
+			continue
+		}
+		u.NotifyUser(ctx, attendance.UserID, domain.NotificationMissingClockOut, "missing_clock_out:"+strconv.FormatUint(uint64(attendance.ID), 10), map[string]string{
+			"clockIn": attendance.ClockIn.Format("15:04"),
+			"date":    attendance.Date.Format(dateLayout),
+		})
+	}
+	return nil
+}
+
+func (u *notificationUsecase) Inbox(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.NotificationInboxFilter) ([]domain.NotificationResponse, int, error) {
+	notifications, totalPages, err := u.repo.ListInbox(ctx, paginationFilter, filter, userId)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.NotificationResponse, len(notifications))
+	for i := range notifications {
+		responses[i] = *domain.NewNotificationResponse(&notifications[i])
+	}
+	return responses, totalPages, nil
+}
+
+func (u *notificationUsecase) Unread(ctx context.Context, userId uint) (*domain.UnreadNotificationsResponse, error) {
+	unread, err := u.repo.CountUnread(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UnreadNotificationsResponse{Unread: unread}, nil
+}
+
+func (u *notificationUsecase) MarkRead(ctx context.Context, userId uint, id uint) error {
+	notification, err := u.repo.GetInboxById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && notification.UserID != userId) {
+		return apperror.NotFound("Notification not found")
+	} else if err != nil {
+		return err
+	}
+	return u.repo.MarkRead(ctx, userId, notification.ID, u.now())
+}
+
+func (u *notificationUsecase) MarkAllRead(ctx context.Context, userId uint) error {
+	return u.repo.MarkRead(ctx, userId, 0, u.now())
+}
+
+func (u *notificationUsecase) GetSettings(ctx context.Context, userId uint) (*domain.NotificationSettingsResponse, error) {
+	settings, err := u.repo.GetSettings(ctx, []uint{userId})
+	if err != nil {
+		return nil, err
+	}
+	preferences, err := u.repo.GetUserPreferences(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string]domain.NotificationPreference{}
+	for _, preference := range preferences {
+		byType[preference.Type] = preference
+	}
+
+	response := &domain.NotificationSettingsResponse{Preferences: []domain.NotificationPreferenceResponse{}}
+	if len(settings) > 0 {
+		response.QuietStart, response.QuietEnd = settings[0].QuietStart, settings[0].QuietEnd
+	}
+	for _, notificationType := range notificationTypes {
+		preference, ok := byType[notificationType]
+		if !ok {
+			tmpl := notificationTemplates[notificationType]
+			preference = domain.NotificationPreference{Email: tmpl.email, Push: tmpl.push, InApp: tmpl.inApp}
+		}
+		response.Preferences = append(response.Preferences, domain.NotificationPreferenceResponse{
+			Type:  notificationType,
+			Email: preference.Email,
+			Push:  preference.Push,
+			InApp: preference.InApp,
+		})
+	}
+	return response, nil
+}
+
+func (u *notificationUsecase) SaveSettings(ctx context.Context, userId uint, input domain.NotificationSettingsRequest) (*domain.NotificationSettingsResponse, error) {
+	settings := &domain.NotificationSettings{UserID: userId, QuietStart: input.QuietStart, QuietEnd: input.QuietEnd}
+	preferences := make([]domain.NotificationPreference, 0, len(input.Preferences))
+	seen := map[string]bool{}
+	for i, preference := range input.Preferences {
+		if seen[preference.Type] {
+			return nil, apperror.Validation(types.FieldError{Field: fmt.Sprintf("preferences[%d].type", i), Message: "type is listed twice"})
+		}
+		seen[preference.Type] = true
+		preferences = append(preferences, domain.NotificationPreference{
+			UserID: userId,
+			Type:   preference.Type,
+			Email:  preference.Email,
+			Push:   preference.Push,
+			InApp:  preference.InApp,
+		})
+	}
+	if err := u.repo.SaveSettings(ctx, settings, preferences); err != nil {
+		return nil, err
+	}
+	return u.GetSettings(ctx, userId)
+}
+
+func (u *notificationUsecase) ListDevices(ctx context.Context, userId uint) ([]domain.PushDeviceResponse, error) {
+	devices, err := u.repo.ListDevices(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]domain.PushDeviceResponse, len(devices))
+	for i := range devices {
+		responses[i] = *domain.NewPushDeviceResponse(&devices[i])
+	}
+	return responses, nil
+}
+
+// RegisterDevice adds the device of the user, registering a known token
+// again only refreshes it
+func (u *notificationUsecase) RegisterDevice(ctx context.Context, userId uint, input domain.PushDeviceRequest) (*domain.PushDeviceResponse, error) {
+	device := &domain.PushDevice{UserID: userId, Token: input.Token, Platform: input.Platform, LastSeenAt: u.now()}
+	if err := u.repo.SaveDevice(ctx, device); err != nil {
+		return nil, err
+	}
+	return domain.NewPushDeviceResponse(device), nil
+}
+
+func (u *notificationUsecase) RemoveDevice(ctx context.Context, userId uint, id uint) error {
+	device, err := u.repo.GetDevice(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && device.UserID != userId) {
+		return apperror.NotFound("Device not found")
+	} else if err != nil {
+		return err
+	}
+	return u.repo.DeleteDevice(ctx, device.ID)
+}
+
+type emailSender struct {
+	mail mailer.Mailer
+}
+
+// NewEmailSender delivers notifications by email to the address of the user
+func NewEmailSender(mail mailer.Mailer) NotificationSender {
+	return &emailSender{mail}
+}
+
+func (s *emailSender) Channel() string {
+	return domain.ChannelEmail
+}
+
+func (s *emailSender) Send(ctx context.Context, notification *domain.Notification) error {
+	// Deleted users are not loaded with the notification
+	if notification.User.Email == "" {
+		return errNothingToSend
+	}
+	return s.mail.Send(ctx, mailer.Message{
+		To:      notification.User.Email,
+		Subject: notification.Title,
+		Body:    notification.Body + "\n\nYou can choose which notifications you receive in the app settings.",
+	})
+}
+
+type pushSender struct {
+	repo   repository.NotificationRepository
+	pusher push.Pusher
+}
+
+// NewPushSender delivers notifications to every device of the user and
+// forgets the devices the gateway no longer knows
+func NewPushSender(repo repository.NotificationRepository, pusher push.Pusher) NotificationSender {
+	return &pushSender{repo, pusher}
+}
+
+func (s *pushSender) Channel() string {
+	return domain.ChannelPush
+}
+
+func (s *pushSender) Send(ctx context.Context, notification *domain.Notification) error {
+	devices, err := s.repo.ListDevices(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	var errs []error
+	for _, device := range devices {
+		err := s.pusher.Push(ctx, push.Message{
+			Token: device.Token,
+			Title: notification.Title,
+			Body:  notification.Body,
+			Data:  map[string]string{"notification_id": strconv.FormatUint(uint64(notification.ID), 10), "type": notification.Type},
+		})
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, push.ErrInvalidToken):
+			if err := s.repo.DeleteDevice(ctx, device.ID); err != nil {
+				logger.FromContext(ctx).Error("failed to forget push device", "device_id", device.ID, "error", err.Error())
+			}
+		default:
+			errs = append(errs, err)
+		}
+	}
+
+	// A retry would notify the devices that got it again, so only an
+	// attempt without any success is retried
+	if sent > 0 {
+		return nil
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return errNothingToSend
+}
+
+type inboxSender struct {
+	repo repository.NotificationRepository
+	now  func() time.Time
+}
+
+// NewInboxSender shows notifications in the in-app inbox
+func NewInboxSender(repo repository.NotificationRepository) NotificationSender {
+	return &inboxSender{repo, time.Now}
+}
+
+func (s *inboxSender) Channel() string {
+	return domain.ChannelInApp
+}
+
+func (s *inboxSender) Send(ctx context.Context, notification *domain.Notification) error {
+	return s.repo.MarkInbox(ctx, notification.ID, s.now())
+}
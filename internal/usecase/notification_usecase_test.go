@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+)
+
+// fakeNotificationRepository keeps the queued notifications, every user
+// has the default preferences
+type fakeNotificationRepository struct {
+	repository.NotificationRepository
+	settings      []domain.NotificationSettings
+	attendances   []domain.TeacherAttendance
+	since         time.Time
+	notifications []*domain.Notification
+}
+
+func (r *fakeNotificationRepository) GetUsers(ctx context.Context, userIds []uint) ([]domain.User, error) {
+	users := make([]domain.User, len(userIds))
+	for i, userId := range userIds {
+		users[i] = domain.User{ID: userId}
+	}
+	return users, nil
+}
+
+func (r *fakeNotificationRepository) GetPreferences(ctx context.Context, userIds []uint, notificationType string) ([]domain.NotificationPreference, error) {
+	return nil, nil
+}
+
+func (r *fakeNotificationRepository) GetSettings(ctx context.Context, userIds []uint) ([]domain.NotificationSettings, error) {
+	return r.settings, nil
+}
+
+func (r *fakeNotificationRepository) HasKey(ctx context.Context, userId uint, key string) (bool, error) {
+	for _, notification := range r.notifications {
+		if notification.UserID == userId && notification.DedupeKey != nil && *notification.DedupeKey == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeNotificationRepository) Create(ctx context.Context, notification *domain.Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *fakeNotificationRepository) GetOpenTeacherAttendances(ctx context.Context, since time.Time) ([]domain.TeacherAttendance, error) {
+	r.since = since
+	var open []domain.TeacherAttendance
+	for _, attendance := range r.attendances {
+		if !attendance.Date.Before(since) {
+			open = append(open, attendance)
+		}
+	}
+	return open, nil
+}
+
+func newTestNotificationUsecase(repo *fakeNotificationRepository, calendar CenterCalendar, now *time.Time) *notificationUsecase {
+	cfg := &config.Config{NotificationClockOutGrace: time.Hour}
+	return &notificationUsecase{repo: repo, calendar: calendar, cfg: cfg, now: func() time.Time { return *now }}
+}
+
+func TestQuietUntil(t *testing.T) {
+	day := date(2025, 2, 20)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		now        time.Time
+		until      time.Time
+		quiet      bool
+	}{
+		{"no quiet hours", "", "", at(23, 0), time.Time{}, false},
+		{"empty quiet hours", "22:00", "22:00", at(22, 0), time.Time{}, false},
+
+		{"before daytime quiet hours", "12:00", "14:00", at(11, 59), time.Time{}, false},
+		{"start of daytime quiet hours", "12:00", "14:00", at(12, 0), at(14, 0), true},
+		{"end of daytime quiet hours", "12:00", "14:00", at(13, 59), at(14, 0), true},
+		{"after daytime quiet hours", "12:00", "14:00", at(14, 0), time.Time{}, false},
+
+		{"before overnight quiet hours", "22:00", "07:00", at(21, 59), time.Time{}, false},
+		{"start of overnight quiet hours", "22:00", "07:00", at(22, 0), day.AddDate(0, 0, 1).Add(7 * time.Hour), true},
+		{"before midnight", "22:00", "07:00", at(23, 59), day.AddDate(0, 0, 1).Add(7 * time.Hour), true},
+		{"midnight", "22:00", "07:00", at(0, 0), at(7, 0), true},
+		{"end of overnight quiet hours", "22:00", "07:00", at(6, 59), at(7, 0), true},
+		{"after overnight quiet hours", "22:00", "07:00", at(7, 0), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		until, quiet := quietUntil(domain.NotificationSettings{QuietStart: tt.start, QuietEnd: tt.end}, tt.now)
+		if quiet != tt.quiet || !until.Equal(tt.until) {
+			t.Errorf("%s: quietUntil(%s) = %v, %v, want %v, %v", tt.name, tt.now.Format("15:04"), until, quiet, tt.until, tt.quiet)
+		}
+	}
+}
+
+func TestQueueHoldsEmailAndPushUntilQuietHoursEnd(t *testing.T) {
+	now := time.Date(2025, 2, 20, 23, 0, 0, 0, time.UTC)
+	repo := &fakeNotificationRepository{settings: []domain.NotificationSettings{{UserID: 1, QuietStart: "22:00", QuietEnd: "07:00"}}}
+	u := newTestNotificationUsecase(repo, &fakeCenterCalendar{}, &now)
+
+	u.NotifyUser(context.Background(), 1, domain.NotificationMissingClockOut, "", map[string]string{"clockIn": "08:00", "date": "2025-02-20"})
+	if len(repo.notifications) != 1 {
+		t.Fatalf("queued %d notifications, want 1", len(repo.notifications))
+	}
+	morning := time.Date(2025, 2, 21, 7, 0, 0, 0, time.UTC)
+	for _, delivery := range repo.notifications[0].Deliveries {
+		want := morning
+		if delivery.Channel == domain.ChannelInApp {
+			want = now
+		}
+		if !delivery.NextAttemptAt.Equal(want) {
+			t.Errorf("%s delivery at %v, want %v", delivery.Channel, delivery.NextAttemptAt, want)
+		}
+	}
+}
+
+func TestCheckClockOutsRemindsOncePastClosing(t *testing.T) {
+	// The center closes at 18:00 and reminders wait an hour longer.
+	// 2025-02-22 is a Saturday the center is closed.
+	calendar := &fakeCenterCalendar{closed: map[string]domain.CalendarDay{"2025-02-22": {Name: "Weekend"}}}
+	clockIn := func(day time.Time) domain.TeacherAttendance {
+		at := day.Add(8 * time.Hour)
+		return domain.TeacherAttendance{ID: uint(day.Day()), UserID: 1, Date: day, ClockIn: &at}
+	}
+
+	tests := []struct {
+		name     string
+		clockIn  time.Time
+		now      time.Time
+		reminded bool
+	}{
+		{"before the grace period ends", date(2025, 2, 20), date(2025, 2, 20).Add(18*time.Hour + 59*time.Minute), false},
+		{"when the grace period ends", date(2025, 2, 20), date(2025, 2, 20).Add(19 * time.Hour), true},
+		{"on a later day", date(2025, 2, 18), date(2025, 2, 20).Add(9 * time.Hour), true},
+		{"on a closed day before midnight", date(2025, 2, 22), date(2025, 2, 22).Add(23 * time.Hour), false},
+		{"on a closed day after midnight", date(2025, 2, 22), date(2025, 2, 23), true},
+		{"older than the lookback", date(2025, 2, 10), date(2025, 2, 20).Add(9 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		now := tt.now
+		repo := &fakeNotificationRepository{attendances: []domain.TeacherAttendance{clockIn(tt.clockIn)}}
+		u := newTestNotificationUsecase(repo, calendar, &now)
+
+		if err := u.CheckClockOuts(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := len(repo.notifications) == 1; got != tt.reminded {
+			t.Errorf("%s: reminded = %v, want %v", tt.name, got, tt.reminded)
+		}
+		if want := today(now).AddDate(0, 0, -clockOutLookbackDays); !repo.since.Equal(want) {
+			t.Errorf("%s: looked back to %v, want %v", tt.name, repo.since, want)
+		}
+
+		// The next run does not remind again
+		now = now.Add(time.Hour)
+		if err := u.CheckClockOuts(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tt.reminded && len(repo.notifications) != 1 {
+			t.Errorf("%s: reminded %d times, want once", tt.name, len(repo.notifications))
+		}
+	}
+}
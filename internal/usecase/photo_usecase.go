@@ -0,0 +1,456 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// maxArchiveDays bounds the bulk download of the photos of a child to a
+// school term
+const maxArchiveDays = 184
+
+type PhotoUsecase interface {
+	GetConsent(ctx context.Context, userId uint, childId uint) (*domain.PhotoConsentResponse, error)
+	SetConsent(ctx context.Context, userId uint, childId uint, input domain.PhotoConsentRequest) (*domain.PhotoConsentResponse, error)
+	Feed(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.PhotoFeedFilter) ([]domain.PhotoPostResponse, int, error)
+	GetPost(ctx context.Context, userId uint, id uint) (*domain.PhotoPostResponse, error)
+	CreatePost(ctx context.Context, userId uint, input domain.PhotoPostRequest) (*domain.PhotoPostResponse, error)
+	UpdatePost(ctx context.Context, userId uint, id uint, input domain.PhotoPostRequest) (*domain.PhotoPostResponse, error)
+	AddPhoto(ctx context.Context, userId uint, id uint, fileName string, data []byte) (*domain.PhotoPostResponse, error)
+	Publish(ctx context.Context, userId uint, id uint) (*domain.PhotoPostResponse, error)
+	DeletePost(ctx context.Context, userId uint, id uint) error
+	React(ctx context.Context, userId uint, id uint, input domain.PhotoReactionRequest) (*domain.PhotoPostResponse, error)
+	Unreact(ctx context.Context, userId uint, id uint) error
+	Comment(ctx context.Context, userId uint, id uint, input domain.PhotoCommentRequest) (*domain.PhotoCommentResponse, error)
+	DeleteComment(ctx context.Context, userId uint, commentId uint) error
+	ChildPhotos(ctx context.Context, userId uint, childId uint, query domain.PhotoArchiveQuery) (*domain.Child, []domain.Attachment, error)
+	WriteArchive(ctx context.Context, w io.Writer, attachments []domain.Attachment) error
+}
+
+type photoUsecase struct {
+	repo  repository.PhotoRepository
+	files AttachmentStore
+	now   func() time.Time
+}
+
+func NewPhotoUsecase(repo repository.PhotoRepository, files AttachmentStore) PhotoUsecase {
+	return &photoUsecase{repo, files, time.Now}
+}
+
+func (u *photoUsecase) GetConsent(ctx context.Context, userId uint, childId uint) (*domain.PhotoConsentResponse, error) {
+	if _, err := u.repo.GetChild(ctx, childId); err != nil {
+		return nil, err
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, childId); err != nil {
+		return nil, err
+	}
+	consent, err := u.repo.GetConsent(ctx, childId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.PhotoConsentResponse{ChildID: childId}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &domain.PhotoConsentResponse{
+		ChildID:   childId,
+		Granted:   consent.Granted,
+		UpdatedBy: &consent.UpdatedBy,
+		UpdatedAt: &consent.UpdatedAt,
+	}, nil
+}
+
+// SetConsent gives or withdraws the photo consent of the child. Only its
+// parents and admins may change it. Withdrawing it removes the tags of the
+// child from every post, so its parents no longer see them.
+func (u *photoUsecase) SetConsent(ctx context.Context, userId uint, childId uint, input domain.PhotoConsentRequest) (*domain.PhotoConsentResponse, error) {
+	if _, err := u.repo.GetChild(ctx, childId); err != nil {
+		return nil, err
+	}
+	isParent, err := u.repo.IsParentOf(ctx, userId, childId)
+	if err != nil {
+		return nil, err
+	}
+	if !isParent {
+		user, err := u.repo.GetUserWithRoles(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRole(user.Roles, domain.RoleAdmin) {
+			return nil, apperror.Forbidden("Only the parents of the child and admins can change the photo consent")
+		}
+	}
+
+	now := u.now()
+	consent := &domain.PhotoConsent{ChildID: childId, Granted: *input.Granted, UpdatedBy: userId, UpdatedAt: now}
+	if err := u.repo.SaveConsent(ctx, consent); err != nil {
+		return nil, err
+	}
+	response := &domain.PhotoConsentResponse{ChildID: childId, Granted: consent.Granted, UpdatedBy: &userId, UpdatedAt: &now}
+	if !consent.Granted {
+		if response.UntaggedPosts, err = u.repo.UntagChild(ctx, childId); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// Feed returns the posts to staff, drafts included when asked, and the
+// published posts tagging their children to parents
+func (u *photoUsecase) Feed(ctx context.Context, userId uint, paginationFilter types.PaginationFilter, filter domain.PhotoFeedFilter) ([]domain.PhotoPostResponse, int, error) {
+	viewer, err := u.viewer(ctx, userId)
+	if err != nil {
+		return nil, 0, err
+	}
+	var parentId uint
+	if viewer.childIds != nil {
+		parentId = userId
+	}
+	posts, totalPages, err := u.repo.ListPosts(ctx, paginationFilter, filter, parentId)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.PhotoPostResponse, len(posts))
+	for i := range posts {
+		responses[i] = *u.response(&posts[i], viewer)
+	}
+	return responses, totalPages, nil
+}
+
+func (u *photoUsecase) GetPost(ctx context.Context, userId uint, id uint) (*domain.PhotoPostResponse, error) {
+	post, viewer, err := u.visiblePost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.response(post, viewer), nil
+}
+
+// CreatePost starts a draft tagged with the children. Photos are added one
+// by one before it is published.
+func (u *photoUsecase) CreatePost(ctx context.Context, userId uint, input domain.PhotoPostRequest) (*domain.PhotoPostResponse, error) {
+	children, err := u.tags(ctx, input.ChildIDs)
+	if err != nil {
+		return nil, err
+	}
+	post := &domain.PhotoPost{AuthorID: userId, Caption: input.Caption, Children: children}
+	if err := u.repo.CreatePost(ctx, post); err != nil {
+		return nil, err
+	}
+	return u.GetPost(ctx, userId, post.ID)
+}
+
+// UpdatePost changes the caption and the tags. Only the author and admins
+// may change a post.
+func (u *photoUsecase) UpdatePost(ctx context.Context, userId uint, id uint, input domain.PhotoPostRequest) (*domain.PhotoPostResponse, error) {
+	post, err := u.ownPost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	children, err := u.tags(ctx, input.ChildIDs)
+	if err != nil {
+		return nil, err
+	}
+	post.Caption = input.Caption
+	post.Children = children
+	if err := u.repo.UpdatePost(ctx, post); err != nil {
+		return nil, err
+	}
+	return u.GetPost(ctx, userId, post.ID)
+}
+
+// AddPhoto stores an image on the post, without its metadata
+func (u *photoUsecase) AddPhoto(ctx context.Context, userId uint, id uint, fileName string, data []byte) (*domain.PhotoPostResponse, error) {
+	post, err := u.ownPost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	attachment := &domain.Attachment{OwnerType: domain.AttachmentPost, OwnerID: post.ID, UploadedBy: userId}
+	if err := u.files.Attach(ctx, attachment, fileName, data, "image/jpeg", "image/png"); err != nil {
+		return nil, err
+	}
+	return u.GetPost(ctx, userId, post.ID)
+}
+
+// Publish shares the post with the parents of the tagged children
+func (u *photoUsecase) Publish(ctx context.Context, userId uint, id uint) (*domain.PhotoPostResponse, error) {
+	post, err := u.ownPost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.PublishedAt != nil {
+		return nil, apperror.Conflict("the post is already published")
+	}
+	if len(post.Photos) == 0 {
+		return nil, apperror.Conflict("add at least one photo before publishing")
+	}
+	if len(post.Children) == 0 {
+		return nil, apperror.Conflict("tag at least one child before publishing")
+	}
+
+	now := u.now()
+	post.PublishedAt = &now
+	if err := u.repo.PublishPost(ctx, post); err != nil {
+		return nil, err
+	}
+	return u.GetPost(ctx, userId, post.ID)
+}
+
+// DeletePost removes the post with its photos, reactions and comments
+func (u *photoUsecase) DeletePost(ctx context.Context, userId uint, id uint) error {
+	post, err := u.ownPost(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.DeletePost(ctx, post); err != nil {
+		return err
+	}
+	u.files.RemoveFiles(ctx, post.Photos)
+	return nil
+}
+
+// React sets the reaction of the user on a post, replacing an earlier one
+func (u *photoUsecase) React(ctx context.Context, userId uint, id uint, input domain.PhotoReactionRequest) (*domain.PhotoPostResponse, error) {
+	post, _, err := u.visiblePost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.PublishedAt == nil {
+		return nil, apperror.Conflict("drafts cannot get reactions")
+	}
+	if err := u.repo.SaveReaction(ctx, &domain.PhotoReaction{PostID: post.ID, UserID: userId, Kind: input.Kind}); err != nil {
+		return nil, err
+	}
+	return u.GetPost(ctx, userId, post.ID)
+}
+
+func (u *photoUsecase) Unreact(ctx context.Context, userId uint, id uint) error {
+	post, _, err := u.visiblePost(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	return u.repo.DeleteReaction(ctx, post.ID, userId)
+}
+
+func (u *photoUsecase) Comment(ctx context.Context, userId uint, id uint, input domain.PhotoCommentRequest) (*domain.PhotoCommentResponse, error) {
+	post, viewer, err := u.visiblePost(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.PublishedAt == nil {
+		return nil, apperror.Conflict("drafts cannot get comments")
+	}
+	comment := &domain.PhotoComment{PostID: post.ID, UserID: userId, Body: input.Body}
+	if err := u.repo.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	comment.User = viewer.user
+	return domain.NewPhotoCommentResponse(comment), nil
+}
+
+// DeleteComment removes a comment. Only its writer and admins may delete it.
+func (u *photoUsecase) DeleteComment(ctx context.Context, userId uint, commentId uint) error {
+	comment, err := u.repo.GetCommentById(ctx, commentId)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userId {
+		user, err := u.repo.GetUserWithRoles(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if !hasRole(user.Roles, domain.RoleAdmin) {
+			return apperror.Forbidden("Only the writer and admins can delete this comment")
+		}
+	}
+	return u.repo.DeleteComment(ctx, comment)
+}
+
+// ChildPhotos returns the photos of the published posts tagging the child
+// between the two days, at most a term apart
+func (u *photoUsecase) ChildPhotos(ctx context.Context, userId uint, childId uint, query domain.PhotoArchiveQuery) (*domain.Child, []domain.Attachment, error) {
+	from, _ := time.ParseInLocation(dateLayout, query.From, time.Local)
+	to, _ := time.ParseInLocation(dateLayout, query.To, time.Local)
+	if to.Before(from) {
+		return nil, nil, apperror.Validation(types.FieldError{Field: "to", Message: "to must not be before from"})
+	}
+	if to.Sub(from) > maxArchiveDays*24*time.Hour {
+		return nil, nil, apperror.Validation(types.FieldError{Field: "to", Message: fmt.Sprintf("the download is limited to %d days", maxArchiveDays)})
+	}
+
+	child, err := u.repo.GetChild(ctx, childId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := checkChildAccess(ctx, u.repo, userId, childId); err != nil {
+		return nil, nil, err
+	}
+	attachments, err := u.repo.GetChildPhotos(ctx, childId, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, nil, apperror.NotFound("no photos of the child in this period")
+	}
+	return child, attachments, nil
+}
+
+// WriteArchive writes the photos as a zip. Photos are already compressed so
+// they are stored as they are. A missing file is skipped.
+func (u *photoUsecase) WriteArchive(ctx context.Context, w io.Writer, attachments []domain.Attachment) error {
+	archive := zip.NewWriter(w)
+	for i := range attachments {
+		attachment := &attachments[i]
+		file, err := u.files.Open(ctx, attachment)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to open photo for archive", "attachment_id", attachment.ID, "error", err.Error())
+			continue
+		}
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%s-%d%s", attachment.CreatedAt.Format("2006-01-02"), attachment.ID, filepath.Ext(attachment.FileName)),
+			Method:   zip.Store,
+			Modified: attachment.CreatedAt,
+		})
+		if err == nil {
+			_, err = io.Copy(entry, file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// photoViewer is the user reading posts. childIds holds the children of a
+// parent and is nil for staff.
+type photoViewer struct {
+	user     domain.User
+	childIds []uint
+}
+
+func (u *photoUsecase) viewer(ctx context.Context, userId uint) (*photoViewer, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) {
+		return &photoViewer{user: user}, nil
+	}
+	childIds, err := u.repo.GetParentChildIds(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if childIds == nil {
+		childIds = []uint{}
+	}
+	return &photoViewer{user, childIds}, nil
+}
+
+// visiblePost returns the post to staff and, once published, to the parents
+// of a tagged child. Other posts do not exist for the user.
+func (u *photoUsecase) visiblePost(ctx context.Context, userId uint, id uint) (*domain.PhotoPost, *photoViewer, error) {
+	post, err := u.repo.GetPostById(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	viewer, err := u.viewer(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if viewer.childIds == nil {
+		return post, viewer, nil
+	}
+	if post.PublishedAt != nil {
+		for _, child := range post.Children {
+			if slices.Contains(viewer.childIds, child.ID) {
+				return post, viewer, nil
+			}
+		}
+	}
+	return nil, nil, apperror.NotFound("post not found")
+}
+
+func (u *photoUsecase) ownPost(ctx context.Context, userId uint, id uint) (*domain.PhotoPost, error) {
+	post, err := u.repo.GetPostById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != userId {
+		user, err := u.repo.GetUserWithRoles(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRole(user.Roles, domain.RoleAdmin) {
+			return nil, apperror.Forbidden("Only the author and admins can change this post")
+		}
+	}
+	return post, nil
+}
+
+// tags loads the children to tag, each of them needs a granted consent
+func (u *photoUsecase) tags(ctx context.Context, childIds []uint) ([]domain.Child, error) {
+	children, err := u.repo.GetChildren(ctx, childIds)
+	if err != nil {
+		return nil, err
+	}
+	consented, err := u.repo.GetConsentedChildIds(ctx, childIds)
+	if err != nil {
+		return nil, err
+	}
+	for i, childId := range childIds {
+		field := fmt.Sprintf("childIds[%d]", i)
+		if !slices.ContainsFunc(children, func(child domain.Child) bool { return child.ID == childId }) {
+			return nil, apperror.Validation(types.FieldError{Field: field, Message: "unknown child id"})
+		}
+		if !slices.Contains(consented, childId) {
+			return nil, apperror.Validation(types.FieldError{Field: field, Message: "the child has no photo consent"})
+		}
+	}
+	return children, nil
+}
+
+// response shows parents only their own children among the tags and only
+// their own and the staff comments
+func (u *photoUsecase) response(post *domain.PhotoPost, viewer *photoViewer) *domain.PhotoPostResponse {
+	response := &domain.PhotoPostResponse{
+		ID:          post.ID,
+		AuthorID:    post.AuthorID,
+		AuthorName:  post.Author.Name,
+		Caption:     post.Caption,
+		Children:    []domain.PhotoTagResponse{},
+		Photos:      u.files.Signed(post.Photos),
+		Reactions:   map[string]int{},
+		Comments:    []domain.PhotoCommentResponse{},
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+	}
+	for _, child := range post.Children {
+		if viewer.childIds == nil || slices.Contains(viewer.childIds, child.ID) {
+			response.Children = append(response.Children, domain.PhotoTagResponse{ChildID: child.ID, Name: child.Name})
+		}
+	}
+	for _, reaction := range post.Reactions {
+		response.Reactions[reaction.Kind]++
+		if reaction.UserID == viewer.user.ID {
+			response.MyReaction = reaction.Kind
+		}
+	}
+	for i := range post.Comments {
+		comment := &post.Comments[i]
+		if viewer.childIds == nil || comment.UserID == viewer.user.ID || isStaff(comment.User) {
+			response.Comments = append(response.Comments, *domain.NewPhotoCommentResponse(comment))
+		}
+	}
+	return response
+}
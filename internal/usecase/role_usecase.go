@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+)
+
+type RoleUsecase interface {
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	CreateRole(ctx context.Context, name string) (*domain.Role, error)
+	RenameRole(ctx context.Context, id uint, name string) (*domain.Role, error)
+	DeleteRole(ctx context.Context, id uint) error
+	SetPermissions(ctx context.Context, id uint, permissionIds []uint) (*domain.Role, error)
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	CreatePermission(ctx context.Context, input domain.PermissionRequest) (*domain.Permission, error)
+}
+
+type roleUsecase struct {
+	repo repository.RoleRepository
+}
+
+func NewRoleUsecase(repo repository.RoleRepository) RoleUsecase {
+	return &roleUsecase{repo}
+}
+
+func (u *roleUsecase) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	return u.repo.GetAll(ctx)
+}
+
+func (u *roleUsecase) CreateRole(ctx context.Context, name string) (*domain.Role, error) {
+	name = strings.TrimSpace(name)
+	if err := u.checkNameFree(ctx, name, 0); err != nil {
+		return nil, err
+	}
+
+	role := &domain.Role{Name: name}
+	if err := u.repo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// RenameRole refuses system roles, the code checks them by name.
+func (u *roleUsecase) RenameRole(ctx context.Context, id uint, name string) (*domain.Role, error) {
+	role, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if domain.IsSystemRole(role.Name) {
+		return nil, apperror.Forbidden(fmt.Sprintf("system role %q cannot be renamed", role.Name))
+	}
+
+	name = strings.TrimSpace(name)
+	if err := u.checkNameFree(ctx, name, role.ID); err != nil {
+		return nil, err
+	}
+
+	role.Name = name
+	if err := u.repo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole only deletes roles no user or pending invitation holds.
+func (u *roleUsecase) DeleteRole(ctx context.Context, id uint) error {
+	role, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if domain.IsSystemRole(role.Name) {
+		return apperror.Forbidden(fmt.Sprintf("system role %q cannot be deleted", role.Name))
+	}
+
+	usage, err := u.repo.CountUsage(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+	if usage > 0 {
+		return apperror.Conflict(fmt.Sprintf("role is still assigned to %d user(s) or invitation(s)", usage)).WithCode(apperror.CodeRoleInUse)
+	}
+
+	return u.repo.Delete(ctx, role)
+}
+
+func (u *roleUsecase) SetPermissions(ctx context.Context, id uint, permissionIds []uint) (*domain.Role, error) {
+	role, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := u.repo.GetPermissionsByIds(ctx, permissionIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) != len(permissionIds) {
+		return nil, apperror.Validation(types.FieldError{
+			Field:   "permissions",
+			Message: "unknown permission id: " + missingIds(permissionIds, permissions),
+		})
+	}
+
+	if err := u.repo.ReplacePermissions(ctx, role, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+	return role, nil
+}
+
+func (u *roleUsecase) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	return u.repo.GetAllPermissions(ctx)
+}
+
+func (u *roleUsecase) CreatePermission(ctx context.Context, input domain.PermissionRequest) (*domain.Permission, error) {
+	name := strings.TrimSpace(input.Name)
+	exists, err := u.repo.PermissionNameExists(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, apperror.Conflict("permission name already exists")
+	}
+
+	permission := &domain.Permission{Name: name, Description: input.Description}
+	if err := u.repo.CreatePermission(ctx, permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (u *roleUsecase) checkNameFree(ctx context.Context, name string, exceptId uint) error {
+	if name == "" {
+		return apperror.Validation(types.FieldError{Field: "name", Message: "name is required"})
+	}
+	exists, err := u.repo.NameExists(ctx, name, exceptId)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperror.Conflict("role name already exists")
+	}
+	return nil
+}
+
+func missingIds(ids []uint, permissions []domain.Permission) string {
+	found := make(map[uint]bool, len(permissions))
+	for _, permission := range permissions {
+		found[permission.ID] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, strconv.FormatUint(uint64(id), 10))
+		}
+	}
+	return strings.Join(missing, ", ")
+}
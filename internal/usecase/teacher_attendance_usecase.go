@@ -1,100 +1,286 @@
 package usecase
 
 import (
-	"fmt"
+	"context"
 	"math"
+	"time"
 
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
 	"github.com/whyaji/daycare-preschool-api/pkg/types"
 )
 
 type TeacherAttendanceUsecase interface {
-	CreateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error
-	CheckUserTeacher(userId uint) (bool, error)
-	ValidateRequiredFieldsClock(requestData *domain.CreateTeacherAttendanceRequest) []string
-	CheckLastIsClockedOut(userId uint) (*domain.TeacherAttendance, error)
-	CheckLastIsClockedIn(userId uint) (*domain.TeacherAttendance, error)
-	UpdateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error
-	GetLastTeacherAttendanceByUserId(userId uint) (domain.TeacherAttendance, error)
-	CheckIsInWorkLocation(latitude, longitude float64) (bool, error)
-	GetTeacherAttendanceByUserId(userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error)
+	CreateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error
+	CheckUserTeacher(ctx context.Context, userId uint) (bool, error)
+	CheckLastIsClockedOut(ctx context.Context, userId uint) (*domain.TeacherAttendance, error)
+	CheckLastIsClockedIn(ctx context.Context, userId uint) (*domain.TeacherAttendance, error)
+	UpdateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error
+	ClockIn(ctx context.Context, userId uint, input domain.CreateTeacherAttendanceRequest) (bool, error)
+	ClockOut(ctx context.Context, teacherAttendance *domain.TeacherAttendance, isOvertimeEvening bool) error
+	GetLastTeacherAttendanceByUserId(ctx context.Context, userId uint) (domain.TeacherAttendance, error)
+	CheckIsInWorkLocation(ctx context.Context, latitude, longitude float64) (bool, error)
+	GetTeacherAttendanceByUserId(ctx context.Context, userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error)
+	CenterHours(ctx context.Context, t time.Time) (domain.OpeningHours, error)
 }
 
 type teacherAttendanceUsecase struct {
-	repo repository.TeacherAttendanceRepository
+	repo       repository.TeacherAttendanceRepository
+	calendar   CenterCalendar
+	compliance ComplianceChecker
+	events     EventPublisher
+	now        func() time.Time
 }
 
-func NewTeacherAttendanceUsecase(repo repository.TeacherAttendanceRepository) TeacherAttendanceUsecase {
-	return &teacherAttendanceUsecase{repo}
+var errNotClockedIn = apperror.Conflict("you have not clocked in yet").WithCode(apperror.CodeNotClockedIn)
+
+func NewTeacherAttendanceUsecase(repo repository.TeacherAttendanceRepository, calendar CenterCalendar, compliance ComplianceChecker, events EventPublisher) TeacherAttendanceUsecase {
+	return &teacherAttendanceUsecase{repo, calendar, compliance, events, time.Now}
 }
 
-func (u *teacherAttendanceUsecase) CreateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error {
-	return u.repo.Create(teacherAttendance)
+func (u *teacherAttendanceUsecase) CreateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error {
+	if err := u.repo.Create(ctx, teacherAttendance); err != nil {
+		return err
+	}
+	u.checkCompliance(ctx, teacherAttendance)
+	u.publish(ctx, teacherAttendance)
+	return nil
 }
 
-func (u *teacherAttendanceUsecase) CheckUserTeacher(userId uint) (bool, error) {
-	user, err := u.repo.GetUserWithRoles(userId)
+func (u *teacherAttendanceUsecase) CheckUserTeacher(ctx context.Context, userId uint) (bool, error) {
+	user, err := u.repo.GetUserWithRoles(ctx, userId)
 	if err != nil {
 		return false, err
 	}
 	for _, role := range user.Roles {
-		if role.Name == "teacher" {
+		if role.Name == domain.RoleTeacher {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (u *teacherAttendanceUsecase) ValidateRequiredFieldsClock(requestData *domain.CreateTeacherAttendanceRequest) []string {
-	var errors []string
-	if requestData.Latitude == 0 {
-		errors = append(errors, "latitude is required")
+func (u *teacherAttendanceUsecase) CheckLastIsClockedOut(ctx context.Context, userId uint) (*domain.TeacherAttendance, error) {
+	teacherAttendance, err := u.repo.GetLastTeacherAttendanceByUserId(ctx, userId)
+	if err != nil {
+		return nil, nil
 	}
-	if requestData.Longitude == 0 {
-		errors = append(errors, "longitude is required")
+	if teacherAttendance.ClockOut == nil && teacherAttendance.ClockIn != nil {
+		return nil, apperror.Conflict("you have not clocked out yet").WithCode(apperror.CodeNotClockedOut)
 	}
-	return errors
+	return &teacherAttendance, nil
 }
 
-func (u *teacherAttendanceUsecase) CheckLastIsClockedOut(userId uint) (*domain.TeacherAttendance, error) {
-	teacherAttendance, err := u.repo.GetLastTeacherAttendanceByUserId(userId)
+func (u *teacherAttendanceUsecase) CheckLastIsClockedIn(ctx context.Context, userId uint) (*domain.TeacherAttendance, error) {
+	teacherAttendance, err := u.repo.GetLastTeacherAttendanceByUserId(ctx, userId)
 	if err != nil {
-		return nil, nil
+		return nil, errNotClockedIn
 	}
-	if teacherAttendance.ClockOut == nil && teacherAttendance.ClockIn != nil {
-		return nil, fmt.Errorf("you have not clocked out yet")
+	// a closed attendance is not clocked out twice
+	if teacherAttendance.ClockOut != nil {
+		return nil, errNotClockedIn
 	}
 	return &teacherAttendance, nil
 }
 
-func (u *teacherAttendanceUsecase) CheckLastIsClockedIn(userId uint) (*domain.TeacherAttendance, error) {
-	teacherAttendance, err := u.repo.GetLastTeacherAttendanceByUserId(userId)
+// UpdateTeacherAttendance saves a clock-in on an attendance that had none
+func (u *teacherAttendanceUsecase) UpdateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance) error {
+	if err := u.repo.UpdateTeacherAttendance(ctx, teacherAttendance); err != nil {
+		return err
+	}
+	u.checkCompliance(ctx, teacherAttendance)
+	u.publish(ctx, teacherAttendance)
+	return nil
+}
+
+// ClockIn stamps the clock-in with the current time, on the attendance
+// already created for today without one or on a new attendance, which it
+// reports. Clock-ins away from the work locations or on days the center is
+// closed are rejected.
+func (u *teacherAttendanceUsecase) ClockIn(ctx context.Context, userId uint, input domain.CreateTeacherAttendanceRequest) (bool, error) {
+	lastTeacherAttendance, err := u.CheckLastIsClockedOut(ctx, userId)
+	if err != nil {
+		metrics.ClockInRejected(metrics.ReasonNotClockedOut)
+		return false, err
+	}
+
+	now := u.now()
+	isArroundWorkLocation, err := u.CheckIsInWorkLocation(ctx, *input.Latitude, *input.Longitude)
 	if err != nil {
-		return nil, fmt.Errorf("you have not clocked in yet")
+		return false, err
 	}
-	if teacherAttendance.ClockOut != nil && teacherAttendance.ClockIn == nil {
-		return nil, fmt.Errorf("you have not clocked in yet")
+	if !isArroundWorkLocation {
+		metrics.ClockInRejected(metrics.ReasonOutsideGeofence)
+		return false, apperror.BadRequest("You are not in work location").WithCode(apperror.CodeOutsideWorkLocation)
 	}
-	return &teacherAttendance, nil
+
+	// holidays, closures and days off have no work hours, half days open
+	// and close at their own hours
+	hours, err := u.CenterHours(ctx, now)
+	if err != nil {
+		return false, err
+	}
+	if !hours.Open {
+		metrics.ClockInRejected(metrics.ReasonCenterClosed)
+		return false, apperror.Conflict("the center is closed on this day").WithCode(apperror.CodeCenterClosed)
+	}
+
+	var morningOvertime int
+	if input.IsOvertimeMorning {
+		// opening time subtrack with now in minutes, a clock-in after
+		// opening has none
+		// morningOvertime maximal is 60 minutes
+		morningOvertime = min(max(int(hours.Opens.Minutes())-(now.Hour()*60+now.Minute()), 0), 60)
+	}
+
+	// if lastTeacherAttendanceDate is today, then update the lastTeacherAttendance
+	if lastTeacherAttendance != nil && lastTeacherAttendance.Date.Format(dateLayout) == now.Format(dateLayout) && lastTeacherAttendance.ClockIn == nil {
+		lastTeacherAttendance.ClockIn = &now
+		lastTeacherAttendance.OvertimeMorning = morningOvertime
+		if err := u.UpdateTeacherAttendance(ctx, lastTeacherAttendance); err != nil {
+			return false, err
+		}
+		metrics.ClockIn()
+		return false, nil
+	}
+
+	teacherAttendance := &domain.TeacherAttendance{
+		UserID:          userId,
+		Date:            now,
+		ClockIn:         &now,
+		OvertimeMorning: morningOvertime,
+	}
+	if err := u.CreateTeacherAttendance(ctx, teacherAttendance); err != nil {
+		return false, err
+	}
+	metrics.ClockIn()
+	return true, nil
+}
+
+// ClockOut stamps the clock-out with the current time and closes the
+// attendance. The work hours count from opening, or the clock-in when later,
+// to closing, or the clock-out when earlier. The closed attendance is
+// announced to the webhooks, an attendance already closed is rejected.
+func (u *teacherAttendanceUsecase) ClockOut(ctx context.Context, teacherAttendance *domain.TeacherAttendance, isOvertimeEvening bool) error {
+	if teacherAttendance.ClockOut != nil {
+		return errNotClockedIn
+	}
+
+	now := u.now()
+	hours, err := u.CenterHours(ctx, teacherAttendance.Date)
+	if err != nil {
+		return err
+	}
+	opensHour := float32(hours.Opens.Hours())
+	closesHour := float32(hours.Closes.Hours())
+
+	var afternoonOvertime int
+	if isOvertimeEvening {
+		// now subtrack with closing time in minutes, a clock-out before
+		// closing has none
+		// afternoonOvertime maximal is 60 minutes
+		afternoonOvertime = min(max((now.Hour()*60+now.Minute())-int(hours.Closes.Minutes()), 0), 60)
+	}
+
+	var startHour float32
+	if teacherAttendance.ClockIn != nil {
+		startHourFlat := float32(teacherAttendance.ClockIn.Hour()) + float32(teacherAttendance.ClockIn.Minute())/60
+		// if clockIn before opening time, then startHour is start from opening time
+		if startHourFlat < opensHour {
+			startHour = opensHour
+		} else {
+			startHour = startHourFlat
+		}
+	}
+
+	// if clockOut after closing time, or on a later day than the clockIn,
+	// then endHour is closing time
+	var endHour float32
+	endHourFlat := float32(now.Hour()) + float32(now.Minute())/60
+	if endHourFlat > closesHour || now.Format(dateLayout) != teacherAttendance.Date.Format(dateLayout) {
+		endHour = closesHour
+	} else {
+		endHour = endHourFlat
+	}
+
+	// calculate workHour
+	workHour := max(endHour-startHour, 0)
+
+	teacherAttendance.ClockOut = &now
+	teacherAttendance.OvertimeEvening = afternoonOvertime
+	// workHour with 1 decimal
+	teacherAttendance.WorkHour = float32(int(workHour*10)) / 10
+
+	var events []domain.OutboxEvent
+	if teacherAttendance.ClockIn != nil {
+		closed, err := newOutboxEvent(domain.WebhookTeacherAttendanceClosed, domain.TeacherAttendanceClosedData{
+			AttendanceID:    teacherAttendance.ID,
+			UserID:          teacherAttendance.UserID,
+			Date:            teacherAttendance.Date.Format(dateLayout),
+			ClockIn:         *teacherAttendance.ClockIn,
+			ClockOut:        now,
+			WorkHour:        teacherAttendance.WorkHour,
+			OvertimeRegular: teacherAttendance.OvertimeRegular,
+			OvertimeMorning: teacherAttendance.OvertimeMorning,
+			OvertimeEvening: teacherAttendance.OvertimeEvening,
+		})
+		if err != nil {
+			return err
+		}
+		events = append(events, closed)
+	}
+	if err := u.repo.UpdateTeacherAttendance(ctx, teacherAttendance, events...); err != nil {
+		return err
+	}
+	u.checkCompliance(ctx, teacherAttendance)
+	u.publish(ctx, teacherAttendance)
+	return nil
+}
+
+// checkCompliance re-evaluates the classrooms of the teacher after a clock-in
+// or clock-out changed who is on the floor.
+func (u *teacherAttendanceUsecase) checkCompliance(ctx context.Context, teacherAttendance *domain.TeacherAttendance) {
+	trigger := TriggerTeacherClockIn
+	if teacherAttendance.ClockOut != nil {
+		trigger = TriggerTeacherClockOut
+	}
+	u.compliance.CheckTeacher(ctx, teacherAttendance.UserID, trigger)
+}
+
+// publish tells the staff streams about the clock-in or clock-out
+func (u *teacherAttendanceUsecase) publish(ctx context.Context, teacherAttendance *domain.TeacherAttendance) {
+	eventType := domain.EventTeacherClockedIn
+	if teacherAttendance.ClockOut != nil {
+		eventType = domain.EventTeacherClockedOut
+	}
+	u.events.Publish(ctx, eventType, staffAudience, domain.TeacherAttendanceEvent{
+		UserID:       teacherAttendance.UserID,
+		AttendanceID: teacherAttendance.ID,
+		ClockIn:      teacherAttendance.ClockIn,
+		ClockOut:     teacherAttendance.ClockOut,
+	})
 }
 
-func (u *teacherAttendanceUsecase) UpdateTeacherAttendance(teacherAttendance *domain.TeacherAttendance) error {
-	return u.repo.UpdateTeacherAttendance(teacherAttendance)
+func (u *teacherAttendanceUsecase) GetLastTeacherAttendanceByUserId(ctx context.Context, userId uint) (domain.TeacherAttendance, error) {
+	return u.repo.GetLastTeacherAttendanceByUserId(ctx, userId)
 }
 
-func (u *teacherAttendanceUsecase) GetLastTeacherAttendanceByUserId(userId uint) (domain.TeacherAttendance, error) {
-	return u.repo.GetLastTeacherAttendanceByUserId(userId)
+func (u *teacherAttendanceUsecase) GetTeacherAttendanceByUserId(ctx context.Context, userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error) {
+	return u.repo.GetTeacherAttendanceByUserId(ctx, userId, paginationFilter)
 }
 
-func (u *teacherAttendanceUsecase) GetTeacherAttendanceByUserId(userId uint, paginationFilter types.PaginationFilter) ([]domain.TeacherAttendance, int, error) {
-	return u.repo.GetTeacherAttendanceByUserId(userId, paginationFilter)
+// CenterHours returns the opening hours of the center on the day of t, which
+// bound the regular work hours and the overtime
+func (u *teacherAttendanceUsecase) CenterHours(ctx context.Context, t time.Time) (domain.OpeningHours, error) {
+	return u.calendar.Hours(ctx, t)
 }
 
-func (u *teacherAttendanceUsecase) CheckIsInWorkLocation(latitude, longitude float64) (bool, error) {
+func (u *teacherAttendanceUsecase) CheckIsInWorkLocation(ctx context.Context, latitude, longitude float64) (bool, error) {
 	const tolerance = 0.3 // 300 meters in kilometers
 
-	workLocations, err := u.repo.GetAllWorkLocation()
+	workLocations, err := u.repo.GetAllWorkLocation(ctx)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/events"
+)
+
+// fakeTeacherAttendanceRepository keeps the last attendance of a teacher and
+// the outbox events written with it
+type fakeTeacherAttendanceRepository struct {
+	repository.TeacherAttendanceRepository
+	last   domain.TeacherAttendance
+	outbox []domain.OutboxEvent
+}
+
+func (r *fakeTeacherAttendanceRepository) GetLastTeacherAttendanceByUserId(ctx context.Context, userId uint) (domain.TeacherAttendance, error) {
+	return r.last, nil
+}
+
+func (r *fakeTeacherAttendanceRepository) UpdateTeacherAttendance(ctx context.Context, teacherAttendance *domain.TeacherAttendance, events ...domain.OutboxEvent) error {
+	r.last = *teacherAttendance
+	r.outbox = append(r.outbox, events...)
+	return nil
+}
+
+type fakeComplianceChecker struct{}
+
+func (fakeComplianceChecker) CheckTeacher(ctx context.Context, userId uint, trigger string) {}
+
+func (fakeComplianceChecker) CheckChild(ctx context.Context, childId uint, trigger string) {}
+
+type fakeEventPublisher struct{}
+
+func (fakeEventPublisher) Publish(ctx context.Context, eventType string, audience events.Audience, data any) {
+}
+
+func TestClockOutRejectsAClosedAttendance(t *testing.T) {
+	day := date(2025, 2, 20)
+	clockIn := day.Add(8 * time.Hour)
+	now := day.Add(17 * time.Hour)
+	repo := &fakeTeacherAttendanceRepository{last: domain.TeacherAttendance{ID: 1, UserID: 1, Date: day, ClockIn: &clockIn}}
+	u := &teacherAttendanceUsecase{
+		repo:       repo,
+		calendar:   &fakeCenterCalendar{},
+		compliance: fakeComplianceChecker{},
+		events:     fakeEventPublisher{},
+		now:        func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	attendance, err := u.CheckLastIsClockedIn(ctx, 1)
+	if err != nil {
+		t.Fatalf("CheckLastIsClockedIn() = %v", err)
+	}
+	if err := u.ClockOut(ctx, attendance, false); err != nil {
+		t.Fatalf("ClockOut() = %v", err)
+	}
+	if len(repo.outbox) != 1 || repo.outbox[0].Type != domain.WebhookTeacherAttendanceClosed {
+		t.Fatalf("outbox = %+v, want one %s", repo.outbox, domain.WebhookTeacherAttendanceClosed)
+	}
+
+	// A second clock-out an hour later changes nothing
+	now = now.Add(time.Hour)
+	if _, err := u.CheckLastIsClockedIn(ctx, 1); err != errNotClockedIn {
+		t.Errorf("CheckLastIsClockedIn() = %v, want %v", err, errNotClockedIn)
+	}
+	if err := u.ClockOut(ctx, attendance, true); err != errNotClockedIn {
+		t.Errorf("ClockOut() = %v, want %v", err, errNotClockedIn)
+	}
+	if len(repo.outbox) != 1 {
+		t.Errorf("outbox has %d events, want 1", len(repo.outbox))
+	}
+	if repo.last.ClockOut == nil || !repo.last.ClockOut.Equal(day.Add(17*time.Hour)) || repo.last.WorkHour != 9 {
+		t.Errorf("attendance closed at %v with %v work hours, want %v with 9", repo.last.ClockOut, repo.last.WorkHour, day.Add(17*time.Hour))
+	}
+}
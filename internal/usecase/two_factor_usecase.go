@@ -0,0 +1,368 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/ratelimit"
+	"github.com/whyaji/daycare-preschool-api/pkg/secretbox"
+	"github.com/whyaji/daycare-preschool-api/pkg/totp"
+	"gorm.io/gorm"
+)
+
+// TokenTypeMFAChallenge marks challenge tokens so they are never accepted as
+// access tokens.
+const TokenTypeMFAChallenge = "mfa_challenge"
+
+const recoveryCodeCount = 10
+
+var (
+	errInvalidTwoFactorCode = apperror.Unauthorized("invalid two-factor code").WithCode(apperror.CodeInvalidTwoFactorCode)
+	errInvalidChallenge     = apperror.Unauthorized("invalid or expired challenge token")
+)
+
+type TwoFactorUsecase interface {
+	Status(ctx context.Context, userId uint) (*domain.TwoFactorStatus, error)
+	Challenge(ctx context.Context, user *domain.User) (*domain.MFAChallenge, error)
+	ParseChallenge(token string, challengeType string) (uint, error)
+	Enroll(ctx context.Context, userId uint) (*domain.TOTPEnrollment, error)
+	Confirm(ctx context.Context, userId uint, code string) ([]string, error)
+	Verify(ctx context.Context, userId uint, code, recoveryCode string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId uint, code string) ([]string, error)
+	Disable(ctx context.Context, userId uint, code string) error
+}
+
+type twoFactorUsecase struct {
+	repo     repository.TwoFactorRepository
+	userRepo repository.UserRepository
+	cfg      *config.Config
+	box      *secretbox.Box
+	lockout  *ratelimit.Lockout
+	now      func() time.Time
+}
+
+func NewTwoFactorUsecase(repo repository.TwoFactorRepository, userRepo repository.UserRepository, cfg *config.Config, limits ratelimit.Store) (TwoFactorUsecase, error) {
+	box, err := secretbox.New(cfg.TOTPEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	lockout := ratelimit.NewLockout(limits, cfg.LoginLockoutMax, cfg.LoginLockoutWindow, cfg.LoginLockoutBase, cfg.LoginLockoutCap)
+	return &twoFactorUsecase{repo, userRepo, cfg, box, lockout, time.Now}, nil
+}
+
+// isRequired applies the TOTP_REQUIRED_ROLES policy to the user's roles.
+func (u *twoFactorUsecase) isRequired(user *domain.User) bool {
+	for _, role := range user.Roles {
+		if slices.Contains(u.cfg.TOTPRequiredRoles, role.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmed returns the confirmed secret of the user, nil when two-factor is
+// not enabled.
+func (u *twoFactorUsecase) confirmed(ctx context.Context, userId uint) (*domain.UserTOTP, error) {
+	secret, err := u.repo.GetByUserId(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.ConfirmedAt == nil {
+		return nil, nil
+	}
+	return secret, nil
+}
+
+func (u *twoFactorUsecase) Status(ctx context.Context, userId uint) (*domain.TwoFactorStatus, error) {
+	user, err := u.userRepo.GetByIdWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := u.confirmed(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.TwoFactorStatus{Enabled: secret != nil, Required: u.isRequired(user)}
+	if secret != nil {
+		left, err := u.repo.CountRecoveryCodes(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesLeft = int(left)
+	}
+	return status, nil
+}
+
+// Challenge decides whether a user who passed the password check still has
+// to answer a two-factor challenge. It returns nil when a token can be
+// issued right away.
+func (u *twoFactorUsecase) Challenge(ctx context.Context, user *domain.User) (*domain.MFAChallenge, error) {
+	secret, err := u.confirmed(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var challengeType string
+	switch {
+	case secret != nil:
+		challengeType = domain.MFAChallengeTOTP
+	case u.isRequired(user):
+		challengeType = domain.MFAChallengeEnroll
+	default:
+		return nil, nil
+	}
+
+	exp := u.now().Add(u.cfg.MFAChallengeTTL)
+	claims := jwt.MapClaims{
+		"id":        user.ID,
+		"exp":       exp.Unix(),
+		"typ":       TokenTypeMFAChallenge,
+		"challenge": challengeType,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(u.cfg.JWTSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAChallenge{Type: challengeType, Token: token, ExpiredAt: exp.Format(time.RFC3339)}, nil
+}
+
+// ParseChallenge validates a challenge token of the given type and returns
+// the user id it was issued for.
+func (u *twoFactorUsecase) ParseChallenge(token string, challengeType string) (uint, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(u.cfg.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, errInvalidChallenge.Wrap(err)
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok || claims["typ"] != TokenTypeMFAChallenge || claims["challenge"] != challengeType {
+		return 0, errInvalidChallenge
+	}
+	return uint(id), nil
+}
+
+// Enroll creates a new unconfirmed secret, replacing any earlier unconfirmed
+// one. Two-factor is only enforced after Confirm.
+func (u *twoFactorUsecase) Enroll(ctx context.Context, userId uint) (*domain.TOTPEnrollment, error) {
+	user, err := u.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.repo.GetByUserId(ctx, userId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && existing.ConfirmedAt != nil {
+		return nil, apperror.Conflict("two-factor authentication is already enabled")
+	}
+	if err != nil {
+		existing = &domain.UserTOTP{UserID: userId}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if existing.Secret, err = u.box.Seal(secret); err != nil {
+		return nil, err
+	}
+	existing.LastUsedStep = 0
+	if err := u.repo.Save(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	uri := totp.ProvisioningURI(u.cfg.TOTPIssuer, user.Email, secret)
+	png, err := totp.QRCodePNG(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURL: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm checks the first code from the authenticator, enables two-factor
+// and returns the recovery codes. They are only shown this once.
+func (u *twoFactorUsecase) Confirm(ctx context.Context, userId uint, code string) ([]string, error) {
+	secret, err := u.repo.GetByUserId(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.BadRequest("start two-factor enrollment first")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.ConfirmedAt != nil {
+		return nil, apperror.Conflict("two-factor authentication is already enabled")
+	}
+
+	step, err := u.checkCode(ctx, secret, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	secret.ConfirmedAt = &now
+	secret.LastUsedStep = step
+	if err := u.repo.Confirm(ctx, secret, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts either a code from the authenticator or an unused recovery
+// code. Failures count towards the same lockout as passwords.
+func (u *twoFactorUsecase) Verify(ctx context.Context, userId uint, code, recoveryCode string) error {
+	secret, err := u.confirmed(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return apperror.BadRequest("two-factor authentication is not enabled")
+	}
+
+	if code != "" {
+		_, err := u.checkCode(ctx, secret, code)
+		return err
+	}
+
+	account := twoFactorAccount(userId)
+	if err := u.checkLock(ctx, account); err != nil {
+		return err
+	}
+	used, err := u.repo.UseRecoveryCode(ctx, userId, totp.HashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return u.fail(ctx, account)
+	}
+	return u.lockout.Success(ctx, account)
+}
+
+func (u *twoFactorUsecase) RegenerateRecoveryCodes(ctx context.Context, userId uint, code string) ([]string, error) {
+	if err := u.Verify(ctx, userId, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor off, unless the policy requires it for one of the
+// user's roles.
+func (u *twoFactorUsecase) Disable(ctx context.Context, userId uint, code string) error {
+	user, err := u.userRepo.GetByIdWithRoles(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if u.isRequired(user) {
+		return apperror.Forbidden("two-factor authentication is required for your role").WithCode(apperror.CodeTwoFactorRequired)
+	}
+
+	if err := u.Verify(ctx, userId, code, ""); err != nil {
+		return err
+	}
+	return u.repo.Delete(ctx, userId)
+}
+
+// checkCode validates a TOTP code under the lockout and marks its time step
+// as used so the same code cannot be replayed.
+func (u *twoFactorUsecase) checkCode(ctx context.Context, secret *domain.UserTOTP, code string) (int64, error) {
+	account := twoFactorAccount(secret.UserID)
+	if err := u.checkLock(ctx, account); err != nil {
+		return 0, err
+	}
+
+	plain, err := u.box.Open(secret.Secret)
+	if err != nil {
+		return 0, apperror.Internal(fmt.Errorf("decrypting totp secret: %w", err))
+	}
+
+	step, ok := totp.Validate(plain, code, u.now())
+	if !ok || step <= secret.LastUsedStep {
+		return 0, u.fail(ctx, account)
+	}
+
+	// Unconfirmed secrets are updated together with the confirmation
+	if secret.ConfirmedAt != nil {
+		used, err := u.repo.UseStep(ctx, secret.UserID, step)
+		if err != nil {
+			return 0, err
+		}
+		if !used {
+			return 0, u.fail(ctx, account)
+		}
+	}
+
+	return step, u.lockout.Success(ctx, account)
+}
+
+func (u *twoFactorUsecase) checkLock(ctx context.Context, account string) error {
+	wait, err := u.lockout.Check(ctx, account)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return apperror.TooManyRequests("too many failed two-factor attempts, try again later", wait)
+	}
+	return nil
+}
+
+func (u *twoFactorUsecase) fail(ctx context.Context, account string) error {
+	wait, err := u.lockout.Fail(ctx, account)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return apperror.TooManyRequests("too many failed two-factor attempts, try again later", wait)
+	}
+	return errInvalidTwoFactorCode
+}
+
+func twoFactorAccount(userId uint) string {
+	return fmt.Sprintf("2fa:%d", userId)
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/ratelimit"
+	"github.com/whyaji/daycare-preschool-api/pkg/secretbox"
+	"github.com/whyaji/daycare-preschool-api/pkg/totp"
+)
+
+// twoFactorSecret is the seed of the RFC 6238 test vectors
+const twoFactorSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// fakeTwoFactorRepository keeps the confirmed secret of a single user
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	secret *domain.UserTOTP
+}
+
+func (r *fakeTwoFactorRepository) GetByUserId(ctx context.Context, userId uint) (*domain.UserTOTP, error) {
+	secret := *r.secret
+	return &secret, nil
+}
+
+func (r *fakeTwoFactorRepository) UseStep(ctx context.Context, userId uint, step int64) (bool, error) {
+	if step <= r.secret.LastUsedStep {
+		return false, nil
+	}
+	r.secret.LastUsedStep = step
+	return true, nil
+}
+
+func newTestTwoFactorUsecase(t *testing.T, now time.Time) *twoFactorUsecase {
+	t.Helper()
+	box, err := secretbox.New("test key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := box.Seal(twoFactorSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmedAt := now.Add(-time.Hour)
+	repo := &fakeTwoFactorRepository{secret: &domain.UserTOTP{UserID: 1, Secret: sealed, ConfirmedAt: &confirmedAt}}
+	lockout := ratelimit.NewLockout(ratelimit.NewMemoryStore(), 5, time.Minute, time.Minute, time.Hour)
+	return &twoFactorUsecase{repo: repo, cfg: &config.Config{}, box: box, lockout: lockout, now: func() time.Time { return now }}
+}
+
+func testCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := totp.Code(twoFactorSecret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestVerifyAcceptsOneStepOfDrift(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := totp.Step(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"previous step", -1, true},
+		{"current step", 0, true},
+		{"next step", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		u := newTestTwoFactorUsecase(t, now)
+		err := u.Verify(context.Background(), 1, testCode(t, current+tt.offset), "")
+		if tt.valid && err != nil {
+			t.Errorf("%s: Verify() = %v, want nil", tt.name, err)
+		}
+		if !tt.valid && err != errInvalidTwoFactorCode {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, err, errInvalidTwoFactorCode)
+		}
+	}
+}
+
+func TestVerifyRejectsReplayedCodes(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := totp.Step(now)
+	u := newTestTwoFactorUsecase(t, now)
+	ctx := context.Background()
+
+	if err := u.Verify(ctx, 1, testCode(t, current), ""); err != nil {
+		t.Fatalf("first use: Verify() = %v, want nil", err)
+	}
+	if err := u.Verify(ctx, 1, testCode(t, current), ""); err != errInvalidTwoFactorCode {
+		t.Errorf("replay: Verify() = %v, want %v", err, errInvalidTwoFactorCode)
+	}
+	// A code of an earlier step in the window is as good as a replay
+	if err := u.Verify(ctx, 1, testCode(t, current-1), ""); err != errInvalidTwoFactorCode {
+		t.Errorf("earlier step: Verify() = %v, want %v", err, errInvalidTwoFactorCode)
+	}
+	if err := u.Verify(ctx, 1, testCode(t, current+1), ""); err != nil {
+		t.Errorf("later step: Verify() = %v, want nil", err)
+	}
+}
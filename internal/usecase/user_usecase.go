@@ -1,47 +1,100 @@
 package usecase
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/whyaji/daycare-preschool-api/config"
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
 	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/mailer"
+	"github.com/whyaji/daycare-preschool-api/pkg/ratelimit"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserUsecase interface {
-	CheckRegisteredEmail(email string) (*domain.RegisteredEmail, error)
-	Register(user *domain.User, registeredEmail *domain.RegisteredEmail) error
-	AssignRegisteredAtEmail(registeredEmail domain.RegisteredEmail) error
-	GetUserByEmail(email string) (*domain.User, error)
-	GetUserById(id uint) (*domain.User, error)
-	VerifyPassword(user *domain.User, password string) error
-	Login(user *domain.User) (*string, *string, error)
-	CheckUserAdmin(userId uint) (bool, error)
-	RegisterEmail(registeredEmail *domain.RegisteredEmail) error
-	ParseRoles(rolesString string) ([]domain.Role, error)
+	CheckRegisteredEmail(ctx context.Context, email string) (*domain.RegisteredEmail, error)
+	Register(ctx context.Context, user *domain.User, registeredEmail *domain.RegisteredEmail) error
+	AssignRegisteredAtEmail(ctx context.Context, registeredEmail domain.RegisteredEmail) error
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserById(ctx context.Context, id uint) (*domain.User, error)
+	VerifyPassword(ctx context.Context, user *domain.User, password string) error
+	Authenticate(ctx context.Context, email, password string) (*domain.User, error)
+	CheckRegisterAttempt(ctx context.Context, email string) error
+	Login(ctx context.Context, user *domain.User) (*string, *string, error)
+	ValidateToken(ctx context.Context, userId uint, version uint) error
+	CheckUserAdmin(ctx context.Context, userId uint) (bool, error)
+	CheckUserHasRole(ctx context.Context, userId uint, roles ...string) (bool, error)
+	RegisterEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error
+	ParseRoles(ctx context.Context, roleIds []uint) ([]domain.Role, error)
+	GetProfile(ctx context.Context, userId uint) (*domain.User, error)
+	UpdateProfile(ctx context.Context, userId uint, input domain.UpdateProfileRequest) (*domain.User, error)
+	RequestEmailChange(ctx context.Context, userId uint, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ListUsers(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.UserListFilter) ([]domain.User, int, error)
+	GetUser(ctx context.Context, userId uint) (*domain.User, error)
+	UpdateUserRoles(ctx context.Context, actorId, userId uint, roleIds []uint) (*domain.User, error)
+	DeactivateUser(ctx context.Context, actorId, userId uint) error
+	ReactivateUser(ctx context.Context, userId uint) error
+	ListInvitations(ctx context.Context, paginationFilter types.PaginationFilter) ([]domain.RegisteredEmail, int, error)
+	SendInvitation(ctx context.Context, registeredEmail *domain.RegisteredEmail) error
+	ResendInvitation(ctx context.Context, id uint) error
 }
 
 type userUsecase struct {
-	repo repository.UserRepository
+	repo    repository.UserRepository
+	cfg     *config.Config
+	limits  ratelimit.Store
+	lockout *ratelimit.Lockout
+	mail    mailer.Mailer
 }
 
-func NewUserUsecase(repo repository.UserRepository) UserUsecase {
-	return &userUsecase{repo}
+func NewUserUsecase(repo repository.UserRepository, cfg *config.Config, limits ratelimit.Store, mail mailer.Mailer) UserUsecase {
+	lockout := ratelimit.NewLockout(limits, cfg.LoginLockoutMax, cfg.LoginLockoutWindow, cfg.LoginLockoutBase, cfg.LoginLockoutCap)
+	return &userUsecase{repo, cfg, limits, lockout, mail}
 }
 
-func (u *userUsecase) CheckRegisteredEmail(email string) (*domain.RegisteredEmail, error) {
-	registered_email, err := u.repo.GetRegisteredByEmail(email)
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// compareDummyHash spends the same bcrypt time as a real check so response
+// timing does not reveal whether an email has an account.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *userUsecase) CheckRegisteredEmail(ctx context.Context, email string) (*domain.RegisteredEmail, error) {
+	registered_email, err := u.repo.GetRegisteredByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	return registered_email, err
 }
 
-func (u *userUsecase) Register(user *domain.User, registeredEmail *domain.RegisteredEmail) error {
+func (u *userUsecase) Register(ctx context.Context, user *domain.User, registeredEmail *domain.RegisteredEmail) error {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -51,32 +104,32 @@ func (u *userUsecase) Register(user *domain.User, registeredEmail *domain.Regist
 
 	// Add roles from registered_email table to user.Roles
 	user.Roles = append(user.Roles, registeredEmail.Roles...)
-	return u.repo.Create(user)
+	return u.repo.Create(ctx, user)
 }
 
-func (u *userUsecase) AssignRegisteredAtEmail(registeredEmail domain.RegisteredEmail) error {
+func (u *userUsecase) AssignRegisteredAtEmail(ctx context.Context, registeredEmail domain.RegisteredEmail) error {
 	now := time.Now()
 	registeredEmail.RegisteredAt = &now
-	return u.repo.UpdateRegisteredEmail(&registeredEmail)
+	return u.repo.UpdateRegisteredEmail(ctx, &registeredEmail)
 }
 
-func (u *userUsecase) GetUserByEmail(email string) (*domain.User, error) {
-	user, err := u.repo.GetByEmail(email)
+func (u *userUsecase) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := u.repo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (u *userUsecase) GetUserById(id uint) (*domain.User, error) {
-	user, err := u.repo.GetById(id)
+func (u *userUsecase) GetUserById(ctx context.Context, id uint) (*domain.User, error) {
+	user, err := u.repo.GetById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (u *userUsecase) VerifyPassword(user *domain.User, password string) error {
+func (u *userUsecase) VerifyPassword(ctx context.Context, user *domain.User, password string) error {
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
 		return err
@@ -84,16 +137,67 @@ func (u *userUsecase) VerifyPassword(user *domain.User, password string) error {
 	return nil
 }
 
-func (u *userUsecase) Login(user *domain.User) (*string, *string, error) {
+// Authenticate checks the credentials with per-account lockout. Unknown
+// emails and wrong passwords fail the same way and count the same towards
+// the lockout, so neither reveals which emails exist.
+func (u *userUsecase) Authenticate(ctx context.Context, email, password string) (*domain.User, error) {
+	account := accountKey(email)
+
+	if wait, err := u.lockout.Check(ctx, account); err != nil {
+		return nil, err
+	} else if wait > 0 {
+		return nil, apperror.TooManyRequests("too many failed login attempts, try again later", wait)
+	}
+
+	user, err := u.repo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err != nil {
+		compareDummyHash(password)
+	} else if err = u.VerifyPassword(ctx, user, password); err == nil {
+		return user, u.lockout.Success(ctx, account)
+	}
+
+	wait, lockErr := u.lockout.Fail(ctx, account)
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	if wait > 0 {
+		return nil, apperror.TooManyRequests("too many failed login attempts, try again later", wait)
+	}
+	return nil, apperror.Unauthorized("Invalid email or password")
+}
+
+// CheckRegisterAttempt limits registration attempts per email, on top of the
+// per-IP limit, so a single allowlisted email cannot be hammered.
+func (u *userUsecase) CheckRegisterAttempt(ctx context.Context, email string) error {
+	count, resetAt, err := u.limits.Hit(ctx, "register:"+accountKey(email), u.cfg.LoginLockoutWindow)
+	if err != nil {
+		return err
+	}
+	if count > u.cfg.LoginLockoutMax {
+		return apperror.TooManyRequests("too many registration attempts, try again later", time.Until(resetAt))
+	}
+	return nil
+}
+
+// TokenTypeAccess marks tokens accepted by the JWT middleware.
+const TokenTypeAccess = "access"
+
+func (u *userUsecase) Login(ctx context.Context, user *domain.User) (*string, *string, error) {
 	// Generate JWT token
-	exp := time.Now().Add(time.Hour * 24)
+	exp := time.Now().Add(u.cfg.JWTTTL)
 	claims := jwt.MapClaims{
 		"id":  user.ID,
 		"exp": exp.Unix(),
+		"typ": TokenTypeAccess,
+		"ver": user.TokenVersion,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(config.GetConfig().JWTSecret))
+	t, err := token.SignedString([]byte(u.cfg.JWTSecret))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -103,44 +207,276 @@ func (u *userUsecase) Login(user *domain.User) (*string, *string, error) {
 	return &t, &expString, nil
 }
 
-func (u *userUsecase) CheckUserAdmin(userId uint) (bool, error) {
-	user, err := u.repo.GetByIdWithRoles(userId)
+// ValidateToken rejects access tokens of deactivated users and tokens
+// revoked by bumping the token version of the user
+func (u *userUsecase) ValidateToken(ctx context.Context, userId uint, version uint) error {
+	user, err := u.repo.GetById(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Unauthorized("account is deactivated")
+	}
+	if err != nil {
+		return err
+	}
+	if user.TokenVersion != version {
+		return apperror.Unauthorized("token has been revoked")
+	}
+	return nil
+}
+
+func (u *userUsecase) CheckUserAdmin(ctx context.Context, userId uint) (bool, error) {
+	return u.CheckUserHasRole(ctx, userId, domain.RoleAdmin)
+}
+
+// CheckUserHasRole reports whether the user has at least one of the roles
+func (u *userUsecase) CheckUserHasRole(ctx context.Context, userId uint, roles ...string) (bool, error) {
+	user, err := u.repo.GetByIdWithRoles(ctx, userId)
 	if err != nil {
 		return false, err
 	}
-	for _, role := range user.Roles {
-		if role.Name == "admin" {
+	for _, role := range roles {
+		if hasRole(user.Roles, role) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (u *userUsecase) RegisterEmail(registeredEmail *domain.RegisteredEmail) error {
-	return u.repo.CreateRegisteredEmail(registeredEmail)
+// RegisterEmail allowlists the email and sends the invitation. A failed
+// email does not undo the allowlisting, it can be resent later.
+func (u *userUsecase) RegisterEmail(ctx context.Context, registeredEmail *domain.RegisteredEmail) error {
+	if err := u.repo.CreateRegisteredEmail(ctx, registeredEmail); err != nil {
+		return err
+	}
+	if err := u.SendInvitation(ctx, registeredEmail); err != nil {
+		logger.FromContext(ctx).Error("failed to send invitation", "registered_email_id", registeredEmail.ID, "error", err.Error())
+	}
+	return nil
 }
 
-func (u *userUsecase) ParseRoles(rolesString string) ([]domain.Role, error) {
-	roles, err := u.repo.GetAllRoles()
+// ParseRoles resolves role ids and fails with a validation error on the
+// roles field when any id is unknown.
+func (u *userUsecase) ParseRoles(ctx context.Context, roleIds []uint) ([]domain.Role, error) {
+	roles, err := u.repo.GetAllRoles(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// rolesString "[1,2]" to []uint{1, 2}
-	var roleIds []uint
-	if err := json.Unmarshal([]byte(rolesString), &roleIds); err != nil {
-		return nil, err
+	rolesById := make(map[uint]domain.Role, len(roles))
+	for _, role := range roles {
+		rolesById[role.ID] = role
 	}
 
-	// Get roles from roleIds
-	var returnRoles []domain.Role
+	returnRoles := make([]domain.Role, 0, len(roleIds))
+	var unknown []string
 	for _, roleId := range roleIds {
-		for _, role := range roles {
-			if role.ID == roleId {
-				returnRoles = append(returnRoles, role)
-			}
+		role, ok := rolesById[roleId]
+		if !ok {
+			unknown = append(unknown, strconv.FormatUint(uint64(roleId), 10))
+			continue
 		}
+		returnRoles = append(returnRoles, role)
 	}
 
+	if len(unknown) > 0 {
+		return nil, apperror.Validation(types.FieldError{
+			Field:   "roles",
+			Message: "unknown role id: " + strings.Join(unknown, ", "),
+		})
+	}
 	return returnRoles, nil
 }
+
+func (u *userUsecase) GetProfile(ctx context.Context, userId uint) (*domain.User, error) {
+	return u.repo.GetByIdWithRoles(ctx, userId)
+}
+
+func (u *userUsecase) UpdateProfile(ctx context.Context, userId uint, input domain.UpdateProfileRequest) (*domain.User, error) {
+	user, err := u.repo.GetByIdWithRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Phone != nil {
+		user.Phone = *input.Phone
+	}
+	if input.Address != nil {
+		user.Address = *input.Address
+	}
+	if input.JobTitle != nil {
+		user.JobTitle = *input.JobTitle
+	}
+	if input.JobPlace != nil {
+		user.JobPlace = *input.JobPlace
+	}
+
+	if err := u.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequestEmailChange stores the new address as pending and mails a
+// verification token to it. The login email only changes once the token is
+// verified, whoever asked for the change.
+func (u *userUsecase) RequestEmailChange(ctx context.Context, userId uint, email string) error {
+	user, err := u.repo.GetByIdIncludingDeactivated(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, email) {
+		return apperror.BadRequest("email is unchanged")
+	}
+
+	inUse, err := u.repo.EmailInUse(ctx, email, user.ID)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return apperror.Conflict("email is already in use")
+	}
+
+	token, hash, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(u.cfg.EmailVerificationTTL)
+	user.PendingEmail = &email
+	user.EmailVerificationHash = &hash
+	user.EmailVerificationExpiresAt = &expiresAt
+	if err := u.repo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", u.cfg.AppBaseURL, url.QueryEscape(token))
+	return u.mail.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Verify your email for %s", u.cfg.AppName),
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm this address to use it for your %s account:\n%s\n\nThe link expires on %s. If you did not ask for this, ignore this email.\n",
+			user.Name, u.cfg.AppName, link, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+func (u *userUsecase) VerifyEmail(ctx context.Context, token string) error {
+	invalid := apperror.BadRequest("invalid or expired verification token")
+
+	user, err := u.repo.GetByEmailVerificationHash(ctx, hashVerificationToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalid
+	}
+	if err != nil {
+		return err
+	}
+	if user.PendingEmail == nil || user.EmailVerificationExpiresAt == nil || time.Now().After(*user.EmailVerificationExpiresAt) {
+		return invalid
+	}
+
+	user.Email = *user.PendingEmail
+	user.PendingEmail = nil
+	user.EmailVerificationHash = nil
+	user.EmailVerificationExpiresAt = nil
+	return u.repo.Update(ctx, user)
+}
+
+func (u *userUsecase) ListUsers(ctx context.Context, paginationFilter types.PaginationFilter, filter domain.UserListFilter) ([]domain.User, int, error) {
+	return u.repo.List(ctx, paginationFilter, filter)
+}
+
+func (u *userUsecase) GetUser(ctx context.Context, userId uint) (*domain.User, error) {
+	return u.repo.GetByIdIncludingDeactivated(ctx, userId)
+}
+
+func (u *userUsecase) UpdateUserRoles(ctx context.Context, actorId, userId uint, roleIds []uint) (*domain.User, error) {
+	user, err := u.repo.GetByIdIncludingDeactivated(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := u.ParseRoles(ctx, roleIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins cannot lock themselves out of user management
+	if actorId == userId && !hasRole(roles, domain.RoleAdmin) {
+		return nil, apperror.BadRequest("you cannot remove your own admin role")
+	}
+
+	if err := u.repo.ReplaceRoles(ctx, user, roles); err != nil {
+		return nil, err
+	}
+	user.Roles = roles
+	return user, nil
+}
+
+func (u *userUsecase) DeactivateUser(ctx context.Context, actorId, userId uint) error {
+	if actorId == userId {
+		return apperror.BadRequest("you cannot deactivate your own account")
+	}
+	if _, err := u.repo.GetById(ctx, userId); err != nil {
+		return err
+	}
+	return u.repo.Deactivate(ctx, userId)
+}
+
+func (u *userUsecase) ReactivateUser(ctx context.Context, userId uint) error {
+	user, err := u.repo.GetByIdIncludingDeactivated(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.DeletedAt.Valid {
+		return apperror.Conflict("user is already active")
+	}
+	return u.repo.Reactivate(ctx, userId)
+}
+
+func (u *userUsecase) ListInvitations(ctx context.Context, paginationFilter types.PaginationFilter) ([]domain.RegisteredEmail, int, error) {
+	return u.repo.ListPendingRegisteredEmails(ctx, paginationFilter)
+}
+
+func (u *userUsecase) SendInvitation(ctx context.Context, registeredEmail *domain.RegisteredEmail) error {
+	return u.mail.Send(ctx, mailer.Message{
+		To:      registeredEmail.Email,
+		Subject: fmt.Sprintf("You are invited to %s", u.cfg.AppName),
+		Body: fmt.Sprintf("Hello,\n\nAn account was prepared for you on %s. Register with this email address at:\n%s/register\n",
+			u.cfg.AppName, u.cfg.AppBaseURL),
+	})
+}
+
+func (u *userUsecase) ResendInvitation(ctx context.Context, id uint) error {
+	registeredEmail, err := u.repo.GetRegisteredEmailById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if registeredEmail.RegisteredAt != nil {
+		return apperror.Conflict("invitation was already used to register")
+	}
+	return u.SendInvitation(ctx, registeredEmail)
+}
+
+func hasRole(roles []domain.Role, name string) bool {
+	for _, role := range roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newVerificationToken returns a random token for the email and the hash
+// that is stored in its place.
+func newVerificationToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashVerificationToken(token), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,365 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/internal/repository"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/metrics"
+	"github.com/whyaji/daycare-preschool-api/pkg/secretbox"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/webhook"
+	"gorm.io/gorm"
+)
+
+// webhookSecretPrefix marks the signing secrets so they are recognised in
+// the config of the receiver
+const webhookSecretPrefix = "whsec_"
+
+type WebhookUsecase interface {
+	// Run fans the outbox events out to the subscriptions and sends the due
+	// deliveries until ctx is done
+	Run(ctx context.Context)
+	List(ctx context.Context) ([]domain.WebhookSubscriptionResponse, error)
+	Get(ctx context.Context, id uint) (*domain.WebhookSubscriptionResponse, error)
+	Create(ctx context.Context, userId uint, input domain.WebhookSubscriptionRequest) (*domain.WebhookSubscriptionResponse, error)
+	Update(ctx context.Context, id uint, input domain.WebhookSubscriptionRequest) (*domain.WebhookSubscriptionResponse, error)
+	Delete(ctx context.Context, id uint) error
+	RotateSecret(ctx context.Context, id uint) (*domain.WebhookSubscriptionResponse, error)
+	ListDeliveries(ctx context.Context, id uint, paginationFilter types.PaginationFilter, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDeliveryResponse, int, error)
+	Replay(ctx context.Context, deliveryId uint) (*domain.WebhookDeliveryResponse, error)
+}
+
+type webhookUsecase struct {
+	repo   repository.WebhookRepository
+	cfg    *config.Config
+	box    *secretbox.Box
+	client *webhook.Client
+	now    func() time.Time
+}
+
+func NewWebhookUsecase(repo repository.WebhookRepository, cfg *config.Config) (WebhookUsecase, error) {
+	box, err := secretbox.New(cfg.WebhookEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookUsecase{repo, cfg, box, webhook.NewClient(cfg.WebhookTimeout), time.Now}, nil
+}
+
+// newOutboxEvent builds the event a repository stores in the transaction of
+// the change it describes
+func newOutboxEvent(eventType string, data any) (domain.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return domain.OutboxEvent{}, err
+	}
+	return domain.OutboxEvent{Type: eventType, Payload: string(payload)}, nil
+}
+
+func (u *webhookUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.cfg.WebhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		u.dispatchEvents(ctx)
+		u.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchEvents turns the new outbox events into deliveries, batch after
+// batch until none is left
+func (u *webhookUsecase) dispatchEvents(ctx context.Context) {
+	for ctx.Err() == nil {
+		dispatched, err := u.repo.DispatchEvents(ctx, u.now(), u.cfg.WebhookBatchSize)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to dispatch outbox events", "error", err.Error())
+			return
+		}
+		if dispatched < u.cfg.WebhookBatchSize {
+			return
+		}
+	}
+}
+
+// sendDue sends the due deliveries, batch after batch until none is left.
+// The lease covers a whole batch of receivers timing out one after another.
+func (u *webhookUsecase) sendDue(ctx context.Context) {
+	lease := time.Duration(u.cfg.WebhookBatchSize)*u.cfg.WebhookTimeout + time.Minute
+	for ctx.Err() == nil {
+		deliveries, err := u.repo.ClaimDue(ctx, u.now(), lease, u.cfg.WebhookBatchSize)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to claim webhook deliveries", "error", err.Error())
+			return
+		}
+		for i := range deliveries {
+			u.deliver(ctx, &deliveries[i])
+		}
+		if len(deliveries) < u.cfg.WebhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliver posts one delivery and records the outcome. Failed attempts are
+// retried with a doubling backoff until the attempts run out. A delivery
+// that started is finished on shutdown.
+func (u *webhookUsecase) deliver(ctx context.Context, delivery *domain.WebhookDelivery) {
+	ctx = context.WithoutCancel(ctx)
+	log := logger.FromContext(ctx).With("delivery_id", delivery.ID, "event_type", delivery.Event.Type)
+
+	response, err := u.post(ctx, delivery)
+
+	now := u.now()
+	delivery.Attempts++
+	delivery.ResponseStatus, delivery.ResponseBody = response.Status, response.Body
+	var outcome string
+	switch {
+	case err == nil:
+		delivery.Status, delivery.DeliveredAt, delivery.LastError = domain.WebhookDelivered, &now, ""
+		outcome = domain.WebhookDelivered
+	case delivery.Attempts >= u.cfg.WebhookMaxAttempts:
+		delivery.Status, delivery.LastError = domain.WebhookFailed, err.Error()
+		outcome = domain.WebhookFailed
+		log.Error("webhook delivery failed", "attempts", delivery.Attempts, "error", err.Error())
+	default:
+		delivery.NextAttemptAt = now.Add(u.cfg.WebhookRetryBackoff << min(delivery.Attempts-1, maxBackoffDoublings))
+		delivery.LastError = err.Error()
+		outcome = "retry"
+		log.Warn("webhook delivery will be retried", "attempts", delivery.Attempts, "error", err.Error())
+	}
+	metrics.WebhookDelivery(delivery.Event.Type, outcome)
+
+	if err := u.repo.SaveDelivery(ctx, delivery); err != nil {
+		log.Error("failed to save webhook delivery", "error", err.Error())
+	}
+}
+
+func (u *webhookUsecase) post(ctx context.Context, delivery *domain.WebhookDelivery) (webhook.Response, error) {
+	// Deleted subscriptions are not loaded with the delivery
+	if delivery.Subscription.ID == 0 {
+		return webhook.Response{}, errors.New("subscription was deleted")
+	}
+	secret, err := u.box.Open(delivery.Subscription.Secret)
+	if err != nil {
+		return webhook.Response{}, err
+	}
+	body, err := json.Marshal(domain.WebhookPayload{
+		ID:        delivery.Event.ID,
+		Type:      delivery.Event.Type,
+		CreatedAt: delivery.Event.CreatedAt,
+		Data:      json.RawMessage(delivery.Event.Payload),
+	})
+	if err != nil {
+		return webhook.Response{}, err
+	}
+	return u.client.Send(ctx, webhook.Request{
+		URL:       delivery.Subscription.URL,
+		Secret:    secret,
+		EventID:   delivery.Event.ID,
+		EventType: delivery.Event.Type,
+		Body:      body,
+	})
+}
+
+func (u *webhookUsecase) List(ctx context.Context) ([]domain.WebhookSubscriptionResponse, error) {
+	subscriptions, err := u.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]domain.WebhookSubscriptionResponse, len(subscriptions))
+	for i := range subscriptions {
+		responses[i] = *domain.NewWebhookSubscriptionResponse(&subscriptions[i])
+	}
+	return responses, nil
+}
+
+func (u *webhookUsecase) Get(ctx context.Context, id uint) (*domain.WebhookSubscriptionResponse, error) {
+	subscription, err := u.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewWebhookSubscriptionResponse(subscription), nil
+}
+
+// Create adds the subscription with a new signing secret, which is only
+// returned this once
+func (u *webhookUsecase) Create(ctx context.Context, userId uint, input domain.WebhookSubscriptionRequest) (*domain.WebhookSubscriptionResponse, error) {
+	if err := u.checkURL(input.URL); err != nil {
+		return nil, err
+	}
+	secret, sealed, err := u.newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &domain.WebhookSubscription{
+		Name:       input.Name,
+		URL:        input.URL,
+		EventTypes: joinEventTypes(input.EventTypes),
+		Secret:     sealed,
+		Active:     input.Active == nil || *input.Active,
+		CreatedBy:  userId,
+	}
+	if err := u.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	response := domain.NewWebhookSubscriptionResponse(subscription)
+	response.Secret = secret
+	return response, nil
+}
+
+// Update replaces the subscription, keeping it active or paused when active
+// is left out. Deliveries of a paused subscription wait until it is active
+// again.
+func (u *webhookUsecase) Update(ctx context.Context, id uint, input domain.WebhookSubscriptionRequest) (*domain.WebhookSubscriptionResponse, error) {
+	subscription, err := u.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkURL(input.URL); err != nil {
+		return nil, err
+	}
+
+	subscription.Name = input.Name
+	subscription.URL = input.URL
+	subscription.EventTypes = joinEventTypes(input.EventTypes)
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+	if err := u.repo.UpdateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return domain.NewWebhookSubscriptionResponse(subscription), nil
+}
+
+func (u *webhookUsecase) Delete(ctx context.Context, id uint) error {
+	subscription, err := u.getSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.repo.DeleteSubscription(ctx, subscription.ID, "subscription was deleted")
+}
+
+// RotateSecret replaces the signing secret, which is only returned this
+// once. Deliveries still pending are signed with the new secret.
+func (u *webhookUsecase) RotateSecret(ctx context.Context, id uint) (*domain.WebhookSubscriptionResponse, error) {
+	subscription, err := u.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	secret, sealed, err := u.newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Secret = sealed
+	if err := u.repo.UpdateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	response := domain.NewWebhookSubscriptionResponse(subscription)
+	response.Secret = secret
+	return response, nil
+}
+
+func (u *webhookUsecase) ListDeliveries(ctx context.Context, id uint, paginationFilter types.PaginationFilter, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDeliveryResponse, int, error) {
+	subscription, err := u.getSubscription(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	deliveries, totalPages, err := u.repo.ListDeliveries(ctx, paginationFilter, filter, subscription.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses := make([]domain.WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = *domain.NewWebhookDeliveryResponse(&deliveries[i])
+	}
+	return responses, totalPages, nil
+}
+
+// Replay sends the event of the delivery to its subscription again as a new
+// delivery, so the log keeps the earlier attempts
+func (u *webhookUsecase) Replay(ctx context.Context, deliveryId uint) (*domain.WebhookDeliveryResponse, error) {
+	delivery, err := u.repo.GetDelivery(ctx, deliveryId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Webhook delivery not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := u.repo.GetSubscription(ctx, delivery.SubscriptionID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Conflict("the subscription of this delivery was deleted")
+	} else if err != nil {
+		return nil, err
+	}
+
+	replay := &domain.WebhookDelivery{
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		Status:         domain.WebhookPending,
+		NextAttemptAt:  u.now(),
+	}
+	if err := u.repo.CreateDelivery(ctx, replay); err != nil {
+		return nil, err
+	}
+	return domain.NewWebhookDeliveryResponse(replay), nil
+}
+
+func (u *webhookUsecase) getSubscription(ctx context.Context, id uint) (*domain.WebhookSubscription, error) {
+	subscription, err := u.repo.GetSubscription(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Webhook subscription not found")
+	}
+	return subscription, err
+}
+
+// checkURL only accepts https endpoints in production, plain http is allowed
+// elsewhere for local receivers
+func (u *webhookUsecase) checkURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return apperror.Validation(types.FieldError{Field: "url", Message: "url must be an absolute URL"})
+	}
+	if parsed.Scheme == "https" || (parsed.Scheme == "http" && !u.cfg.IsProduction()) {
+		return nil
+	}
+	if u.cfg.IsProduction() {
+		return apperror.Validation(types.FieldError{Field: "url", Message: "url must use https"})
+	}
+	return apperror.Validation(types.FieldError{Field: "url", Message: "url must use http or https"})
+}
+
+// newSecret returns a random signing secret and its sealed form to store
+func (u *webhookUsecase) newSecret() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret := webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	sealed, err := u.box.Seal(secret)
+	return secret, sealed, err
+}
+
+// joinEventTypes stores the event types sorted and without duplicates
+func joinEventTypes(eventTypes []string) string {
+	sorted := slices.Clone(eventTypes)
+	slices.Sort(sorted)
+	return strings.Join(slices.Compact(sorted), ",")
+}
@@ -0,0 +1,187 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Code is the machine-readable error code returned to clients.
+type Code string
+
+const (
+	CodeBadRequest       Code = "BAD_REQUEST"
+	CodeValidation       Code = "VALIDATION_FAILED"
+	CodeUnauthorized     Code = "UNAUTHORIZED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeConflict         Code = "CONFLICT"
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+	CodeTooLarge         Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMedia Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeTooManyRequests  Code = "TOO_MANY_REQUESTS"
+	CodeInternal         Code = "INTERNAL_ERROR"
+	CodeUnavailable      Code = "SERVICE_UNAVAILABLE"
+
+	CodeNotClockedIn        Code = "NOT_CLOCKED_IN"
+	CodeNotClockedOut       Code = "NOT_CLOCKED_OUT"
+	CodeOutsideWorkLocation Code = "OUTSIDE_WORK_LOCATION"
+	CodeChildNotEnrolled    Code = "CHILD_NOT_ENROLLED"
+	CodeCenterClosed        Code = "CENTER_CLOSED"
+	CodeChildAlreadyArrived Code = "CHILD_ALREADY_ARRIVED"
+
+	CodeRegistrationNotAllowed Code = "REGISTRATION_NOT_ALLOWED"
+	CodeInvalidTwoFactorCode   Code = "INVALID_TWO_FACTOR_CODE"
+	CodeTwoFactorRequired      Code = "TWO_FACTOR_REQUIRED"
+	CodeRoleInUse              Code = "ROLE_IN_USE"
+)
+
+// AppError is the single error type understood by the central error handler.
+// Err keeps the underlying cause for logging and is never sent to clients.
+type AppError struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []types.FieldError
+	Headers map[string]string
+	Err     error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code Code, message string) *AppError {
+	return &AppError{Status: status, Code: code, Message: message}
+}
+
+// WithCode returns a copy of the error with a more specific code.
+func (e *AppError) WithCode(code Code) *AppError {
+	clone := *e
+	clone.Code = code
+	return &clone
+}
+
+// Wrap returns a copy of the error that keeps err as its cause.
+func (e *AppError) Wrap(err error) *AppError {
+	clone := *e
+	clone.Err = err
+	return &clone
+}
+
+// WithHeader returns a copy of the error that sets a response header.
+func (e *AppError) WithHeader(key, value string) *AppError {
+	clone := *e
+	clone.Headers = make(map[string]string, len(e.Headers)+1)
+	for k, v := range e.Headers {
+		clone.Headers[k] = v
+	}
+	clone.Headers[key] = value
+	return &clone
+}
+
+func BadRequest(message string) *AppError {
+	return New(fiber.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Validation(fields ...types.FieldError) *AppError {
+	err := New(fiber.StatusBadRequest, CodeValidation, "request validation failed")
+	err.Fields = fields
+	return err
+}
+
+func Unauthorized(message string) *AppError {
+	return New(fiber.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *AppError {
+	return New(fiber.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *AppError {
+	return New(fiber.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *AppError {
+	return New(fiber.StatusConflict, CodeConflict, message)
+}
+
+// TooManyRequests sets Retry-After to the remaining wait, rounded up to a
+// whole second.
+func TooManyRequests(message string, retryAfter time.Duration) *AppError {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return New(fiber.StatusTooManyRequests, CodeTooManyRequests, message).
+		WithHeader(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+}
+
+func Internal(err error) *AppError {
+	return New(fiber.StatusInternalServerError, CodeInternal, "internal server error").Wrap(err)
+}
+
+// From converts any error into an AppError. Known persistence errors are
+// mapped to client errors; everything else becomes an opaque 500.
+func From(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return New(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("resource not found").Wrap(err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict("resource already exists").Wrap(err)
+	}
+
+	return Internal(err)
+}
+
+func codeForStatus(status int) Code {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeBadRequest
+	case fiber.StatusUnprocessableEntity:
+		return CodeValidation
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case fiber.StatusConflict:
+		return CodeConflict
+	case fiber.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case fiber.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
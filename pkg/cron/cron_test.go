@@ -0,0 +1,86 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2025-02-20 is a Thursday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 2, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/5 * * * *", at(20, 10, 2).Add(30 * time.Second), at(20, 10, 5)},
+		// Next is strictly after the time
+		{"30 3 * * *", at(20, 3, 30), at(21, 3, 30)},
+		{"0 9 * * 1-5", at(21, 10, 0), at(24, 9, 0)},
+		{"0 0 1,15 * *", at(16, 0, 0), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 8-18/2 * * *", at(20, 9, 0), at(20, 10, 0)},
+		{"0 8-18/2 * * *", at(20, 18, 30), at(21, 8, 0)},
+		{"5/15 * * * *", at(20, 10, 51), at(20, 11, 5)},
+		// Sunday is 0 or 7
+		{"0 0 * * 0", at(20, 0, 0), at(23, 0, 0)},
+		{"0 0 * * 7", at(20, 0, 0), at(23, 0, 0)},
+		// With both day fields restricted either one matches: Friday the 7th
+		// comes before the 13th
+		{"0 12 13 * 5", at(1, 0, 0), at(7, 12, 0)},
+		{"0 12 13 * 5", at(8, 0, 0), at(13, 12, 0)},
+		// A day field starting with * leaves the other one alone
+		{"0 12 13 * *", at(1, 0, 0), at(13, 12, 0)},
+		{"0 12 * * 5", at(8, 0, 0), at(14, 12, 0)},
+		{"0 12 */1 * 5", at(8, 0, 0), at(14, 12, 0)},
+		{"0 0 29 2 *", at(1, 0, 0), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Never matches
+		{"0 0 30 2 *", at(1, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("WIB", 7*3600)
+	schedule, err := Parse("30 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := schedule.Next(time.Date(2025, 2, 20, 4, 0, 0, 0, loc))
+	if want := time.Date(2025, 2, 21, 3, 30, 0, 0, loc); !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid spec", spec)
+		}
+	}
+}
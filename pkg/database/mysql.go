@@ -2,29 +2,56 @@ package database
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-func ConnectDb(cfg config.Config) (*gorm.DB, error) {
+// SchemaVersion is the schema version this build expects. Bump it whenever
+// scripts/migrations.go changes so /readyz reports pending migrations.
+const SchemaVersion = 19
+
+func ConnectDb(cfg *config.Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=%s",
 		cfg.DBUserName,
 		cfg.DBPassword,
 		cfg.DBHost,
 		cfg.DBPort,
 		cfg.DBDatabase,
+		cfg.DBConnectTimeout,
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		// Translate driver errors such as duplicate keys into gorm errors
+		TranslateError: true,
+		// Only errors and slow queries by default, SQL without bound values
+		Logger: logger.NewGormLogger(slog.Default(), logger.ParseGormLevel(cfg.LogLevel), cfg.DBSlowQuery),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	db.Logger = logger.Default.LogMode(logger.Info)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
 
 	return db, nil
 }
+
+// Close closes the underlying connection pool.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,185 @@
+// Package events fans domain events out to the open event streams. The
+// in-memory broker serves a single instance; a shared broker such as Redis
+// can implement the same interface when the API runs on several instances.
+package events
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("events: broker closed")
+
+// Event is a domain event. Its data only carries ids and states, clients load
+// the details through the API with its access checks.
+type Event struct {
+	ID       uint64
+	Type     string
+	Data     json.RawMessage
+	Audience Audience
+	At       time.Time
+}
+
+// Audience selects who may receive an event: staff receive every event,
+// parents the events of their children and those meant for everyone
+type Audience struct {
+	StaffOnly bool
+	Everyone  bool
+	ChildIDs  []uint
+}
+
+// Subscriber is the user reading a stream. ChildIDs holds the children of a
+// parent.
+type Subscriber struct {
+	UserID   uint
+	Staff    bool
+	ChildIDs []uint
+}
+
+func (a Audience) Allows(s Subscriber) bool {
+	if s.Staff {
+		return true
+	}
+	if a.StaffOnly {
+		return false
+	}
+	if a.Everyone {
+		return true
+	}
+	for _, childId := range a.ChildIDs {
+		if slices.Contains(s.ChildIDs, childId) {
+			return true
+		}
+	}
+	return false
+}
+
+type Broker interface {
+	// Publish assigns the id and the time of the event and sends it to the
+	// subscriptions
+	Publish(ctx context.Context, event Event) error
+	// Subscribe replays the events after lastEventID before the new ones.
+	// When they are no longer available the subscription is marked for a
+	// resync.
+	Subscribe(ctx context.Context, lastEventID uint64) (*Subscription, error)
+	// Close ends every subscription, used on shutdown
+	Close()
+}
+
+// Subscription delivers events until it is closed. Events is closed too when
+// the reader falls too far behind, it then reconnects with the last id.
+type Subscription struct {
+	Events <-chan Event
+	Resync bool
+	close  func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// subscriberBuffer is the number of events a slow reader may lag behind
+const subscriberBuffer = 256
+
+// MemoryBroker keeps the latest events in a ring for replays. Ids start at
+// the start time in microseconds, so ids of an earlier process are always
+// older than the ring and their clients get a resync.
+type MemoryBroker struct {
+	mu     sync.Mutex
+	nextID uint64
+	ring   []Event
+	size   int
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+func NewMemoryBroker(replaySize int) *MemoryBroker {
+	return &MemoryBroker{
+		nextID: uint64(time.Now().UnixMicro()),
+		size:   replaySize,
+		subs:   map[chan Event]struct{}{},
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	b.nextID++
+	event.ID = b.nextID
+	event.At = time.Now()
+	if b.size > 0 {
+		if len(b.ring) == b.size {
+			b.ring = b.ring[1:]
+		}
+		b.ring = append(b.ring, event)
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			// Too slow, the client catches up from the ring on reconnect
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, lastEventID uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	var replay []Event
+	resync := false
+	if lastEventID != 0 && lastEventID < b.nextID {
+		start, _ := slices.BinarySearchFunc(b.ring, lastEventID+1, func(event Event, id uint64) int {
+			return cmp.Compare(event.ID, id)
+		})
+		// The event right after lastEventID must still be in the ring
+		if start == len(b.ring) || b.ring[start].ID != lastEventID+1 {
+			resync = true
+		} else {
+			replay = b.ring[start:]
+		}
+	} else if lastEventID > b.nextID {
+		resync = true
+	}
+
+	ch := make(chan Event, len(replay)+subscriberBuffer)
+	for _, event := range replay {
+		ch <- event
+	}
+	b.subs[ch] = struct{}{}
+	return &Subscription{Events: ch, Resync: resync, close: func() { b.unsubscribe(ch) }}, nil
+}
+
+func (b *MemoryBroker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
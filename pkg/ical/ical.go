@@ -0,0 +1,239 @@
+// Package ical reads and writes the subset of iCalendar (RFC 5545) needed to
+// import holidays and publish the center calendar: VEVENTs with a summary,
+// description, location and start and end, either all-day or timed.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+// Event is a calendar event. All-day events start at midnight and End is
+// exclusive, so a single day event ends at the next midnight.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// Parse reads the VEVENTs of a calendar. Dates without a time zone are read
+// in loc.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	hasEnd := false
+	for n, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing ':'", n+1)
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current, hasEnd = &Event{}, false
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", n+1)
+			}
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event %q has no DTSTART", n+1, current.Summary)
+			}
+			if !hasEnd {
+				current.End = current.Start
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			// Calendar level properties and other components are ignored
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "DESCRIPTION":
+			current.Description = unescape(value)
+		case name == "LOCATION":
+			current.Location = unescape(value)
+		case name == "DTSTART":
+			start, allDay, err := parseTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: DTSTART: %w", n+1, err)
+			}
+			current.Start, current.AllDay = start, allDay
+		case name == "DTEND":
+			end, _, err := parseTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: DTEND: %w", n+1, err)
+			}
+			current.End, hasEnd = end, true
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// Calendar is a calendar to publish
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Encode writes the calendar in iCalendar format. Timed events are written in
+// UTC.
+func (c *Calendar) Encode(w io.Writer) error {
+	b := &builder{}
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:" + escape(c.ProdID))
+	b.line("CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		b.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	stamp := time.Now().UTC().Format(dateTimeLayout) + "Z"
+	for _, event := range c.Events {
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + escape(event.UID))
+		b.line("DTSTAMP:" + stamp)
+		if event.AllDay {
+			b.line("DTSTART;VALUE=DATE:" + event.Start.Format(dateLayout))
+			b.line("DTEND;VALUE=DATE:" + event.End.Format(dateLayout))
+		} else {
+			b.line("DTSTART:" + event.Start.UTC().Format(dateTimeLayout) + "Z")
+			b.line("DTEND:" + event.End.UTC().Format(dateTimeLayout) + "Z")
+		}
+		b.line("SUMMARY:" + escape(event.Summary))
+		if event.Description != "" {
+			b.line("DESCRIPTION:" + escape(event.Description))
+		}
+		if event.Location != "" {
+			b.line("LOCATION:" + escape(event.Location))
+		}
+		b.line("END:VEVENT")
+	}
+	b.line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// unfold joins continuation lines, which start with a space or a tab
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine splits NAME;PARAM=VALUE;...:VALUE. Parameter values may be
+// quoted and contain ':' or ';'.
+func splitLine(line string) (string, map[string]string, string, bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeLayout, strings.TrimSuffix(value, "Z"))
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, value, loc)
+	return t, false, err
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+func unescape(text string) string {
+	return unescaper.Replace(text)
+}
+
+// builder writes content lines with CRLF endings, folded at 75 octets
+// without splitting UTF-8 sequences. The space starting a continuation line
+// counts towards its 75 octets.
+type builder struct {
+	strings.Builder
+}
+
+func (b *builder) line(content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
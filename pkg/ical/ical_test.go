@@ -0,0 +1,113 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	loc := time.FixedZone("WIB", 7*3600)
+	want := []Event{
+		{
+			UID:         "holiday-1@daycare",
+			Summary:     "Hari Raya; closed, all day",
+			Description: `Line one\with a backslash` + "\nline two " + strings.Repeat("é", 60),
+			Location:    "Main building, hall 2, " + strings.Repeat("north wing ", 20),
+			Start:       time.Date(2025, 3, 31, 0, 0, 0, 0, loc),
+			End:         time.Date(2025, 4, 2, 0, 0, 0, 0, loc),
+			AllDay:      true,
+		},
+		{
+			UID:     "event-2@daycare",
+			Summary: "Parents meeting",
+			Start:   time.Date(2025, 2, 20, 15, 0, 0, 0, loc),
+			End:     time.Date(2025, 2, 20, 16, 30, 0, 0, loc),
+		},
+	}
+
+	var buf bytes.Buffer
+	calendar := &Calendar{ProdID: "-//Daycare//Calendar//EN", Name: "Daycare", Events: want}
+	if err := calendar.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets is not folded: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("folding split a UTF-8 sequence: %q", line)
+		}
+	}
+
+	got, err := Parse(&buf, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsed %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.UID != w.UID || g.Summary != w.Summary || g.Description != w.Description || g.Location != w.Location || g.AllDay != w.AllDay {
+			t.Errorf("event %d = %+v, want %+v", i, g, w)
+		}
+		if !g.Start.Equal(w.Start) || !g.End.Equal(w.End) {
+			t.Errorf("event %d runs %v to %v, want %v to %v", i, g.Start, g.End, w.Start, w.End)
+		}
+	}
+}
+
+func TestParseUnfoldsAndUnescapes(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Teachers\\, staff\\; and \r\n" +
+		"\tparents\\nwelcome\r\n" +
+		"DTSTART;VALUE=DATE:20250417\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Timed\r\n" +
+		"DTSTART;TZID=\"Asia/Jakarta\":20250220T080000\r\n" +
+		"DTEND:20250220T030000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := Parse(strings.NewReader(input), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("parsed %d events, want 2", len(events))
+	}
+
+	allDay := events[0]
+	if want := "Teachers, staff; and parents\nwelcome"; allDay.Summary != want {
+		t.Errorf("Summary = %q, want %q", allDay.Summary, want)
+	}
+	// An all-day event without DTEND lasts one day
+	if !allDay.AllDay || !allDay.End.Equal(time.Date(2025, 4, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("all-day event = %+v, want one day", allDay)
+	}
+
+	timed := events[1]
+	if timed.AllDay || !timed.Start.Equal(timed.End.Add(-2*time.Hour)) {
+		t.Errorf("timed event runs %v to %v, want two hours", timed.Start, timed.End)
+	}
+}
+
+func TestParseRejectsBrokenEvents(t *testing.T) {
+	for name, input := range map[string]string{
+		"missing colon":   "BEGIN:VEVENT\nSUMMARY\nEND:VEVENT\n",
+		"no DTSTART":      "BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\n",
+		"unterminated":    "BEGIN:VEVENT\nDTSTART:20250101\n",
+		"END only":        "END:VEVENT\n",
+		"invalid DTSTART": "BEGIN:VEVENT\nDTSTART:2025-01-01\nEND:VEVENT\n",
+	} {
+		if _, err := Parse(strings.NewReader(input), time.UTC); err == nil {
+			t.Errorf("%s: Parse() accepted the calendar", name)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package imaging cleans uploaded photos and makes their thumbnails. Images
+// are decoded and encoded again, which drops every metadata block such as
+// EXIF with its GPS position.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+var ErrUnsupported = errors.New("imaging: unsupported image format")
+
+// Image is an encoded image with its size
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// maxPixels bounds the decoded size so a small file cannot claim a huge
+// canvas and exhaust memory
+const maxPixels = 50_000_000
+
+// Sanitize decodes a JPEG or PNG, applies the EXIF orientation of JPEGs and
+// returns it encoded again without metadata, with a thumbnail fitting in
+// thumbSize x thumbSize.
+func Sanitize(data []byte, thumbSize int) (*Image, *Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupported
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, nil, ErrUnsupported
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, nil, errors.New("imaging: image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	cleaned, err := encode(img, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	thumbnail, err := encode(Fit(img, thumbSize), format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cleaned, thumbnail, nil
+}
+
+func encode(img image.Image, format string) (*Image, error) {
+	var buf bytes.Buffer
+	contentType := "image/png"
+	var err error
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	return &Image{Data: buf.Bytes(), ContentType: contentType, Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+// Fit scales the image down, keeping its aspect ratio, so that both sides
+// are at most size. Each target pixel averages the source pixels it covers.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	targetWidth, targetHeight := size, height*size/width
+	if height > width {
+		targetWidth, targetHeight = width*size/height, size
+	}
+	targetWidth, targetHeight = max(targetWidth, 1), max(targetHeight, 1)
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := range targetHeight {
+		y0, y1 := y*height/targetHeight, max((y+1)*height/targetHeight, y*height/targetHeight+1)
+		for x := range targetWidth {
+			x0, x1 := x*width/targetWidth, max((x+1)*width/targetWidth, x*width/targetWidth+1)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient turns the image upright according to the EXIF orientation (1 to 8)
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = width-1-y, x
+			case 7:
+				dx, dy = width-1-y, height-1-x
+			case 8:
+				dx, dy = y, height-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the orientation tag of the EXIF block of a JPEG, 1
+// (upright) when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := range entries {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM logs to slog with the request id from the query
+// context. Bound parameters are never interpolated into the logged SQL, so
+// user data such as names or medical notes does not end up in the logs.
+type GormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+func NewGormLogger(logger *slog.Logger, level gormlogger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: logger, level: level, slowThreshold: slowThreshold}
+}
+
+// ParseGormLevel maps the application log level onto GORM's: SQL traces are
+// only logged at debug.
+func ParseGormLevel(level string) gormlogger.LogLevel {
+	switch ParseLevel(level) {
+	case slog.LevelDebug:
+		return gormlogger.Info
+	case slog.LevelError:
+		return gormlogger.Error
+	}
+	return gormlogger.Warn
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.with(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.with(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.with(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.with(ctx).Error("query failed", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds(), "error", err.Error())
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.with(ctx).Warn("slow query", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.with(ctx).Debug("query", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// ParamsFilter keeps bound parameters out of the SQL passed to Trace.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (l *GormLogger) with(ctx context.Context) *slog.Logger {
+	log := l.logger.With("component", "gorm")
+	if requestID := RequestIDFrom(ctx); requestID != "" {
+		log = log.With("request_id", requestID)
+	}
+	return log
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type contextKey struct{}
+
+// redactedKeys are attribute and JSON keys whose values are never logged.
+// Matching is case insensitive and ignores "_" and "-".
+var redactedKeys = map[string]bool{
+	"password":        true,
+	"newpassword":     true,
+	"currentpassword": true,
+	"token":           true,
+	"accesstoken":     true,
+	"refreshtoken":    true,
+	"authorization":   true,
+	"secret":          true,
+	"jwtsecret":       true,
+	"dbpassword":      true,
+	"totp":            true,
+	"otp":             true,
+	"recoverycode":    true,
+	"alergyinfo":      true,
+	"allergyinfo":     true,
+	"notes":           true,
+	"medicalnotes":    true,
+	"conditionnotes":  true,
+	"healthcondition": true,
+	"conditions":      true,
+	"allergen":        true,
+	"allergies":       true,
+	"reaction":        true,
+	"firstaid":        true,
+	"actionplan":      true,
+}
+
+const redacted = "[REDACTED]"
+
+// New builds the application logger. format is "json" or "text" and level
+// one of debug, info, warn, error.
+func New(w io.Writer, format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       ParseLevel(level),
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(handler)
+}
+
+// Setup builds the logger for stdout and installs it as the slog default.
+func Setup(format, level string) *slog.Logger {
+	log := New(os.Stdout, format, level)
+	slog.SetDefault(log)
+	return log
+}
+
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// WithRequestID stores the request id in ctx so downstream logs, including
+// GORM's, can be correlated with the access log.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the default logger annotated with the request id.
+func FromContext(ctx context.Context) *slog.Logger {
+	if requestID := RequestIDFrom(ctx); requestID != "" {
+		return slog.Default().With("request_id", requestID)
+	}
+	return slog.Default()
+}
+
+// IsSensitive reports whether values under key must be redacted.
+func IsSensitive(key string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	return redactedKeys[normalized]
+}
+
+// RedactJSON returns body with every sensitive key replaced, for logging
+// request payloads. Non JSON bodies are dropped entirely.
+func RedactJSON(body []byte) any {
+	if len(body) == 0 {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return redacted
+	}
+	return redactValue(value)
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if IsSensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(item)
+			}
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	}
+	return value
+}
+
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
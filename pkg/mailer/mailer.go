@@ -0,0 +1,80 @@
+// Package mailer sends plain text emails such as invitations and address
+// verification links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer delivers through an SMTP relay. net/smtp upgrades to STARTTLS
+// when the server offers it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{addr: net.JoinHostPort(host, strconv.Itoa(port)), auth: auth, from: from}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mailer: header contains a line break")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("mailer: sending to smtp relay: %w", err)
+	}
+	return nil
+}
+
+// LogMailer only logs messages, for development without an SMTP relay. The
+// body can hold links with tokens, so it is only logged when logBody is set.
+type LogMailer struct {
+	logBody bool
+}
+
+func NewLogMailer(logBody bool) *LogMailer {
+	return &LogMailer{logBody}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log := logger.FromContext(ctx).With("to", msg.To, "subject", msg.Subject)
+	if m.logBody {
+		log = log.With("body", msg.Body)
+	}
+	log.Info("email not sent, no SMTP relay configured")
+	return nil
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+)
+
+const unmatchedRoute = "unmatched"
+
+var knownMethods = map[string]bool{
+	fiber.MethodGet: true, fiber.MethodHead: true, fiber.MethodPost: true, fiber.MethodPut: true,
+	fiber.MethodPatch: true, fiber.MethodDelete: true, fiber.MethodOptions: true,
+}
+
+// Middleware records request count and latency. It must run outside the
+// request logger so the status is the one rendered by the error handler.
+// Labels use the route template, never the raw path.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		err := c.Next()
+
+		statusCode := c.Response().StatusCode()
+		if err != nil {
+			statusCode = apperror.From(err).Status
+		}
+
+		route := c.Route().Path
+		// Requests that matched no route end on the catch-all "/" of a Use
+		if statusCode == fiber.StatusNotFound && (route == "/" || route == "") {
+			route = unmatchedRoute
+		}
+		method := c.Method()
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+		status := strconv.Itoa(statusCode)
+
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// Handler exposes the registry in the Prometheus text format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
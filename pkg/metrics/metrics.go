@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "daycare"
+
+// Clock-in rejection reasons. Reasons are a closed set to keep the label
+// cardinality bounded.
+const (
+	ReasonOutsideGeofence = "outside_geofence"
+	ReasonNotClockedOut   = "not_clocked_out"
+	ReasonCenterClosed    = "center_closed"
+)
+
+// Overtime periods for child overtime blocks
+const (
+	PeriodMorning = "morning"
+	PeriodEvening = "evening"
+)
+
+// Registry holds every metric exposed on /metrics. A dedicated registry keeps
+// the output limited to what this service registers.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// HTTP
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+)
+
+// Database
+var (
+	dbQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM query latency by operation and table.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	dbQueryErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "GORM queries that failed, excluding record not found.",
+	}, []string{"operation", "table"})
+)
+
+// Domain
+var (
+	clockIns = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "teacher_clock_ins_total",
+		Help:      "Successful teacher clock-ins.",
+	})
+
+	clockInRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "teacher_clock_in_rejections_total",
+		Help:      "Rejected teacher clock-ins by reason.",
+	}, []string{"reason"})
+
+	childArrivals = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "child_arrivals_total",
+		Help:      "Recorded child arrivals.",
+	})
+
+	childArrivalRejections = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "child_arrival_rejections_total",
+		Help:      "Child arrivals rejected because the child is not enrolled or the center is closed.",
+	})
+
+	childOvertimeBlocks = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "child_overtime_blocks_total",
+		Help:      "15 minute child overtime blocks accrued by period.",
+	}, []string{"period"})
+
+	classroomRatioAlerts = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "classroom_ratio_alerts_total",
+		Help:      "Classroom staff-to-child ratio alerts opened.",
+	})
+
+	incidents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "incidents_reported_total",
+		Help:      "Child incidents reported by severity.",
+	}, []string{"severity"})
+
+	notificationDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_deliveries_total",
+		Help:      "Notification delivery attempts by channel and outcome (sent, skipped, retry, failed).",
+	}, []string{"channel", "outcome"})
+
+	webhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by event type and outcome (delivered, retry, failed).",
+	}, []string{"event_type", "outcome"})
+
+	jobRuns = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Background job runs by type and outcome (succeeded, retry, dead, interrupted).",
+	}, []string{"type", "outcome"})
+)
+
+func ClockIn() {
+	clockIns.Inc()
+}
+
+func ClockInRejected(reason string) {
+	clockInRejections.WithLabelValues(reason).Inc()
+}
+
+func ChildArrival() {
+	childArrivals.Inc()
+}
+
+func ChildArrivalRejected() {
+	childArrivalRejections.Inc()
+}
+
+func ChildOvertimeBlocks(period string, blocks int) {
+	if blocks > 0 {
+		childOvertimeBlocks.WithLabelValues(period).Add(float64(blocks))
+	}
+}
+
+func ClassroomRatioAlert() {
+	classroomRatioAlerts.Inc()
+}
+
+func IncidentReported(severity string) {
+	incidents.WithLabelValues(severity).Inc()
+}
+
+func NotificationDelivery(channel, outcome string) {
+	notificationDeliveries.WithLabelValues(channel, outcome).Inc()
+}
+
+func WebhookDelivery(eventType, outcome string) {
+	webhookDeliveries.WithLabelValues(eventType, outcome).Inc()
+}
+
+func JobRun(jobType, outcome string) {
+	jobRuns.WithLabelValues(jobType, outcome).Inc()
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+	"github.com/whyaji/daycare-preschool-api/pkg/utils"
+)
+
+// ErrorHandler is the central Fiber error handler. Handlers return errors
+// and this renders them in the standard response envelope.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	appErr := apperror.From(err)
+	requestID := utils.GetRequestID(c)
+
+	if appErr.Status >= fiber.StatusInternalServerError {
+		logger.FromContext(c.UserContext()).Error("request failed",
+			"method", c.Method(),
+			"path", c.Path(),
+			"error", err.Error(),
+		)
+	}
+
+	for key, value := range appErr.Headers {
+		c.Set(key, value)
+	}
+
+	return c.Status(appErr.Status).JSON(types.Response{
+		Code:      string(appErr.Code),
+		Message:   appErr.Message,
+		Errors:    appErr.Fields,
+		RequestID: requestID,
+	})
+}
@@ -1,36 +1,89 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/whyaji/daycare-preschool-api/config"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
 
 	jwtware "github.com/gofiber/contrib/jwt"
 )
 
-func JWTProtected(c *fiber.Ctx) error {
+// accessTokenType matches usecase.TokenTypeAccess
+const accessTokenType = "access"
+
+// TokenValidator rejects access tokens of users that may no longer use the
+// API, such as deactivated users
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, userId uint, version uint) error
+}
+
+// NewJWTProtected builds the JWT middleware once with the signing key from the
+// loaded config, instead of re-reading the environment on every request.
+func NewJWTProtected(cfg *config.Config, tokens TokenValidator) fiber.Handler {
 	return jwtware.New(jwtware.Config{
-		KeyFunc: customKeyFunc(),
+		KeyFunc: customKeyFunc([]byte(cfg.JWTSecret)),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			// Return status 401 and failed authentication error.
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   true,
-				"message": err.Error(),
-			})
+			return apperror.Unauthorized(err.Error())
 		},
-	})(c)
+		SuccessHandler: checkAccessToken(tokens),
+	})
 }
 
-func customKeyFunc() jwt.Keyfunc {
+// NewJWTStreamProtected also reads the access token from the access_token
+// query parameter, for the event stream opened by browsers with EventSource,
+// which cannot send headers. The request logger leaves the query out.
+func NewJWTStreamProtected(cfg *config.Config, tokens TokenValidator) fiber.Handler {
+	return jwtware.New(jwtware.Config{
+		KeyFunc:     customKeyFunc([]byte(cfg.JWTSecret)),
+		TokenLookup: "header:Authorization,query:access_token",
+		AuthScheme:  "Bearer",
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return apperror.Unauthorized(err.Error())
+		},
+		SuccessHandler: checkAccessToken(tokens),
+	})
+}
+
+// checkAccessToken stops tokens signed for another purpose, such as the
+// two-factor login challenge, from being used as access tokens, and tokens
+// the validator rejects. Tokens issued before the typ and ver claims existed
+// have none, they are accepted as access tokens of version 0.
+func checkAccessToken(tokens TokenValidator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := c.Locals("user").(*jwt.Token)
+		if !ok {
+			return apperror.Unauthorized("missing token")
+		}
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return apperror.Unauthorized("invalid token claims")
+		}
+		if typ, ok := claims["typ"]; ok && typ != accessTokenType {
+			return apperror.Unauthorized("token cannot be used for this request")
+		}
+		id, ok := claims["id"].(float64)
+		if !ok {
+			return apperror.Unauthorized("invalid token claims")
+		}
+		version, _ := claims["ver"].(float64)
+		if err := tokens.ValidateToken(c.UserContext(), uint(id), uint(version)); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+func customKeyFunc(signingKey []byte) jwt.Keyfunc {
 	return func(t *jwt.Token) (interface{}, error) {
 		if t.Method.Alg() != jwtware.HS256 {
 			return nil, fmt.Errorf("unexpected jwt signing method=%v", t.Header["alg"])
 		}
 
-		signingKey := config.GetConfig().JWTSecret
-
-		return []byte(signingKey), nil
+		return signingKey, nil
 	}
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	fiberutils "github.com/gofiber/fiber/v2/utils"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+)
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID reuses a well formed X-Request-ID from the client or generates
+// one, echoes it in the response and stores it in the user context so the
+// repositories pass it on to GORM.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = fiberutils.UUIDv4()
+		}
+
+		c.Set(fiber.HeaderXRequestID, requestID)
+		c.SetUserContext(logger.WithRequestID(c.UserContext(), requestID))
+		return c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+)
+
+// RequestLogger writes one structured access log line per request. Handler
+// errors are rendered here through the app error handler so the logged
+// status is the one the client received.
+func RequestLogger(log *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		attrs := []slog.Attr{
+			slog.String("request_id", logger.RequestIDFrom(c.UserContext())),
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if userID, ok := userIDFromLocals(c); ok {
+			attrs = append(attrs, slog.Uint64("user_id", userID))
+		}
+		if log.Enabled(c.UserContext(), slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("body", logger.RedactJSON(c.Body())))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		log.LogAttrs(context.Background(), level, "request", attrs...)
+		return nil
+	}
+}
+
+// userIDFromLocals reads the user id set by the JWT middleware, if the
+// route was protected.
+func userIDFromLocals(c *fiber.Ctx) (uint64, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return 0, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return uint64(id), true
+}
@@ -0,0 +1,282 @@
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Document is the subset of the OpenAPI 3 document model used by this API.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps a lower case HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Route describes one endpoint. Body and Response are sample values (usually
+// zero values of the DTO) whose types are turned into schemas.
+type Route struct {
+	Method      string
+	Path        string // Fiber style path, e.g. /api/v1/childs/:id
+	Tag         string
+	Summary     string
+	Auth        bool
+	Body        any
+	Form        bool         // body is also accepted as form data
+	RawBody     *RequestBody // overrides Body, e.g. for non JSON uploads
+	Query       []Parameter
+	Status      int
+	Response    any
+	Paginated   bool
+	RawResponse *Response // overrides the enveloped response, e.g. for non JSON content
+}
+
+const bearerAuth = "bearerAuth"
+
+var fiberParam = regexp.MustCompile(`:([A-Za-z0-9_]+)\??`)
+
+// New creates a document with the standard response envelope and bearer
+// auth already registered.
+func New(title, version string) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	doc.Components.Schemas["FieldError"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"field":   {Type: "string"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"field", "message"},
+	}
+	doc.Components.Schemas["PaginationMeta"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"page":       {Type: "integer"},
+			"total_page": {Type: "integer"},
+		},
+	}
+	doc.Components.Schemas["Response"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":       {Type: "string", Example: "OK"},
+			"message":    {Type: "string"},
+			"data":       {},
+			"meta":       {Ref: "#/components/schemas/PaginationMeta"},
+			"errors":     {Type: "array", Items: &Schema{Ref: "#/components/schemas/FieldError"}},
+			"request_id": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+	return doc
+}
+
+// Add registers a route in the document.
+func (d *Document) Add(r Route) {
+	path := Path(r.Path)
+	method := strings.ToLower(r.Method)
+
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: operationID(method, path),
+		Responses:   map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+		d.addTag(r.Tag)
+	}
+
+	for _, match := range fiberParam.FindAllStringSubmatch(r.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	if r.Paginated {
+		op.Parameters = append(op.Parameters, paginationParameters()...)
+	}
+	op.Parameters = append(op.Parameters, r.Query...)
+
+	if r.Body != nil {
+		schema := d.SchemaOf(r.Body)
+		content := map[string]MediaType{"application/json": {Schema: schema}}
+		if r.Form {
+			content["application/x-www-form-urlencoded"] = MediaType{Schema: schema}
+			content["multipart/form-data"] = MediaType{Schema: schema}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: content}
+	}
+	if r.RawBody != nil {
+		op.RequestBody = r.RawBody
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = 200
+	}
+	if r.RawResponse != nil {
+		op.Responses[strconv.Itoa(status)] = *r.RawResponse
+	} else {
+		op.Responses[strconv.Itoa(status)] = d.envelope("Success", r.Response, r.Paginated)
+		op.Responses["400"] = d.envelope("Invalid request", nil, false)
+		op.Responses["500"] = d.envelope("Internal error", nil, false)
+	}
+
+	if r.Auth {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+		op.Responses["401"] = d.envelope("Missing or invalid token", nil, false)
+		op.Responses["403"] = d.envelope("Not allowed", nil, false)
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+	item[method] = op
+}
+
+// Has reports whether the document contains an operation for the Fiber
+// method and path.
+func (d *Document) Has(method, path string) bool {
+	item, ok := d.Paths[Path(path)]
+	if !ok {
+		return false
+	}
+	_, ok = item[strings.ToLower(method)]
+	return ok
+}
+
+// Path converts a Fiber path into an OpenAPI path: ":id" becomes "{id}" and
+// a trailing slash is dropped.
+func Path(path string) string {
+	path = fiberParam.ReplaceAllString(path, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func (d *Document) envelope(description string, data any, paginated bool) Response {
+	schema := &Schema{Ref: "#/components/schemas/Response"}
+	if data != nil {
+		dataSchema := d.SchemaOf(data)
+		if paginated {
+			dataSchema = &Schema{Type: "array", Items: dataSchema}
+		}
+		schema = &Schema{AllOf: []*Schema{
+			schema,
+			{Type: "object", Properties: map[string]*Schema{"data": dataSchema}},
+		}}
+	}
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func (d *Document) addTag(name string) {
+	for _, tag := range d.Tags {
+		if tag.Name == name {
+			return
+		}
+	}
+	d.Tags = append(d.Tags, Tag{Name: name})
+	sort.Slice(d.Tags, func(i, j int) bool { return d.Tags[i].Name < d.Tags[j].Name })
+}
+
+func paginationParameters() []Parameter {
+	return []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Minimum: ptr(1.0)}},
+		{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: ptr(1.0)}},
+		{Name: "orderBy", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "sort", In: "query", Schema: &Schema{Type: "string", Enum: []any{"asc", "desc"}}},
+		{Name: "search", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "filter", In: "query", Description: "key:value[,value][:operator];... e.g. year:2025;month:2", Schema: &Schema{Type: "string"}},
+	}
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '{' || r == '}' || r == '.' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
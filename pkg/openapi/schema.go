@@ -0,0 +1,218 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	dateLayouts   = map[string]string{"2006-01-02": "date"}
+	layoutPattern = strings.NewReplacer(
+		"2006", `\d{4}`, "01", `\d{2}`, "02", `\d{2}`,
+		"15", `\d{2}`, "04", `\d{2}`, "05", `\d{2}`,
+	)
+)
+
+// SchemaOf returns the schema for the type of v. Named structs are added to
+// the components and referenced, which also keeps recursive models finite.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schemaOfType(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOfType(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := d.schemaOfType(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOfType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOfType(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		// gorm.DeletedAt and similar nullable wrappers
+		if t.Name() == "DeletedAt" || strings.HasPrefix(t.Name(), "Null") {
+			return &Schema{Type: "string", Format: "date-time", Nullable: true}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Reserve the name before walking fields to break cycles
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitted := jsonName(field)
+		if omitted {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := d.structSchema(field.Type)
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		property := d.schemaOfType(field.Type)
+		if applyValidateTag(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name := strings.SplitN(tag, ",", 2)[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+// applyValidateTag maps the validator tags used by the DTOs onto schema
+// keywords and reports whether the field is required.
+func applyValidateTag(schema *Schema, tag string) bool {
+	if tag == "" || schema.Ref != "" {
+		return strings.Contains(tag, "required")
+	}
+
+	required := false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		key, param, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			if schema.Items != nil {
+				target = schema.Items
+			}
+		case "required":
+			required = true
+		case "email":
+			target.Format = "email"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				target.Enum = append(target.Enum, value)
+			}
+		case "datetime":
+			if format, ok := dateLayouts[param]; ok {
+				target.Format = format
+			} else {
+				target.Pattern = "^" + layoutPattern.Replace(param) + "$"
+				target.Example = param
+			}
+		case "latitude":
+			target.Minimum, target.Maximum = ptr(-90.0), ptr(90.0)
+		case "longitude":
+			target.Minimum, target.Maximum = ptr(-180.0), ptr(180.0)
+		case "unique":
+			target.UniqueItems = true
+		case "min", "gte", "gt":
+			applyBound(target, param, true, key == "gt")
+		case "max", "lte":
+			applyBound(target, param, false, false)
+		case "len":
+			applyBound(target, param, true, false)
+			applyBound(target, param, false, false)
+		case "numeric":
+			target.Pattern = "^[0-9]+$"
+		}
+	}
+	return required
+}
+
+func applyBound(schema *Schema, param string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	if exclusive && schema.Type == "integer" {
+		n++
+	}
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = ptr(int(n))
+		} else {
+			schema.MaxLength = ptr(int(n))
+		}
+	case "array":
+		if lower {
+			schema.MinItems = ptr(int(n))
+		} else {
+			schema.MaxItems = ptr(int(n))
+		}
+	default:
+		if lower {
+			schema.Minimum = ptr(n)
+		} else {
+			schema.Maximum = ptr(n)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package push sends push notifications to the devices of users.
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+)
+
+// ErrInvalidToken means the device token is no longer registered and should
+// be forgotten
+var ErrInvalidToken = errors.New("push: device token is not registered")
+
+type Message struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+type Pusher interface {
+	Push(ctx context.Context, msg Message) error
+}
+
+// HTTPPusher posts FCM-style JSON messages to a push gateway with a server
+// key
+type HTTPPusher struct {
+	endpoint  string
+	serverKey string
+	client    *http.Client
+}
+
+func NewHTTPPusher(endpoint, serverKey string) *HTTPPusher {
+	return &HTTPPusher{endpoint: endpoint, serverKey: serverKey, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type fcmRequest struct {
+	To           string            `json:"to"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmResponse struct {
+	Failure int `json:"failure"`
+	Results []struct {
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+func (p *HTTPPusher) Push(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(fcmRequest{
+		To:           msg.Token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+p.serverKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push: sending to gateway: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrInvalidToken
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push: gateway responded %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var result fcmResponse
+	if err := json.Unmarshal(body, &result); err != nil || result.Failure == 0 || len(result.Results) == 0 {
+		return nil
+	}
+	switch result.Results[0].Error {
+	case "NotRegistered", "InvalidRegistration", "MismatchSenderId":
+		return ErrInvalidToken
+	default:
+		return fmt.Errorf("push: gateway rejected the message: %s", result.Results[0].Error)
+	}
+}
+
+// LogPusher only logs messages, for development without a push gateway
+type LogPusher struct{}
+
+func NewLogPusher() *LogPusher {
+	return &LogPusher{}
+}
+
+func (p *LogPusher) Push(ctx context.Context, msg Message) error {
+	logger.FromContext(ctx).Info("push not sent, no push gateway configured", "title", msg.Title)
+	return nil
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Lockout locks an account after Threshold failures within Window. Each new
+// lock while the previous lock level is remembered doubles the duration,
+// starting at BaseLock and capped at MaxLock.
+type Lockout struct {
+	store     Store
+	threshold int
+	window    time.Duration
+	baseLock  time.Duration
+	maxLock   time.Duration
+	now       func() time.Time
+}
+
+// levelMemory is how long a lock level is remembered after the last lock
+const levelMemory = 24 * time.Hour
+
+func NewLockout(store Store, threshold int, window, baseLock, maxLock time.Duration) *Lockout {
+	return &Lockout{store, threshold, window, baseLock, maxLock, time.Now}
+}
+
+// Check returns how long the account stays locked, 0 when not locked.
+func (l *Lockout) Check(ctx context.Context, account string) (time.Duration, error) {
+	count, resetAt, err := l.store.Get(ctx, "lock:"+account)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	return resetAt.Sub(l.now()), nil
+}
+
+// Fail records a failed attempt and returns the lock duration if this
+// attempt triggered a lock, 0 otherwise.
+func (l *Lockout) Fail(ctx context.Context, account string) (time.Duration, error) {
+	failures, _, err := l.store.Hit(ctx, "fail:"+account, l.window)
+	if err != nil || failures < l.threshold {
+		return 0, err
+	}
+
+	level, _, err := l.store.Hit(ctx, "level:"+account, levelMemory)
+	if err != nil {
+		return 0, err
+	}
+
+	duration := l.baseLock
+	for i := 1; i < level && duration < l.maxLock; i++ {
+		duration *= 2
+	}
+	if duration > l.maxLock {
+		duration = l.maxLock
+	}
+
+	if err := l.store.Reset(ctx, "fail:"+account); err != nil {
+		return 0, err
+	}
+	if _, _, err := l.store.Hit(ctx, "lock:"+account, duration); err != nil {
+		return 0, err
+	}
+	return duration, nil
+}
+
+// Success clears the failure counter and lock level.
+func (l *Lockout) Success(ctx context.Context, account string) error {
+	if err := l.store.Reset(ctx, "fail:"+account); err != nil {
+		return err
+	}
+	return l.store.Reset(ctx, "level:"+account)
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestLockout() (*Lockout, *fakeClock) {
+	store, clock := newTestStore()
+	lockout := NewLockout(store, 3, time.Minute, time.Minute, 4*time.Minute)
+	lockout.now = clock.Now
+	return lockout, clock
+}
+
+// failUntilLocked records failures up to the threshold and returns the lock
+// the last one triggered
+func failUntilLocked(t *testing.T, lockout *Lockout, account string) time.Duration {
+	t.Helper()
+	ctx := context.Background()
+	for i := 1; i < lockout.threshold; i++ {
+		wait, err := lockout.Fail(ctx, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != 0 {
+			t.Fatalf("failure %d locked the account for %v", i, wait)
+		}
+	}
+	wait, err := lockout.Fail(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wait
+}
+
+func TestLockoutLocksAfterThreshold(t *testing.T) {
+	lockout, clock := newTestLockout()
+	ctx := context.Background()
+
+	if wait := failUntilLocked(t, lockout, "user"); wait != time.Minute {
+		t.Fatalf("lock = %v, want %v", wait, time.Minute)
+	}
+	if wait, _ := lockout.Check(ctx, "user"); wait != time.Minute {
+		t.Errorf("Check() = %v, want %v", wait, time.Minute)
+	}
+	if wait, _ := lockout.Check(ctx, "other"); wait != 0 {
+		t.Errorf("Check() of another account = %v, want 0", wait)
+	}
+
+	clock.Advance(20 * time.Second)
+	if wait, _ := lockout.Check(ctx, "user"); wait != 40*time.Second {
+		t.Errorf("Check() after 20s = %v, want 40s", wait)
+	}
+	clock.Advance(40 * time.Second)
+	if wait, _ := lockout.Check(ctx, "user"); wait != 0 {
+		t.Errorf("Check() after the lock = %v, want 0", wait)
+	}
+}
+
+func TestLockoutForgetsFailuresOutsideWindow(t *testing.T) {
+	lockout, clock := newTestLockout()
+	ctx := context.Background()
+
+	lockout.Fail(ctx, "user")
+	lockout.Fail(ctx, "user")
+	clock.Advance(time.Minute)
+	if wait, _ := lockout.Fail(ctx, "user"); wait != 0 {
+		t.Errorf("failure in a new window locked the account for %v", wait)
+	}
+}
+
+func TestLockoutDoublesUpToMaxLock(t *testing.T) {
+	lockout, clock := newTestLockout()
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		if wait := failUntilLocked(t, lockout, "user"); wait != want {
+			t.Errorf("lock = %v, want %v", wait, want)
+		}
+		clock.Advance(want)
+	}
+}
+
+func TestLockoutSuccessResetsLevel(t *testing.T) {
+	lockout, clock := newTestLockout()
+	ctx := context.Background()
+
+	failUntilLocked(t, lockout, "user")
+	clock.Advance(time.Minute)
+	if err := lockout.Success(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := failUntilLocked(t, lockout, "user"); wait != time.Minute {
+		t.Errorf("lock after a success = %v, want %v", wait, time.Minute)
+	}
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+)
+
+type Rule struct {
+	Name   string // key prefix, e.g. "login"
+	Max    int
+	Window time.Duration
+}
+
+// Middleware limits requests per client IP for the rule. Routes sharing a
+// rule name share the budget.
+func Middleware(store Store, rule Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		count, resetAt, err := store.Hit(c.UserContext(), "ip:"+rule.Name+":"+c.IP(), rule.Window)
+		if err != nil {
+			// Fail open, a broken limiter must not take login down
+			return c.Next()
+		}
+
+		remaining := rule.Max - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Set("X-RateLimit-Limit", strconv.Itoa(rule.Max))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if count > rule.Max {
+			return apperror.TooManyRequests("too many requests, try again later", time.Until(resetAt))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps fixed window counters. Implementations must be safe for
+// concurrent use; the in-memory store is the default and a shared store
+// (e.g. Redis) can be plugged in for multiple API instances.
+type Store interface {
+	// Hit increments key and returns the count in the current window and
+	// when the window resets. A new window starts when the previous expired.
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Get returns the current count without incrementing, 0 when expired.
+	Get(ctx context.Context, key string) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type entry struct {
+	count   int
+	resetAt time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	hits    int
+	now     func() time.Time
+}
+
+const sweepEvery = 1024
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]entry{}, now: time.Now}
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.hits++
+	if s.hits%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		e = entry{resetAt: now.Add(window)}
+	}
+	e.count++
+	s.entries[key] = e
+	return e.count, e.resetAt, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.resetAt) {
+		return 0, time.Time{}, nil
+	}
+	return e.count, e.resetAt, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired entries so the map does not grow with every IP seen
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.resetAt) {
+			delete(s.entries, key)
+		}
+	}
+}
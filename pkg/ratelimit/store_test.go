@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a time source tests move forward by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 2, 20, 8, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryStoreCountsWithinWindow(t *testing.T) {
+	store, clock := newTestStore()
+	ctx := context.Background()
+	start := clock.Now()
+
+	for want := 1; want <= 3; want++ {
+		count, resetAt, err := store.Hit(ctx, "key", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("hit %d: count = %d", want, count)
+		}
+		// The window is fixed from the first hit
+		if !resetAt.Equal(start.Add(time.Minute)) {
+			t.Errorf("hit %d: resetAt = %v, want %v", want, resetAt, start.Add(time.Minute))
+		}
+		clock.Advance(10 * time.Second)
+	}
+
+	count, _, err := store.Get(ctx, "key")
+	if err != nil || count != 3 {
+		t.Errorf("Get() = %d, %v, want 3", count, err)
+	}
+	if count, _, _ := store.Get(ctx, "other"); count != 0 {
+		t.Errorf("Get() of an unknown key = %d, want 0", count)
+	}
+}
+
+func TestMemoryStoreStartsNewWindowAfterReset(t *testing.T) {
+	store, clock := newTestStore()
+	ctx := context.Background()
+
+	store.Hit(ctx, "key", time.Minute)
+	store.Hit(ctx, "key", time.Minute)
+
+	clock.Advance(time.Minute - time.Second)
+	if count, _, _ := store.Get(ctx, "key"); count != 2 {
+		t.Errorf("before the window ends: Get() = %d, want 2", count)
+	}
+
+	clock.Advance(time.Second)
+	if count, _, _ := store.Get(ctx, "key"); count != 0 {
+		t.Errorf("when the window ends: Get() = %d, want 0", count)
+	}
+	count, resetAt, _ := store.Hit(ctx, "key", time.Minute)
+	if count != 1 || !resetAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("new window: Hit() = %d, %v, want 1, %v", count, resetAt, clock.Now().Add(time.Minute))
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	store, _ := newTestStore()
+	ctx := context.Background()
+
+	store.Hit(ctx, "key", time.Minute)
+	if err := store.Reset(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, _ := store.Get(ctx, "key"); count != 0 {
+		t.Errorf("after Reset: Get() = %d, want 0", count)
+	}
+	if count, _, _ := store.Hit(ctx, "key", time.Minute); count != 1 {
+		t.Errorf("after Reset: Hit() = %d, want 1", count)
+	}
+}
@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// recoveryAlphabet leaves out characters that are easy to misread
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes returns n single-use codes formatted as xxxxx-xxxxx.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	size := big.NewInt(int64(len(recoveryAlphabet)))
+	for i := range codes {
+		var b strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			idx, err := rand.Int(rand.Reader, size)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteByte(recoveryAlphabet[idx.Int64()])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the value stored for a recovery code. The codes
+// are random with about 49 bits of entropy, so a fast hash is enough.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// QRCodePNG renders the provisioning URI as a PNG QR code.
+func QRCodePNG(uri string) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, 256)
+}
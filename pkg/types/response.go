@@ -1,8 +1,24 @@
 package types
 
-// type struct for pagination response generic type page, total_page, data
-type PaginationResponse struct {
-	Page      int         `json:"page"`
-	TotalPage int         `json:"total_page"`
-	Data      interface{} `json:"data"`
+// Response is the envelope used by every endpoint, for both success and error
+// responses.
+type Response struct {
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Data      any             `json:"data,omitempty"`
+	Meta      *PaginationMeta `json:"meta,omitempty"`
+	Errors    []FieldError    `json:"errors,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// type struct for pagination meta page, total_page
+type PaginationMeta struct {
+	Page      int `json:"page"`
+	TotalPage int `json:"total_page"`
 }
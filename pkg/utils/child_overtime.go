@@ -2,10 +2,9 @@ package utils
 
 import "time"
 
-func CalculateChildMorningOvertime(inputTime time.Time) int {
-	// cutoff 07:45:00
-	cutoff := time.Date(inputTime.Year(), inputTime.Month(), inputTime.Day(), 7, 45, 0, 0, inputTime.Location())
-
+// CalculateChildMorningOvertime counts the started 15 minute blocks the
+// child arrived before the cutoff, 15 minutes before the center opens
+func CalculateChildMorningOvertime(inputTime time.Time, cutoff time.Time) int {
 	if inputTime.Before(cutoff) {
 		diff := cutoff.Sub(inputTime)
 		totalMinutes := int(diff.Minutes())
@@ -15,10 +14,9 @@ func CalculateChildMorningOvertime(inputTime time.Time) int {
 	return 0
 }
 
-func CalculateChildEveningOvertime(inputTime time.Time) int {
-	// cutoff 16:15:00
-	cutoff := time.Date(inputTime.Year(), inputTime.Month(), inputTime.Day(), 16, 15, 0, 0, inputTime.Location())
-
+// CalculateChildEveningOvertime counts the started 15 minute blocks the
+// child left after the cutoff, 15 minutes after the center closes
+func CalculateChildEveningOvertime(inputTime time.Time, cutoff time.Time) int {
 	if inputTime.After(cutoff) {
 		diff := inputTime.Sub(cutoff)
 		totalMinutes := int(diff.Minutes())
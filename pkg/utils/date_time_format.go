@@ -5,9 +5,13 @@ import (
 	"time"
 )
 
+// Dates and times from requests are local wall-clock values, the same
+// location the database connection uses (loc=Local), so a date keeps its
+// calendar day when stored.
+
 // convert string to time.Time
 func ParseDateStringToTime(dateStr string) (*time.Time, error) {
-	date, err := time.Parse("2006-01-02", dateStr)
+	date, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format. use YYYY-MM-DD")
 	}
@@ -15,7 +19,7 @@ func ParseDateStringToTime(dateStr string) (*time.Time, error) {
 }
 
 func ParseDateTimeStringToTime(dateTimeStr string) (*time.Time, error) {
-	dateTime, err := time.Parse("2006-01-02 15:04:05", dateTimeStr)
+	dateTime, err := time.ParseInLocation("2006-01-02 15:04:05", dateTimeStr, time.Local)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date time format. use YYYY-MM-DD HH:mm:ss")
 	}
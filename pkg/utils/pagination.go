@@ -24,6 +24,21 @@ func GetPageAndLimitFromQuery(c *fiber.Ctx) (page, limit int) {
 	return page, limit
 }
 
+// MaxPageLimit caps the page size accepted by ClampPagination
+const MaxPageLimit = 100
+
+// ClampPagination keeps page and limit in a range the paginated queries can
+// use without dividing by zero or loading whole tables.
+func ClampPagination(paginationFilter types.PaginationFilter) types.PaginationFilter {
+	if paginationFilter.Page < 1 {
+		paginationFilter.Page = 1
+	}
+	if paginationFilter.Limit < 1 || paginationFilter.Limit > MaxPageLimit {
+		paginationFilter.Limit = MaxPageLimit
+	}
+	return paginationFilter
+}
+
 func GetOrderByAndSortFromQuery(c *fiber.Ctx) (orderBy, sort string) {
 	orderBy = c.Query("orderBy")
 	if orderBy == "" {
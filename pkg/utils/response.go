@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/logger"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+)
+
+func GetRequestID(c *fiber.Ctx) string {
+	return logger.RequestIDFrom(c.UserContext())
+}
+
+func SendSuccess(c *fiber.Ctx, status int, message string, data any) error {
+	return c.Status(status).JSON(types.Response{
+		Code:      "OK",
+		Message:   message,
+		Data:      data,
+		RequestID: GetRequestID(c),
+	})
+}
+
+func SendPaginated(c *fiber.Ctx, page, totalPage int, data any) error {
+	return c.Status(fiber.StatusOK).JSON(types.Response{
+		Code:      "OK",
+		Message:   "success",
+		Data:      data,
+		Meta:      &types.PaginationMeta{Page: page, TotalPage: totalPage},
+		RequestID: GetRequestID(c),
+	})
+}
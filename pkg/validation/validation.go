@@ -0,0 +1,151 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/whyaji/daycare-preschool-api/pkg/apperror"
+	"github.com/whyaji/daycare-preschool-api/pkg/types"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their json name so errors match the request body
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
+
+// ParseBody parses the request body (JSON or form) into out and validates it
+// with the `validate` struct tags. All field errors are collected into a
+// single validation AppError.
+func ParseBody(c *fiber.Ctx, out any) error {
+	if err := c.BodyParser(out); err != nil {
+		return apperror.BadRequest("invalid request body").Wrap(err)
+	}
+	return Struct(out)
+}
+
+// Struct validates s and returns nil or a validation AppError.
+func Struct(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return apperror.Internal(err)
+	}
+
+	fields := make([]types.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, types.FieldError{
+			Field:   fieldPath(fe),
+			Message: message(fe),
+		})
+	}
+	return apperror.Validation(fields...)
+}
+
+// fieldPath drops the top-level struct name, e.g. "CreateChildRequest.parents[0]"
+// becomes "parents[0]".
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func message(fe validator.FieldError) string {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must match format %s", field, humanLayout(fe.Param()))
+	case "min":
+		if isCollection(fe) {
+			return fmt.Sprintf("%s must contain at least %s item(s)", field, fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at least %s characters", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		if isCollection(fe) {
+			return fmt.Sprintf("%s must contain at most %s item(s)", field, fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at most %s characters", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "gte":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, fe.Param())
+	case "lte":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, fe.Param())
+	case "latitude":
+		return fmt.Sprintf("%s must be a valid latitude", field)
+	case "longitude":
+		return fmt.Sprintf("%s must be a valid longitude", field)
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicates", field)
+	case "len":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be exactly %s characters", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must have length %s", field, fe.Param())
+	case "numeric":
+		return fmt.Sprintf("%s must contain only digits", field)
+	case "gtefield":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, lowerFirst(fe.Param()))
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is empty", field, lowerFirst(fe.Param()))
+	case "required_if":
+		other, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("%s is required when %s is %s", field, lowerFirst(other), value)
+	}
+	return fmt.Sprintf("%s is invalid (%s)", field, fe.Tag())
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func isCollection(fe validator.FieldError) bool {
+	return fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map || fe.Kind() == reflect.Array
+}
+
+// humanLayout turns a Go time layout into the format clients know.
+func humanLayout(layout string) string {
+	return strings.NewReplacer(
+		"2006", "YYYY", "01", "MM", "02", "DD",
+		"15", "HH", "04", "mm", "05", "ss",
+	).Replace(layout)
+}
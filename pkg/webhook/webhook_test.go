@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"whsec_test", 1740000000, `{"type":"teacher_attendance.closed"}`, "sha256=dd91d80045578b119f2732f4e759e19d780a389e24bd3c7713d546f3fdc994b7"},
+		{"whsec_test", 1740000000, "", "sha256=37fbd456c92969e2e212b72935ae07f61324d3f76fde7d70e6ff5a2cfdb52260"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("Sign(%q, %d, %q) = %s, want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+
+	// The timestamp and the secret are part of the signature
+	body := []byte(`{"id":1}`)
+	base := Sign("whsec_test", 1740000000, body)
+	if Sign("whsec_test", 1740000001, body) == base {
+		t.Error("Sign ignores the timestamp")
+	}
+	if Sign("whsec_other", 1740000000, body) == base {
+		t.Error("Sign ignores the secret")
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	body := []byte(`{"type":"teacher_attendance.closed"}`)
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Write([]byte("received"))
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	client.now = func() time.Time { return time.Unix(1740000000, 0) }
+	response, err := client.Send(context.Background(), Request{
+		URL: server.URL, Secret: "whsec_test", EventID: 42, EventType: "teacher_attendance.closed", Body: body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != http.StatusOK || response.Body != "received" {
+		t.Errorf("response = %+v", response)
+	}
+
+	if string(gotBody) != string(body) {
+		t.Errorf("body = %s, want %s", gotBody, body)
+	}
+	headers := map[string]string{
+		"X-Webhook-Id":        "42",
+		"X-Webhook-Event":     "teacher_attendance.closed",
+		"X-Webhook-Timestamp": "1740000000",
+		"X-Webhook-Signature": "sha256=dd91d80045578b119f2732f4e759e19d780a389e24bd3c7713d546f3fdc994b7",
+	}
+	for name, want := range headers {
+		if value := got.Header.Get(name); value != want {
+			t.Errorf("%s = %q, want %q", name, value, want)
+		}
+	}
+}
+
+func TestSendFailsOutside2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		case "/elsewhere":
+			t.Error("the redirect was followed")
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", 3*maxResponseBody)))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	response, err := client.Send(context.Background(), Request{URL: server.URL + "/fail", Secret: "s"})
+	if err == nil || response.Status != http.StatusInternalServerError {
+		t.Errorf("Send() = %+v, %v, want a 500 with an error", response, err)
+	}
+	if len(response.Body) != maxResponseBody {
+		t.Errorf("kept %d bytes of the response, want %d", len(response.Body), maxResponseBody)
+	}
+
+	response, err = client.Send(context.Background(), Request{URL: server.URL + "/redirect", Secret: "s"})
+	if err == nil || response.Status != http.StatusFound {
+		t.Errorf("Send() = %+v, %v, want a 302 with an error", response, err)
+	}
+}
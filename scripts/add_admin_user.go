@@ -26,7 +26,7 @@ func addAdminUser(db *gorm.DB) error {
 	}
 
 	var adminRole domain.Role
-	if err := db.Where("name = ?", "admin").First(&adminRole).Error; err != nil {
+	if err := db.Where("name = ?", domain.RoleAdmin).First(&adminRole).Error; err != nil {
 		return err
 	}
 
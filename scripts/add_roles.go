@@ -16,11 +16,13 @@ func RunAddRoles(db *gorm.DB) {
 	log.Println("Roles added successfully! 🚀")
 }
 
-// AddRoles adds roles to the database
+// AddRoles adds the system roles to the database, skipping existing ones
 func addRoles(db *gorm.DB) error {
-	roles := []string{"teacher", "parent", "admin", "psychologist"}
-	for _, role := range roles {
-		db.Create(&domain.Role{Name: role})
+	for _, name := range domain.SystemRoles {
+		role := domain.Role{Name: name}
+		if err := db.Where(domain.Role{Name: name}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -2,8 +2,10 @@ package scripts
 
 import (
 	"log"
+	"time"
 
 	"github.com/whyaji/daycare-preschool-api/internal/domain"
+	"github.com/whyaji/daycare-preschool-api/pkg/database"
 	"gorm.io/gorm"
 )
 
@@ -16,12 +18,35 @@ func RunMigrations(db *gorm.DB) {
 	log.Println("Database migration completed successfully! 🚀")
 }
 
+// enrollmentsSchemaVersion is the schema version that introduced enrollments
+const enrollmentsSchemaVersion = 6
+
 func migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	previous, err := appliedSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if err := dedupeRoles(db); err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(
 		&domain.RegisteredEmail{},
 		&domain.User{},
+		&domain.UserTOTP{},
+		&domain.UserRecoveryCode{},
 		&domain.Role{},
+		&domain.Permission{},
 		&domain.Child{},
+		&domain.Classroom{},
+		&domain.ClassroomTeacher{},
+		&domain.ClassroomAlert{},
+		&domain.Enrollment{},
+		&domain.EnrollmentEvent{},
+		&domain.ChildAbsence{},
+		&domain.CalendarDay{},
+		&domain.CalendarEvent{},
+		&domain.CalendarFeedToken{},
 		&domain.TeacherAttendance{},
 		&domain.ChildAttendance{},
 		&domain.ChildDiary{},
@@ -29,7 +54,100 @@ func migrate(db *gorm.DB) error {
 		&domain.ChildSleep{},
 		&domain.ChildToilet{},
 		&domain.ChildCondition{},
+		&domain.ChildMedicalProfile{},
+		&domain.ChildAllergy{},
+		&domain.ChildMedication{},
+		&domain.ChildImmunization{},
+		&domain.ChildEmergencyContact{},
+		&domain.MedicationRequest{},
+		&domain.MedicationAdministration{},
+		&domain.Incident{},
+		&domain.IncidentWitness{},
+		&domain.IncidentPhoto{},
+		&domain.Attachment{},
+		&domain.PhotoConsent{},
+		&domain.PhotoPost{},
+		&domain.PhotoReaction{},
+		&domain.PhotoComment{},
+		&domain.MessageThread{},
+		&domain.Message{},
+		&domain.MessageRead{},
+		&domain.Notification{},
+		&domain.NotificationDelivery{},
+		&domain.NotificationPreference{},
+		&domain.NotificationSettings{},
+		&domain.PushDevice{},
+		&domain.OutboxEvent{},
+		&domain.WebhookSubscription{},
+		&domain.WebhookDelivery{},
+		&domain.Job{},
 		&domain.LeaveRequest{},
 		&domain.WorkLocation{},
+		&domain.SchemaMigration{},
 	)
+	if err != nil {
+		return err
+	}
+	// Children created since have their own enrollment, the backfill only
+	// runs when upgrading from a schema without enrollments
+	if previous < enrollmentsSchemaVersion {
+		if err := backfillEnrollments(db); err != nil {
+			return err
+		}
+	}
+
+	// Record the schema version so the API readiness check can verify it
+	migration := domain.SchemaMigration{Version: database.SchemaVersion, AppliedAt: time.Now()}
+	return db.Where(domain.SchemaMigration{Version: database.SchemaVersion}).FirstOrCreate(&migration).Error
+}
+
+// appliedSchemaVersion returns the highest schema version recorded before
+// this migration, 0 on a new database
+func appliedSchemaVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&domain.SchemaMigration{}) {
+		return 0, nil
+	}
+	var version uint
+	err := db.Model(&domain.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// dedupeRoles merges roles with the same name into the one with the lowest
+// id before the unique index on roles.name is created. Earlier versions of
+// the add_roles script inserted the roles again on every run.
+func dedupeRoles(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&domain.Role{}) {
+		return nil
+	}
+
+	const keep = "(SELECT name, MIN(id) AS keep_id FROM roles GROUP BY name)"
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, joinTable := range []string{"user_roles", "registered_email_roles"} {
+			if !tx.Migrator().HasTable(joinTable) {
+				continue
+			}
+			// Repoint links to the kept role, then drop the links that
+			// already existed for it
+			if err := tx.Exec("UPDATE IGNORE " + joinTable + " j JOIN roles r ON r.id = j.role_id JOIN " + keep + " k ON k.name = r.name SET j.role_id = k.keep_id WHERE j.role_id <> k.keep_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE j FROM " + joinTable + " j JOIN roles r ON r.id = j.role_id JOIN " + keep + " k ON k.name = r.name WHERE j.role_id <> k.keep_id").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("DELETE r FROM roles r JOIN " + keep + " k ON k.name = r.name WHERE r.id <> k.keep_id").Error
+	})
+}
+
+// backfillEnrollments enrolls the children created before enrollments
+// existed from their registered date, so their attendance is not rejected.
+// Their programme and schedule default to full-day daycare on weekdays and
+// should be reviewed.
+func backfillEnrollments(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO enrollments (child_id, classroom_id, status, programme, session, schedule_days, start_date, priority, notes, created_at, updated_at)
+		SELECT c.id, c.classroom_id, ?, ?, ?, ?, DATE(c.registered_date), 0, '', NOW(), NOW()
+		FROM children c
+		WHERE c.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM enrollments e WHERE e.child_id = c.id)`,
+		domain.EnrollmentEnrolled, domain.ProgrammeDaycare, domain.SessionFullDay, "mon,tue,wed,thu,fri",
+	).Error
 }
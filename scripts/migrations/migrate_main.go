@@ -9,9 +9,11 @@ import (
 )
 
 func main() {
-	// Load env variables
-	config.LoadEnv()
-	cfg := config.GetConfig()
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Connect to database
 	db, err := database.ConnectDb(cfg)